@@ -0,0 +1,432 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/anycable/anycable-go/broker"
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/server"
+	"github.com/anycable/anycable-go/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/joomcode/errorx"
+)
+
+const (
+	adminKeyPhrase = "admin-cable"
+
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+
+	defaultStreamsLimit = 10
+	maxStreamsLimit     = 1000
+
+	// How long to wait for other nodes to respond to cluster-wide queries
+	clusterQueryTimeout = time.Second
+)
+
+// Inspector provides information about the connected sessions and their subscriptions
+type Inspector interface {
+	Inspect(query *common.InspectQuery) (*common.InspectResult, error)
+	InspectCluster(ctx context.Context, query *common.InspectQuery) ([]*common.InspectResult, error)
+}
+
+type historyEntry struct {
+	Offset uint64 `json:"offset"`
+	Data   string `json:"data"`
+}
+
+type historyResponse struct {
+	Stream  string          `json:"stream"`
+	Epoch   string          `json:"epoch"`
+	Entries []*historyEntry `json:"entries"`
+}
+
+type streamsResponse struct {
+	Streams []*broker.StreamInfo `json:"streams"`
+}
+
+type sessionsResponse struct {
+	Sessions []*common.SessionInfo `json:"sessions"`
+}
+
+type sessionResponse struct {
+	Session *common.SessionInfo `json:"session"`
+}
+
+type subscribersResponse struct {
+	Streams []*common.StreamSubscribersInfo `json:"streams"`
+}
+
+type epochResponse struct {
+	Epoch string `json:"epoch"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server provides HTTP API to inspect and manage the server state (e.g., streams history, sessions)
+type Server struct {
+	conf       *Config
+	broker     broker.Broker
+	inspector  Inspector
+	authHeader string
+	server     *server.HTTPServer
+	log        *slog.Logger
+}
+
+// NewServer builds a new admin API server
+func NewServer(b broker.Broker, i Inspector, config *Config, l *slog.Logger) *Server {
+	return &Server{
+		conf:      config,
+		broker:    b,
+		inspector: i,
+		log:       l.With("context", "admin"),
+	}
+}
+
+// Prepare calculates the authentication token.
+// Admin API must always be protected, so a secret is required.
+func (s *Server) Prepare() error {
+	if s.conf.Secret == "" && s.conf.SecretBase != "" {
+		secret, err := utils.NewMessageVerifier(s.conf.SecretBase).Sign([]byte(adminKeyPhrase))
+
+		if err != nil {
+			return errorx.Decorate(err, "failed to auto-generate authentication key for admin API")
+		}
+
+		s.log.Info("auto-generated authorization secret from the application secret")
+		s.conf.Secret = string(secret)
+	}
+
+	if s.conf.Secret == "" {
+		return errors.New("admin API requires a secret to be configured")
+	}
+
+	s.authHeader = fmt.Sprintf("Bearer %s", s.conf.Secret)
+
+	return nil
+}
+
+// Start attaches admin API handlers to the HTTP server (and starts it if necessary)
+func (s *Server) Start(done chan (error)) error {
+	err := s.Prepare()
+	if err != nil {
+		return err
+	}
+
+	srv, err := server.ForPort(strconv.Itoa(s.conf.Port))
+	if err != nil {
+		return err
+	}
+
+	s.server = srv
+	s.server.SetupHandler(s.conf.Path+"/*", s.Handler())
+
+	s.log.Info(fmt.Sprintf("Handle admin API requests at %s%s (authorization required)", s.server.Address(), s.conf.Path))
+
+	go func() {
+		if err := s.server.StartAndAnnounce("admin HTTP server"); err != nil {
+			if !s.server.Stopped() {
+				done <- fmt.Errorf("admin HTTP server at %s stopped: %v", s.server.Address(), err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Shutdown stops the HTTP server
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.server != nil {
+		s.server.Shutdown(ctx) //nolint:errcheck
+	}
+
+	return nil
+}
+
+// Handler returns the admin API HTTP handler
+func (s *Server) Handler() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(s.authenticate)
+
+	r.Get(s.conf.Path+"/streams", s.listStreams)
+	r.Get(s.conf.Path+"/streams/{stream}/history", s.readHistory)
+	r.Delete(s.conf.Path+"/streams/{stream}/history", s.deleteHistory)
+	r.Post(s.conf.Path+"/epoch", s.rotateEpoch)
+	r.Get(s.conf.Path+"/sessions", s.listSessions)
+	r.Get(s.conf.Path+"/sessions/{sid}", s.showSession)
+	r.Get(s.conf.Path+"/subscribers", s.listSubscribers)
+
+	return r
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != s.authHeader {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) listStreams(w http.ResponseWriter, r *http.Request) {
+	admin, ok := s.historyAdmin(w)
+	if !ok {
+		return
+	}
+
+	streams, err := admin.Streams()
+
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, &streamsResponse{Streams: streams})
+}
+
+func (s *Server) readHistory(w http.ResponseWriter, r *http.Request) {
+	admin, ok := s.historyAdmin(w)
+	if !ok {
+		return
+	}
+
+	stream, err := streamParam(r)
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, &errorResponse{Error: err.Error()})
+		return
+	}
+
+	var offset uint64
+
+	if val := r.URL.Query().Get("offset"); val != "" {
+		offset, err = strconv.ParseUint(val, 10, 64)
+
+		if err != nil {
+			s.writeJSON(w, http.StatusBadRequest, &errorResponse{Error: "invalid offset"})
+			return
+		}
+	}
+
+	limit := defaultHistoryLimit
+
+	if val := r.URL.Query().Get("limit"); val != "" {
+		limit, err = strconv.Atoi(val)
+
+		if err != nil || limit <= 0 {
+			s.writeJSON(w, http.StatusBadRequest, &errorResponse{Error: "invalid limit"})
+			return
+		}
+	}
+
+	limit = min(limit, maxHistoryLimit)
+
+	history, err := admin.ReadHistory(stream, offset, limit)
+
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	res := &historyResponse{Stream: stream, Entries: make([]*historyEntry, 0, len(history))}
+
+	for _, msg := range history {
+		res.Epoch = msg.Epoch
+		res.Entries = append(res.Entries, &historyEntry{Offset: msg.Offset, Data: msg.Data})
+	}
+
+	s.writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) deleteHistory(w http.ResponseWriter, r *http.Request) {
+	admin, ok := s.historyAdmin(w)
+	if !ok {
+		return
+	}
+
+	stream, err := streamParam(r)
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, &errorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := admin.DeleteHistory(stream); err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	s.log.Info("stream history deleted via admin API", "stream", stream)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) rotateEpoch(w http.ResponseWriter, r *http.Request) {
+	admin, ok := s.historyAdmin(w)
+	if !ok {
+		return
+	}
+
+	epoch, err := admin.RotateEpoch()
+
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	s.log.Info("epoch rotated via admin API", "epoch", epoch)
+
+	s.writeJSON(w, http.StatusOK, &epochResponse{Epoch: epoch})
+}
+
+func (s *Server) listSessions(w http.ResponseWriter, r *http.Request) {
+	identifier := r.URL.Query().Get("identifier")
+
+	if identifier == "" {
+		s.writeJSON(w, http.StatusBadRequest, &errorResponse{Error: "identifier is required"})
+		return
+	}
+
+	results, ok := s.inspect(w, r, &common.InspectQuery{Kind: common.InspectSessionsQuery, Identifier: identifier})
+	if !ok {
+		return
+	}
+
+	res := &sessionsResponse{Sessions: []*common.SessionInfo{}}
+
+	for _, result := range results {
+		res.Sessions = append(res.Sessions, result.Sessions...)
+	}
+
+	s.writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) showSession(w http.ResponseWriter, r *http.Request) {
+	sid := chi.URLParam(r, "sid")
+
+	results, ok := s.inspect(w, r, &common.InspectQuery{Kind: common.InspectSessionQuery, SID: sid})
+	if !ok {
+		return
+	}
+
+	for _, result := range results {
+		if len(result.Sessions) > 0 {
+			s.writeJSON(w, http.StatusOK, &sessionResponse{Session: result.Sessions[0]})
+			return
+		}
+	}
+
+	s.writeJSON(w, http.StatusNotFound, &errorResponse{Error: "session not found"})
+}
+
+func (s *Server) listSubscribers(w http.ResponseWriter, r *http.Request) {
+	limit := defaultStreamsLimit
+
+	if val := r.URL.Query().Get("limit"); val != "" {
+		var err error
+
+		limit, err = strconv.Atoi(val)
+
+		if err != nil || limit <= 0 {
+			s.writeJSON(w, http.StatusBadRequest, &errorResponse{Error: "invalid limit"})
+			return
+		}
+	}
+
+	limit = min(limit, maxStreamsLimit)
+
+	results, ok := s.inspect(w, r, &common.InspectQuery{Kind: common.InspectStreamsQuery, Limit: limit})
+	if !ok {
+		return
+	}
+
+	// Merge counts from all nodes
+	counts := make(map[string]int)
+
+	for _, result := range results {
+		for _, info := range result.Streams {
+			counts[info.Stream] += info.Subscribers
+		}
+	}
+
+	s.writeJSON(w, http.StatusOK, &subscribersResponse{Streams: common.TopStreamsSubscribers(counts, limit)})
+}
+
+// inspect performs the introspection query locally or cluster-wide (if the "cluster" query parameter is set)
+func (s *Server) inspect(w http.ResponseWriter, r *http.Request, query *common.InspectQuery) ([]*common.InspectResult, bool) {
+	if s.inspector == nil {
+		s.writeJSON(w, http.StatusNotImplemented, &errorResponse{Error: "sessions introspection is not available"})
+		return nil, false
+	}
+
+	cluster, _ := strconv.ParseBool(r.URL.Query().Get("cluster"))
+
+	if !cluster {
+		res, err := s.inspector.Inspect(query)
+
+		if err != nil {
+			s.writeError(w, err)
+			return nil, false
+		}
+
+		return []*common.InspectResult{res}, true
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), clusterQueryTimeout)
+	defer cancel()
+
+	results, err := s.inspector.InspectCluster(ctx, query)
+
+	if err != nil {
+		s.writeError(w, err)
+		return nil, false
+	}
+
+	return results, true
+}
+
+func (s *Server) historyAdmin(w http.ResponseWriter) (broker.HistoryAdmin, bool) {
+	admin, ok := s.broker.(broker.HistoryAdmin)
+
+	if !ok {
+		s.writeJSON(w, http.StatusNotImplemented, &errorResponse{Error: "broker doesn't support history management"})
+		return nil, false
+	}
+
+	return admin, true
+}
+
+func (s *Server) writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, broker.ErrStreamNotFound) {
+		s.writeJSON(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+		return
+	}
+
+	s.log.Error("admin API request failed", "error", err)
+	s.writeJSON(w, http.StatusInternalServerError, &errorResponse{Error: err.Error()})
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, val interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(val); err != nil {
+		s.log.Debug("failed to write admin API response", "error", err)
+	}
+}
+
+// streamParam returns the stream name from the URL (stream names may contain slashes, so they must be escaped)
+func streamParam(r *http.Request) (string, error) {
+	return url.PathUnescape(chi.URLParam(r, "stream"))
+}
@@ -0,0 +1,253 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anycable/anycable-go/broker"
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHandler struct{}
+
+func (fakeHandler) Broadcast(msg *common.StreamMessage)                   {}
+func (fakeHandler) ExecuteRemoteCommand(msg *common.RemoteCommandMessage) {}
+
+type fakeInspector struct {
+	nodes map[string]*common.InspectResult
+}
+
+func (i *fakeInspector) Inspect(query *common.InspectQuery) (*common.InspectResult, error) {
+	return i.filter(i.nodes["a"], query), nil
+}
+
+func (i *fakeInspector) InspectCluster(ctx context.Context, query *common.InspectQuery) ([]*common.InspectResult, error) {
+	return []*common.InspectResult{i.filter(i.nodes["a"], query), i.filter(i.nodes["b"], query)}, nil
+}
+
+func (i *fakeInspector) filter(node *common.InspectResult, query *common.InspectQuery) *common.InspectResult {
+	res := &common.InspectResult{Node: node.Node}
+
+	switch query.Kind {
+	case common.InspectSessionsQuery:
+		for _, session := range node.Sessions {
+			if session.Identifiers == query.Identifier {
+				res.Sessions = append(res.Sessions, session)
+			}
+		}
+	case common.InspectSessionQuery:
+		for _, session := range node.Sessions {
+			if session.ID == query.SID {
+				res.Sessions = append(res.Sessions, session)
+			}
+		}
+	case common.InspectStreamsQuery:
+		res.Streams = node.Streams
+	}
+
+	return res
+}
+
+func newTestInspector() *fakeInspector {
+	return &fakeInspector{
+		nodes: map[string]*common.InspectResult{
+			"a": {
+				Node:     "a",
+				Sessions: []*common.SessionInfo{{ID: "s1", Node: "a", Identifiers: "user:1"}, {ID: "s2", Node: "a", Identifiers: "user:2"}},
+				Streams:  []*common.StreamSubscribersInfo{{Stream: "chat", Subscribers: 2}, {Stream: "news", Subscribers: 1}},
+			},
+			"b": {
+				Node:     "b",
+				Sessions: []*common.SessionInfo{{ID: "s3", Node: "b", Identifiers: "user:1"}},
+				Streams:  []*common.StreamSubscribersInfo{{Stream: "news", Subscribers: 3}},
+			},
+		},
+	}
+}
+
+func newTestServer(t *testing.T) (*Server, *broker.Memory) {
+	bconfig := broker.NewConfig()
+	b := broker.NewMemoryBroker(pubsub.NewLegacySubscriber(fakeHandler{}), &bconfig)
+
+	for _, data := range []string{"a", "b", "c"} {
+		b.HandleBroadcast(&common.StreamMessage{Stream: "chat/1", Data: data})
+	}
+
+	b.HandleBroadcast(&common.StreamMessage{Stream: "news", Data: "x"})
+
+	config := NewConfig()
+	config.Secret = "admin-secret"
+
+	s := NewServer(b, nil, &config, slog.Default())
+	require.NoError(t, s.Prepare())
+
+	return s, b
+}
+
+func doRequest(s *Server, method string, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, req)
+
+	return w
+}
+
+func TestServer_Prepare(t *testing.T) {
+	config := NewConfig()
+
+	s := NewServer(nil, nil, &config, slog.Default())
+	require.Error(t, s.Prepare())
+
+	config.SecretBase = "qwerty"
+	require.NoError(t, s.Prepare())
+	assert.NotEmpty(t, config.Secret)
+}
+
+func TestServer_Unauthorized(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	req := httptest.NewRequest("GET", "/_admin/streams", nil)
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestServer_Streams(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	w := doRequest(s, "GET", "/_admin/streams")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res streamsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+
+	require.Len(t, res.Streams, 2)
+	assert.Equal(t, &broker.StreamInfo{Name: "chat/1", Low: 1, High: 3, Entries: 3, Bytes: 3}, res.Streams[0])
+	assert.Equal(t, "news", res.Streams[1].Name)
+}
+
+func TestServer_ReadHistory(t *testing.T) {
+	s, b := newTestServer(t)
+
+	w := doRequest(s, "GET", "/_admin/streams/chat%2F1/history?offset=2&limit=1")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res historyResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+
+	assert.Equal(t, "chat/1", res.Stream)
+	assert.Equal(t, b.GetEpoch(), res.Epoch)
+	require.Len(t, res.Entries, 1)
+	assert.Equal(t, &historyEntry{Offset: 2, Data: "b"}, res.Entries[0])
+
+	w = doRequest(s, "GET", "/_admin/streams/unknown/history")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest(s, "GET", "/_admin/streams/news/history?limit=-1")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestServer_DeleteHistory(t *testing.T) {
+	s, b := newTestServer(t)
+
+	w := doRequest(s, "DELETE", "/_admin/streams/chat%2F1/history")
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	_, err := b.HistoryFrom("chat/1", b.GetEpoch(), 0)
+	require.Error(t, err)
+
+	// Offsets are preserved
+	b.HandleBroadcast(&common.StreamMessage{Stream: "chat/1", Data: "d"})
+
+	history, err := b.ReadHistory("chat/1", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.EqualValues(t, 4, history[0].Offset)
+}
+
+func TestServer_RotateEpoch(t *testing.T) {
+	s, b := newTestServer(t)
+
+	prevEpoch := b.GetEpoch()
+
+	w := doRequest(s, "POST", "/_admin/epoch")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res epochResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+
+	assert.NotEqual(t, prevEpoch, res.Epoch)
+	assert.Equal(t, b.GetEpoch(), res.Epoch)
+}
+
+func TestServer_Sessions(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.inspector = newTestInspector()
+
+	w := doRequest(s, "GET", "/_admin/sessions?identifier=user:1")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res sessionsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+
+	require.Len(t, res.Sessions, 1)
+	assert.Equal(t, "s1", res.Sessions[0].ID)
+
+	w = doRequest(s, "GET", "/_admin/sessions?identifier=user:1&cluster=true")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+
+	require.Len(t, res.Sessions, 2)
+	assert.Equal(t, "b", res.Sessions[1].Node)
+
+	w = doRequest(s, "GET", "/_admin/sessions")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestServer_Session(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.inspector = newTestInspector()
+
+	w := doRequest(s, "GET", "/_admin/sessions/s3")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest(s, "GET", "/_admin/sessions/s3?cluster=true")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res sessionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+
+	assert.Equal(t, "s3", res.Session.ID)
+	assert.Equal(t, "b", res.Session.Node)
+}
+
+func TestServer_Subscribers(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	w := doRequest(s, "GET", "/_admin/subscribers")
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	s.inspector = newTestInspector()
+
+	w = doRequest(s, "GET", "/_admin/subscribers?cluster=true&limit=1")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res subscribersResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+
+	assert.Equal(t, []*common.StreamSubscribersInfo{{Stream: "news", Subscribers: 4}}, res.Streams)
+
+	w = doRequest(s, "GET", "/_admin/subscribers?limit=abc")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
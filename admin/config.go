@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultPath = "/_admin"
+)
+
+// Config contains admin API configuration
+type Config struct {
+	// Enable admin API
+	Enabled bool `toml:"enabled"`
+	// Port to listen on (the main server port is used by default)
+	Port int `toml:"port"`
+	// Path is the admin API endpoints prefix
+	Path string `toml:"path"`
+	// Secret token to authorize requests
+	Secret string `toml:"secret"`
+	// SecretBase is a secret used to generate a token if none provided
+	SecretBase string `toml:"-"`
+}
+
+// NewConfig builds a new config for admin API
+func NewConfig() Config {
+	return Config{
+		Path: defaultPath,
+	}
+}
+
+func (c Config) ToToml() string {
+	var result strings.Builder
+
+	result.WriteString("# Enable admin API\n")
+	if c.Enabled {
+		result.WriteString("enabled = true\n")
+	} else {
+		result.WriteString("# enabled = true\n")
+	}
+
+	result.WriteString("# Admin API server port (the main server port is used by default)\n")
+	if c.Port != 0 {
+		result.WriteString(fmt.Sprintf("port = %d\n", c.Port))
+	} else {
+		result.WriteString("# port = 8080\n")
+	}
+
+	result.WriteString("# Admin API endpoints path prefix\n")
+	result.WriteString(fmt.Sprintf("path = \"%s\"\n", c.Path))
+
+	result.WriteString("# Secret token to authenticate admin API requests\n")
+	if c.Secret != "" {
+		result.WriteString(fmt.Sprintf("secret = \"%s\"\n", c.Secret))
+	} else {
+		result.WriteString("# secret = \"\"\n")
+	}
+
+	result.WriteString("\n")
+
+	return result.String()
+}
@@ -0,0 +1,34 @@
+package broker
+
+import (
+	"errors"
+
+	"github.com/anycable/anycable-go/common"
+)
+
+var ErrStreamNotFound = errors.New("stream not found")
+
+// StreamInfo describes the stream history state
+type StreamInfo struct {
+	Name string `json:"name"`
+	// The lowest available offset
+	Low uint64 `json:"low"`
+	// The latest offset
+	High uint64 `json:"high"`
+	// The number of entries stored in the history
+	Entries int `json:"entries"`
+	// The total size of entries stored in the history (bytes)
+	Bytes int64 `json:"bytes"`
+}
+
+// HistoryAdmin is implemented by brokers which support inspecting and managing streams history
+type HistoryAdmin interface {
+	// Returns the information about streams with history
+	Streams() ([]*StreamInfo, error)
+	// Returns at most limit stream history entries starting from the specified offset (inclusive)
+	ReadHistory(stream string, offset uint64, limit int) ([]common.StreamMessage, error)
+	// Deletes the stream history (offsets are kept, so clients don't receive the same offsets twice)
+	DeleteHistory(stream string) error
+	// Generates a new epoch, so clients can no longer restore history using the previous one
+	RotateEpoch() (string, error)
+}
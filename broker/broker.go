@@ -46,6 +46,59 @@ func WithPresenceInfoOptions(opts *PresenceInfoOptions) PresenceInfoOption {
 	}
 }
 
+// HistoryOptions limits the number of messages retrieved from the history
+type HistoryOptions struct {
+	// Max number of messages to return (zero means no limit)
+	Limit int
+	// Whether to return the latest messages instead of the earliest ones
+	Latest bool
+}
+
+func NewHistoryOptions() *HistoryOptions {
+	return &HistoryOptions{}
+}
+
+type HistoryOption func(*HistoryOptions)
+
+// WithHistoryLimit returns at most limit messages starting from the requested position
+func WithHistoryLimit(limit int) HistoryOption {
+	return func(o *HistoryOptions) {
+		o.Limit = limit
+		o.Latest = false
+	}
+}
+
+// WithLatestHistory returns at most limit latest messages after the requested position
+func WithLatestHistory(limit int) HistoryOption {
+	return func(o *HistoryOptions) {
+		o.Limit = limit
+		o.Latest = true
+	}
+}
+
+// Bounds returns the range of items to return out of the total number
+func (o *HistoryOptions) Bounds(total int) (int, int) {
+	if o == nil || o.Limit <= 0 || total <= o.Limit {
+		return 0, total
+	}
+
+	if o.Latest {
+		return total - o.Limit, total
+	}
+
+	return 0, o.Limit
+}
+
+func buildHistoryOptions(opts []HistoryOption) *HistoryOptions {
+	options := NewHistoryOptions()
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
 // Broker is responsible for:
 // - Managing streams history.
 // - Managing presence information.
@@ -67,9 +120,9 @@ type Broker interface {
 	// (Maybe) unregisters the stream and return its unique identifier
 	Unsubscribe(stream string) string
 	// Retrieves stream messages from history from the specified offset within the specified epoch
-	HistoryFrom(stream string, epoch string, offset uint64) ([]common.StreamMessage, error)
+	HistoryFrom(stream string, epoch string, offset uint64, opts ...HistoryOption) ([]common.StreamMessage, error)
 	// Retrieves stream messages from history from the specified timestamp
-	HistorySince(stream string, ts int64) ([]common.StreamMessage, error)
+	HistorySince(stream string, ts int64, opts ...HistoryOption) ([]common.StreamMessage, error)
 
 	// Saves session's state in cache
 	CommitSession(sid string, session Cacheable) error
@@ -86,6 +139,10 @@ type Broker interface {
 	// record for the presence ID (pid).
 	PresenceRemove(stream string, sid string) (*common.PresenceEvent, error)
 
+	// Updates the presence info for the session's presence record in the stream.
+	// Returns the update event to broadcast to other subscribers.
+	PresenceUpdate(stream string, sid string, info interface{}) (*common.PresenceEvent, error)
+
 	// Retrieves presence information for the stream (counts, records, etc. depending on the options)
 	PresenceInfo(stream string, opts ...PresenceInfoOption) (*common.PresenceInfo, error)
 
@@ -93,15 +150,42 @@ type Broker interface {
 	FinishPresence(sid string) error
 }
 
+// Distributed is implemented by brokers sharing their state (e.g., presence) across the cluster
+type Distributed interface {
+	IsDistributed() bool
+}
+
+// SessionsQuota is implemented by brokers which can limit the number of sessions per key (e.g., connection identifiers).
+// Distributed brokers keep the slots of the connected sessions alive, so slots of crashed nodes expire automatically.
+type SessionsQuota interface {
+	// AcquireSessionQuota atomically adds the session to the key's sessions unless there are already limit sessions.
+	// Returns false if the quota is exceeded.
+	AcquireSessionQuota(key string, sid string, limit int) (bool, error)
+	// ReleaseSessionQuota removes the session from the key's sessions
+	ReleaseSessionQuota(key string, sid string) error
+}
+
+// StreamsMatcher is implemented by brokers that can resolve wildcard streams
+// into the names of the streams with history (see utils.MatchWildcardStream)
+type StreamsMatcher interface {
+	MatchStreams(pattern string) ([]string, error)
+}
+
 // LocalBroker is a single-node broker that can used to store streams data locally
 type LocalBroker interface {
 	Start(done chan (error)) error
 	Shutdown(ctx context.Context) error
 	SetEpoch(epoch string)
 	GetEpoch() string
-	HistoryFrom(stream string, epoch string, offset uint64) ([]common.StreamMessage, error)
-	HistorySince(stream string, ts int64) ([]common.StreamMessage, error)
+	HistoryFrom(stream string, epoch string, offset uint64, opts ...HistoryOption) ([]common.StreamMessage, error)
+	HistorySince(stream string, ts int64, opts ...HistoryOption) ([]common.StreamMessage, error)
+	// Stores the message with the specified offset (or with the next one if seq is zero)
 	Store(stream string, msg []byte, seq uint64, ts time.Time) (uint64, error)
+	// Stores the message and removes the previous message with the same compaction key from the history
+	StoreCompacted(stream string, msg []byte, key string, seq uint64, ts time.Time) (uint64, error)
+
+	HistoryAdmin
+	StreamsMatcher
 }
 
 type StreamsTracker struct {
@@ -209,11 +293,11 @@ func (b *LegacyBroker) Unsubscribe(stream string) string {
 	return stream
 }
 
-func (LegacyBroker) HistoryFrom(stream string, epoch string, offset uint64) ([]common.StreamMessage, error) {
+func (LegacyBroker) HistoryFrom(stream string, epoch string, offset uint64, opts ...HistoryOption) ([]common.StreamMessage, error) {
 	return nil, errors.New("history not supported")
 }
 
-func (LegacyBroker) HistorySince(stream string, ts int64) ([]common.StreamMessage, error) {
+func (LegacyBroker) HistorySince(stream string, ts int64, opts ...HistoryOption) ([]common.StreamMessage, error) {
 	return nil, errors.New("history not supported")
 }
 
@@ -237,6 +321,10 @@ func (LegacyBroker) PresenceRemove(stream string, sid string) (*common.PresenceE
 	return nil, errors.New("presence not supported")
 }
 
+func (LegacyBroker) PresenceUpdate(stream string, sid string, info interface{}) (*common.PresenceEvent, error) {
+	return nil, errors.New("presence not supported")
+}
+
 func (LegacyBroker) PresenceInfo(stream string, opts ...PresenceInfoOption) (*common.PresenceInfo, error) {
 	return nil, errors.New("presence not supported")
 }
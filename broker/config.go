@@ -16,6 +16,14 @@ type Config struct {
 	SessionsTTL int64 `toml:"sessions_ttl"`
 	// Presence expire TTL in seconds (after disconnect)
 	PresenceTTL int64 `toml:"presence_ttl"`
+	// Directory to persist streams history to (memory and NATS brokers)
+	HistoryDir string `toml:"history_dir"`
+	// File to save the memory broker state to on shutdown (and to restore it from on start)
+	SnapshotPath string `toml:"snapshot_path"`
+	// Per-stream history retention policies
+	Retention []RetentionPolicy `toml:"retention"`
+	// Whether wildcard streams are enabled (set from the app config)
+	Wildcards bool `toml:"-"`
 }
 
 func NewConfig() Config {
@@ -47,12 +55,36 @@ func (c Config) ToToml() string {
 	result.WriteString("# Max number of messages to keep in a stream history\n")
 	result.WriteString(fmt.Sprintf("history_limit = %d\n", c.HistoryLimit))
 
+	result.WriteString("# Directory to persist streams history to (keeps history and epoch across restarts)\n")
+	if c.HistoryDir == "" {
+		result.WriteString("# history_dir = \"/var/lib/anycable/history\"\n")
+	} else {
+		result.WriteString(fmt.Sprintf("history_dir = \"%s\"\n", c.HistoryDir))
+	}
+
+	result.WriteString("# File to save the memory broker state to on shutdown (and restore from on start)\n")
+	if c.SnapshotPath == "" {
+		result.WriteString("# snapshot_path = \"/var/lib/anycable/broker.snapshot\"\n")
+	} else {
+		result.WriteString(fmt.Sprintf("snapshot_path = \"%s\"\n", c.SnapshotPath))
+	}
+
 	result.WriteString("# For how long to store sessions state for resumeability (seconds)\n")
 	result.WriteString(fmt.Sprintf("sessions_ttl = %d\n", c.SessionsTTL))
 
 	result.WriteString("# For how long to keep presence information after session disconnect (seconds)\n")
 	result.WriteString(fmt.Sprintf("presence_ttl = %d\n", c.PresenceTTL))
 
+	result.WriteString("# Per-stream history retention policies (the first matching pattern wins)\n")
+	if len(c.Retention) == 0 {
+		result.WriteString("# [[broker.retention]]\n# pattern = \"chat:*\"\n# ttl = 3600\n# limit = 1000\n# max_bytes = 1048576\n")
+		result.WriteString("# [[broker.retention]]\n# pattern = \"cursors:*\"\n# disabled = true\n")
+	} else {
+		for _, policy := range c.Retention {
+			result.WriteString(policy.ToToml())
+		}
+	}
+
 	result.WriteString("\n")
 
 	return result.String()
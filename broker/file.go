@@ -0,0 +1,865 @@
+package broker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anycable/anycable-go/common"
+	nanoid "github.com/matoous/go-nanoid"
+)
+
+var errFileStreamRemoved = errors.New("stream has been removed")
+
+const (
+	fileEpochName     = "EPOCH"
+	fileStreamsDir    = "streams"
+	fileSegmentExt    = ".log"
+	fileCompactPeriod = time.Second
+
+	// offset (8) + timestamp (8) + data length (4) + checksum (4) + compaction key length (2)
+	fileRecordHeaderSize = 26
+	// The max number of entries per segment when history is not limited
+	fileSegmentMaxEntries = 1024
+)
+
+type fileIndexEntry struct {
+	offset    uint64
+	timestamp int64
+	// position of the record data in the segment file
+	pos  int64
+	size uint32
+	// compaction key (if any)
+	key string
+}
+
+// fileSegment is an append-only log file containing stream entries starting from the base offset.
+// We keep an in-memory index of the segment entries (it's rebuilt on load).
+type fileSegment struct {
+	path    string
+	base    uint64
+	size    int64
+	entries []*fileIndexEntry
+}
+
+func (s *fileSegment) last() *fileIndexEntry {
+	if len(s.entries) == 0 {
+		return nil
+	}
+
+	return s.entries[len(s.entries)-1]
+}
+
+type filestream struct {
+	dir      string
+	offset   uint64
+	deadline int64
+	segments []*fileSegment
+	// the last segment file opened for appending
+	active *os.File
+
+	ttl   int64
+	limit int
+	// max total size of entries data (zero means unlimited)
+	maxBytes int64
+	// compaction key -> offset of the latest entry with this key
+	keys map[string]uint64
+	// set when the stream has been removed from disk (so it must not be used anymore)
+	removed bool
+	// broker-wide history stats
+	stats *historyStats
+
+	mu sync.RWMutex
+}
+
+func (fs *filestream) insert(data []byte, key string, offset uint64, t time.Time) (uint64, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.removed {
+		return 0, errFileStreamRemoved
+	}
+
+	if offset == 0 {
+		offset = fs.offset + 1
+	}
+
+	if fs.offset >= offset {
+		return 0, fmt.Errorf("offset %d is already taken", offset)
+	}
+
+	if t == (time.Time{}) {
+		t = time.Now()
+	}
+
+	ts := t.Unix()
+
+	if err := fs.maybeRoll(offset, ts); err != nil {
+		return 0, err
+	}
+
+	segment := fs.segments[len(fs.segments)-1]
+
+	if len(key) > math.MaxUint16 {
+		return 0, fmt.Errorf("compaction key is too long: %d", len(key))
+	}
+
+	// The key is written right after the header, so the checksum covers both the key and data
+	record := make([]byte, fileRecordHeaderSize+len(key)+len(data))
+	copy(record[fileRecordHeaderSize:], key)
+	copy(record[fileRecordHeaderSize+len(key):], data)
+
+	binary.BigEndian.PutUint64(record[0:], offset)
+	binary.BigEndian.PutUint64(record[8:], uint64(ts))
+	binary.BigEndian.PutUint32(record[16:], uint32(len(data)))
+	binary.BigEndian.PutUint32(record[20:], crc32.ChecksumIEEE(record[fileRecordHeaderSize:]))
+	binary.BigEndian.PutUint16(record[24:], uint16(len(key)))
+
+	if _, err := fs.active.Write(record); err != nil {
+		return 0, err
+	}
+
+	segment.entries = append(segment.entries, &fileIndexEntry{
+		offset:    offset,
+		timestamp: ts,
+		pos:       segment.size + fileRecordHeaderSize + int64(len(key)),
+		size:      uint32(len(data)),
+		key:       key,
+	})
+	segment.size += int64(len(record))
+
+	fs.trackKey(key, offset)
+	fs.stats.add(1, int64(len(data)))
+
+	fs.offset = offset
+	// We keep stream alive for 10 times longer than ttl (so we can re-use it and its offset)
+	fs.deadline = time.Now().Add(time.Duration(fs.ttl*10) * time.Second).Unix()
+
+	return offset, nil
+}
+
+// maybeRoll creates a new segment if the active one is full or contains entries to be expired soon
+func (fs *filestream) maybeRoll(offset uint64, ts int64) error {
+	if fs.active != nil && len(fs.segments) > 0 {
+		current := fs.segments[len(fs.segments)-1]
+		maxEntries := fs.limit
+
+		if maxEntries <= 0 {
+			maxEntries = fileSegmentMaxEntries
+		}
+
+		if len(current.entries) == 0 {
+			return nil
+		}
+
+		if len(current.entries) < maxEntries && current.entries[0].timestamp+fs.ttl > ts {
+			return nil
+		}
+	}
+
+	segment := &fileSegment{
+		path: filepath.Join(fs.dir, fmt.Sprintf("%020d%s", offset, fileSegmentExt)),
+		base: offset,
+	}
+
+	f, err := os.OpenFile(segment.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+
+	if err != nil {
+		return err
+	}
+
+	if fs.active != nil {
+		fs.active.Close()
+	}
+
+	fs.active = f
+	fs.segments = append(fs.segments, segment)
+
+	return nil
+}
+
+// available returns index entries which are not expired, compacted or evicted due to retention limits,
+// along with the lowest available offset.
+// Offsets are not necessarily sequential (due to compaction), so limits are applied to the actual entries.
+// Compacted entries don't move the lowest offset, so clients could still catch up using their offsets.
+func (fs *filestream) available(now int64) ([]*fileIndexEntry, uint64) {
+	deadline := now - fs.ttl
+	all := []*fileIndexEntry{}
+
+	for _, segment := range fs.segments {
+		last := segment.last()
+
+		if last == nil || last.timestamp < deadline {
+			continue
+		}
+
+		for _, entry := range segment.entries {
+			if entry.timestamp >= deadline {
+				all = append(all, entry)
+			}
+		}
+	}
+
+	if len(all) == 0 {
+		return all, 0
+	}
+
+	res := []*fileIndexEntry{}
+	start := 0
+	size := int64(0)
+
+	for i := len(all) - 1; i >= 0; i-- {
+		entry := all[i]
+
+		if entry.key != "" && fs.keys[entry.key] != entry.offset {
+			continue
+		}
+
+		// Always keep the latest entry even if it exceeds the size limit
+		if len(res) > 0 && ((fs.limit > 0 && len(res) >= fs.limit) || (fs.maxBytes > 0 && size+int64(entry.size) > fs.maxBytes)) {
+			start = i + 1
+			break
+		}
+
+		res = append(res, entry)
+		size += int64(entry.size)
+	}
+
+	slices.Reverse(res)
+
+	return res, all[start].offset
+}
+
+// trackKey remembers the latest offset for the compaction key
+func (fs *filestream) trackKey(key string, offset uint64) {
+	if key == "" {
+		return
+	}
+
+	if fs.keys == nil {
+		fs.keys = make(map[string]uint64)
+	}
+
+	fs.keys[key] = offset
+}
+
+func (fs *filestream) read(entries []*fileIndexEntry, callback func(e *fileIndexEntry, data []byte)) error {
+	var (
+		segment *fileSegment
+		f       *os.File
+		err     error
+	)
+
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	for _, entry := range entries {
+		if segment == nil || entry.offset < segment.base || (segment.last() != nil && entry.offset > segment.last().offset) {
+			if f != nil {
+				f.Close()
+			}
+
+			segment = fs.segmentFor(entry.offset)
+
+			if segment == nil {
+				return fmt.Errorf("segment not found for offset: %d", entry.offset)
+			}
+
+			f, err = os.Open(segment.path)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		buf := make([]byte, entry.size)
+
+		if _, err := f.ReadAt(buf, entry.pos); err != nil {
+			return err
+		}
+
+		callback(entry, buf)
+	}
+
+	return nil
+}
+
+func (fs *filestream) segmentFor(offset uint64) *fileSegment {
+	i := sort.Search(len(fs.segments), func(i int) bool {
+		return fs.segments[i].base > offset
+	})
+
+	if i == 0 {
+		return nil
+	}
+
+	return fs.segments[i-1]
+}
+
+func (fs *filestream) filterByOffset(offset uint64, opts *HistoryOptions, callback func(e *fileIndexEntry, data []byte)) error {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	entries, low := fs.available(time.Now().Unix())
+
+	if len(entries) == 0 {
+		return fmt.Errorf("stream is empty")
+	}
+
+	if low > offset {
+		return fmt.Errorf("requested offset couldn't be found: %d, lowest: %d", offset, low)
+	}
+
+	if latest := entries[len(entries)-1].offset; latest < offset {
+		return fmt.Errorf("requested offset couldn't be found: %d, latest: %d", offset, latest)
+	}
+
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].offset > offset
+	})
+
+	// Only the requested entries are read from disk
+	entries = entries[i:]
+	from, to := opts.Bounds(len(entries))
+
+	return fs.read(entries[from:to], callback)
+}
+
+func (fs *filestream) filterByTime(since int64, opts *HistoryOptions, callback func(e *fileIndexEntry, data []byte)) error {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	entries, _ := fs.available(time.Now().Unix())
+
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].timestamp >= since
+	})
+
+	// Only the requested entries are read from disk
+	entries = entries[i:]
+	from, to := opts.Bounds(len(entries))
+
+	return fs.read(entries[from:to], callback)
+}
+
+// compact removes segments containing only unavailable entries (the active segment is always kept
+// to preserve the stream offset).
+// If the stream itself has expired, its directory is removed and true is returned.
+func (fs *filestream) compact(now int64) (bool, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.deadline < now {
+		if fs.active != nil {
+			fs.active.Close()
+			fs.active = nil
+		}
+
+		if err := os.RemoveAll(fs.dir); err != nil {
+			return false, err
+		}
+
+		fs.untrack(fs.segments)
+		fs.removed = true
+		fs.segments = nil
+		fs.keys = nil
+
+		return true, nil
+	}
+
+	entries, low := fs.available(now)
+
+	// Everything has expired
+	if len(entries) == 0 {
+		low = fs.offset + 1
+	}
+
+	i := 0
+
+	for _, segment := range fs.segments[:max(len(fs.segments)-1, 0)] {
+		last := segment.last()
+
+		if last != nil && last.offset >= low {
+			break
+		}
+
+		if err := os.Remove(segment.path); err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+
+		for _, entry := range segment.entries {
+			if entry.key != "" && fs.keys[entry.key] == entry.offset {
+				delete(fs.keys, entry.key)
+			}
+		}
+
+		i++
+	}
+
+	fs.untrack(fs.segments[:i])
+	fs.segments = fs.segments[i:]
+
+	return false, nil
+}
+
+// untrack excludes the removed segments entries from the history stats
+func (fs *filestream) untrack(segments []*fileSegment) {
+	for _, segment := range segments {
+		size := int64(0)
+
+		for _, entry := range segment.entries {
+			size += int64(entry.size)
+		}
+
+		fs.stats.add(-len(segment.entries), -size)
+	}
+}
+
+func (fs *filestream) close() {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.active != nil {
+		fs.active.Sync() // nolint:errcheck
+		fs.active.Close()
+		fs.active = nil
+	}
+}
+
+// load reads stream segments from disk and rebuilds the index.
+// Partially written (or corrupted) records at the end of the last segment are truncated.
+func (fs *filestream) load() error {
+	files, err := os.ReadDir(fs.dir)
+
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		name := file.Name()
+
+		if file.IsDir() || !strings.HasSuffix(name, fileSegmentExt) {
+			continue
+		}
+
+		base, err := strconv.ParseUint(strings.TrimSuffix(name, fileSegmentExt), 10, 64)
+
+		if err != nil {
+			continue
+		}
+
+		fs.segments = append(fs.segments, &fileSegment{path: filepath.Join(fs.dir, name), base: base})
+	}
+
+	sort.Slice(fs.segments, func(i, j int) bool {
+		return fs.segments[i].base < fs.segments[j].base
+	})
+
+	for _, segment := range fs.segments {
+		if err := fs.loadSegment(segment); err != nil {
+			return err
+		}
+
+		if last := segment.last(); last != nil {
+			fs.offset = last.offset
+			fs.deadline = time.Unix(last.timestamp, 0).Add(time.Duration(fs.ttl*10) * time.Second).Unix()
+		}
+	}
+
+	if len(fs.segments) > 0 {
+		segment := fs.segments[len(fs.segments)-1]
+
+		f, err := os.OpenFile(segment.path, os.O_WRONLY|os.O_APPEND, 0o644)
+
+		if err != nil {
+			return err
+		}
+
+		fs.active = f
+	}
+
+	return nil
+}
+
+func (fs *filestream) loadSegment(segment *fileSegment) error {
+	f, err := os.Open(segment.path)
+
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	header := make([]byte, fileRecordHeaderSize)
+	pos := int64(0)
+
+	for {
+		if _, err := f.ReadAt(header, pos); err != nil {
+			if err == io.EOF {
+				break
+			}
+
+			return err
+		}
+
+		offset := binary.BigEndian.Uint64(header[0:])
+		ts := int64(binary.BigEndian.Uint64(header[8:]))
+		size := binary.BigEndian.Uint32(header[16:])
+		checksum := binary.BigEndian.Uint32(header[20:])
+		keySize := binary.BigEndian.Uint16(header[24:])
+
+		payload := make([]byte, int(keySize)+int(size))
+
+		if _, err := f.ReadAt(payload, pos+fileRecordHeaderSize); err != nil || crc32.ChecksumIEEE(payload) != checksum {
+			break
+		}
+
+		key := string(payload[:keySize])
+
+		segment.entries = append(segment.entries, &fileIndexEntry{
+			offset:    offset,
+			timestamp: ts,
+			pos:       pos + fileRecordHeaderSize + int64(keySize),
+			size:      size,
+			key:       key,
+		})
+
+		fs.trackKey(key, offset)
+		fs.stats.add(1, int64(size))
+
+		pos += fileRecordHeaderSize + int64(len(payload))
+	}
+
+	segment.size = pos
+
+	if info, err := f.Stat(); err == nil && info.Size() > pos {
+		return os.Truncate(segment.path, pos)
+	}
+
+	return nil
+}
+
+// File is a LocalBroker which persists streams history on disk.
+// Every stream is stored as a sequence of append-only segment files,
+// so history and epoch survive restarts.
+type File struct {
+	config *Config
+	dir    string
+
+	epoch   string
+	streams map[string]*filestream
+	stats   historyStats
+
+	streamsMu sync.RWMutex
+	epochMu   sync.RWMutex
+
+	shutdownCtx context.Context
+	shutdownFn  func()
+
+	log *slog.Logger
+}
+
+var _ LocalBroker = (*File)(nil)
+
+func NewFileBroker(config *Config, dir string, l *slog.Logger) *File {
+	shutdownCtx, shutdownFn := context.WithCancel(context.Background())
+
+	return &File{
+		config:      config,
+		dir:         dir,
+		streams:     make(map[string]*filestream),
+		shutdownCtx: shutdownCtx,
+		shutdownFn:  shutdownFn,
+		log:         l.With("context", "broker").With("provider", "file"),
+	}
+}
+
+func (b *File) Start(done chan (error)) error {
+	if err := os.MkdirAll(filepath.Join(b.dir, fileStreamsDir), 0o755); err != nil {
+		return err
+	}
+
+	if err := b.loadEpoch(); err != nil {
+		return err
+	}
+
+	if err := b.loadStreams(); err != nil {
+		return err
+	}
+
+	go b.compactLoop()
+
+	b.log.Debug("streams history loaded", "dir", b.dir, "streams", len(b.streams), "epoch", b.GetEpoch())
+
+	return nil
+}
+
+func (b *File) Shutdown(ctx context.Context) error {
+	b.shutdownFn()
+
+	b.streamsMu.Lock()
+	defer b.streamsMu.Unlock()
+
+	for _, stream := range b.streams {
+		stream.close()
+	}
+
+	return nil
+}
+
+func (b *File) GetEpoch() string {
+	b.epochMu.RLock()
+	defer b.epochMu.RUnlock()
+
+	return b.epoch
+}
+
+func (b *File) SetEpoch(v string) {
+	b.epochMu.Lock()
+	defer b.epochMu.Unlock()
+
+	if b.epoch == v {
+		return
+	}
+
+	b.epoch = v
+
+	// Epoch could be set before the broker is started (e.g., by NATS broker)
+	if err := os.MkdirAll(b.dir, 0o755); err != nil {
+		b.log.Error("failed to persist epoch", "error", err)
+		return
+	}
+
+	if err := os.WriteFile(filepath.Join(b.dir, fileEpochName), []byte(v), 0o644); err != nil {
+		b.log.Error("failed to persist epoch", "error", err)
+	}
+}
+
+func (b *File) HistoryFrom(name string, epoch string, offset uint64, opts ...HistoryOption) ([]common.StreamMessage, error) {
+	bepoch := b.GetEpoch()
+
+	if bepoch != epoch {
+		return nil, fmt.Errorf("unknown epoch: %s, current: %s", epoch, bepoch)
+	}
+
+	stream := b.get(name)
+
+	if stream == nil {
+		return nil, ErrStreamNotFound
+	}
+
+	history := []common.StreamMessage{}
+
+	err := stream.filterByOffset(offset, buildHistoryOptions(opts), func(entry *fileIndexEntry, data []byte) {
+		history = append(history, common.StreamMessage{
+			Stream: name,
+			Data:   string(data),
+			Offset: entry.offset,
+			Epoch:  bepoch,
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+func (b *File) HistorySince(name string, ts int64, opts ...HistoryOption) ([]common.StreamMessage, error) {
+	stream := b.get(name)
+
+	if stream == nil {
+		return nil, nil
+	}
+
+	bepoch := b.GetEpoch()
+	history := []common.StreamMessage{}
+
+	err := stream.filterByTime(ts, buildHistoryOptions(opts), func(entry *fileIndexEntry, data []byte) {
+		history = append(history, common.StreamMessage{
+			Stream: name,
+			Data:   string(data),
+			Offset: entry.offset,
+			Epoch:  bepoch,
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+func (b *File) Store(name string, data []byte, offset uint64, ts time.Time) (uint64, error) {
+	return b.StoreCompacted(name, data, "", offset, ts)
+}
+
+func (b *File) StoreCompacted(name string, data []byte, key string, offset uint64, ts time.Time) (uint64, error) {
+	for {
+		stream, err := b.fetchOrCreate(name)
+
+		if err != nil {
+			return 0, err
+		}
+
+		res, err := stream.insert(data, key, offset, ts)
+
+		// The stream has been removed concurrently, so we must create a new one
+		if err == errFileStreamRemoved {
+			continue
+		}
+
+		return res, err
+	}
+}
+
+func (b *File) fetchOrCreate(name string) (*filestream, error) {
+	b.streamsMu.Lock()
+	defer b.streamsMu.Unlock()
+
+	if stream, ok := b.streams[name]; ok {
+		return stream, nil
+	}
+
+	dir := filepath.Join(b.dir, fileStreamsDir, base64.RawURLEncoding.EncodeToString([]byte(name)))
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	stream := b.newStream(name, dir)
+	b.streams[name] = stream
+
+	return stream, nil
+}
+
+func (b *File) newStream(name string, dir string) *filestream {
+	retention := b.config.RetentionFor(name)
+
+	return &filestream{
+		dir:      dir,
+		ttl:      retention.TTL,
+		limit:    retention.Limit,
+		maxBytes: retention.MaxBytes,
+		stats:    &b.stats,
+	}
+}
+
+func (b *File) get(name string) *filestream {
+	b.streamsMu.RLock()
+	defer b.streamsMu.RUnlock()
+
+	return b.streams[name]
+}
+
+func (b *File) loadEpoch() error {
+	path := filepath.Join(b.dir, fileEpochName)
+
+	data, err := os.ReadFile(path)
+
+	if err == nil && len(data) > 0 {
+		b.epochMu.Lock()
+		b.epoch = strings.TrimSpace(string(data))
+		b.epochMu.Unlock()
+		return nil
+	}
+
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	epoch, _ := nanoid.Nanoid(4)
+
+	b.SetEpoch(epoch)
+
+	return nil
+}
+
+func (b *File) loadStreams() error {
+	root := filepath.Join(b.dir, fileStreamsDir)
+
+	dirs, err := os.ReadDir(root)
+
+	if err != nil {
+		return err
+	}
+
+	b.streamsMu.Lock()
+	defer b.streamsMu.Unlock()
+
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+
+		name, err := base64.RawURLEncoding.DecodeString(dir.Name())
+
+		if err != nil {
+			b.log.Warn("unknown stream directory", "dir", dir.Name())
+			continue
+		}
+
+		stream := b.newStream(string(name), filepath.Join(root, dir.Name()))
+
+		if err := stream.load(); err != nil {
+			return fmt.Errorf("failed to load stream %s: %w", name, err)
+		}
+
+		b.streams[string(name)] = stream
+	}
+
+	return nil
+}
+
+func (b *File) compactLoop() {
+	ticker := time.NewTicker(fileCompactPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.shutdownCtx.Done():
+			return
+		case <-ticker.C:
+			b.compact()
+		}
+	}
+}
+
+func (b *File) compact() {
+	b.streamsMu.Lock()
+	defer b.streamsMu.Unlock()
+
+	now := time.Now().Unix()
+
+	for name, stream := range b.streams {
+		removed, err := stream.compact(now)
+
+		if err != nil {
+			b.log.Error("failed to compact stream", "stream", name, "error", err)
+			continue
+		}
+
+		if removed {
+			delete(b.streams, name)
+		}
+	}
+}
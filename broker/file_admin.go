@@ -0,0 +1,174 @@
+package broker
+
+import (
+	"cmp"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/utils"
+	nanoid "github.com/matoous/go-nanoid"
+)
+
+var _ HistoryAdmin = (*File)(nil)
+
+func (fs *filestream) info(name string) *StreamInfo {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	entries, low := fs.available(time.Now().Unix())
+
+	info := &StreamInfo{Name: name, High: fs.offset, Low: low}
+	info.Entries = len(entries)
+
+	for _, entry := range entries {
+		info.Bytes += int64(entry.size)
+	}
+
+	return info
+}
+
+func (fs *filestream) readFrom(offset uint64, limit int, callback func(e *fileIndexEntry, data []byte)) error {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	entries, _ := fs.available(time.Now().Unix())
+
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].offset >= offset
+	})
+
+	entries = entries[i:]
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	return fs.read(entries, callback)
+}
+
+// clear removes all the stream segments but keeps the current offset
+func (fs *filestream) clear() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.active != nil {
+		fs.active.Close()
+		fs.active = nil
+	}
+
+	for _, segment := range fs.segments {
+		if err := os.Remove(segment.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	fs.untrack(fs.segments)
+	fs.segments = nil
+	fs.keys = nil
+
+	return nil
+}
+
+// historyStats returns stats of the entries stored on disk (including the superseded ones which haven't been removed yet)
+func (b *File) historyStats() (int, int64, int64) {
+	b.streamsMu.RLock()
+	streams := len(b.streams)
+	b.streamsMu.RUnlock()
+
+	return streams, b.stats.entries.Load(), b.stats.bytes.Load()
+}
+
+func (b *File) Streams() ([]*StreamInfo, error) {
+	b.streamsMu.RLock()
+
+	streams := make([]*StreamInfo, 0, len(b.streams))
+
+	for name, stream := range b.streams {
+		streams = append(streams, stream.info(name))
+	}
+
+	b.streamsMu.RUnlock()
+
+	slices.SortFunc(streams, func(a, b *StreamInfo) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return streams, nil
+}
+
+func (b *File) MatchStreams(pattern string) ([]string, error) {
+	b.streamsMu.RLock()
+
+	streams := []string{}
+
+	for name := range b.streams {
+		if utils.MatchWildcardStream(pattern, name) {
+			streams = append(streams, name)
+		}
+	}
+
+	b.streamsMu.RUnlock()
+
+	slices.Sort(streams)
+
+	return streams, nil
+}
+
+func (b *File) ReadHistory(name string, offset uint64, limit int) ([]common.StreamMessage, error) {
+	stream := b.get(name)
+
+	if stream == nil {
+		return nil, ErrStreamNotFound
+	}
+
+	epoch := b.GetEpoch()
+	history := []common.StreamMessage{}
+
+	err := stream.readFrom(offset, limit, func(entry *fileIndexEntry, data []byte) {
+		history = append(history, common.StreamMessage{
+			Stream: name,
+			Data:   string(data),
+			Offset: entry.offset,
+			Epoch:  epoch,
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+func (b *File) DeleteHistory(name string) error {
+	stream := b.get(name)
+
+	if stream == nil {
+		return ErrStreamNotFound
+	}
+
+	if err := stream.clear(); err != nil {
+		return err
+	}
+
+	b.log.Info("stream history deleted", "stream", name)
+
+	return nil
+}
+
+func (b *File) RotateEpoch() (string, error) {
+	epoch, err := nanoid.Nanoid(4)
+
+	if err != nil {
+		return "", err
+	}
+
+	b.SetEpoch(epoch)
+
+	b.log.Info("epoch rotated", "epoch", epoch)
+
+	return epoch, nil
+}
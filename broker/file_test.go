@@ -0,0 +1,369 @@
+package broker
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_Store(t *testing.T) {
+	config := NewConfig()
+	dir := t.TempDir()
+
+	broker := NewFileBroker(&config, dir, slog.Default())
+	require.NoError(t, broker.Start(nil))
+	defer broker.Shutdown(context.Background()) // nolint:errcheck
+
+	ts := time.Now()
+
+	offset, err := broker.Store("test", []byte("a"), 10, ts)
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, offset)
+
+	offset, err = broker.Store("test", []byte("b"), 0, ts)
+	require.NoError(t, err)
+	assert.EqualValues(t, 11, offset)
+
+	_, err = broker.Store("test", []byte("c"), 3, ts)
+	assert.Error(t, err)
+
+	history, err := broker.HistoryFrom("test", broker.GetEpoch(), 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.EqualValues(t, 11, history[0].Offset)
+	assert.Equal(t, "b", history[0].Data)
+
+	_, err = broker.HistoryFrom("test", broker.GetEpoch(), 20)
+	assert.Error(t, err)
+
+	_, err = broker.HistoryFrom("test", "unknown", 10)
+	assert.Error(t, err)
+
+	history, err = broker.HistorySince("test", ts.Unix()-10)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+}
+
+func TestFile_Restart(t *testing.T) {
+	config := NewConfig()
+	config.HistoryLimit = 3
+	dir := t.TempDir()
+
+	broker := NewFileBroker(&config, dir, slog.Default())
+	require.NoError(t, broker.Start(nil))
+
+	for _, data := range []string{"a", "b", "c", "d", "e"} {
+		_, err := broker.Store("test/1", []byte(data), 0, time.Now())
+		require.NoError(t, err)
+	}
+
+	epoch := broker.GetEpoch()
+
+	require.NoError(t, broker.Shutdown(context.Background()))
+
+	restarted := NewFileBroker(&config, dir, slog.Default())
+	require.NoError(t, restarted.Start(nil))
+	defer restarted.Shutdown(context.Background()) // nolint:errcheck
+
+	assert.Equal(t, epoch, restarted.GetEpoch())
+
+	history, err := restarted.HistoryFrom("test/1", epoch, 3)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.EqualValues(t, 4, history[0].Offset)
+	assert.Equal(t, "d", history[0].Data)
+	assert.EqualValues(t, 5, history[1].Offset)
+
+	// Entries beyond the limit are not available
+	_, err = restarted.HistoryFrom("test/1", epoch, 2)
+	require.Error(t, err)
+
+	// Offsets continue from the persisted ones
+	offset, err := restarted.Store("test/1", []byte("f"), 0, time.Now())
+	require.NoError(t, err)
+	assert.EqualValues(t, 6, offset)
+}
+
+func TestFile_Compaction(t *testing.T) {
+	config := NewConfig()
+	dir := t.TempDir()
+
+	broker := NewFileBroker(&config, dir, slog.Default())
+	require.NoError(t, broker.Start(nil))
+
+	ts := time.Now()
+
+	_, err := broker.StoreCompacted("test", []byte("doc 1: draft"), "doc/1", 0, ts)
+	require.NoError(t, err)
+	_, err = broker.StoreCompacted("test", []byte("doc 2: draft"), "doc/2", 0, ts)
+	require.NoError(t, err)
+	_, err = broker.Store("test", []byte("hello"), 0, ts)
+	require.NoError(t, err)
+	_, err = broker.StoreCompacted("test", []byte("doc 1: published"), "doc/1", 0, ts)
+	require.NoError(t, err)
+
+	epoch := broker.GetEpoch()
+
+	require.NoError(t, broker.Shutdown(context.Background()))
+
+	restarted := NewFileBroker(&config, dir, slog.Default())
+	require.NoError(t, restarted.Start(nil))
+	defer restarted.Shutdown(context.Background()) // nolint:errcheck
+
+	history, err := restarted.HistoryFrom("test", epoch, 1)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, "doc 2: draft", history[0].Data)
+	assert.Equal(t, "hello", history[1].Data)
+	assert.EqualValues(t, 4, history[2].Offset)
+	assert.Equal(t, "doc 1: published", history[2].Data)
+
+	history, err = restarted.HistorySince("test", ts.Unix()-10)
+	require.NoError(t, err)
+	assert.Len(t, history, 3)
+}
+
+func TestFile_LimitWithCompaction(t *testing.T) {
+	config := NewConfig()
+	config.HistoryLimit = 2
+	dir := t.TempDir()
+
+	broker := NewFileBroker(&config, dir, slog.Default())
+	require.NoError(t, broker.Start(nil))
+	defer broker.Shutdown(context.Background()) // nolint:errcheck
+
+	ts := time.Now()
+
+	_, err := broker.Store("test", []byte("a"), 0, ts)
+	require.NoError(t, err)
+	_, err = broker.StoreCompacted("test", []byte("doc: draft"), "doc", 0, ts)
+	require.NoError(t, err)
+	_, err = broker.Store("test", []byte("b"), 0, ts)
+	require.NoError(t, err)
+	_, err = broker.StoreCompacted("test", []byte("doc: published"), "doc", 0, ts)
+	require.NoError(t, err)
+
+	// The limit is applied to the entries left after compaction
+	history, err := broker.HistoryFrom("test", broker.GetEpoch(), 2)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "b", history[0].Data)
+	assert.Equal(t, "doc: published", history[1].Data)
+
+	_, err = broker.HistoryFrom("test", broker.GetEpoch(), 1)
+	require.Error(t, err)
+}
+
+func TestFile_MaxBytes(t *testing.T) {
+	config := NewConfig()
+	config.Retention = []RetentionPolicy{{Pattern: "test", MaxBytes: 5}}
+	dir := t.TempDir()
+
+	broker := NewFileBroker(&config, dir, slog.Default())
+	require.NoError(t, broker.Start(nil))
+	defer broker.Shutdown(context.Background()) // nolint:errcheck
+
+	for _, data := range []string{"abc", "de", "fgh"} {
+		_, err := broker.Store("test", []byte(data), 0, time.Now())
+		require.NoError(t, err)
+	}
+
+	history, err := broker.HistorySince("test", time.Now().Unix()-10)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "de", history[0].Data)
+	assert.Equal(t, "fgh", history[1].Data)
+
+	// The latest entry is always kept
+	_, err = broker.Store("test", []byte("ijklmn"), 0, time.Now())
+	require.NoError(t, err)
+
+	history, err = broker.HistorySince("test", time.Now().Unix()-10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "ijklmn", history[0].Data)
+}
+
+func TestFile_RecoverCorruptedTail(t *testing.T) {
+	config := NewConfig()
+	dir := t.TempDir()
+
+	broker := NewFileBroker(&config, dir, slog.Default())
+	require.NoError(t, broker.Start(nil))
+
+	_, err := broker.Store("test", []byte("a"), 0, time.Now())
+	require.NoError(t, err)
+	_, err = broker.Store("test", []byte("b"), 0, time.Now())
+	require.NoError(t, err)
+
+	path := broker.get("test").segments[0].path
+
+	require.NoError(t, broker.Shutdown(context.Background()))
+
+	// Simulate a partially written record
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 0, 1, 2})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	restarted := NewFileBroker(&config, dir, slog.Default())
+	require.NoError(t, restarted.Start(nil))
+	defer restarted.Shutdown(context.Background()) // nolint:errcheck
+
+	offset, err := restarted.Store("test", []byte("c"), 0, time.Now())
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, offset)
+
+	history, err := restarted.HistoryFrom("test", restarted.GetEpoch(), 1)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "b", history[0].Data)
+	assert.Equal(t, "c", history[1].Data)
+}
+
+func TestFile_Compact(t *testing.T) {
+	config := NewConfig()
+	config.HistoryTTL = 1
+	dir := t.TempDir()
+
+	broker := NewFileBroker(&config, dir, slog.Default())
+	require.NoError(t, broker.Start(nil))
+	defer broker.Shutdown(context.Background()) // nolint:errcheck
+
+	start := time.Now().Unix() - 10
+
+	_, err := broker.Store("test", []byte("a"), 0, time.Now().Add(-2*time.Second))
+	require.NoError(t, err)
+	_, err = broker.Store("test", []byte("b"), 0, time.Now().Add(-2*time.Second))
+	require.NoError(t, err)
+	_, err = broker.Store("test", []byte("c"), 0, time.Now())
+	require.NoError(t, err)
+
+	history, err := broker.HistorySince("test", start)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.EqualValues(t, 3, history[0].Offset)
+
+	stream := broker.get("test")
+	require.Len(t, stream.segments, 2)
+
+	broker.compact()
+
+	require.Len(t, stream.segments, 1)
+
+	files, err := filepath.Glob(filepath.Join(stream.dir, "*"+fileSegmentExt))
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestFile_StoreToRemovedStream(t *testing.T) {
+	config := NewConfig()
+	config.HistoryTTL = 1
+	dir := t.TempDir()
+
+	broker := NewFileBroker(&config, dir, slog.Default())
+	require.NoError(t, broker.Start(nil))
+	defer broker.Shutdown(context.Background()) // nolint:errcheck
+
+	_, err := broker.Store("test", []byte("a"), 0, time.Now())
+	require.NoError(t, err)
+
+	stream := broker.get("test")
+
+	// Make the stream expire
+	stream.deadline = time.Now().Unix() - 1
+	broker.compact()
+
+	assert.Nil(t, broker.get("test"))
+
+	_, err = stream.insert([]byte("b"), "", 0, time.Now())
+	require.ErrorIs(t, err, errFileStreamRemoved)
+
+	offset, err := broker.Store("test", []byte("b"), 0, time.Now())
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, offset)
+
+	history, err := broker.HistorySince("test", time.Now().Unix()-10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "b", history[0].Data)
+}
+
+func TestMemory_WithFileHistory(t *testing.T) {
+	config := NewConfig()
+	dir := t.TempDir()
+
+	broker := NewMemoryBroker(nil, &config, WithMemoryHistory(NewFileBroker(&config, dir, slog.Default())))
+	require.NoError(t, broker.Start(nil))
+
+	broker.add("test", "a") // nolint:errcheck
+	broker.add("test", "b") // nolint:errcheck
+
+	epoch := broker.GetEpoch()
+
+	require.NoError(t, broker.Shutdown(context.Background()))
+
+	restarted := NewMemoryBroker(nil, &config, WithMemoryHistory(NewFileBroker(&config, dir, slog.Default())))
+	require.NoError(t, restarted.Start(nil))
+	defer restarted.Shutdown(context.Background()) // nolint:errcheck
+
+	assert.Equal(t, epoch, restarted.GetEpoch())
+
+	history, err := restarted.HistoryFrom("test", epoch, 1)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "b", history[0].Data)
+}
+
+func TestFile_Admin(t *testing.T) {
+	config := NewConfig()
+	dir := t.TempDir()
+
+	broker := NewFileBroker(&config, dir, slog.Default())
+	require.NoError(t, broker.Start(nil))
+	defer broker.Shutdown(context.Background()) // nolint:errcheck
+
+	ts := time.Now()
+
+	for _, data := range []string{"a", "b", "c"} {
+		_, err := broker.Store("test", []byte(data), 0, ts)
+		require.NoError(t, err)
+	}
+
+	streams, err := broker.Streams()
+	require.NoError(t, err)
+	require.Len(t, streams, 1)
+	assert.Equal(t, &StreamInfo{Name: "test", Low: 1, High: 3, Entries: 3, Bytes: 3}, streams[0])
+
+	history, err := broker.ReadHistory("test", 2, 1)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "b", history[0].Data)
+
+	_, err = broker.ReadHistory("unknown", 0, 1)
+	assert.ErrorIs(t, err, ErrStreamNotFound)
+
+	require.NoError(t, broker.DeleteHistory("test"))
+
+	history, err = broker.ReadHistory("test", 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	offset, err := broker.Store("test", []byte("d"), 0, ts)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, offset)
+
+	epoch := broker.GetEpoch()
+
+	newEpoch, err := broker.RotateEpoch()
+	require.NoError(t, err)
+	assert.NotEqual(t, epoch, newEpoch)
+	assert.Equal(t, newEpoch, broker.GetEpoch())
+}
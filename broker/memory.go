@@ -1,14 +1,17 @@
 package broker
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"slices"
 	"sync"
 	"time"
 
 	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/metrics"
 	"github.com/anycable/anycable-go/utils"
 	nanoid "github.com/matoous/go-nanoid"
 )
@@ -17,6 +20,8 @@ type entry struct {
 	timestamp int64
 	offset    uint64
 	data      string
+	// Compaction key (if any)
+	key string
 }
 
 type memstream struct {
@@ -27,11 +32,19 @@ type memstream struct {
 	data  []*entry
 	ttl   int64
 	limit int
+	// Max total size of entries data (zero means unlimited)
+	maxBytes int64
+	// Current total size of entries data
+	bytes int64
+	// Compaction key -> offset of the latest entry with this key
+	keys map[string]uint64
+	// Broker-wide history stats
+	stats *historyStats
 
 	mu sync.RWMutex
 }
 
-func (ms *memstream) add(data string) uint64 {
+func (ms *memstream) add(data string, key string) uint64 {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
@@ -43,6 +56,7 @@ func (ms *memstream) add(data string) uint64 {
 		offset:    ms.offset,
 		timestamp: ts,
 		data:      data,
+		key:       key,
 	}
 
 	ms.appendEntry(entry)
@@ -50,7 +64,7 @@ func (ms *memstream) add(data string) uint64 {
 	return ms.offset
 }
 
-func (ms *memstream) insert(data string, offset uint64, t time.Time) (uint64, error) {
+func (ms *memstream) insert(data string, key string, offset uint64, t time.Time) (uint64, error) {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
@@ -60,6 +74,10 @@ func (ms *memstream) insert(data string, offset uint64, t time.Time) (uint64, er
 
 	ts := t.Unix()
 
+	if offset == 0 {
+		offset = ms.offset + 1
+	}
+
 	if ms.offset >= offset {
 		return 0, fmt.Errorf("offset %d is already taken", offset)
 	}
@@ -70,6 +88,7 @@ func (ms *memstream) insert(data string, offset uint64, t time.Time) (uint64, er
 		offset:    offset,
 		timestamp: ts,
 		data:      data,
+		key:       key,
 	}
 
 	ms.appendEntry(entry)
@@ -78,10 +97,32 @@ func (ms *memstream) insert(data string, offset uint64, t time.Time) (uint64, er
 }
 
 func (ms *memstream) appendEntry(entry *entry) {
+	// Compacted entries are removed from the history, but the lowest offset stays the same,
+	// so clients could still catch up using their offsets
+	if entry.key != "" {
+		if prev, ok := ms.keys[entry.key]; ok {
+			ms.removeEntry(prev)
+		}
+
+		if ms.keys == nil {
+			ms.keys = make(map[string]uint64)
+		}
+
+		ms.keys[entry.key] = entry.offset
+	}
+
 	ms.data = append(ms.data, entry)
+	ms.bytes += int64(len(entry.data))
+	ms.stats.add(1, int64(len(entry.data)))
 
 	if len(ms.data) > ms.limit {
-		ms.data = ms.data[1:]
+		ms.shift(1)
+		ms.low = ms.data[0].offset
+	}
+
+	// Always keep the latest entry even if it exceeds the size limit
+	for ms.maxBytes > 0 && ms.bytes > ms.maxBytes && len(ms.data) > 1 {
+		ms.shift(1)
 		ms.low = ms.data[0].offset
 	}
 
@@ -94,6 +135,35 @@ func (ms *memstream) appendEntry(entry *entry) {
 	ms.deadline = time.Now().Add(time.Duration(ms.ttl*10) * time.Second).Unix()
 }
 
+// shift removes the first n entries from the stream
+func (ms *memstream) shift(n int) {
+	for _, entry := range ms.data[:n] {
+		ms.bytes -= int64(len(entry.data))
+		ms.stats.add(-1, -int64(len(entry.data)))
+
+		if entry.key != "" && ms.keys[entry.key] == entry.offset {
+			delete(ms.keys, entry.key)
+		}
+	}
+
+	ms.data = ms.data[n:]
+}
+
+// removeEntry removes the entry with the specified offset from the stream (if any)
+func (ms *memstream) removeEntry(offset uint64) {
+	i, found := slices.BinarySearchFunc(ms.data, offset, func(e *entry, target uint64) int {
+		return cmp.Compare(e.offset, target)
+	})
+
+	if !found {
+		return
+	}
+
+	ms.bytes -= int64(len(ms.data[i].data))
+	ms.stats.add(-1, -int64(len(ms.data[i].data)))
+	ms.data = slices.Delete(ms.data, i, i+1)
+}
+
 func (ms *memstream) expire() {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
@@ -116,7 +186,7 @@ func (ms *memstream) expire() {
 		return
 	}
 
-	ms.data = ms.data[cutIndex:]
+	ms.shift(cutIndex)
 
 	if len(ms.data) > 0 {
 		ms.low = ms.data[0].offset
@@ -125,7 +195,7 @@ func (ms *memstream) expire() {
 	}
 }
 
-func (ms *memstream) filterByOffset(offset uint64, callback func(e *entry)) error {
+func (ms *memstream) filterByOffset(offset uint64, opts *HistoryOptions, callback func(e *entry)) error {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 
@@ -133,31 +203,46 @@ func (ms *memstream) filterByOffset(offset uint64, callback func(e *entry)) erro
 		return fmt.Errorf("requested offset couldn't be found: %d, lowest: %d", offset, ms.low)
 	}
 
-	if ms.low == 0 {
+	if ms.low == 0 || len(ms.data) == 0 {
 		return fmt.Errorf("stream is empty")
 	}
 
-	start := (offset - ms.low) + 1
-
-	if start > uint64(len(ms.data)) {
-		return fmt.Errorf("requested offset couldn't be found: %d, latest: %d", offset, ms.data[len(ms.data)-1].offset)
+	if latest := ms.data[len(ms.data)-1].offset; offset > latest {
+		return fmt.Errorf("requested offset couldn't be found: %d, latest: %d", offset, latest)
 	}
 
-	for _, v := range ms.data[start:] {
+	// Offsets are not necessarily sequential (due to compaction), so we use binary search
+	start, _ := slices.BinarySearchFunc(ms.data, offset+1, func(e *entry, target uint64) int {
+		return cmp.Compare(e.offset, target)
+	})
+
+	entries := ms.data[start:]
+	from, to := opts.Bounds(len(entries))
+
+	for _, v := range entries[from:to] {
 		callback(v)
 	}
 
 	return nil
 }
 
-func (ms *memstream) filterByTime(since int64, callback func(e *entry)) error {
+func (ms *memstream) filterByTime(since int64, opts *HistoryOptions, callback func(e *entry)) error {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 
-	for _, v := range ms.data {
-		if v.timestamp >= since {
-			callback(v)
-		}
+	start := slices.IndexFunc(ms.data, func(e *entry) bool {
+		return e.timestamp >= since
+	})
+
+	if start < 0 {
+		return nil
+	}
+
+	entries := ms.data[start:]
+	from, to := opts.Bounds(len(entries))
+
+	for _, v := range entries[from:to] {
+		callback(v)
 	}
 
 	return nil
@@ -235,6 +320,16 @@ type Memory struct {
 	expireSessions []*expireSessionEntry
 
 	presence *presenceState
+	quotas   *LocalSessionsQuota
+	stats    historyStats
+
+	// External history storage (if any)
+	history LocalBroker
+	// File to persist the broker state to on shutdown
+	snapshotPath string
+
+	metrics metrics.Instrumenter
+	log     *slog.Logger
 
 	streamsMu  sync.RWMutex
 	sessionsMu sync.RWMutex
@@ -242,22 +337,75 @@ type Memory struct {
 }
 
 var _ Broker = (*Memory)(nil)
+var _ SessionsQuota = (*Memory)(nil)
+
+type MemoryOption func(*Memory)
 
-func NewMemoryBroker(node Broadcaster, config *Config) *Memory {
+// WithMemoryHistory configures the broker to keep streams history in the provided storage
+// (e.g., on disk) instead of memory
+func WithMemoryHistory(h LocalBroker) MemoryOption {
+	return func(m *Memory) {
+		m.history = h
+	}
+}
+
+// WithMemoryInstrumenter configures the broker to report metrics
+func WithMemoryInstrumenter(m metrics.Instrumenter) MemoryOption {
+	return func(b *Memory) {
+		b.metrics = m
+	}
+}
+
+// WithMemoryLogger configures the broker logger
+func WithMemoryLogger(l *slog.Logger) MemoryOption {
+	return func(m *Memory) {
+		m.log = l.With("context", "broker").With("provider", "memory")
+	}
+}
+
+// WithMemorySnapshot configures the broker to save its state to the specified file on shutdown
+// and restore it on start
+func WithMemorySnapshot(path string) MemoryOption {
+	return func(m *Memory) {
+		m.snapshotPath = path
+	}
+}
+
+func NewMemoryBroker(node Broadcaster, config *Config, opts ...MemoryOption) *Memory {
 	epoch, _ := nanoid.Nanoid(4)
 
-	return &Memory{
+	b := &Memory{
 		broadcaster: node,
 		config:      config,
 		tracker:     NewStreamsTracker(),
 		streams:     make(map[string]*memstream),
 		sessions:    make(map[string]*sessionEntry),
 		presence:    newPresenceState(),
+		quotas:      NewLocalSessionsQuota(),
 		epoch:       epoch,
+		metrics:     metrics.NoopMetrics{},
+		log:         slog.Default().With("context", "broker").With("provider", "memory"),
+	}
+
+	for _, opt := range opts {
+		opt(b)
 	}
+
+	registerMetrics(b.metrics)
+
+	return b
 }
 
 func (b *Memory) Announce() string {
+	if b.history != nil {
+		return fmt.Sprintf(
+			"Using in-memory broker with persistent history (history limit: %d, history ttl: %ds, sessions ttl: %ds)",
+			b.config.HistoryLimit,
+			b.config.HistoryTTL,
+			b.config.SessionsTTL,
+		)
+	}
+
 	return fmt.Sprintf(
 		"Using in-memory broker (epoch: %s, history limit: %d, history ttl: %ds, sessions ttl: %ds)",
 		b.GetEpoch(),
@@ -268,6 +416,10 @@ func (b *Memory) Announce() string {
 }
 
 func (b *Memory) GetEpoch() string {
+	if b.history != nil {
+		return b.history.GetEpoch()
+	}
+
 	b.epochMu.RLock()
 	defer b.epochMu.RUnlock()
 
@@ -275,6 +427,11 @@ func (b *Memory) GetEpoch() string {
 }
 
 func (b *Memory) SetEpoch(v string) {
+	if b.history != nil {
+		b.history.SetEpoch(v)
+		return
+	}
+
 	b.epochMu.Lock()
 	defer b.epochMu.Unlock()
 
@@ -282,12 +439,40 @@ func (b *Memory) SetEpoch(v string) {
 }
 
 func (b *Memory) Start(done chan (error)) error {
+	if b.history != nil {
+		if err := b.history.Start(done); err != nil {
+			return err
+		}
+	}
+
+	if b.snapshotPath != "" {
+		restored, err := b.loadSnapshot(b.snapshotPath)
+
+		if err != nil {
+			b.log.Error("failed to restore state from snapshot", "path", b.snapshotPath, "error", err)
+		} else if restored {
+			b.log.Info("state restored from snapshot", "path", b.snapshotPath, "epoch", b.GetEpoch())
+		}
+	}
+
 	go b.expireLoop()
 
 	return nil
 }
 
 func (b *Memory) Shutdown(ctx context.Context) error {
+	if b.snapshotPath != "" {
+		if err := b.saveSnapshot(b.snapshotPath); err != nil {
+			b.log.Error("failed to save state snapshot", "path", b.snapshotPath, "error", err)
+		} else {
+			b.log.Debug("state snapshot saved", "path", b.snapshotPath)
+		}
+	}
+
+	if b.history != nil {
+		return b.history.Shutdown(ctx)
+	}
+
 	return nil
 }
 
@@ -297,7 +482,24 @@ func (b *Memory) HandleBroadcast(msg *common.StreamMessage) {
 		return
 	}
 
-	offset := b.add(msg.Stream, msg.Data)
+	if b.config.RetentionFor(msg.Stream).Disabled {
+		b.broadcaster.Broadcast(msg)
+		return
+	}
+
+	key := ""
+
+	if msg.Meta != nil {
+		key = msg.Meta.CompactionKey
+	}
+
+	offset, err := b.addCompacted(msg.Stream, msg.Data, key)
+
+	if err != nil {
+		b.log.Error("failed to add message to history", "stream", msg.Stream, "error", err)
+		b.broadcaster.Broadcast(msg)
+		return
+	}
 
 	msg.Epoch = b.GetEpoch()
 	msg.Offset = offset
@@ -331,7 +533,19 @@ func (b *Memory) Unsubscribe(stream string) string {
 	return stream
 }
 
-func (b *Memory) HistoryFrom(name string, epoch string, offset uint64) ([]common.StreamMessage, error) {
+func (b *Memory) HistoryFrom(name string, epoch string, offset uint64, opts ...HistoryOption) ([]common.StreamMessage, error) {
+	history, err := b.historyFrom(name, epoch, offset, opts...)
+
+	trackHistory(b.metrics, err)
+
+	return history, err
+}
+
+func (b *Memory) historyFrom(name string, epoch string, offset uint64, opts ...HistoryOption) ([]common.StreamMessage, error) {
+	if b.history != nil {
+		return b.history.HistoryFrom(name, epoch, offset, opts...)
+	}
+
 	bepoch := b.GetEpoch()
 
 	if bepoch != epoch {
@@ -341,12 +555,12 @@ func (b *Memory) HistoryFrom(name string, epoch string, offset uint64) ([]common
 	stream := b.get(name)
 
 	if stream == nil {
-		return nil, errors.New("stream not found")
+		return nil, ErrStreamNotFound
 	}
 
 	history := []common.StreamMessage{}
 
-	err := stream.filterByOffset(offset, func(entry *entry) {
+	err := stream.filterByOffset(offset, buildHistoryOptions(opts), func(entry *entry) {
 		history = append(history, common.StreamMessage{
 			Stream: name,
 			Data:   entry.data,
@@ -362,17 +576,26 @@ func (b *Memory) HistoryFrom(name string, epoch string, offset uint64) ([]common
 	return history, nil
 }
 
-func (b *Memory) HistorySince(name string, ts int64) ([]common.StreamMessage, error) {
+func (b *Memory) HistorySince(name string, ts int64, opts ...HistoryOption) ([]common.StreamMessage, error) {
+	if b.history != nil {
+		history, err := b.history.HistorySince(name, ts, opts...)
+
+		trackHistory(b.metrics, err)
+
+		return history, err
+	}
+
 	stream := b.get(name)
 
 	if stream == nil {
+		b.metrics.CounterIncrement(metricsHistoryMisses)
 		return nil, nil
 	}
 
 	bepoch := b.GetEpoch()
 	history := []common.StreamMessage{}
 
-	err := stream.filterByTime(ts, func(entry *entry) {
+	err := stream.filterByTime(ts, buildHistoryOptions(opts), func(entry *entry) {
 		history = append(history, common.StreamMessage{
 			Stream: name,
 			Data:   entry.data,
@@ -381,6 +604,8 @@ func (b *Memory) HistorySince(name string, ts int64) ([]common.StreamMessage, er
 		})
 	})
 
+	trackHistory(b.metrics, err)
+
 	if err != nil {
 		return nil, err
 	}
@@ -389,21 +614,21 @@ func (b *Memory) HistorySince(name string, ts int64) ([]common.StreamMessage, er
 }
 
 func (b *Memory) Store(name string, data []byte, offset uint64, ts time.Time) (uint64, error) {
+	return b.StoreCompacted(name, data, "", offset, ts)
+}
+
+func (b *Memory) StoreCompacted(name string, data []byte, key string, offset uint64, ts time.Time) (uint64, error) {
 	b.streamsMu.Lock()
 
 	if _, ok := b.streams[name]; !ok {
-		b.streams[name] = &memstream{
-			data:  []*entry{},
-			ttl:   b.config.HistoryTTL,
-			limit: b.config.HistoryLimit,
-		}
+		b.streams[name] = b.newStream(name)
 	}
 
 	stream := b.streams[name]
 
 	b.streamsMu.Unlock()
 
-	return stream.insert(string(data), offset, ts)
+	return stream.insert(string(data), key, offset, ts)
 }
 
 func (b *Memory) CommitSession(sid string, session Cacheable) error {
@@ -412,6 +637,8 @@ func (b *Memory) CommitSession(sid string, session Cacheable) error {
 
 	cached, err := session.ToCacheEntry()
 
+	trackSessionCommit(b.metrics, err)
+
 	if err != nil {
 		return err
 	}
@@ -426,9 +653,12 @@ func (b *Memory) RestoreSession(from string) ([]byte, error) {
 	defer b.sessionsMu.RUnlock()
 
 	if cached, ok := b.sessions[from]; ok {
+		trackSessionRestore(b.metrics, cached.data, nil)
 		return cached.data, nil
 	}
 
+	trackSessionRestore(b.metrics, nil, nil)
+
 	return nil, nil
 }
 
@@ -445,6 +675,14 @@ func (b *Memory) FinishSession(sid string) error {
 	return nil
 }
 
+func (b *Memory) AcquireSessionQuota(key string, sid string, limit int) (bool, error) {
+	return b.quotas.AcquireSessionQuota(key, sid, limit)
+}
+
+func (b *Memory) ReleaseSessionQuota(key string, sid string) error {
+	return b.quotas.ReleaseSessionQuota(key, sid)
+}
+
 func (b *Memory) FinishPresence(sid string) error {
 	b.presence.mu.Lock()
 
@@ -557,6 +795,37 @@ func (b *Memory) PresenceRemove(stream string, sid string) (*common.PresenceEven
 	return nil, nil
 }
 
+func (b *Memory) PresenceUpdate(stream string, sid string, info interface{}) (*common.PresenceEvent, error) {
+	b.presence.mu.Lock()
+	defer b.presence.mu.Unlock()
+
+	ses, ok := b.presence.sessions[sid]
+
+	if !ok {
+		return nil, errors.New("presence info not found")
+	}
+
+	pid, ok := ses.streams[stream]
+
+	if !ok {
+		return nil, errors.New("presence info not found")
+	}
+
+	entry, ok := b.presence.streams[stream][pid]
+
+	if !ok {
+		return nil, errors.New("presence record not found")
+	}
+
+	entry.info = info
+
+	return &common.PresenceEvent{
+		Type: common.PresenceUpdateType,
+		ID:   pid,
+		Info: info,
+	}, nil
+}
+
 func (b *Memory) PresenceInfo(stream string, opts ...PresenceInfoOption) (*common.PresenceInfo, error) {
 	options := NewPresenceInfoOptions()
 	for _, opt := range opts {
@@ -590,22 +859,38 @@ func (b *Memory) PresenceInfo(stream string, opts ...PresenceInfoOption) (*commo
 	return info, nil
 }
 
-func (b *Memory) add(name string, data string) uint64 {
+func (b *Memory) add(name string, data string) (uint64, error) {
+	return b.addCompacted(name, data, "")
+}
+
+func (b *Memory) addCompacted(name string, data string, key string) (uint64, error) {
+	if b.history != nil {
+		return b.history.StoreCompacted(name, []byte(data), key, 0, time.Now())
+	}
+
 	b.streamsMu.Lock()
 
 	if _, ok := b.streams[name]; !ok {
-		b.streams[name] = &memstream{
-			data:  []*entry{},
-			ttl:   b.config.HistoryTTL,
-			limit: b.config.HistoryLimit,
-		}
+		b.streams[name] = b.newStream(name)
 	}
 
 	stream := b.streams[name]
 
 	b.streamsMu.Unlock()
 
-	return stream.add(data)
+	return stream.add(data, key), nil
+}
+
+func (b *Memory) newStream(name string) *memstream {
+	retention := b.config.RetentionFor(name)
+
+	return &memstream{
+		data:     []*entry{},
+		ttl:      retention.TTL,
+		limit:    retention.Limit,
+		maxBytes: retention.MaxBytes,
+		stats:    &b.stats,
+	}
 }
 
 func (b *Memory) get(name string) *memstream {
@@ -638,6 +923,7 @@ func (b *Memory) expire() {
 	}
 
 	for _, name := range toDelete {
+		b.streams[name].clear()
 		delete(b.streams, name)
 	}
 
@@ -662,6 +948,44 @@ func (b *Memory) expire() {
 
 	// presence expiration
 	b.expirePresence()
+
+	b.collectStats()
+}
+
+func (b *Memory) historyStats() (int, int64, int64) {
+	if b.history != nil {
+		if r, ok := b.history.(historyStatsReporter); ok {
+			return r.historyStats()
+		}
+
+		return 0, 0, 0
+	}
+
+	b.streamsMu.RLock()
+	streams := len(b.streams)
+	b.streamsMu.RUnlock()
+
+	return streams, b.stats.entries.Load(), b.stats.bytes.Load()
+}
+
+func (b *Memory) collectStats() {
+	if _, ok := b.metrics.(metrics.NoopMetrics); ok {
+		return
+	}
+
+	reportHistoryStats(b.metrics, b)
+
+	b.presence.mu.RLock()
+
+	records := 0
+
+	for _, sp := range b.presence.sessions {
+		records += len(sp.streams)
+	}
+
+	b.presence.mu.RUnlock()
+
+	b.metrics.GaugeSet(metricsPresenceRecordsNum, uint64(records))
 }
 
 func (b *Memory) expirePresence() {
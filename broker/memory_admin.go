@@ -0,0 +1,160 @@
+package broker
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/utils"
+	nanoid "github.com/matoous/go-nanoid"
+)
+
+var _ HistoryAdmin = (*Memory)(nil)
+
+func (ms *memstream) info(name string) *StreamInfo {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	return &StreamInfo{
+		Name:    name,
+		Low:     ms.low,
+		High:    ms.offset,
+		Entries: len(ms.data),
+		Bytes:   ms.bytes,
+	}
+}
+
+// read returns at most limit entries starting from the specified offset (inclusive)
+func (ms *memstream) read(offset uint64, limit int, callback func(e *entry)) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	start, _ := slices.BinarySearchFunc(ms.data, offset, func(e *entry, target uint64) int {
+		return cmp.Compare(e.offset, target)
+	})
+
+	entries := ms.data[start:]
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	for _, v := range entries {
+		callback(v)
+	}
+}
+
+// clear removes all entries but keeps the current offset
+func (ms *memstream) clear() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.stats.add(-len(ms.data), -ms.bytes)
+
+	ms.data = nil
+	ms.keys = nil
+	ms.bytes = 0
+	ms.low = 0
+}
+
+func (b *Memory) Streams() ([]*StreamInfo, error) {
+	if b.history != nil {
+		return b.history.Streams()
+	}
+
+	b.streamsMu.RLock()
+
+	streams := make([]*StreamInfo, 0, len(b.streams))
+
+	for name, stream := range b.streams {
+		streams = append(streams, stream.info(name))
+	}
+
+	b.streamsMu.RUnlock()
+
+	slices.SortFunc(streams, func(a, b *StreamInfo) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return streams, nil
+}
+
+func (b *Memory) MatchStreams(pattern string) ([]string, error) {
+	if b.history != nil {
+		return b.history.MatchStreams(pattern)
+	}
+
+	b.streamsMu.RLock()
+
+	streams := []string{}
+
+	for name := range b.streams {
+		if utils.MatchWildcardStream(pattern, name) {
+			streams = append(streams, name)
+		}
+	}
+
+	b.streamsMu.RUnlock()
+
+	slices.Sort(streams)
+
+	return streams, nil
+}
+
+func (b *Memory) ReadHistory(name string, offset uint64, limit int) ([]common.StreamMessage, error) {
+	if b.history != nil {
+		return b.history.ReadHistory(name, offset, limit)
+	}
+
+	stream := b.get(name)
+
+	if stream == nil {
+		return nil, ErrStreamNotFound
+	}
+
+	epoch := b.GetEpoch()
+	history := []common.StreamMessage{}
+
+	stream.read(offset, limit, func(entry *entry) {
+		history = append(history, common.StreamMessage{
+			Stream: name,
+			Data:   entry.data,
+			Offset: entry.offset,
+			Epoch:  epoch,
+		})
+	})
+
+	return history, nil
+}
+
+func (b *Memory) DeleteHistory(name string) error {
+	if b.history != nil {
+		return b.history.DeleteHistory(name)
+	}
+
+	stream := b.get(name)
+
+	if stream == nil {
+		return ErrStreamNotFound
+	}
+
+	stream.clear()
+
+	b.log.Info("stream history deleted", "stream", name)
+
+	return nil
+}
+
+func (b *Memory) RotateEpoch() (string, error) {
+	epoch, err := nanoid.Nanoid(4)
+
+	if err != nil {
+		return "", err
+	}
+
+	b.SetEpoch(epoch)
+
+	b.log.Info("epoch rotated", "epoch", epoch)
+
+	return epoch, nil
+}
@@ -0,0 +1,253 @@
+package broker
+
+import (
+	"cmp"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// memorySnapshot contains the in-memory broker state to be restored after restart
+type memorySnapshot struct {
+	Epoch    string                         `json:"epoch"`
+	Streams  map[string]*memstreamSnapshot  `json:"streams,omitempty"`
+	Sessions map[string]*sessionSnapshot    `json:"sessions,omitempty"`
+	Presence map[string][]*presenceSnapshot `json:"presence,omitempty"`
+	// Presence sessions expiration deadlines
+	Expire map[string]int64 `json:"presence_expire,omitempty"`
+}
+
+type memstreamSnapshot struct {
+	Offset   uint64           `json:"offset"`
+	Low      uint64           `json:"low,omitempty"`
+	Deadline int64            `json:"deadline"`
+	Entries  []*entrySnapshot `json:"entries"`
+}
+
+type entrySnapshot struct {
+	Offset    uint64 `json:"offset"`
+	Timestamp int64  `json:"ts"`
+	Data      string `json:"data"`
+	Key       string `json:"key,omitempty"`
+}
+
+type sessionSnapshot struct {
+	Data []byte `json:"data"`
+	// Zero for sessions which hasn't been finished yet
+	Deadline int64 `json:"deadline,omitempty"`
+}
+
+type presenceSnapshot struct {
+	ID       string      `json:"id"`
+	Info     interface{} `json:"info,omitempty"`
+	Sessions []string    `json:"sessions"`
+}
+
+func (b *Memory) snapshot() *memorySnapshot {
+	snap := &memorySnapshot{
+		Epoch:    b.GetEpoch(),
+		Streams:  make(map[string]*memstreamSnapshot),
+		Sessions: make(map[string]*sessionSnapshot),
+		Presence: make(map[string][]*presenceSnapshot),
+		Expire:   make(map[string]int64),
+	}
+
+	// History is persisted by the history storage itself
+	if b.history == nil {
+		b.streamsMu.RLock()
+
+		for name, stream := range b.streams {
+			stream.mu.RLock()
+
+			ms := &memstreamSnapshot{
+				Offset:   stream.offset,
+				Low:      stream.low,
+				Deadline: stream.deadline,
+				Entries:  make([]*entrySnapshot, 0, len(stream.data)),
+			}
+
+			for _, e := range stream.data {
+				ms.Entries = append(ms.Entries, &entrySnapshot{Offset: e.offset, Timestamp: e.timestamp, Data: e.data, Key: e.key})
+			}
+
+			stream.mu.RUnlock()
+
+			snap.Streams[name] = ms
+		}
+
+		b.streamsMu.RUnlock()
+	}
+
+	b.sessionsMu.RLock()
+
+	for sid, session := range b.sessions {
+		snap.Sessions[sid] = &sessionSnapshot{Data: session.data}
+	}
+
+	for _, expired := range b.expireSessions {
+		if session, ok := snap.Sessions[expired.sid]; ok {
+			session.Deadline = expired.deadline
+		}
+	}
+
+	b.sessionsMu.RUnlock()
+
+	b.presence.mu.RLock()
+
+	for stream, records := range b.presence.streams {
+		for _, record := range records {
+			snap.Presence[stream] = append(snap.Presence[stream], &presenceSnapshot{
+				ID:       record.id,
+				Info:     record.info,
+				Sessions: record.sessions,
+			})
+		}
+	}
+
+	for sid, session := range b.presence.sessions {
+		if session.deadline > 0 {
+			snap.Expire[sid] = session.deadline
+		}
+	}
+
+	b.presence.mu.RUnlock()
+
+	return snap
+}
+
+func (b *Memory) restore(snap *memorySnapshot) {
+	if b.history == nil {
+		if snap.Epoch != "" {
+			b.SetEpoch(snap.Epoch)
+		}
+
+		b.streamsMu.Lock()
+
+		for name, ms := range snap.Streams {
+			stream := b.newStream(name)
+			stream.deadline = ms.Deadline
+
+			// Re-append entries to apply the current retention settings (they could be changed)
+			for _, e := range ms.Entries {
+				stream.appendEntry(&entry{offset: e.Offset, timestamp: e.Timestamp, data: e.Data, key: e.Key})
+			}
+
+			// Compacted entries could precede the first entry, restore the lowest offset unless some entries were dropped
+			if ms.Low > 0 && ms.Low < stream.low && len(stream.data) == len(ms.Entries) {
+				stream.low = ms.Low
+			}
+
+			stream.offset = ms.Offset
+			stream.deadline = ms.Deadline
+
+			b.streams[name] = stream
+		}
+
+		b.streamsMu.Unlock()
+	}
+
+	// Sessions which hasn't been finished before shutdown are no longer active
+	now := time.Now().Unix()
+
+	b.sessionsMu.Lock()
+
+	for sid, session := range snap.Sessions {
+		b.sessions[sid] = &sessionEntry{data: session.Data}
+
+		deadline := session.Deadline
+
+		if deadline == 0 {
+			deadline = now + b.config.SessionsTTL
+		}
+
+		b.expireSessions = append(b.expireSessions, &expireSessionEntry{sid: sid, deadline: deadline})
+	}
+
+	// Expiration queue must be ordered by deadline
+	slices.SortFunc(b.expireSessions, func(a, b *expireSessionEntry) int {
+		return cmp.Compare(a.deadline, b.deadline)
+	})
+
+	b.sessionsMu.Unlock()
+
+	b.presence.mu.Lock()
+
+	for stream, records := range snap.Presence {
+		if _, ok := b.presence.streams[stream]; !ok {
+			b.presence.streams[stream] = make(map[string]*presenceEntry)
+		}
+
+		for _, record := range records {
+			b.presence.streams[stream][record.ID] = &presenceEntry{
+				id:       record.ID,
+				info:     record.Info,
+				sessions: record.Sessions,
+			}
+
+			for _, sid := range record.Sessions {
+				if _, ok := b.presence.sessions[sid]; !ok {
+					b.presence.sessions[sid] = &presenceSessionEntry{streams: make(map[string]string)}
+				}
+
+				b.presence.sessions[sid].streams[stream] = record.ID
+			}
+		}
+	}
+
+	for sid, session := range b.presence.sessions {
+		if deadline, ok := snap.Expire[sid]; ok {
+			session.deadline = deadline
+		} else if session.deadline == 0 {
+			session.deadline = now + b.config.PresenceTTL
+		}
+	}
+
+	b.presence.mu.Unlock()
+}
+
+// saveSnapshot writes the broker state to the specified file (atomically)
+func (b *Memory) saveSnapshot(path string) error {
+	data, err := json.Marshal(b.snapshot())
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// loadSnapshot restores the broker state from the specified file (if any).
+// The file is removed after loading to avoid restoring a stale state after an unexpected crash.
+func (b *Memory) loadSnapshot(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	var snap memorySnapshot
+
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return false, err
+	}
+
+	b.restore(&snap)
+
+	return true, os.Remove(path)
+}
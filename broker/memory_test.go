@@ -1,11 +1,16 @@
 package broker
 
 import (
+	"context"
+	"log/slog"
+	"path/filepath"
 	"slices"
 	"testing"
 	"time"
 
 	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/metrics"
+	"github.com/anycable/anycable-go/pubsub"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -130,14 +135,14 @@ func TestMemstream_filterByOffset(t *testing.T) {
 		limit: 5,
 	}
 
-	ms.add("test1")
-	ms.add("test2")
+	ms.add("test1", "")
+	ms.add("test2", "")
 
 	// Should return error if offset is out of range
-	err := ms.filterByOffset(10, func(e *entry) {})
+	err := ms.filterByOffset(10, nil, func(e *entry) {})
 	require.Error(t, err)
 
-	err = ms.filterByOffset(1, func(e *entry) {
+	err = ms.filterByOffset(1, nil, func(e *entry) {
 		assert.Equal(t, "test2", e.data)
 	})
 	require.NoError(t, err)
@@ -146,12 +151,68 @@ func TestMemstream_filterByOffset(t *testing.T) {
 
 	ms.expire()
 
-	err = ms.filterByOffset(1, func(e *entry) {
+	err = ms.filterByOffset(1, nil, func(e *entry) {
 		assert.Failf(t, "entry should be expired", "entry: %v", e)
 	})
 	require.Error(t, err)
 }
 
+func TestMemstream_compaction(t *testing.T) {
+	ms := &memstream{
+		ttl:   100,
+		limit: 4,
+	}
+
+	ms.add("doc 1: draft", "doc/1")
+	ms.add("doc 2: draft", "doc/2")
+	ms.add("doc 1: review", "doc/1")
+	ms.add("hello", "")
+	ms.add("doc 1: published", "doc/1")
+
+	history := []*entry{}
+
+	err := ms.filterByOffset(1, nil, func(e *entry) {
+		history = append(history, e)
+	})
+	require.NoError(t, err)
+
+	require.Len(t, history, 3)
+	assert.EqualValues(t, 2, history[0].offset)
+	assert.Equal(t, "doc 2: draft", history[0].data)
+	assert.EqualValues(t, 4, history[1].offset)
+	assert.EqualValues(t, 5, history[2].offset)
+	assert.Equal(t, "doc 1: published", history[2].data)
+
+	// Compaction doesn't affect the lowest offset
+	assert.EqualValues(t, 1, ms.low)
+
+	history = []*entry{}
+
+	err = ms.filterByOffset(2, nil, func(e *entry) {
+		history = append(history, e)
+	})
+	require.NoError(t, err)
+
+	require.Len(t, history, 2)
+	assert.EqualValues(t, 4, history[0].offset)
+
+	ms.add("doc 2: review", "doc/2")
+	ms.add("bye", "")
+	ms.add("doc 3: draft", "doc/3")
+
+	// Limit is applied to the compacted history
+	assert.Len(t, ms.data, 4)
+	assert.EqualValues(t, 5, ms.low)
+
+	ms.add("again", "")
+
+	assert.EqualValues(t, 6, ms.low)
+	assert.NotContains(t, ms.keys, "doc/1")
+
+	err = ms.filterByOffset(3, nil, func(e *entry) {})
+	require.Error(t, err)
+}
+
 func TestMemory_Presence(t *testing.T) {
 	config := NewConfig()
 
@@ -212,6 +273,38 @@ func TestMemory_Presence(t *testing.T) {
 	assert.Equal(t, 1, info.Total)
 }
 
+func TestMemory_PresenceUpdate(t *testing.T) {
+	config := NewConfig()
+
+	broker := NewMemoryBroker(nil, &config)
+
+	_, err := broker.PresenceUpdate("a", "s1", "away")
+	require.Error(t, err)
+
+	_, err = broker.PresenceAdd("a", "s1", "user_1", map[string]interface{}{"name": "John"})
+	require.NoError(t, err)
+
+	_, err = broker.PresenceAdd("a", "s2", "user_1", map[string]interface{}{"name": "John"})
+	require.NoError(t, err)
+
+	ev, err := broker.PresenceUpdate("a", "s2", map[string]interface{}{"name": "John", "status": "away"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "update", ev.Type)
+	assert.Equal(t, "user_1", ev.ID)
+	assert.Equal(t, map[string]interface{}{"name": "John", "status": "away"}, ev.Info)
+
+	info, err := broker.PresenceInfo("a")
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, info.Total)
+	require.Len(t, info.Records, 1)
+	assert.Equal(t, map[string]interface{}{"name": "John", "status": "away"}, info.Records[0].Info)
+
+	_, err = broker.PresenceUpdate("b", "s1", "away")
+	require.Error(t, err)
+}
+
 func TestMemory_expirePresence(t *testing.T) {
 	config := NewConfig()
 	config.PresenceTTL = 1
@@ -241,3 +334,230 @@ func TestMemory_expirePresence(t *testing.T) {
 	assert.Equal(t, 1, info.Total)
 	assert.Equal(t, "user_1", info.Records[0].ID)
 }
+
+func TestMemory_Snapshot(t *testing.T) {
+	config := NewConfig()
+	path := filepath.Join(t.TempDir(), "broker.snapshot")
+
+	broker := NewMemoryBroker(nil, &config, WithMemorySnapshot(path))
+	require.NoError(t, broker.Start(nil))
+
+	broker.add("test", "a") // nolint:errcheck
+	broker.add("test", "b") // nolint:errcheck
+	broker.add("test", "c") // nolint:errcheck
+
+	require.NoError(t, broker.CommitSession("s1", &TestCacheable{"cache-me"}))
+	require.NoError(t, broker.FinishSession("s1"))
+
+	broker.PresenceAdd("a", "s1", "user_1", "john") // nolint:errcheck
+	broker.PresenceAdd("a", "s2", "user_2", "kate") // nolint:errcheck
+	broker.FinishPresence("s1")                     // nolint:errcheck
+
+	epoch := broker.GetEpoch()
+
+	require.NoError(t, broker.Shutdown(context.Background()))
+	require.FileExists(t, path)
+
+	restored := NewMemoryBroker(nil, &config, WithMemorySnapshot(path))
+	require.NoError(t, restored.Start(nil))
+
+	assert.Equal(t, epoch, restored.GetEpoch())
+	assert.NoFileExists(t, path)
+
+	history, err := restored.HistoryFrom("test", epoch, 1)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "b", history[0].Data)
+	assert.EqualValues(t, 3, history[1].Offset)
+
+	// Offsets are preserved
+	offset, _ := restored.add("test", "d")
+	assert.EqualValues(t, 4, offset)
+
+	data, err := restored.RestoreSession("s1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("cache-me"), data)
+
+	info, err := restored.PresenceInfo("a")
+	require.NoError(t, err)
+	assert.Equal(t, 2, info.Total)
+
+	assert.Equal(t, broker.presence.sessions["s1"].deadline, restored.presence.sessions["s1"].deadline)
+	// Active sessions must be expired eventually
+	assert.NotZero(t, restored.presence.sessions["s2"].deadline)
+}
+
+func TestMemory_Retention(t *testing.T) {
+	config := NewConfig()
+	config.Retention = []RetentionPolicy{
+		{Pattern: "chat:*", MaxBytes: 5},
+		{Pattern: "cursors:*", Disabled: true},
+	}
+
+	broker := NewMemoryBroker(pubsub.NewLegacySubscriber(FakeBroadastHandler{}), &config)
+
+	start := time.Now().Unix() - 10
+
+	broker.add("chat:1", "ab")  // nolint:errcheck
+	broker.add("chat:1", "cd")  // nolint:errcheck
+	broker.add("chat:1", "efg") // nolint:errcheck
+
+	history, err := broker.HistorySince("chat:1", start)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "cd", history[0].Data)
+	assert.Equal(t, "efg", history[1].Data)
+
+	// The latest entry is kept even if it exceeds the limit
+	broker.add("chat:1", "too long") // nolint:errcheck
+
+	history, err = broker.HistorySince("chat:1", start)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.EqualValues(t, 4, history[0].Offset)
+
+	msg := &common.StreamMessage{Stream: "cursors:1", Data: "x"}
+	broker.HandleBroadcast(msg)
+
+	assert.EqualValues(t, 0, msg.Offset)
+
+	history, err = broker.HistorySince("cursors:1", start)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestMemory_HistoryWithLimit(t *testing.T) {
+	config := NewConfig()
+
+	broker := NewMemoryBroker(nil, &config)
+
+	start := time.Now().Unix() - 10
+
+	for _, data := range []string{"a", "b", "c", "d", "e"} {
+		broker.add("test", data) // nolint:errcheck
+	}
+
+	epoch := broker.GetEpoch()
+
+	history, err := broker.HistoryFrom("test", epoch, 1, WithHistoryLimit(2))
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "b", history[0].Data)
+	assert.Equal(t, "c", history[1].Data)
+
+	history, err = broker.HistoryFrom("test", epoch, 1, WithLatestHistory(2))
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "d", history[0].Data)
+	assert.Equal(t, "e", history[1].Data)
+
+	history, err = broker.HistorySince("test", start, WithHistoryLimit(3))
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, "a", history[0].Data)
+
+	history, err = broker.HistorySince("test", start, WithLatestHistory(10))
+	require.NoError(t, err)
+	assert.Len(t, history, 5)
+}
+
+func TestMemory_MatchStreams(t *testing.T) {
+	config := NewConfig()
+	broker := NewMemoryBroker(pubsub.NewLegacySubscriber(FakeBroadastHandler{}), &config)
+
+	broker.HandleBroadcast(&common.StreamMessage{Stream: "project:1:tasks", Data: "a"})
+	broker.HandleBroadcast(&common.StreamMessage{Stream: "project:1:comments", Data: "b"})
+	broker.HandleBroadcast(&common.StreamMessage{Stream: "project:2:tasks", Data: "c"})
+
+	streams, err := broker.MatchStreams("project:1:*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"project:1:comments", "project:1:tasks"}, streams)
+
+	streams, err = broker.MatchStreams("project:*:tasks")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"project:1:tasks", "project:2:tasks"}, streams)
+
+	streams, err = broker.MatchStreams("chat:*")
+	require.NoError(t, err)
+	assert.Empty(t, streams)
+}
+
+func TestMemory_Metrics(t *testing.T) {
+	config := NewConfig()
+	m := metrics.NewMetrics(nil, 10, slog.Default())
+
+	broker := NewMemoryBroker(pubsub.NewLegacySubscriber(FakeBroadastHandler{}), &config, WithMemoryInstrumenter(m))
+
+	broker.HandleBroadcast(&common.StreamMessage{Stream: "test", Data: "a"})
+	broker.HandleBroadcast(&common.StreamMessage{Stream: "test", Data: "bc"})
+	broker.HandleBroadcast(&common.StreamMessage{Stream: "other", Data: "d"})
+
+	_, err := broker.HistoryFrom("test", broker.GetEpoch(), 1)
+	require.NoError(t, err)
+
+	_, err = broker.HistoryFrom("test", "unknown", 1)
+	require.Error(t, err)
+
+	_, err = broker.HistoryFrom("missing", broker.GetEpoch(), 1)
+	require.Error(t, err)
+
+	assert.EqualValues(t, 1, m.Counter(metricsHistoryHits).Value())
+	assert.EqualValues(t, 2, m.Counter(metricsHistoryMisses).Value())
+
+	require.NoError(t, broker.CommitSession("s1", &TestCacheable{"cache-me"}))
+
+	_, err = broker.RestoreSession("s1")
+	require.NoError(t, err)
+
+	_, err = broker.RestoreSession("s2")
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 1, m.Counter(metricsSessionCommits).Value())
+	assert.EqualValues(t, 1, m.Counter(metricsSessionRestores).Value())
+	assert.EqualValues(t, 1, m.Counter(metricsSessionRestoreFailures).Value())
+
+	_, err = broker.PresenceAdd("test", "s1", "u1", "alice")
+	require.NoError(t, err)
+
+	_, err = broker.PresenceAdd("other", "s1", "u1", "alice")
+	require.NoError(t, err)
+
+	broker.collectStats()
+
+	assert.EqualValues(t, 2, m.Gauge(metricsStreamsNum).Value())
+	assert.EqualValues(t, 3, m.Gauge(metricsHistoryEntriesNum).Value())
+	assert.EqualValues(t, 4, m.Gauge(metricsHistoryBytes).Value())
+	assert.EqualValues(t, 2, m.Gauge(metricsPresenceRecordsNum).Value())
+}
+
+func TestMemory_SessionsQuota(t *testing.T) {
+	config := NewConfig()
+	broker := NewMemoryBroker(nil, &config)
+
+	ok, err := broker.AcquireSessionQuota("john", "s1", 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = broker.AcquireSessionQuota("john", "s2", 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Acquiring is idempotent
+	ok, err = broker.AcquireSessionQuota("john", "s1", 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = broker.AcquireSessionQuota("john", "s3", 2)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = broker.AcquireSessionQuota("jack", "s3", 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, broker.ReleaseSessionQuota("john", "s1"))
+
+	ok, err = broker.AcquireSessionQuota("john", "s3", 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
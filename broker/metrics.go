@@ -0,0 +1,109 @@
+package broker
+
+import (
+	"sync/atomic"
+
+	"github.com/anycable/anycable-go/metrics"
+)
+
+const (
+	metricsStreamsNum             = "broker_streams_num"
+	metricsHistoryEntriesNum      = "broker_history_entries_num"
+	metricsHistoryBytes           = "broker_history_bytes"
+	metricsHistoryHits            = "broker_history_hits_total"
+	metricsHistoryMisses          = "broker_history_misses_total"
+	metricsSessionCommits         = "broker_session_commits_total"
+	metricsSessionCommitFailures  = "broker_session_commit_failures_total"
+	metricsSessionRestores        = "broker_session_restores_total"
+	metricsSessionRestoreFailures = "broker_session_restore_failures_total"
+	metricsPresenceRecordsNum     = "broker_presence_records_num"
+
+	metricsNATSErrors  = "broker_nats_errors_total"
+	metricsRedisErrors = "broker_redis_errors_total"
+)
+
+func registerMetrics(m metrics.Instrumenter) {
+	m.RegisterGauge(metricsStreamsNum, "The number of streams with history tracked by the broker")
+	m.RegisterGauge(metricsHistoryEntriesNum, "The total number of history entries kept by the broker")
+	m.RegisterGauge(metricsHistoryBytes, "The total size of history entries kept by the broker (in bytes)")
+	m.RegisterGauge(metricsPresenceRecordsNum, "The number of presence records (stream-session pairs) tracked by the broker")
+
+	m.RegisterCounter(metricsHistoryHits, "The total number of successful history requests")
+	m.RegisterCounter(metricsHistoryMisses, "The total number of failed history requests (unknown stream, epoch or offset)")
+	m.RegisterCounter(metricsSessionCommits, "The total number of committed sessions")
+	m.RegisterCounter(metricsSessionCommitFailures, "The total number of failed session commits")
+	m.RegisterCounter(metricsSessionRestores, "The total number of restored sessions")
+	m.RegisterCounter(metricsSessionRestoreFailures, "The total number of failed session restores (including missing or expired sessions)")
+}
+
+func registerNATSMetrics(m metrics.Instrumenter) {
+	registerMetrics(m)
+
+	m.RegisterCounter(metricsNATSErrors, "The total number of failed NATS KV and JetStream operations")
+}
+
+func registerRedisMetrics(m metrics.Instrumenter) {
+	m.RegisterGauge(metricsPresenceRecordsNum, "The number of presence records (stream-session pairs) tracked by the broker")
+
+	m.RegisterCounter(metricsHistoryHits, "The total number of successful history requests")
+	m.RegisterCounter(metricsHistoryMisses, "The total number of failed history requests (unknown stream, epoch or offset)")
+	m.RegisterCounter(metricsSessionCommits, "The total number of committed sessions")
+	m.RegisterCounter(metricsSessionCommitFailures, "The total number of failed session commits")
+	m.RegisterCounter(metricsSessionRestores, "The total number of restored sessions")
+	m.RegisterCounter(metricsSessionRestoreFailures, "The total number of failed session restores (including missing or expired sessions)")
+	m.RegisterCounter(metricsRedisErrors, "The total number of failed Redis commands")
+}
+
+func trackHistory(m metrics.Instrumenter, err error) {
+	if err != nil {
+		m.CounterIncrement(metricsHistoryMisses)
+	} else {
+		m.CounterIncrement(metricsHistoryHits)
+	}
+}
+
+func trackSessionCommit(m metrics.Instrumenter, err error) {
+	if err != nil {
+		m.CounterIncrement(metricsSessionCommitFailures)
+	} else {
+		m.CounterIncrement(metricsSessionCommits)
+	}
+}
+
+func trackSessionRestore(m metrics.Instrumenter, data []byte, err error) {
+	if err != nil || data == nil {
+		m.CounterIncrement(metricsSessionRestoreFailures)
+	} else {
+		m.CounterIncrement(metricsSessionRestores)
+	}
+}
+
+// historyStats keeps the total number and size of history entries up to date,
+// so we don't need to iterate over all streams to report metrics
+type historyStats struct {
+	entries atomic.Int64
+	bytes   atomic.Int64
+}
+
+func (s *historyStats) add(entries int, bytes int64) {
+	if s == nil {
+		return
+	}
+
+	s.entries.Add(int64(entries))
+	s.bytes.Add(bytes)
+}
+
+// historyStatsReporter is implemented by history storages tracking their stats incrementally
+type historyStatsReporter interface {
+	// historyStats returns the number of streams, history entries and their total size
+	historyStats() (int, int64, int64)
+}
+
+func reportHistoryStats(m metrics.Instrumenter, r historyStatsReporter) {
+	streams, entries, bytes := r.historyStats()
+
+	m.GaugeSet(metricsStreamsNum, uint64(streams))
+	m.GaugeSet(metricsHistoryEntriesNum, uint64(max(entries, 0)))
+	m.GaugeSet(metricsHistoryBytes, uint64(max(bytes, 0)))
+}
@@ -2,6 +2,7 @@ package broker
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/metrics"
 	natsconfig "github.com/anycable/anycable-go/nats"
 	"github.com/anycable/anycable-go/utils"
 	"github.com/joomcode/errorx"
@@ -27,9 +29,13 @@ type NATS struct {
 	nconf *natsconfig.NATSConfig
 	conn  *nats.Conn
 
-	js      jetstream.JetStream
-	kv      jetstream.KeyValue
-	epochKV jetstream.KeyValue
+	js         jetstream.JetStream
+	kv         jetstream.KeyValue
+	epochKV    jetstream.KeyValue
+	presenceKV jetstream.KeyValue
+
+	presenceSessions *natsPresenceSessions
+	quotaSessions    *sessionsQuotaTracker
 
 	jstreams   *lru[string]
 	jconsumers *lru[jetstream.Consumer]
@@ -50,7 +56,8 @@ type NATS struct {
 	broadcastBacklog []*common.StreamMessage
 	backlogMu        sync.Mutex
 
-	log *slog.Logger
+	metrics metrics.Instrumenter
+	log     *slog.Logger
 }
 
 const (
@@ -60,10 +67,15 @@ const (
 	sessionsPrefix = ""
 	streamPrefix   = "_ac_"
 
+	natsCompactionKeyHeader    = "Ac-Compaction-Key"
+	natsCompactionSubjectInfix = ".k."
+
 	jetstreamReadyTimeout = 1 * time.Second
+	natsStatsInterval     = 5 * time.Second
 )
 
 var _ Broker = (*NATS)(nil)
+var _ SessionsQuota = (*NATS)(nil)
 
 type NATSOption func(*NATS)
 
@@ -73,6 +85,13 @@ func WithNATSLocalBroker(b LocalBroker) NATSOption {
 	}
 }
 
+// WithNATSInstrumenter configures the broker to report metrics
+func WithNATSInstrumenter(m metrics.Instrumenter) NATSOption {
+	return func(n *NATS) {
+		n.metrics = m
+	}
+}
+
 func NewNATSBroker(broadcaster Broadcaster, c *Config, nc *natsconfig.NATSConfig, l *slog.Logger, opts ...NATSOption) *NATS {
 	shutdownCtx, shutdownFn := context.WithCancel(context.Background())
 
@@ -85,8 +104,11 @@ func NewNATSBroker(broadcaster Broadcaster, c *Config, nc *natsconfig.NATSConfig
 		tracker:          NewStreamsTracker(),
 		broadcastBacklog: []*common.StreamMessage{},
 		streamSync:       newStreamsSynchronizer(),
+		presenceSessions: newNATSPresenceSessions(),
+		quotaSessions:    newSessionsQuotaTracker(),
 		jstreams:         newLRU[string](time.Duration(c.HistoryTTL * int64(time.Second))),
 		jconsumers:       newLRU[jetstream.Consumer](time.Duration(c.HistoryTTL * int64(time.Second))),
+		metrics:          metrics.NoopMetrics{},
 		log:              l.With("context", "broker").With("provider", "nats"),
 	}
 
@@ -94,8 +116,10 @@ func NewNATSBroker(broadcaster Broadcaster, c *Config, nc *natsconfig.NATSConfig
 		opt(&n)
 	}
 
+	registerNATSMetrics(n.metrics)
+
 	if n.local == nil {
-		n.local = NewMemoryBroker(nil, c)
+		n.local = NewMemoryBroker(nil, c, WithMemoryLogger(l))
 	}
 
 	return &n
@@ -227,6 +251,16 @@ func (n *NATS) initJetStream() error {
 
 	n.kv = kv
 
+	// Presence records are refreshed by the nodes they belong to,
+	// so records of crashed nodes are eventually removed by the bucket TTL
+	presenceKV, err := n.fetchBucketWithTTL(presenceBucket, n.presenceBucketTTL())
+
+	if err != nil {
+		return errorx.Decorate(err, "failed to connect to JetStream KV")
+	}
+
+	n.presenceKV = presenceKV
+
 	epoch, err := n.calculateEpoch()
 
 	if err != nil {
@@ -246,6 +280,15 @@ func (n *NATS) initJetStream() error {
 		n.log.Warn("failed to set up epoch watcher", "error", err)
 	}
 
+	err = n.watchDeletedHistory()
+
+	if err != nil {
+		n.log.Warn("failed to subscribe to history deletion notifications", "error", err)
+	}
+
+	go n.presenceLoop(n.shutdownCtx)
+	go n.collectStats(n.shutdownCtx)
+
 	n.log.Info("NATS broker is ready", "epoch", epoch)
 	return nil
 }
@@ -274,6 +317,11 @@ func (n *NATS) Announce() string {
 	return fmt.Sprintf("Using NATS broker: %s %s", n.nconf.Servers, brokerParams)
 }
 
+// IsDistributed returns true, since NATS broker state is shared across the cluster
+func (n *NATS) IsDistributed() bool {
+	return true
+}
+
 func (n *NATS) Epoch() string {
 	n.epochMu.RLock()
 	defer n.epochMu.RUnlock()
@@ -317,6 +365,11 @@ func (n *NATS) HandleBroadcast(msg *common.StreamMessage) {
 		return
 	}
 
+	if n.conf.RetentionFor(msg.Stream).Disabled {
+		n.broadcaster.Broadcast(msg)
+		return
+	}
+
 	err := n.Ready(jetstreamReadyTimeout)
 	if err != nil {
 		n.log.Debug("JetStream is not ready yet to publish messages, add to backlog")
@@ -324,9 +377,16 @@ func (n *NATS) HandleBroadcast(msg *common.StreamMessage) {
 		return
 	}
 
-	offset, err := n.add(msg.Stream, msg.Data)
+	key := ""
+
+	if msg.Meta != nil {
+		key = msg.Meta.CompactionKey
+	}
+
+	offset, err := n.add(msg.Stream, msg.Data, key)
 
 	if err != nil {
+		n.metrics.CounterIncrement(metricsNATSErrors)
 		n.log.Error("failed to add message to JetStream Stream", "stream", msg.Stream, "error", err)
 		return
 	}
@@ -345,7 +405,11 @@ func (n *NATS) Subscribe(stream string) string {
 	isNew := n.tracker.Add(stream)
 
 	if isNew {
-		n.addStreamConsumer(stream)
+		// Wildcard streams have no history on their own
+		if !n.conf.Wildcards || !utils.IsStreamPattern(stream) {
+			n.addStreamConsumer(stream)
+		}
+
 		n.broadcaster.Subscribe(stream)
 	}
 
@@ -362,27 +426,211 @@ func (n *NATS) Unsubscribe(stream string) string {
 	return stream
 }
 
-func (n *NATS) HistoryFrom(stream string, epoch string, offset uint64) ([]common.StreamMessage, error) {
+func (n *NATS) HistoryFrom(stream string, epoch string, offset uint64, opts ...HistoryOption) ([]common.StreamMessage, error) {
 	err := n.Ready(jetstreamReadyTimeout)
 	if err != nil {
 		return nil, err
 	}
 
-	n.streamSync.sync(stream)
-	return n.local.HistoryFrom(stream, epoch, offset)
+	var history []common.StreamMessage
+
+	if n.shouldFetchHistory(stream, opts) {
+		history, err = n.fetchHistoryFrom(stream, epoch, offset, buildHistoryOptions(opts))
+	} else {
+		n.ensureStreamConsumer(stream)
+		n.streamSync.sync(stream)
+		history, err = n.local.HistoryFrom(stream, epoch, offset, opts...)
+	}
+
+	trackHistory(n.metrics, err)
+
+	return history, err
 }
 
-func (n *NATS) HistorySince(stream string, since int64) ([]common.StreamMessage, error) {
+func (n *NATS) HistorySince(stream string, since int64, opts ...HistoryOption) ([]common.StreamMessage, error) {
 	err := n.Ready(jetstreamReadyTimeout)
 	if err != nil {
 		return nil, err
 	}
 
-	n.streamSync.sync(stream)
-	return n.local.HistorySince(stream, since)
+	var history []common.StreamMessage
+
+	if n.shouldFetchHistory(stream, opts) {
+		history, err = n.fetchHistorySince(stream, since, buildHistoryOptions(opts))
+	} else {
+		n.ensureStreamConsumer(stream)
+		n.streamSync.sync(stream)
+		history, err = n.local.HistorySince(stream, since, opts...)
+	}
+
+	trackHistory(n.metrics, err)
+
+	return history, err
+}
+
+// shouldFetchHistory returns true if the bounded history must be read from JetStream directly
+// (instead of synchronizing the whole stream locally). Streams we already consume are served from the local copy.
+func (n *NATS) shouldFetchHistory(stream string, opts []HistoryOption) bool {
+	if buildHistoryOptions(opts).Limit <= 0 {
+		return false
+	}
+
+	_, ok := n.jconsumers.read(stream)
+
+	return !ok
+}
+
+func (n *NATS) fetchHistoryFrom(stream string, epoch string, offset uint64, opts *HistoryOptions) ([]common.StreamMessage, error) {
+	if current := n.Epoch(); current != epoch {
+		return nil, fmt.Errorf("unknown epoch: %s, current: %s", epoch, current)
+	}
+
+	state, err := n.streamState(stream)
+
+	if err == jetstream.ErrStreamNotFound {
+		return nil, ErrStreamNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Msgs == 0 {
+		return nil, errors.New("stream is empty")
+	}
+
+	if state.LastSeq < offset {
+		return nil, fmt.Errorf("requested offset couldn't be found: %d, latest: %d", offset, state.LastSeq)
+	}
+
+	// Compaction purges superseded messages, which may advance the first sequence of the stream,
+	// so we start from the first available message (as the memory broker does)
+	start := max(offset+1, state.FirstSeq)
+
+	if opts.Latest && state.LastSeq >= uint64(opts.Limit) {
+		start = max(start, state.LastSeq-uint64(opts.Limit)+1)
+	}
+
+	if start > state.LastSeq {
+		return []common.StreamMessage{}, nil
+	}
+
+	return n.fetchHistory(stream, jetstream.OrderedConsumerConfig{
+		DeliverPolicy: jetstream.DeliverByStartSequencePolicy,
+		OptStartSeq:   start,
+	}, opts.Limit, 0)
+}
+
+func (n *NATS) fetchHistorySince(stream string, since int64, opts *HistoryOptions) ([]common.StreamMessage, error) {
+	config := jetstream.OrderedConsumerConfig{}
+
+	if opts.Latest {
+		state, err := n.streamState(stream)
+
+		if err == jetstream.ErrStreamNotFound {
+			return nil, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if state.Msgs == 0 {
+			return []common.StreamMessage{}, nil
+		}
+
+		// The latest messages are filtered by time after fetching
+		config.DeliverPolicy = jetstream.DeliverByStartSequencePolicy
+		config.OptStartSeq = 1
+
+		if state.LastSeq > uint64(opts.Limit) {
+			config.OptStartSeq = state.LastSeq - uint64(opts.Limit) + 1
+		}
+	} else {
+		startTime := time.Unix(since, 0)
+		config.DeliverPolicy = jetstream.DeliverByStartTimePolicy
+		config.OptStartTime = &startTime
+	}
+
+	history, err := n.fetchHistory(stream, config, opts.Limit, since)
+
+	if err == jetstream.ErrStreamNotFound {
+		return nil, nil
+	}
+
+	return history, err
+}
+
+// fetchHistory reads at most limit messages from the JetStream stream using a short-lived ordered consumer
+func (n *NATS) fetchHistory(stream string, config jetstream.OrderedConsumerConfig, limit int, since int64) ([]common.StreamMessage, error) {
+	ctx := context.Background()
+
+	cons, err := n.js.OrderedConsumer(ctx, streamPrefix+stream, config)
+
+	if err != nil {
+		if err != jetstream.ErrStreamNotFound {
+			n.metrics.CounterIncrement(metricsNATSErrors)
+		}
+
+		return nil, err
+	}
+
+	batch, err := cons.FetchNoWait(limit)
+
+	if err != nil {
+		n.metrics.CounterIncrement(metricsNATSErrors)
+		return nil, errorx.Decorate(err, "failed to fetch messages from JetStream")
+	}
+
+	epoch := n.Epoch()
+	history := []common.StreamMessage{}
+
+	for msg := range batch.Messages() {
+		meta, err := msg.Metadata()
+
+		if err != nil {
+			return nil, errorx.Decorate(err, "failed to get JetStream message metadata")
+		}
+
+		if meta.Timestamp.Unix() < since {
+			continue
+		}
+
+		history = append(history, common.StreamMessage{
+			Stream: stream,
+			Data:   string(msg.Data()),
+			Offset: meta.Sequence.Stream,
+			Epoch:  epoch,
+		})
+	}
+
+	if err := batch.Error(); err != nil {
+		n.metrics.CounterIncrement(metricsNATSErrors)
+		return nil, errorx.Decorate(err, "failed to fetch messages from JetStream")
+	}
+
+	return history, nil
+}
+
+func (n *NATS) streamState(stream string) (*jetstream.StreamState, error) {
+	jstream, err := n.js.Stream(context.Background(), streamPrefix+stream)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &jstream.CachedInfo().State, nil
 }
 
 func (n *NATS) CommitSession(sid string, session Cacheable) error {
+	err := n.commitSession(sid, session)
+
+	trackSessionCommit(n.metrics, err)
+
+	return err
+}
+
+func (n *NATS) commitSession(sid string, session Cacheable) error {
 	err := n.Ready(jetstreamReadyTimeout)
 	if err != nil {
 		return err
@@ -399,6 +647,7 @@ func (n *NATS) CommitSession(sid string, session Cacheable) error {
 	_, err = n.kv.Put(ctx, key, data)
 
 	if err != nil {
+		n.metrics.CounterIncrement(metricsNATSErrors)
 		return errorx.Decorate(err, "failed to save session to NATS")
 	}
 
@@ -406,6 +655,14 @@ func (n *NATS) CommitSession(sid string, session Cacheable) error {
 }
 
 func (n *NATS) RestoreSession(sid string) ([]byte, error) {
+	data, err := n.restoreSession(sid)
+
+	trackSessionRestore(n.metrics, data, err)
+
+	return data, err
+}
+
+func (n *NATS) restoreSession(sid string) ([]byte, error) {
 	err := n.Ready(jetstreamReadyTimeout)
 	if err != nil {
 		return nil, err
@@ -421,6 +678,7 @@ func (n *NATS) RestoreSession(sid string) ([]byte, error) {
 	}
 
 	if err != nil {
+		n.metrics.CounterIncrement(metricsNATSErrors)
 		return nil, errorx.Decorate(err, "failed to restore session from NATS")
 	}
 
@@ -439,12 +697,14 @@ func (n *NATS) FinishSession(sid string) error {
 	entry, err := n.kv.Get(ctx, key)
 
 	if err != nil {
+		n.metrics.CounterIncrement(metricsNATSErrors)
 		return errorx.Decorate(err, "failed to restore session from NATS")
 	}
 
 	_, err = n.kv.Put(ctx, key, entry.Value())
 
 	if err != nil {
+		n.metrics.CounterIncrement(metricsNATSErrors)
 		return errorx.Decorate(err, "failed to touch session in NATS")
 	}
 
@@ -460,9 +720,13 @@ func (n *NATS) Reset() error {
 	n.clientMu.Lock()
 	defer n.clientMu.Unlock()
 
-	// Delete all sessions
-	if n.kv != nil {
-		keys, err := n.kv.Keys(context.Background())
+	// Delete all sessions and presence records
+	for _, bucket := range []jetstream.KeyValue{n.kv, n.presenceKV} {
+		if bucket == nil {
+			continue
+		}
+
+		keys, err := bucket.Keys(context.Background())
 		if err != nil {
 			if err != jetstream.ErrNoKeysFound {
 				return err
@@ -470,7 +734,7 @@ func (n *NATS) Reset() error {
 		}
 
 		for _, key := range keys {
-			n.kv.Delete(context.Background(), key) // nolint:errcheck
+			bucket.Delete(context.Background(), key) // nolint:errcheck
 		}
 	}
 
@@ -482,23 +746,7 @@ func (n *NATS) Reset() error {
 	return nil
 }
 
-func (n *NATS) PresenceAdd(stream string, sid string, pid string, info interface{}) (*common.PresenceEvent, error) {
-	return nil, errors.New("presence not supported")
-}
-
-func (n *NATS) PresenceRemove(stream string, sid string) (*common.PresenceEvent, error) {
-	return nil, errors.New("presence not supported")
-}
-
-func (n *NATS) PresenceInfo(stream string, opts ...PresenceInfoOption) (*common.PresenceInfo, error) {
-	return nil, errors.New("presence not supported")
-}
-
-func (n *NATS) FinishPresence(sid string) error {
-	return nil
-}
-
-func (n *NATS) add(stream string, data string) (uint64, error) {
+func (n *NATS) add(stream string, data string, compactionKey string) (uint64, error) {
 	err := n.ensureStreamExists(stream)
 
 	if err != nil {
@@ -507,24 +755,59 @@ func (n *NATS) add(stream string, data string) (uint64, error) {
 
 	ctx := context.Background()
 	key := streamPrefix + stream
+	subject := key
+
+	// Messages with compaction keys are published to per-key subjects, so we can purge superseded ones
+	if compactionKey != "" {
+		subject = natsCompactionSubject(key, compactionKey)
+	}
 
 	// Touch on publish to make sure that the subsequent history fetch will return the latest messages
 	n.streamSync.touch(stream)
-	ack, err := n.js.Publish(ctx, key, []byte(data))
+	msg := nats.NewMsg(subject)
+	msg.Data = []byte(data)
+
+	// Compaction key is passed via headers to be used by consumers when storing messages locally
+	if compactionKey != "" {
+		msg.Header.Set(natsCompactionKeyHeader, compactionKey)
+	}
+
+	ack, err := n.js.PublishMsg(ctx, msg)
 
 	if err != nil {
 		return 0, errorx.Decorate(err, "failed to publish message to JetStream")
 	}
 
+	if compactionKey != "" {
+		if err := n.compact(key, subject); err != nil {
+			n.metrics.CounterIncrement(metricsNATSErrors)
+			n.log.Warn("failed to compact JetStream stream", "stream", stream, "error", err)
+		}
+	}
+
 	return ack.Sequence, nil
 }
 
+// compact removes all but the latest message published to the compaction key subject
+func (n *NATS) compact(name string, subject string) error {
+	ctx := context.Background()
+
+	jstream, err := n.js.Stream(ctx, name)
+
+	if err != nil {
+		return err
+	}
+
+	return jstream.Purge(ctx, jetstream.WithPurgeSubject(subject), jetstream.WithPurgeKeep(1))
+}
+
 func (n *NATS) addStreamConsumer(stream string) {
 	attempts := 5
 
 	err := n.ensureStreamExists(stream)
 
 	if err != nil {
+		n.metrics.CounterIncrement(metricsNATSErrors)
 		n.log.Error("failed to create JetStream stream", "stream", stream, "error", err)
 		return
 	}
@@ -538,6 +821,7 @@ createConsumer:
 		})
 
 		if err != nil {
+			n.metrics.CounterIncrement(metricsNATSErrors)
 			n.log.Error("failed to create JetStream stream consumer", "stream", stream, "error", err)
 			return nil, err
 		}
@@ -546,7 +830,7 @@ createConsumer:
 
 		n.streamSync.touch(stream)
 
-		batchSize := n.conf.HistoryLimit
+		batchSize := n.conf.RetentionFor(stream).Limit
 
 		if batchSize == 0 {
 			// TODO: what should we do if history is unlimited?
@@ -555,6 +839,7 @@ createConsumer:
 
 		batch, err := cons.FetchNoWait(batchSize)
 		if err != nil {
+			n.metrics.CounterIncrement(metricsNATSErrors)
 			n.log.Error("failed to fetch initial messages from JetStream", "error", err)
 			return nil, err
 		}
@@ -586,6 +871,16 @@ createConsumer:
 	}
 }
 
+// ensureStreamConsumer makes sure the stream's history is synchronized locally:
+// history could be requested for streams without explicit subscriptions (e.g., matching wildcard subscriptions)
+func (n *NATS) ensureStreamConsumer(stream string) {
+	if _, ok := n.jconsumers.read(stream); ok {
+		return
+	}
+
+	n.addStreamConsumer(stream)
+}
+
 func (n *NATS) consumeMessage(stream string, msg jetstream.Msg) {
 	n.streamSync.touch(stream)
 
@@ -598,7 +893,7 @@ func (n *NATS) consumeMessage(stream string, msg jetstream.Msg) {
 	seq := meta.Sequence.Stream
 	ts := meta.Timestamp
 
-	_, err = n.local.Store(stream, msg.Data(), seq, ts)
+	_, err = n.local.StoreCompacted(stream, msg.Data(), msg.Headers().Get(natsCompactionKeyHeader), seq, ts)
 	if err != nil {
 		n.log.Error("failed to store message in local broker", "error", err)
 		return
@@ -612,19 +907,28 @@ func (n *NATS) ensureStreamExists(stream string) error {
 createStream:
 	_, err := n.jstreams.fetch(stream, func() (string, error) {
 		ctx := context.Background()
+		retention := n.conf.RetentionFor(stream)
 
-		_, err := n.js.CreateStream(ctx, jetstream.StreamConfig{
+		streamConfig := jetstream.StreamConfig{
 			Name:     prefixedStream,
-			MaxMsgs:  int64(n.conf.HistoryLimit),
-			MaxAge:   time.Duration(n.conf.HistoryTTL * int64(time.Second)),
+			Subjects: []string{prefixedStream, prefixedStream + natsCompactionSubjectInfix + "*"},
+			MaxMsgs:  int64(retention.Limit),
+			MaxAge:   time.Duration(retention.TTL * int64(time.Second)),
+			MaxBytes: retention.MaxBytes,
 			Replicas: 1,
-		})
+		}
+
+		_, err := n.js.CreateStream(ctx, streamConfig)
 
 		if err != nil {
-			// That means we updated the stream config (TTL, limit, etc.)
 			if err != jetstream.ErrStreamNameAlreadyInUse {
 				return "", err
 			}
+
+			// That means we updated the stream config (TTL, limit, etc.)
+			if _, err := n.js.UpdateStream(ctx, streamConfig); err != nil {
+				n.log.Warn("failed to update JetStream stream retention settings", "stream", stream, "error", err)
+			}
 		}
 
 		return stream, nil
@@ -986,3 +1290,36 @@ func (n *NATS) backlogFlush() {
 
 	n.broadcastBacklog = []*common.StreamMessage{}
 }
+
+func (n *NATS) collectStats(ctx context.Context) {
+	if _, ok := n.metrics.(metrics.NoopMetrics); ok {
+		return
+	}
+
+	ticker := time.NewTicker(natsStatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n.collectStatsOnce()
+		}
+	}
+}
+
+// collectStatsOnce reports the state of the local streams copy and presence records of the connected sessions
+func (n *NATS) collectStatsOnce() {
+	if r, ok := n.local.(historyStatsReporter); ok {
+		reportHistoryStats(n.metrics, r)
+	}
+
+	n.metrics.GaugeSet(metricsPresenceRecordsNum, uint64(n.presenceSessions.size()))
+}
+
+// natsCompactionSubject returns a subject for messages with the specified compaction key
+// (keys may contain characters which are not allowed in subjects, so we encode them)
+func natsCompactionSubject(name string, key string) string {
+	return name + natsCompactionSubjectInfix + base64.RawURLEncoding.EncodeToString([]byte(key))
+}
@@ -0,0 +1,207 @@
+package broker
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/utils"
+	"github.com/joomcode/errorx"
+	nanoid "github.com/matoous/go-nanoid"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Subject to notify other nodes that the stream history has been deleted
+// (so they can drop their local copies)
+const natsHistoryDeletedSubject = "_anycable_history_deleted_"
+
+var _ HistoryAdmin = (*NATS)(nil)
+
+func (n *NATS) Streams() ([]*StreamInfo, error) {
+	err := n.Ready(jetstreamReadyTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	streams := []*StreamInfo{}
+
+	lister := n.js.ListStreams(context.Background())
+
+	for info := range lister.Info() {
+		if !strings.HasPrefix(info.Config.Name, streamPrefix) {
+			continue
+		}
+
+		streams = append(streams, &StreamInfo{
+			Name:    strings.TrimPrefix(info.Config.Name, streamPrefix),
+			Low:     info.State.FirstSeq,
+			High:    info.State.LastSeq,
+			Entries: int(info.State.Msgs),
+			Bytes:   int64(info.State.Bytes),
+		})
+	}
+
+	if err := lister.Err(); err != nil {
+		return nil, errorx.Decorate(err, "failed to list JetStream streams")
+	}
+
+	slices.SortFunc(streams, func(a, b *StreamInfo) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return streams, nil
+}
+
+var _ StreamsMatcher = (*NATS)(nil)
+
+func (n *NATS) MatchStreams(pattern string) ([]string, error) {
+	err := n.Ready(jetstreamReadyTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	streams := []string{}
+
+	// Only names are requested, so we don't fetch the state of all the streams
+	lister := n.js.StreamNames(context.Background())
+
+	for name := range lister.Name() {
+		if !strings.HasPrefix(name, streamPrefix) {
+			continue
+		}
+
+		name = strings.TrimPrefix(name, streamPrefix)
+
+		if utils.MatchWildcardStream(pattern, name) {
+			streams = append(streams, name)
+		}
+	}
+
+	if err := lister.Err(); err != nil {
+		return nil, errorx.Decorate(err, "failed to list JetStream streams")
+	}
+
+	slices.Sort(streams)
+
+	return streams, nil
+}
+
+func (n *NATS) ReadHistory(name string, offset uint64, limit int) ([]common.StreamMessage, error) {
+	err := n.Ready(jetstreamReadyTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+
+	stream, err := n.js.Stream(ctx, streamPrefix+name)
+
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		return nil, ErrStreamNotFound
+	}
+
+	if err != nil {
+		return nil, errorx.Decorate(err, "failed to fetch JetStream stream")
+	}
+
+	state := stream.CachedInfo().State
+	epoch := n.Epoch()
+	history := []common.StreamMessage{}
+
+	for seq := max(offset, state.FirstSeq); seq <= state.LastSeq; seq++ {
+		if limit > 0 && len(history) >= limit {
+			break
+		}
+
+		msg, err := stream.GetMsg(ctx, seq)
+
+		// Messages could be deleted
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, errorx.Decorate(err, "failed to read JetStream message")
+		}
+
+		history = append(history, common.StreamMessage{
+			Stream: name,
+			Data:   string(msg.Data),
+			Offset: msg.Sequence,
+			Epoch:  epoch,
+		})
+	}
+
+	return history, nil
+}
+
+func (n *NATS) DeleteHistory(name string) error {
+	err := n.Ready(jetstreamReadyTimeout)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	stream, err := n.js.Stream(ctx, streamPrefix+name)
+
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		return ErrStreamNotFound
+	}
+
+	if err != nil {
+		return errorx.Decorate(err, "failed to fetch JetStream stream")
+	}
+
+	// Purging keeps the stream sequence, so offsets stay monotonic
+	if err := stream.Purge(ctx); err != nil {
+		return errorx.Decorate(err, "failed to purge JetStream stream")
+	}
+
+	n.deleteLocalHistory(name)
+
+	if err := n.conn.Publish(natsHistoryDeletedSubject, []byte(name)); err != nil {
+		n.log.Warn("failed to notify other nodes of the deleted history", "stream", name, "error", err)
+	}
+
+	n.log.Info("stream history deleted", "stream", name)
+
+	return nil
+}
+
+func (n *NATS) RotateEpoch() (string, error) {
+	epoch, err := nanoid.Nanoid(4)
+
+	if err != nil {
+		return "", err
+	}
+
+	// Other nodes receive the new epoch via the epoch watcher
+	if err := n.SetEpoch(epoch); err != nil {
+		return "", errorx.Decorate(err, "failed to update epoch")
+	}
+
+	n.log.Info("epoch rotated", "epoch", epoch)
+
+	return epoch, nil
+}
+
+func (n *NATS) watchDeletedHistory() error {
+	_, err := n.conn.Subscribe(natsHistoryDeletedSubject, func(msg *nats.Msg) {
+		n.deleteLocalHistory(string(msg.Data))
+	})
+
+	return err
+}
+
+func (n *NATS) deleteLocalHistory(name string) {
+	err := n.local.DeleteHistory(name)
+
+	if err != nil && !errors.Is(err, ErrStreamNotFound) {
+		n.log.Error("failed to delete local stream history", "stream", name, "error", err)
+	}
+}
@@ -0,0 +1,832 @@
+package broker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/utils"
+	"github.com/joomcode/errorx"
+	nanoid "github.com/matoous/go-nanoid"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	presenceBucket    = "_anycable_presence_"
+	presenceKeyPrefix = "p."
+	// Sessions of every presence ID are stored under a separate key (m.<stream>.<pid>),
+	// so joins and leaves are serialized via revision checks
+	presenceMembersKeyPrefix = "m."
+	// Max number of attempts to update a key in case of concurrent modifications
+	natsCASAttempts = 10
+	// A lease key used to elect a node responsible for expiring stale presence records
+	presenceLockKey = "_lock_"
+)
+
+// natsPresenceEntry is a presence record of a single session in a stream.
+// Every session-stream pair is stored under its own key (p.<stream>.<session>),
+// so writers do not contend for the same key.
+type natsPresenceEntry struct {
+	Session string          `json:"s"`
+	ID      string          `json:"p"`
+	Info    json.RawMessage `json:"i,omitempty"`
+	// Unix time (in milliseconds) after which the record is considered stale.
+	// Connected sessions periodically push it forward.
+	Deadline int64 `json:"d"`
+
+	revision uint64
+}
+
+func (e *natsPresenceEntry) alive(now int64) bool {
+	return e.Deadline >= now
+}
+
+var (
+	errPresenceNotFound = errors.New("presence info not found")
+	errCASConflict      = errors.New("too many concurrent updates")
+)
+
+// natsPresenceRecord is a presence record of a session connected to this node
+type natsPresenceRecord struct {
+	stream   string
+	pid      string
+	info     json.RawMessage
+	revision uint64
+}
+
+// natsPresenceSessions keeps track of presence records of sessions connected to this node,
+// so we can keep them alive
+type natsPresenceSessions struct {
+	records map[string]map[string]*natsPresenceRecord
+
+	mu sync.Mutex
+}
+
+func newNATSPresenceSessions() *natsPresenceSessions {
+	return &natsPresenceSessions{records: make(map[string]map[string]*natsPresenceRecord)}
+}
+
+func (ps *natsPresenceSessions) add(sid string, record *natsPresenceRecord) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, ok := ps.records[sid]; !ok {
+		ps.records[sid] = make(map[string]*natsPresenceRecord)
+	}
+
+	ps.records[sid][record.stream] = record
+}
+
+func (ps *natsPresenceSessions) update(sid string, stream string, info json.RawMessage, revision uint64) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if record, ok := ps.records[sid][stream]; ok {
+		record.info = info
+		record.revision = revision
+	}
+}
+
+func (ps *natsPresenceSessions) touch(sid string, stream string, revision uint64) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if record, ok := ps.records[sid][stream]; ok {
+		record.revision = revision
+	}
+}
+
+func (ps *natsPresenceSessions) remove(sid string, stream string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if streams, ok := ps.records[sid]; ok {
+		delete(streams, stream)
+
+		if len(streams) == 0 {
+			delete(ps.records, sid)
+		}
+	}
+}
+
+// finish stops tracking the session and returns its records
+func (ps *natsPresenceSessions) finish(sid string) []*natsPresenceRecord {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	streams, ok := ps.records[sid]
+
+	if !ok {
+		return nil
+	}
+
+	delete(ps.records, sid)
+
+	res := make([]*natsPresenceRecord, 0, len(streams))
+	for _, record := range streams {
+		res = append(res, record)
+	}
+
+	return res
+}
+
+// snapshot returns copies of all tracked records grouped by session ID
+func (ps *natsPresenceSessions) snapshot() map[string][]natsPresenceRecord {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	res := make(map[string][]natsPresenceRecord, len(ps.records))
+
+	for sid, streams := range ps.records {
+		for _, record := range streams {
+			res[sid] = append(res[sid], *record)
+		}
+	}
+
+	return res
+}
+
+// size returns the number of presence records (stream-session pairs) tracked by this node
+func (ps *natsPresenceSessions) size() int {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	res := 0
+
+	for _, streams := range ps.records {
+		res += len(streams)
+	}
+
+	return res
+}
+
+// natsPresenceExpirer keeps a local copy of presence deadlines (fed by a KV watcher).
+// It's only active on the node holding the presence lock.
+type natsPresenceExpirer struct {
+	entries map[string]*natsPresenceEntry
+	ready   bool
+	stop    func() error
+
+	mu sync.Mutex
+}
+
+func (n *NATS) PresenceAdd(stream string, sid string, pid string, info interface{}) (*common.PresenceEvent, error) {
+	err := n.Ready(jetstreamReadyTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := n.getPresence(stream, sid)
+
+	if err != nil && !errors.Is(err, errPresenceNotFound) {
+		return nil, err
+	}
+
+	if current != nil && current.ID != pid {
+		return nil, errors.New("presence ID mismatch")
+	}
+
+	joined := false
+
+	err = n.updatePresenceMembers(stream, pid, func(members map[string]int64) bool {
+		// The session has already joined, so we only update the record
+		if _, ok := members[sid]; ok {
+			joined = false
+			return false
+		}
+
+		joined = len(members) == 0
+		members[sid] = time.Now().UnixMilli()
+
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	record := &natsPresenceRecord{stream: stream, pid: pid, info: utils.ToJSON(info)}
+
+	revision, err := n.putPresence(sid, record)
+
+	if err != nil {
+		// Roll back the membership, so the presence ID doesn't get stuck
+		if current == nil {
+			n.removePresenceMember(stream, pid, sid) // nolint:errcheck
+		}
+
+		return nil, err
+	}
+
+	record.revision = revision
+	n.presenceSessions.add(sid, record)
+
+	if !joined {
+		return nil, nil
+	}
+
+	return &common.PresenceEvent{
+		Type: common.PresenceJoinType,
+		ID:   pid,
+		Info: info,
+	}, nil
+}
+
+func (n *NATS) PresenceRemove(stream string, sid string) (*common.PresenceEvent, error) {
+	err := n.Ready(jetstreamReadyTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	n.presenceSessions.remove(sid, stream)
+
+	entry, err := n.getPresence(stream, sid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = n.presenceKV.Delete(context.Background(), presenceKey(stream, sid))
+
+	if err != nil {
+		n.metrics.CounterIncrement(metricsNATSErrors)
+		return nil, errorx.Decorate(err, "failed to remove presence from NATS")
+	}
+
+	left, err := n.removePresenceMember(stream, entry.ID, sid)
+
+	if err != nil || !left {
+		return nil, err
+	}
+
+	return &common.PresenceEvent{
+		Type: common.PresenceLeaveType,
+		ID:   entry.ID,
+	}, nil
+}
+
+func (n *NATS) PresenceUpdate(stream string, sid string, info interface{}) (*common.PresenceEvent, error) {
+	err := n.Ready(jetstreamReadyTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := n.getPresence(stream, sid)
+
+	if err != nil {
+		return nil, err
+	}
+
+	record := &natsPresenceRecord{stream: stream, pid: entry.ID, info: utils.ToJSON(info)}
+
+	revision, err := n.putPresence(sid, record)
+
+	if err != nil {
+		return nil, err
+	}
+
+	n.presenceSessions.update(sid, stream, record.info, revision)
+
+	return &common.PresenceEvent{
+		Type: common.PresenceUpdateType,
+		ID:   entry.ID,
+		Info: info,
+	}, nil
+}
+
+func (n *NATS) PresenceInfo(stream string, opts ...PresenceInfoOption) (*common.PresenceInfo, error) {
+	options := NewPresenceInfoOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	err := n.Ready(jetstreamReadyTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := n.fetchPresence(stream)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	records := make(map[string]json.RawMessage)
+
+	for _, entry := range entries {
+		// Do not return records which are about to be expired
+		if entry.alive(now) {
+			records[entry.ID] = entry.Info
+		}
+	}
+
+	info := common.NewPresenceInfo()
+	info.Total = len(records)
+
+	if options.ReturnRecords {
+		info.Records = make([]*common.PresenceEvent, 0, len(records))
+
+		for pid, pinfo := range records {
+			info.Records = append(info.Records, &common.PresenceEvent{
+				Info: pinfo,
+				ID:   pid,
+			})
+		}
+	}
+
+	return info, nil
+}
+
+// FinishPresence stops refreshing session's presence records and sets their deadlines to now + PresenceTTL
+func (n *NATS) FinishPresence(sid string) error {
+	err := n.Ready(jetstreamReadyTimeout)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range n.presenceSessions.finish(sid) {
+		_, err := n.presenceKV.Update(context.Background(), presenceKey(record.stream, sid), n.encodePresence(sid, record), record.revision)
+
+		// The record could have been updated concurrently; it expires by the bucket TTL anyway
+		if err != nil && !errors.Is(err, jetstream.ErrKeyExists) {
+			n.metrics.CounterIncrement(metricsNATSErrors)
+			return errorx.Decorate(err, "failed to update presence in NATS")
+		}
+	}
+
+	return nil
+}
+
+// presenceLoop keeps presence records (and quota slots) of the connected sessions alive and
+// expires stale records (if this node holds the presence lock)
+func (n *NATS) presenceLoop(ctx context.Context) {
+	nodeID, _ := nanoid.Nanoid()
+	expirer := &natsPresenceExpirer{}
+
+	ticker := time.NewTicker(n.presenceRefreshInterval())
+	defer ticker.Stop()
+
+	defer expirer.release()
+
+	for {
+		select {
+		case <-ctx.Done():
+			n.releasePresenceLock(nodeID)
+			return
+		case <-ticker.C:
+			n.refreshPresence()
+			n.refreshSessionsQuotas()
+
+			if n.acquirePresenceLock(nodeID) {
+				n.expirePresence(ctx, expirer)
+			} else {
+				expirer.release()
+			}
+		}
+	}
+}
+
+func (n *NATS) refreshPresence() {
+	ctx := context.Background()
+	snapshot := n.presenceSessions.snapshot()
+
+	for sid, records := range snapshot {
+		for i := range records {
+			record := &records[i]
+			key := presenceKey(record.stream, sid)
+
+			revision, err := n.presenceKV.Update(ctx, key, n.encodePresence(sid, record), record.revision)
+
+			if err == nil {
+				n.presenceSessions.touch(sid, record.stream, revision)
+				continue
+			}
+
+			// The record has been modified concurrently (e.g., by PresenceUpdate), catch up with the latest revision
+			if errors.Is(err, jetstream.ErrKeyExists) {
+				if entry, gerr := n.presenceKV.Get(ctx, key); gerr == nil {
+					n.presenceSessions.touch(sid, record.stream, entry.Revision())
+				}
+				continue
+			}
+
+			n.metrics.CounterIncrement(metricsNATSErrors)
+			n.log.Warn("failed to refresh presence", "stream", record.stream, "sid", sid, "error", err)
+		}
+	}
+
+	n.refreshPresenceMembers(snapshot)
+}
+
+// refreshPresenceMembers keeps the members keys of the local sessions from being expired by the bucket TTL
+func (n *NATS) refreshPresenceMembers(snapshot map[string][]natsPresenceRecord) {
+	type membersKey struct{ stream, pid string }
+
+	keys := make(map[membersKey][]string)
+
+	for sid, records := range snapshot {
+		for _, record := range records {
+			key := membersKey{record.stream, record.pid}
+			keys[key] = append(keys[key], sid)
+		}
+	}
+
+	for key, sids := range keys {
+		err := n.updatePresenceMembers(key.stream, key.pid, func(members map[string]int64) bool {
+			// Only touch the key if it still contains our sessions (they could have been removed concurrently)
+			for _, sid := range sids {
+				if _, ok := members[sid]; ok {
+					return true
+				}
+			}
+
+			return false
+		})
+
+		if err != nil {
+			n.log.Warn("failed to refresh presence", "stream", key.stream, "error", err)
+		}
+	}
+}
+
+// acquirePresenceLock creates or prolongs the presence lock lease.
+// The lease expires via the bucket TTL if the holder stops refreshing it.
+func (n *NATS) acquirePresenceLock(nodeID string) bool {
+	ctx := context.Background()
+
+	_, err := n.presenceKV.Create(ctx, presenceLockKey, []byte(nodeID))
+
+	if err == nil {
+		return true
+	}
+
+	if !errors.Is(err, jetstream.ErrKeyExists) {
+		return false
+	}
+
+	entry, err := n.presenceKV.Get(ctx, presenceLockKey)
+
+	if err != nil || string(entry.Value()) != nodeID {
+		return false
+	}
+
+	_, err = n.presenceKV.Update(ctx, presenceLockKey, []byte(nodeID), entry.Revision())
+
+	return err == nil
+}
+
+func (n *NATS) releasePresenceLock(nodeID string) {
+	ctx := context.Background()
+
+	entry, err := n.presenceKV.Get(ctx, presenceLockKey)
+
+	if err != nil || string(entry.Value()) != nodeID {
+		return
+	}
+
+	n.presenceKV.Delete(ctx, presenceLockKey, jetstream.LastRevision(entry.Revision())) // nolint:errcheck
+}
+
+// expirePresence removes stale presence records and broadcasts leave events.
+// Only a single node (holding the lock) performs expiration, so leave events are not duplicated.
+func (n *NATS) expirePresence(ctx context.Context, expirer *natsPresenceExpirer) {
+	ready, err := expirer.watch(ctx, n.presenceKV)
+
+	if err != nil {
+		n.log.Warn("failed to watch presence records", "error", err)
+		return
+	}
+
+	// Wait for the initial state to be loaded
+	if !ready {
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	stale := expirer.stale(now, n.presenceBucketTTL().Milliseconds())
+
+	leaving := make(map[string][]string)
+
+	for key, entry := range stale {
+		err := n.presenceKV.Delete(ctx, key, jetstream.LastRevision(entry.revision))
+
+		// The record has been refreshed or removed concurrently
+		if err != nil {
+			continue
+		}
+
+		stream := presenceStreamFromKey(key)
+
+		left, err := n.removePresenceMember(stream, entry.ID, entry.Session)
+
+		if err != nil {
+			n.log.Warn("failed to expire presence", "stream", stream, "error", err)
+			continue
+		}
+
+		if left {
+			leaving[stream] = append(leaving[stream], entry.ID)
+		}
+	}
+
+	for stream, pids := range leaving {
+		n.broadcastPresenceLeave(stream, pids)
+	}
+}
+
+// watch starts watching presence records (unless already started) and returns true if the initial state has been loaded
+func (e *natsPresenceExpirer) watch(ctx context.Context, kv jetstream.KeyValue) (bool, error) {
+	e.mu.Lock()
+
+	if e.stop != nil {
+		defer e.mu.Unlock()
+		return e.ready, nil
+	}
+
+	watcher, err := kv.Watch(ctx, presenceKeyPrefix+">")
+
+	if err != nil {
+		e.mu.Unlock()
+		return false, err
+	}
+
+	e.entries = make(map[string]*natsPresenceEntry)
+	e.ready = false
+	e.stop = watcher.Stop
+	e.mu.Unlock()
+
+	go func() {
+		for update := range watcher.Updates() {
+			e.mu.Lock()
+
+			switch {
+			// Initial values have been delivered
+			case update == nil:
+				e.ready = true
+			case update.Operation() == jetstream.KeyValuePut:
+				entry := &natsPresenceEntry{}
+
+				if json.Unmarshal(update.Value(), entry) == nil {
+					entry.revision = update.Revision()
+					e.entries[update.Key()] = entry
+				}
+			default:
+				delete(e.entries, update.Key())
+			}
+
+			e.mu.Unlock()
+		}
+	}()
+
+	return false, nil
+}
+
+// stale returns the records with passed deadlines. Records which must have been
+// already purged by the bucket TTL are dropped.
+func (e *natsPresenceExpirer) stale(now int64, ttl int64) map[string]*natsPresenceEntry {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	res := make(map[string]*natsPresenceEntry)
+
+	for key, entry := range e.entries {
+		if entry.alive(now) {
+			continue
+		}
+
+		if entry.Deadline+ttl < now {
+			delete(e.entries, key)
+			continue
+		}
+
+		res[key] = entry
+	}
+
+	return res
+}
+
+func (e *natsPresenceExpirer) release() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.stop != nil {
+		e.stop() // nolint:errcheck
+		e.stop = nil
+	}
+
+	e.entries = nil
+	e.ready = false
+}
+
+// removePresenceMember removes the session from the presence ID members and
+// returns true if it was the last one (i.e., the presence ID has left the stream)
+func (n *NATS) removePresenceMember(stream string, pid string, sid string) (bool, error) {
+	left := false
+
+	err := n.updatePresenceMembers(stream, pid, func(members map[string]int64) bool {
+		left = false
+
+		if _, ok := members[sid]; !ok {
+			return false
+		}
+
+		delete(members, sid)
+		left = len(members) == 0
+
+		return true
+	})
+
+	return left, err
+}
+
+func (n *NATS) updatePresenceMembers(stream string, pid string, fn func(members map[string]int64) bool) error {
+	err := n.updateSessionsMap(presenceMembersKey(stream, pid), fn)
+
+	if err != nil {
+		return errorx.Decorate(err, "failed to update presence in NATS")
+	}
+
+	return nil
+}
+
+// updateSessionsMap reads a map (sid -> value) stored under the key, applies the update function and writes the result back
+// (if the function returns true) unless the key has been modified concurrently (in this case, we retry)
+func (n *NATS) updateSessionsMap(key string, fn func(sessions map[string]int64) bool) error {
+	ctx := context.Background()
+
+	for attempt := 0; attempt < natsCASAttempts; attempt++ {
+		sessions := make(map[string]int64)
+		var revision uint64
+
+		entry, err := n.presenceKV.Get(ctx, key)
+
+		if err != nil && !errors.Is(err, jetstream.ErrKeyNotFound) {
+			n.metrics.CounterIncrement(metricsNATSErrors)
+			return err
+		}
+
+		if err == nil {
+			revision = entry.Revision()
+
+			if err := json.Unmarshal(entry.Value(), &sessions); err != nil {
+				return err
+			}
+		}
+
+		if !fn(sessions) {
+			return nil
+		}
+
+		if revision == 0 {
+			_, err = n.presenceKV.Create(ctx, key, utils.ToJSON(sessions))
+		} else {
+			_, err = n.presenceKV.Update(ctx, key, utils.ToJSON(sessions), revision)
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		if !errors.Is(err, jetstream.ErrKeyExists) {
+			n.metrics.CounterIncrement(metricsNATSErrors)
+			return err
+		}
+	}
+
+	return errCASConflict
+}
+
+func (n *NATS) putPresence(sid string, record *natsPresenceRecord) (uint64, error) {
+	revision, err := n.presenceKV.Put(context.Background(), presenceKey(record.stream, sid), n.encodePresence(sid, record))
+
+	if err != nil {
+		n.metrics.CounterIncrement(metricsNATSErrors)
+		return 0, errorx.Decorate(err, "failed to update presence in NATS")
+	}
+
+	return revision, nil
+}
+
+func (n *NATS) encodePresence(sid string, record *natsPresenceRecord) []byte {
+	deadline := time.Now().Add(time.Duration(n.conf.PresenceTTL) * time.Second).UnixMilli()
+
+	return utils.ToJSON(&natsPresenceEntry{Session: sid, ID: record.pid, Info: record.info, Deadline: deadline})
+}
+
+func (n *NATS) getPresence(stream string, sid string) (*natsPresenceEntry, error) {
+	kv, err := n.presenceKV.Get(context.Background(), presenceKey(stream, sid))
+
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil, errPresenceNotFound
+	}
+
+	if err != nil {
+		n.metrics.CounterIncrement(metricsNATSErrors)
+		return nil, errorx.Decorate(err, "failed to fetch presence from NATS")
+	}
+
+	entry := &natsPresenceEntry{}
+
+	if err := json.Unmarshal(kv.Value(), entry); err != nil {
+		return nil, errorx.Decorate(err, "failed to decode presence record")
+	}
+
+	entry.revision = kv.Revision()
+
+	return entry, nil
+}
+
+// fetchPresence returns all presence records of the stream (including stale ones)
+func (n *NATS) fetchPresence(stream string) ([]*natsPresenceEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), jetstreamReadyTimeout)
+	defer cancel()
+
+	watcher, err := n.presenceKV.Watch(ctx, presenceStreamPrefix(stream)+"*", jetstream.IgnoreDeletes())
+
+	if err != nil {
+		n.metrics.CounterIncrement(metricsNATSErrors)
+		return nil, errorx.Decorate(err, "failed to fetch presence from NATS")
+	}
+
+	defer watcher.Stop() // nolint:errcheck
+
+	entries := []*natsPresenceEntry{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			n.metrics.CounterIncrement(metricsNATSErrors)
+			return nil, errorx.Decorate(ctx.Err(), "failed to fetch presence from NATS")
+		case update := <-watcher.Updates():
+			// Initial values have been delivered
+			if update == nil {
+				return entries, nil
+			}
+
+			entry := &natsPresenceEntry{}
+
+			if err := json.Unmarshal(update.Value(), entry); err != nil {
+				continue
+			}
+
+			entry.revision = update.Revision()
+			entries = append(entries, entry)
+		}
+	}
+}
+
+func (n *NATS) broadcastPresenceLeave(stream string, pids []string) {
+	if n.broadcaster == nil {
+		return
+	}
+
+	for _, pid := range pids {
+		msg := &common.PresenceEvent{Type: common.PresenceLeaveType, ID: pid}
+
+		n.broadcaster.Broadcast(&common.StreamMessage{
+			Stream: stream,
+			Data:   string(utils.ToJSON(msg)),
+			Meta: &common.StreamMessageMetadata{
+				BroadcastType: common.PresenceType,
+				Transient:     true,
+			},
+		})
+	}
+}
+
+// presenceBucketTTL returns the max age of presence records. Connected sessions refresh
+// their records more often; the extra time leaves a window to detect stale records and notify clients
+func (n *NATS) presenceBucketTTL() time.Duration {
+	return 2 * time.Duration(n.conf.PresenceTTL) * time.Second
+}
+
+func (n *NATS) presenceRefreshInterval() time.Duration {
+	return max(time.Duration(n.conf.PresenceTTL)*time.Second/3, 100*time.Millisecond)
+}
+
+// Stream names may contain characters which are not allowed in KV keys
+func presenceStreamPrefix(stream string) string {
+	return presenceKeyPrefix + base64.RawURLEncoding.EncodeToString([]byte(stream)) + "."
+}
+
+func presenceKey(stream string, sid string) string {
+	return presenceStreamPrefix(stream) + base64.RawURLEncoding.EncodeToString([]byte(sid))
+}
+
+func presenceMembersKey(stream string, pid string) string {
+	return presenceMembersKeyPrefix + base64.RawURLEncoding.EncodeToString([]byte(stream)) + "." + base64.RawURLEncoding.EncodeToString([]byte(pid))
+}
+
+func presenceStreamFromKey(key string) string {
+	parts := strings.SplitN(strings.TrimPrefix(key, presenceKeyPrefix), ".", 2)
+
+	stream, _ := base64.RawURLEncoding.DecodeString(parts[0])
+
+	return string(stream)
+}
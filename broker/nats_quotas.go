@@ -0,0 +1,128 @@
+package broker
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/joomcode/errorx"
+)
+
+// Quota keys are stored in the presence bucket (q.<key>), so they expire by the bucket TTL
+// if no node refreshes them
+const quotaKeyPrefix = "q."
+
+// AcquireSessionQuota adds the session to the key's sessions (sid -> deadline) unless
+// the number of live sessions reached the limit. Updates are performed via compare-and-set,
+// so concurrent acquisitions on different nodes can't exceed the limit.
+func (n *NATS) AcquireSessionQuota(key string, sid string, limit int) (bool, error) {
+	err := n.Ready(jetstreamReadyTimeout)
+	if err != nil {
+		return false, err
+	}
+
+	acquired := false
+
+	err = n.updateQuota(key, func(sessions map[string]int64) bool {
+		acquired = false
+
+		if _, ok := sessions[sid]; !ok && len(sessions) >= limit {
+			return false
+		}
+
+		sessions[sid] = n.quotaDeadline()
+		acquired = true
+
+		return true
+	})
+
+	if err != nil {
+		return false, errorx.Decorate(err, "failed to acquire sessions quota")
+	}
+
+	if acquired {
+		n.quotaSessions.add(sid, key)
+	}
+
+	return acquired, nil
+}
+
+func (n *NATS) ReleaseSessionQuota(key string, sid string) error {
+	n.quotaSessions.remove(sid)
+
+	err := n.Ready(jetstreamReadyTimeout)
+	if err != nil {
+		return err
+	}
+
+	err = n.updateQuota(key, func(sessions map[string]int64) bool {
+		if _, ok := sessions[sid]; !ok {
+			return false
+		}
+
+		delete(sessions, sid)
+
+		return true
+	})
+
+	if err != nil {
+		return errorx.Decorate(err, "failed to release sessions quota")
+	}
+
+	return nil
+}
+
+// refreshSessionsQuotas prolongs the quota slots of the active local sessions (one update per key).
+// Sessions which are no longer members of the key (e.g., expired) are not re-added.
+func (n *NATS) refreshSessionsQuotas() {
+	keys := make(map[string][]string)
+
+	for sid, key := range n.quotaSessions.snapshot() {
+		keys[key] = append(keys[key], sid)
+	}
+
+	for key, sids := range keys {
+		err := n.updateQuota(key, func(sessions map[string]int64) bool {
+			deadline := n.quotaDeadline()
+			changed := false
+
+			for _, sid := range sids {
+				if _, ok := sessions[sid]; !ok {
+					continue
+				}
+
+				sessions[sid] = deadline
+				changed = true
+			}
+
+			return changed
+		})
+
+		if err != nil {
+			n.log.Warn("failed to refresh sessions quota", "key", key, "error", err)
+		}
+	}
+}
+
+// updateQuota applies the update function to the key's live sessions (sid -> deadline)
+func (n *NATS) updateQuota(key string, fn func(sessions map[string]int64) bool) error {
+	return n.updateSessionsMap(natsQuotaKey(key), func(sessions map[string]int64) bool {
+		now := time.Now().UnixMilli()
+
+		for sid, deadline := range sessions {
+			if deadline < now {
+				delete(sessions, sid)
+			}
+		}
+
+		return fn(sessions)
+	})
+}
+
+func (n *NATS) quotaDeadline() int64 {
+	return time.Now().Add(time.Duration(n.conf.PresenceTTL) * time.Second).UnixMilli()
+}
+
+// Quota keys may contain characters which are not allowed in KV keys
+func natsQuotaKey(key string) string {
+	return quotaKeyPrefix + base64.RawURLEncoding.EncodeToString([]byte(key))
+}
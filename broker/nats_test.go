@@ -2,8 +2,10 @@ package broker
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"sync"
 	"testing"
 	"time"
 
@@ -126,6 +128,191 @@ func TestNATSBroker_HistorySince_with_limit(t *testing.T) {
 	assert.Equal(t, "c", history[1].Data)
 }
 
+func TestNATSBroker_BoundedHistory(t *testing.T) {
+	port := 37
+	addr := fmt.Sprintf("nats://127.0.0.1:44%d", port)
+	server, err := startNATSServer(t, addr)
+	require.NoError(t, err)
+	defer server.Shutdown(context.Background()) // nolint:errcheck
+
+	config := NewConfig()
+
+	nconfig := natsconfig.NewNATSConfig()
+	nconfig.Servers = addr
+
+	broadcaster := pubsub.NewLegacySubscriber(FakeBroadastHandler{})
+	broker := NewNATSBroker(broadcaster, &config, &nconfig, slog.Default())
+
+	err = broker.Start(nil)
+	require.NoError(t, err)
+	defer broker.Shutdown(context.Background()) // nolint: errcheck
+
+	// Ensure no stream exists
+	require.NoError(t, broker.Reset())
+
+	start := time.Now().Unix() - 10
+
+	for _, data := range []string{"a", "b", "c", "d", "e"} {
+		broker.HandleBroadcast(&common.StreamMessage{Stream: "bounded", Data: data})
+	}
+
+	history, err := broker.HistoryFrom("bounded", broker.Epoch(), 1, WithHistoryLimit(2))
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.EqualValues(t, 2, history[0].Offset)
+	assert.Equal(t, "c", history[1].Data)
+
+	history, err = broker.HistoryFrom("bounded", broker.Epoch(), 1, WithLatestHistory(2))
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.EqualValues(t, 4, history[0].Offset)
+	assert.Equal(t, "e", history[1].Data)
+
+	history, err = broker.HistorySince("bounded", start, WithHistoryLimit(2))
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "a", history[0].Data)
+	assert.Equal(t, "b", history[1].Data)
+
+	history, err = broker.HistorySince("bounded", start, WithLatestHistory(3))
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, "c", history[0].Data)
+	assert.Equal(t, "e", history[2].Data)
+
+	_, err = broker.HistoryFrom("bounded", "unknown", 1, WithHistoryLimit(2))
+	require.Error(t, err)
+
+	_, err = broker.HistoryFrom("bounded", broker.Epoch(), 10, WithHistoryLimit(2))
+	require.Error(t, err)
+
+	// Bounded history is fetched from JetStream directly, without synchronizing the whole stream locally
+	_, ok := broker.jconsumers.read("bounded")
+	assert.False(t, ok)
+}
+
+func TestNATSBroker_HistoryCompaction(t *testing.T) {
+	port := 35
+	addr := fmt.Sprintf("nats://127.0.0.1:44%d", port)
+	server, err := startNATSServer(t, addr)
+	require.NoError(t, err)
+	defer server.Shutdown(context.Background()) // nolint:errcheck
+
+	config := NewConfig()
+
+	nconfig := natsconfig.NewNATSConfig()
+	nconfig.Servers = addr
+
+	broadcastHandler := FakeBroadastHandler{}
+	broadcaster := pubsub.NewLegacySubscriber(broadcastHandler)
+	broker := NewNATSBroker(broadcaster, &config, &nconfig, slog.Default())
+
+	err = broker.Start(nil)
+	require.NoError(t, err)
+	defer broker.Shutdown(context.Background()) // nolint: errcheck
+
+	// Ensure no stream exists
+	require.NoError(t, broker.Reset())
+
+	// We must subscribe to receive messages from the stream
+	broker.Subscribe("test")
+	defer broker.Unsubscribe("test")
+
+	start := time.Now().Unix() - 10
+
+	broker.HandleBroadcast(&common.StreamMessage{Stream: "test", Data: "doc 1: draft", Meta: &common.StreamMessageMetadata{CompactionKey: "doc/1"}})
+	broker.HandleBroadcast(&common.StreamMessage{Stream: "test", Data: "hello"})
+	broker.HandleBroadcast(&common.StreamMessage{Stream: "test", Data: "doc 1: published", Meta: &common.StreamMessageMetadata{CompactionKey: "doc/1"}})
+
+	history, err := broker.HistorySince("test", start)
+	require.NoError(t, err)
+
+	require.Len(t, history, 2)
+	assert.Equal(t, "hello", history[0].Data)
+	assert.EqualValues(t, 3, history[1].Offset)
+	assert.Equal(t, "doc 1: published", history[1].Data)
+
+	// Superseded messages are purged from JetStream, too
+	jstream, err := broker.js.Stream(context.Background(), streamPrefix+"test")
+	require.NoError(t, err)
+
+	info, err := jstream.Info(context.Background())
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, info.State.Msgs)
+
+	t.Run("HistoryFrom the compacted offset", func(t *testing.T) {
+		history, err := broker.HistoryFrom("test", broker.Epoch(), 1)
+		require.NoError(t, err)
+
+		require.Len(t, history, 2)
+		assert.EqualValues(t, 2, history[0].Offset)
+		assert.Equal(t, "hello", history[0].Data)
+		assert.EqualValues(t, 3, history[1].Offset)
+		assert.Equal(t, "doc 1: published", history[1].Data)
+	})
+}
+
+func TestNATSBroker_Admin(t *testing.T) {
+	port := 36
+	addr := fmt.Sprintf("nats://127.0.0.1:44%d", port)
+	server, err := startNATSServer(t, addr)
+	require.NoError(t, err)
+	defer server.Shutdown(context.Background()) // nolint:errcheck
+
+	config := NewConfig()
+
+	nconfig := natsconfig.NewNATSConfig()
+	nconfig.Servers = addr
+
+	broadcastHandler := FakeBroadastHandler{}
+	broadcaster := pubsub.NewLegacySubscriber(broadcastHandler)
+	broker := NewNATSBroker(broadcaster, &config, &nconfig, slog.Default())
+
+	err = broker.Start(nil)
+	require.NoError(t, err)
+	defer broker.Shutdown(context.Background()) // nolint: errcheck
+
+	// Ensure no stream exists
+	require.NoError(t, broker.Reset())
+
+	broker.Subscribe("test")
+	defer broker.Unsubscribe("test")
+
+	broker.HandleBroadcast(&common.StreamMessage{Stream: "test", Data: "a"})
+	broker.HandleBroadcast(&common.StreamMessage{Stream: "test", Data: "b"})
+	broker.HandleBroadcast(&common.StreamMessage{Stream: "test", Data: "c"})
+
+	streams, err := broker.Streams()
+	require.NoError(t, err)
+	require.Len(t, streams, 1)
+	assert.Equal(t, "test", streams[0].Name)
+	assert.EqualValues(t, 1, streams[0].Low)
+	assert.EqualValues(t, 3, streams[0].High)
+	assert.Equal(t, 3, streams[0].Entries)
+
+	history, err := broker.ReadHistory("test", 2, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "b", history[0].Data)
+	assert.Equal(t, "c", history[1].Data)
+
+	_, err = broker.ReadHistory("unknown", 0, 10)
+	assert.ErrorIs(t, err, ErrStreamNotFound)
+
+	require.NoError(t, broker.DeleteHistory("test"))
+
+	history, err = broker.ReadHistory("test", 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	epoch := broker.Epoch()
+
+	newEpoch, err := broker.RotateEpoch()
+	require.NoError(t, err)
+	assert.NotEqual(t, epoch, newEpoch)
+	assert.Equal(t, newEpoch, broker.Epoch())
+}
+
 func TestNATSBroker_HistoryFrom(t *testing.T) {
 	port := 34
 	addr := fmt.Sprintf("nats://127.0.0.1:44%d", port)
@@ -395,6 +582,170 @@ wait:
 	}
 }
 
+func TestNATSBroker_Presence(t *testing.T) {
+	port := 47
+	addr := fmt.Sprintf("nats://127.0.0.1:44%d", port)
+
+	server, err := startNATSServer(t, addr)
+	require.NoError(t, err)
+	defer server.Shutdown(context.Background()) // nolint:errcheck
+
+	config := NewConfig()
+	config.PresenceTTL = 1
+
+	nconfig := natsconfig.NewNATSConfig()
+	nconfig.Servers = addr
+
+	broker := NewNATSBroker(nil, &config, &nconfig, slog.Default())
+
+	err = broker.Start(nil)
+	require.NoError(t, err)
+	defer broker.Shutdown(context.Background()) // nolint: errcheck
+
+	require.NoError(t, broker.Ready(jetstreamReadyTimeout))
+	broker.Reset() // nolint: errcheck
+
+	anotherBroker := NewNATSBroker(nil, &config, &nconfig, slog.Default())
+	require.NoError(t, anotherBroker.Start(nil))
+	defer anotherBroker.Shutdown(context.Background()) // nolint: errcheck
+
+	require.NoError(t, anotherBroker.Ready(jetstreamReadyTimeout))
+
+	event, err := broker.PresenceAdd("a", "s1", "user_1", map[string]string{"name": "John"})
+	require.NoError(t, err)
+	require.NotNil(t, event)
+	assert.Equal(t, common.PresenceJoinType, event.Type)
+	assert.Equal(t, "user_1", event.ID)
+
+	// Another session with the same presence ID on another node
+	event, err = anotherBroker.PresenceAdd("a", "s2", "user_1", map[string]string{"name": "Jack"})
+	require.NoError(t, err)
+	assert.Nil(t, event)
+
+	event, err = anotherBroker.PresenceAdd("a", "s3", "user_2", "Alice")
+	require.NoError(t, err)
+	require.NotNil(t, event)
+
+	_, err = broker.PresenceAdd("a", "s1", "user_2", nil)
+	require.Error(t, err)
+
+	// Re-joining with the same presence ID
+	event, err = broker.PresenceAdd("a", "s1", "user_1", map[string]string{"name": "John"})
+	require.NoError(t, err)
+	assert.Nil(t, event)
+
+	// Update info from another node
+	event, err = anotherBroker.PresenceUpdate("a", "s2", map[string]string{"name": "Jack", "status": "away"})
+	require.NoError(t, err)
+	require.NotNil(t, event)
+	assert.Equal(t, common.PresenceUpdateType, event.Type)
+	assert.Equal(t, "user_1", event.ID)
+
+	_, err = broker.PresenceUpdate("a", "s4", "unknown")
+	require.Error(t, err)
+
+	info, err := broker.PresenceInfo("a")
+	require.NoError(t, err)
+	assert.Equal(t, 2, info.Total)
+	assert.Len(t, info.Records, 2)
+
+	for _, record := range info.Records {
+		if record.ID == "user_1" {
+			assert.JSONEq(t, `{"name":"Jack","status":"away"}`, string(record.Info.(json.RawMessage)))
+		}
+	}
+
+	event, err = broker.PresenceRemove("a", "s1")
+	require.NoError(t, err)
+	assert.Nil(t, event)
+
+	event, err = anotherBroker.PresenceRemove("a", "s2")
+	require.NoError(t, err)
+	require.NotNil(t, event)
+	assert.Equal(t, common.PresenceLeaveType, event.Type)
+	assert.Equal(t, "user_1", event.ID)
+
+	_, err = anotherBroker.PresenceRemove("a", "s2")
+	require.Error(t, err)
+
+	// Expiration
+	require.NoError(t, anotherBroker.FinishPresence("s3"))
+
+	info, err = broker.PresenceInfo("a", WithPresenceInfoOptions(&PresenceInfoOptions{}))
+	require.NoError(t, err)
+	assert.Equal(t, 1, info.Total)
+	assert.Nil(t, info.Records)
+
+	time.Sleep(3 * time.Second)
+
+	info, err = broker.PresenceInfo("a")
+	require.NoError(t, err)
+	assert.Equal(t, 0, info.Total)
+}
+
+type presenceBroadcastHandler struct {
+	FakeBroadastHandler
+	messages chan *common.StreamMessage
+}
+
+func (h *presenceBroadcastHandler) Broadcast(msg *common.StreamMessage) {
+	h.messages <- msg
+}
+
+func TestNATSBroker_PresenceExpiration(t *testing.T) {
+	port := 48
+	addr := fmt.Sprintf("nats://127.0.0.1:44%d", port)
+
+	server, err := startNATSServer(t, addr)
+	require.NoError(t, err)
+	defer server.Shutdown(context.Background()) // nolint:errcheck
+
+	config := NewConfig()
+	config.PresenceTTL = 1
+
+	nconfig := natsconfig.NewNATSConfig()
+	nconfig.Servers = addr
+
+	handler := &presenceBroadcastHandler{messages: make(chan *common.StreamMessage, 10)}
+	broker := NewNATSBroker(pubsub.NewLegacySubscriber(handler), &config, &nconfig, slog.Default())
+
+	require.NoError(t, broker.Start(nil))
+	defer broker.Shutdown(context.Background()) // nolint: errcheck
+
+	require.NoError(t, broker.Ready(jetstreamReadyTimeout))
+	broker.Reset() // nolint: errcheck
+
+	crashedBroker := NewNATSBroker(nil, &config, &nconfig, slog.Default())
+	require.NoError(t, crashedBroker.Start(nil))
+	require.NoError(t, crashedBroker.Ready(jetstreamReadyTimeout))
+
+	_, err = broker.PresenceAdd("a", "s1", "user_1", nil)
+	require.NoError(t, err)
+
+	_, err = crashedBroker.PresenceAdd("a", "s2", "user_2", nil)
+	require.NoError(t, err)
+
+	// Simulate a node crash: presence records are neither refreshed nor finished
+	require.NoError(t, crashedBroker.Shutdown(context.Background()))
+
+	select {
+	case msg := <-handler.messages:
+		assert.Equal(t, "a", msg.Stream)
+		assert.Equal(t, common.PresenceType, msg.Meta.BroadcastType)
+		assert.JSONEq(t, `{"type":"leave","id":"user_2"}`, msg.Data)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out to receive presence leave event")
+	}
+
+	// Live sessions records are kept alive
+	time.Sleep(2 * time.Second)
+
+	info, err := broker.PresenceInfo("a")
+	require.NoError(t, err)
+	assert.Equal(t, 1, info.Total)
+	assert.Equal(t, "user_1", info.Records[0].ID)
+}
+
 func startNATSServer(t *testing.T, addr string) (*enats.Service, error) {
 	conf := enats.NewConfig()
 	conf.JetStream = true
@@ -462,3 +813,151 @@ func consumerSequence(js jetstream.JetStream, stream string, offset uint64) *con
 
 	return &consumerSequenceReader{seq}
 }
+
+func TestNATSBroker_SessionsQuota(t *testing.T) {
+	port := 49
+	addr := fmt.Sprintf("nats://127.0.0.1:44%d", port)
+
+	server, err := startNATSServer(t, addr)
+	require.NoError(t, err)
+	defer server.Shutdown(context.Background()) // nolint:errcheck
+
+	config := NewConfig()
+	config.PresenceTTL = 1
+
+	nconfig := natsconfig.NewNATSConfig()
+	nconfig.Servers = addr
+
+	broker := NewNATSBroker(nil, &config, &nconfig, slog.Default())
+
+	err = broker.Start(nil)
+	require.NoError(t, err)
+	defer broker.Shutdown(context.Background()) // nolint: errcheck
+
+	require.NoError(t, broker.Ready(jetstreamReadyTimeout))
+	broker.Reset() // nolint: errcheck
+
+	crashedBroker := NewNATSBroker(nil, &config, &nconfig, slog.Default())
+	require.NoError(t, crashedBroker.Start(nil))
+
+	require.NoError(t, crashedBroker.Ready(jetstreamReadyTimeout))
+
+	ok, err := broker.AcquireSessionQuota("user:1", "s1", 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = crashedBroker.AcquireSessionQuota("user:1", "s2", 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = broker.AcquireSessionQuota("user:1", "s3", 2)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, broker.ReleaseSessionQuota("user:1", "s1"))
+
+	ok, err = broker.AcquireSessionQuota("user:1", "s3", 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Slots of the crashed node's sessions expire, while live sessions keep theirs
+	require.NoError(t, crashedBroker.Shutdown(context.Background()))
+
+	assert.Eventually(t, func() bool {
+		ok, err := broker.AcquireSessionQuota("user:1", "s4", 2)
+		return err == nil && ok
+	}, 5*time.Second, 200*time.Millisecond)
+
+	time.Sleep(2 * time.Second)
+
+	ok, err = broker.AcquireSessionQuota("user:1", "s5", 2)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Refresh doesn't bring back sessions removed from the key
+	ok, err = broker.AcquireSessionQuota("user:2", "s6", 1)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, broker.updateQuota("user:2", func(sessions map[string]int64) bool {
+		delete(sessions, "s6")
+		return true
+	}))
+
+	broker.refreshSessionsQuotas()
+
+	ok, err = broker.AcquireSessionQuota("user:2", "s7", 1)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestNATSBroker_PresenceConcurrentMembership(t *testing.T) {
+	port := 50
+	addr := fmt.Sprintf("nats://127.0.0.1:44%d", port)
+
+	server, err := startNATSServer(t, addr)
+	require.NoError(t, err)
+	defer server.Shutdown(context.Background()) // nolint:errcheck
+
+	config := NewConfig()
+
+	nconfig := natsconfig.NewNATSConfig()
+	nconfig.Servers = addr
+
+	broker := NewNATSBroker(nil, &config, &nconfig, slog.Default())
+
+	err = broker.Start(nil)
+	require.NoError(t, err)
+	defer broker.Shutdown(context.Background()) // nolint: errcheck
+
+	require.NoError(t, broker.Ready(jetstreamReadyTimeout))
+	broker.Reset() // nolint: errcheck
+
+	anotherBroker := NewNATSBroker(nil, &config, &nconfig, slog.Default())
+	require.NoError(t, anotherBroker.Start(nil))
+	defer anotherBroker.Shutdown(context.Background()) // nolint: errcheck
+
+	require.NoError(t, anotherBroker.Ready(jetstreamReadyTimeout))
+
+	brokers := []*NATS{broker, anotherBroker}
+	sessions := 10
+
+	countEvents := func(fn func(b *NATS, sid string) (*common.PresenceEvent, error)) int {
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		count := 0
+
+		for i := 0; i < sessions; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				event, err := fn(brokers[i%2], fmt.Sprintf("s%d", i))
+				assert.NoError(t, err)
+
+				if event != nil {
+					mu.Lock()
+					count++
+					mu.Unlock()
+				}
+			}(i)
+		}
+
+		wg.Wait()
+
+		return count
+	}
+
+	joins := countEvents(func(b *NATS, sid string) (*common.PresenceEvent, error) {
+		return b.PresenceAdd("a", sid, "user_1", nil)
+	})
+
+	assert.Equal(t, 1, joins)
+
+	leaves := countEvents(func(b *NATS, sid string) (*common.PresenceEvent, error) {
+		return b.PresenceRemove("a", sid)
+	})
+
+	assert.Equal(t, 1, leaves)
+}
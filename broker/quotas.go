@@ -0,0 +1,95 @@
+package broker
+
+import (
+	"sync"
+)
+
+// LocalSessionsQuota tracks sessions per key in memory (so the quota is enforced per process)
+type LocalSessionsQuota struct {
+	keys map[string]map[string]struct{}
+	mu   sync.Mutex
+}
+
+var _ SessionsQuota = (*LocalSessionsQuota)(nil)
+
+func NewLocalSessionsQuota() *LocalSessionsQuota {
+	return &LocalSessionsQuota{keys: make(map[string]map[string]struct{})}
+}
+
+func (q *LocalSessionsQuota) AcquireSessionQuota(key string, sid string, limit int) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	sessions, ok := q.keys[key]
+
+	if !ok {
+		sessions = make(map[string]struct{})
+		q.keys[key] = sessions
+	}
+
+	if _, ok := sessions[sid]; ok {
+		return true, nil
+	}
+
+	if len(sessions) >= limit {
+		return false, nil
+	}
+
+	sessions[sid] = struct{}{}
+
+	return true, nil
+}
+
+func (q *LocalSessionsQuota) ReleaseSessionQuota(key string, sid string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if sessions, ok := q.keys[key]; ok {
+		delete(sessions, sid)
+
+		if len(sessions) == 0 {
+			delete(q.keys, key)
+		}
+	}
+
+	return nil
+}
+
+// sessionsQuotaTracker keeps track of the quota keys of the sessions connected to this node,
+// so distributed brokers can keep them alive
+type sessionsQuotaTracker struct {
+	sessions map[string]string
+	mu       sync.Mutex
+}
+
+func newSessionsQuotaTracker() *sessionsQuotaTracker {
+	return &sessionsQuotaTracker{sessions: make(map[string]string)}
+}
+
+func (t *sessionsQuotaTracker) add(sid string, key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sessions[sid] = key
+}
+
+func (t *sessionsQuotaTracker) remove(sid string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.sessions, sid)
+}
+
+// snapshot returns a copy of the tracked sessions (sid -> key)
+func (t *sessionsQuotaTracker) snapshot() map[string]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	res := make(map[string]string, len(t.sessions))
+
+	for sid, key := range t.sessions {
+		res[sid] = key
+	}
+
+	return res
+}
@@ -0,0 +1,1486 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/metrics"
+	rconfig "github.com/anycable/anycable-go/redis"
+	"github.com/anycable/anycable-go/utils"
+	"github.com/joomcode/errorx"
+	nanoid "github.com/matoous/go-nanoid"
+	"github.com/redis/rueidis"
+)
+
+const (
+	redisEpochKey         = "$ac:epoch"
+	redisStreamPrefix     = "$ac:h:"
+	redisSessionPrefix    = "$ac:s:"
+	redisPresencePrefix   = "$ac:p:"
+	redisPresenceIndexKey = "$ac:pi"
+	redisPresenceLockKey  = "$ac:pl"
+	redisQuotaPrefix      = "$ac:q:"
+	// Sorted set of streams with history (scored by expiration time) used to resolve wildcard streams
+	redisHistoryIndexKey = "$ac:hi"
+
+	redisExpirePeriod = time.Second
+	// Max number of presence streams to check during a single expiration run
+	redisExpireBatchSize = 100
+	// Number of history index entries to fetch per ZSCAN call
+	redisScanCount = 1000
+	// Number of stream entries to fetch per XRANGE/XREVRANGE call when looking for an offset
+	redisHistoryReadBatch = 100
+)
+
+// Presence records removal logic shared by the scripts below.
+// KEYS[1] — pid -> info hash, KEYS[2] — pid -> sessions count hash,
+// KEYS[3] — sid -> pid hash, KEYS[4] — sid -> deadline sorted set
+const redisPresenceRemoveFn = `
+local function remove(sid)
+  local pid = redis.call('hget', KEYS[3], sid)
+  if not pid then return false end
+  redis.call('hdel', KEYS[3], sid)
+  redis.call('zrem', KEYS[4], sid)
+  local count = redis.call('hincrby', KEYS[2], pid, -1)
+  if count > 0 then return '' end
+  redis.call('hdel', KEYS[2], pid)
+  redis.call('hdel', KEYS[1], pid)
+  return pid
+end
+`
+
+var (
+	// KEYS[1] — history stream, KEYS[2] — offset counter, KEYS[3] — compaction key -> entry ID
+	// ARGV[1] — data, ARGV[2] — limit, ARGV[3] — ttl, ARGV[4] — compaction key (optional)
+	redisHistoryAddScript = rueidis.NewLuaScript(`
+local offset = redis.call('incr', KEYS[2])
+local limit = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+local fields = {'o', offset, 'd', ARGV[1]}
+if ARGV[4] ~= '' then
+  table.insert(fields, 'k')
+  table.insert(fields, ARGV[4])
+  local prev = redis.call('hget', KEYS[3], ARGV[4])
+  if prev then
+    -- The first entry is kept to preserve the lowest offset (superseded entries are skipped on read)
+    local first = redis.call('xrange', KEYS[1], '-', '+', 'COUNT', 1)
+    if first[1] and first[1][1] ~= prev then
+      redis.call('xdel', KEYS[1], prev)
+    end
+  end
+end
+local id
+if limit > 0 then
+  id = redis.call('xadd', KEYS[1], 'MAXLEN', limit, '*', unpack(fields))
+else
+  id = redis.call('xadd', KEYS[1], '*', unpack(fields))
+end
+if ARGV[4] ~= '' then
+  redis.call('hset', KEYS[3], ARGV[4], id)
+end
+if ttl > 0 then
+  local now = redis.call('time')
+  redis.call('xtrim', KEYS[1], 'MINID', (tonumber(now[1]) - ttl) * 1000)
+  -- Keep stream alive for 10 times longer than ttl (so we can re-use its offset)
+  redis.call('expire', KEYS[1], ttl * 10)
+  redis.call('expire', KEYS[2], ttl * 10)
+  if redis.call('exists', KEYS[3]) == 1 then
+    redis.call('expire', KEYS[3], ttl * 10)
+  end
+end
+return offset
+`)
+
+	// ARGV[1] — sid, ARGV[2] — pid, ARGV[3] — info, ARGV[4] — deadline (unix seconds)
+	redisPresenceAddScript = rueidis.NewLuaScript(`
+local current = redis.call('hget', KEYS[3], ARGV[1])
+if current and current ~= ARGV[2] then
+  return redis.error_reply('presence ID mismatch')
+end
+redis.call('hset', KEYS[1], ARGV[2], ARGV[3])
+redis.call('zadd', KEYS[4], ARGV[4], ARGV[1])
+if current then return 0 end
+redis.call('hset', KEYS[3], ARGV[1], ARGV[2])
+return redis.call('hincrby', KEYS[2], ARGV[2], 1)
+`)
+
+	// KEYS[1] — sid -> deadline sorted set.
+	// ARGV[1] — sid, ARGV[2] — limit, ARGV[3] — now (unix seconds), ARGV[4] — deadline, ARGV[5] — key ttl
+	redisQuotaAcquireScript = rueidis.NewLuaScript(`
+redis.call('zremrangebyscore', KEYS[1], '-inf', '(' .. ARGV[3])
+if not redis.call('zscore', KEYS[1], ARGV[1]) and redis.call('zcard', KEYS[1]) >= tonumber(ARGV[2]) then
+  return 0
+end
+redis.call('zadd', KEYS[1], ARGV[4], ARGV[1])
+redis.call('expire', KEYS[1], ARGV[5])
+return 1
+`)
+
+	// ARGV[1] — sid, ARGV[2] — info
+	redisPresenceUpdateScript = rueidis.NewLuaScript(`
+local pid = redis.call('hget', KEYS[3], ARGV[1])
+if not pid then
+  return redis.error_reply('presence info not found')
+end
+redis.call('hset', KEYS[1], pid, ARGV[2])
+return pid
+`)
+
+	// ARGV[1] — sid
+	redisPresenceRemoveScript = rueidis.NewLuaScript(redisPresenceRemoveFn + `
+local pid = remove(ARGV[1])
+if not pid then
+  return redis.error_reply('presence info not found')
+end
+return pid
+`)
+
+	// ARGV[1] — now (unix seconds).
+	// Returns the list of pids left, the last element is the nearest deadline of the remaining records
+	// (or an empty string if the stream has no presence records left)
+	redisPresenceExpireScript = rueidis.NewLuaScript(redisPresenceRemoveFn + `
+local sids = redis.call('zrangebyscore', KEYS[4], '-inf', ARGV[1])
+local left = {}
+for _, sid in ipairs(sids) do
+  local pid = remove(sid)
+  if pid and pid ~= '' then table.insert(left, pid) end
+end
+local next = redis.call('zrange', KEYS[4], 0, 0, 'WITHSCORES')
+if next[2] then
+  table.insert(left, next[2])
+else
+  table.insert(left, '')
+end
+return left
+`)
+)
+
+// Redis broker keeps streams history, sessions and presence in Redis.
+// History is stored in Redis Streams, each entry contains an offset (maintained by a separate counter)
+// and the message data.
+//
+// Presence records have deadlines which are refreshed periodically by the node owning the session,
+// so records of crashed nodes expire automatically. Expiration is performed by a single node at a time
+// (the one holding the lock), streams with presence records are indexed by their nearest deadlines.
+type Redis struct {
+	broadcaster Broadcaster
+	conf        *Config
+	rconf       *rconfig.RedisConfig
+	tracker     *StreamsTracker
+	// Local sessions presence (sid -> streams) to refresh deadlines for
+	presence *presenceTracker
+	// Local sessions quota keys to refresh deadlines for
+	quotas *sessionsQuotaTracker
+	nodeID string
+
+	client   rueidis.Client
+	clientMu sync.RWMutex
+
+	epoch   string
+	epochMu sync.RWMutex
+
+	shutdownCtx context.Context
+	shutdownFn  func()
+
+	metrics metrics.Instrumenter
+	log     *slog.Logger
+}
+
+var _ Broker = (*Redis)(nil)
+var _ SessionsQuota = (*Redis)(nil)
+
+type RedisOption func(*Redis)
+
+// WithRedisInstrumenter configures the broker to report metrics
+func WithRedisInstrumenter(m metrics.Instrumenter) RedisOption {
+	return func(b *Redis) {
+		b.metrics = m
+	}
+}
+
+func NewRedisBroker(broadcaster Broadcaster, c *Config, rc *rconfig.RedisConfig, l *slog.Logger, opts ...RedisOption) *Redis {
+	shutdownCtx, shutdownFn := context.WithCancel(context.Background())
+
+	nodeID, _ := nanoid.Nanoid()
+
+	b := &Redis{
+		broadcaster: broadcaster,
+		conf:        c,
+		rconf:       rc,
+		tracker:     NewStreamsTracker(),
+		presence:    newPresenceTracker(),
+		quotas:      newSessionsQuotaTracker(),
+		nodeID:      nodeID,
+		shutdownCtx: shutdownCtx,
+		shutdownFn:  shutdownFn,
+		metrics:     metrics.NoopMetrics{},
+		log:         l.With("context", "broker").With("provider", "redis"),
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	registerRedisMetrics(b.metrics)
+
+	return b
+}
+
+func (b *Redis) Start(done chan (error)) error {
+	options, err := b.rconf.ToRueidisOptions()
+
+	if err != nil {
+		return err
+	}
+
+	client, err := rueidis.NewClient(*options)
+
+	if err != nil {
+		return errorx.Decorate(err, "failed to connect to Redis")
+	}
+
+	b.clientMu.Lock()
+	b.client = client
+	b.clientMu.Unlock()
+
+	epoch, err := b.calculateEpoch()
+
+	if err != nil {
+		return errorx.Decorate(err, "failed to calculate epoch")
+	}
+
+	b.writeEpoch(epoch)
+
+	go b.expireLoop()
+	go b.refreshPresenceLoop()
+
+	b.log.Info("Redis broker is ready", "epoch", epoch)
+
+	return nil
+}
+
+func (b *Redis) Shutdown(ctx context.Context) error {
+	b.clientMu.Lock()
+	defer b.clientMu.Unlock()
+
+	b.shutdownFn()
+
+	if b.client != nil {
+		b.client.Close()
+		b.client = nil
+	}
+
+	return nil
+}
+
+func (b *Redis) Announce() string {
+	brokerParams := fmt.Sprintf("(history limit: %d, history ttl: %ds, sessions ttl: %ds)", b.conf.HistoryLimit, b.conf.HistoryTTL, b.conf.SessionsTTL)
+
+	// Announce is called before Start, so we must parse the URL to resolve the hostname
+	// (we don't log the URL itself, since it may contain credentials)
+	if _, err := b.rconf.ToRueidisOptions(); err != nil {
+		return fmt.Sprintf("Using Redis broker %s", brokerParams)
+	}
+
+	return fmt.Sprintf("Using Redis broker: %s %s", b.rconf.Hostname(), brokerParams)
+}
+
+// IsDistributed returns true, since Redis broker state is shared across the cluster
+func (b *Redis) IsDistributed() bool {
+	return true
+}
+
+func (b *Redis) Epoch() string {
+	b.epochMu.RLock()
+	defer b.epochMu.RUnlock()
+
+	return b.epoch
+}
+
+func (b *Redis) SetEpoch(epoch string) error {
+	client, err := b.getClient()
+
+	if err != nil {
+		return err
+	}
+
+	err = client.Do(context.Background(), client.B().Set().Key(redisEpochKey).Value(epoch).Build()).Error()
+
+	if err != nil {
+		return err
+	}
+
+	b.writeEpoch(epoch)
+
+	return nil
+}
+
+func (b *Redis) writeEpoch(val string) {
+	b.epochMu.Lock()
+	defer b.epochMu.Unlock()
+
+	b.epoch = val
+}
+
+func (b *Redis) HandleBroadcast(msg *common.StreamMessage) {
+	if msg.Meta != nil && msg.Meta.Transient {
+		b.broadcaster.Broadcast(msg)
+		return
+	}
+
+	if b.conf.RetentionFor(msg.Stream).Disabled {
+		b.broadcaster.Broadcast(msg)
+		return
+	}
+
+	key := ""
+
+	if msg.Meta != nil {
+		key = msg.Meta.CompactionKey
+	}
+
+	offset, err := b.add(msg.Stream, msg.Data, key)
+
+	if err != nil {
+		b.log.Error("failed to add message to Redis stream", "stream", msg.Stream, "error", err)
+		return
+	}
+
+	msg.Epoch = b.Epoch()
+	msg.Offset = offset
+
+	b.broadcaster.Broadcast(msg)
+}
+
+func (b *Redis) HandleCommand(msg *common.RemoteCommandMessage) {
+	b.broadcaster.BroadcastCommand(msg)
+}
+
+func (b *Redis) Subscribe(stream string) string {
+	isNew := b.tracker.Add(stream)
+
+	if isNew {
+		b.broadcaster.Subscribe(stream)
+	}
+
+	return stream
+}
+
+func (b *Redis) Unsubscribe(stream string) string {
+	isLast := b.tracker.Remove(stream)
+
+	if isLast {
+		b.broadcaster.Unsubscribe(stream)
+	}
+
+	return stream
+}
+
+func (b *Redis) HistoryFrom(name string, epoch string, offset uint64, opts ...HistoryOption) ([]common.StreamMessage, error) {
+	history, err := b.historyFrom(name, epoch, offset, opts...)
+
+	trackHistory(b.metrics, err)
+
+	return history, err
+}
+
+func (b *Redis) historyFrom(name string, epoch string, offset uint64, opts ...HistoryOption) ([]common.StreamMessage, error) {
+	bepoch := b.Epoch()
+
+	if bepoch != epoch {
+		return nil, fmt.Errorf("unknown epoch: %s, current: %s", epoch, bepoch)
+	}
+
+	start, err := b.historyStart(name, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	first, err := b.readHistoryRange(name, start, "+", 1, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := b.readHistoryRange(name, "+", start, 1, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(first) == 0 || len(last) == 0 {
+		return nil, errors.New("stream is empty")
+	}
+
+	low, latest := first[0].msg.Offset, last[0].msg.Offset
+
+	if low > offset {
+		return nil, fmt.Errorf("requested offset couldn't be found: %d, lowest: %d", offset, low)
+	}
+
+	if latest < offset {
+		return nil, fmt.Errorf("requested offset couldn't be found: %d, latest: %d", offset, latest)
+	}
+
+	options := buildHistoryOptions(opts)
+
+	var history []common.StreamMessage
+
+	// Offsets are not mapped to entry IDs, so we scan the stream (in batches) from the closest end.
+	// NOTE: The first stream entry (the only one which may be superseded) is never returned, since its offset is not greater than the requested one.
+	if options.Limit > 0 && !options.Latest && offset-low < latest-offset {
+		history, err = b.scanHistoryForward(name, start, offset, options.Limit)
+	} else {
+		limit := 0
+
+		if options.Latest {
+			limit = options.Limit
+		}
+
+		history, err = b.scanHistoryBackward(name, start, offset, limit)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return limitHistory(history, options), nil
+}
+
+func (b *Redis) HistorySince(name string, ts int64, opts ...HistoryOption) ([]common.StreamMessage, error) {
+	history, err := b.historySince(name, ts, opts...)
+
+	trackHistory(b.metrics, err)
+
+	return history, err
+}
+
+func (b *Redis) historySince(name string, ts int64, opts ...HistoryOption) ([]common.StreamMessage, error) {
+	start, err := b.historyStart(name, ts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	options := buildHistoryOptions(opts)
+
+	var entries []redisHistoryEntry
+
+	// We read one more entry in case the first one has been superseded
+	count := int64(0)
+
+	if options.Limit > 0 {
+		count = int64(options.Limit) + 1
+	}
+
+	if options.Latest && count > 0 {
+		entries, err = b.readHistoryRange(name, "+", start, count, true)
+		slices.Reverse(entries)
+	} else {
+		entries, err = b.readHistoryRange(name, start, "+", count, false)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := b.compactHistory(name, entries)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return limitHistory(history, options), nil
+}
+
+func (b *Redis) CommitSession(sid string, session Cacheable) error {
+	err := b.commitSession(sid, session)
+
+	trackSessionCommit(b.metrics, err)
+
+	return err
+}
+
+func (b *Redis) commitSession(sid string, session Cacheable) error {
+	client, err := b.getClient()
+
+	if err != nil {
+		return err
+	}
+
+	data, err := session.ToCacheEntry()
+
+	if err != nil {
+		return errorx.Decorate(err, "failed to serialize session")
+	}
+
+	cmd := client.B().Set().Key(redisSessionPrefix + sid).Value(rueidis.BinaryString(data)).ExSeconds(b.conf.SessionsTTL).Build()
+
+	if err := client.Do(context.Background(), cmd).Error(); err != nil {
+		b.metrics.CounterIncrement(metricsRedisErrors)
+		return errorx.Decorate(err, "failed to save session to Redis")
+	}
+
+	return nil
+}
+
+func (b *Redis) RestoreSession(sid string) ([]byte, error) {
+	data, err := b.restoreSession(sid)
+
+	trackSessionRestore(b.metrics, data, err)
+
+	return data, err
+}
+
+func (b *Redis) restoreSession(sid string) ([]byte, error) {
+	client, err := b.getClient()
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := client.Do(context.Background(), client.B().Get().Key(redisSessionPrefix+sid).Build()).AsBytes()
+
+	if rueidis.IsRedisNil(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		b.metrics.CounterIncrement(metricsRedisErrors)
+		return nil, errorx.Decorate(err, "failed to restore session from Redis")
+	}
+
+	return data, nil
+}
+
+func (b *Redis) FinishSession(sid string) error {
+	client, err := b.getClient()
+
+	if err != nil {
+		return err
+	}
+
+	cmd := client.B().Expire().Key(redisSessionPrefix + sid).Seconds(b.conf.SessionsTTL).Build()
+
+	if err := client.Do(context.Background(), cmd).Error(); err != nil {
+		return errorx.Decorate(err, "failed to touch session in Redis")
+	}
+
+	return nil
+}
+
+func (b *Redis) PresenceAdd(stream string, sid string, pid string, info interface{}) (*common.PresenceEvent, error) {
+	client, err := b.getClient()
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	deadline := b.presenceDeadline()
+
+	count, err := redisPresenceAddScript.Exec(ctx, client, presenceKeys(stream), []string{sid, pid, string(utils.ToJSON(info)), strconv.FormatInt(deadline, 10)}).AsInt64()
+
+	if err != nil {
+		return nil, redisScriptError(err)
+	}
+
+	b.presence.add(sid, stream)
+
+	// Make sure the stream is checked for expired records
+	err = client.Do(ctx, client.B().Zadd().Key(redisPresenceIndexKey).Nx().ScoreMember().ScoreMember(float64(deadline), stream).Build()).Error()
+
+	if err != nil {
+		return nil, errorx.Decorate(err, "failed to index presence stream in Redis")
+	}
+
+	if count == 1 {
+		return &common.PresenceEvent{
+			Type: common.PresenceJoinType,
+			ID:   pid,
+			Info: info,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func (b *Redis) PresenceRemove(stream string, sid string) (*common.PresenceEvent, error) {
+	client, err := b.getClient()
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+
+	b.presence.remove(sid, stream)
+
+	pid, err := redisPresenceRemoveScript.Exec(ctx, client, presenceKeys(stream), []string{sid}).ToString()
+
+	if err != nil {
+		return nil, redisScriptError(err)
+	}
+
+	if pid != "" {
+		return &common.PresenceEvent{
+			Type: common.PresenceLeaveType,
+			ID:   pid,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func (b *Redis) PresenceUpdate(stream string, sid string, info interface{}) (*common.PresenceEvent, error) {
+	client, err := b.getClient()
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+
+	pid, err := redisPresenceUpdateScript.Exec(ctx, client, presenceKeys(stream), []string{sid, string(utils.ToJSON(info))}).ToString()
+
+	if err != nil {
+		return nil, redisScriptError(err)
+	}
+
+	return &common.PresenceEvent{
+		Type: common.PresenceUpdateType,
+		ID:   pid,
+		Info: info,
+	}, nil
+}
+
+func (b *Redis) PresenceInfo(stream string, opts ...PresenceInfoOption) (*common.PresenceInfo, error) {
+	options := NewPresenceInfoOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	client, err := b.getClient()
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	key := presenceKeys(stream)[0]
+	info := common.NewPresenceInfo()
+
+	if !options.ReturnRecords {
+		total, err := client.Do(ctx, client.B().Hlen().Key(key).Build()).AsInt64()
+
+		if err != nil {
+			return nil, errorx.Decorate(err, "failed to retrieve presence info from Redis")
+		}
+
+		info.Total = int(total)
+
+		return info, nil
+	}
+
+	records, err := client.Do(ctx, client.B().Hgetall().Key(key).Build()).AsStrMap()
+
+	if err != nil {
+		return nil, errorx.Decorate(err, "failed to retrieve presence info from Redis")
+	}
+
+	info.Total = len(records)
+	info.Records = make([]*common.PresenceEvent, 0, len(records))
+
+	for pid, data := range records {
+		info.Records = append(info.Records, &common.PresenceEvent{
+			ID:   pid,
+			Info: json.RawMessage(data),
+		})
+	}
+
+	return info, nil
+}
+
+// FinishPresence stops refreshing the session's presence records, so they expire after the presence TTL
+func (b *Redis) FinishPresence(sid string) error {
+	streams := b.presence.finish(sid)
+
+	if len(streams) == 0 {
+		return nil
+	}
+
+	client, err := b.getClient()
+
+	if err != nil {
+		return err
+	}
+
+	if err := b.touchPresence(client, sid, streams); err != nil {
+		return errorx.Decorate(err, "failed to mark presence as finished in Redis")
+	}
+
+	return nil
+}
+
+func (b *Redis) add(stream string, data string, key string) (uint64, error) {
+	client, err := b.getClient()
+
+	if err != nil {
+		return 0, err
+	}
+
+	retention := b.conf.RetentionFor(stream)
+
+	keys := historyKeys(stream)
+	args := []string{
+		data,
+		strconv.Itoa(retention.Limit),
+		strconv.FormatInt(retention.TTL, 10),
+		key,
+	}
+
+	offset, err := redisHistoryAddScript.Exec(context.Background(), client, keys, args).AsInt64()
+
+	if err != nil {
+		return 0, errorx.Decorate(err, "failed to publish message to Redis stream")
+	}
+
+	if b.conf.Wildcards {
+		deadline := float64(time.Now().Unix() + retention.TTL)
+
+		err = client.Do(context.Background(), client.B().Zadd().Key(redisHistoryIndexKey).ScoreMember().ScoreMember(deadline, stream).Build()).Error()
+
+		if err != nil {
+			b.log.Warn("failed to index stream history", "stream", stream, "error", err)
+		}
+	}
+
+	return uint64(offset), nil
+}
+
+var _ StreamsMatcher = (*Redis)(nil)
+
+// MatchStreams returns the streams with history matching the wildcard stream
+// (streams are indexed only if wildcard streams are enabled)
+func (b *Redis) MatchStreams(pattern string) ([]string, error) {
+	client, err := b.getClient()
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	glob := utils.StreamPatternGlob(pattern)
+	now := time.Now().Unix()
+	streams := []string{}
+
+	var cursor uint64
+
+	for {
+		entry, err := client.Do(ctx, client.B().Zscan().Key(redisHistoryIndexKey).Cursor(cursor).Match(glob).Count(redisScanCount).Build()).AsScanEntry()
+
+		if err != nil {
+			return nil, errorx.Decorate(err, "failed to scan history streams in Redis")
+		}
+
+		// ZSCAN returns member-score pairs
+		for i := 0; i+1 < len(entry.Elements); i += 2 {
+			stream := entry.Elements[i]
+			deadline, _ := strconv.ParseFloat(entry.Elements[i+1], 64)
+
+			if int64(deadline) > now && utils.MatchWildcardStream(pattern, stream) {
+				streams = append(streams, stream)
+			}
+		}
+
+		cursor = entry.Cursor
+
+		if cursor == 0 {
+			break
+		}
+	}
+
+	slices.Sort(streams)
+
+	return streams, nil
+}
+
+// redisHistoryEntry is a stream message along with its Redis stream entry ID
+type redisHistoryEntry struct {
+	id  string
+	msg common.StreamMessage
+}
+
+// readHistory returns all non-expired stream entries added since the specified timestamp
+func (b *Redis) readHistory(name string, since int64) ([]common.StreamMessage, error) {
+	start, err := b.historyStart(name, since)
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := b.readHistoryRange(name, start, "+", 0, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]common.StreamMessage, 0, len(entries))
+
+	for _, entry := range entries {
+		history = append(history, entry.msg)
+	}
+
+	return history, nil
+}
+
+// historyStart returns the minimal stream entry ID to read (taking retention into account)
+func (b *Redis) historyStart(name string, since int64) (string, error) {
+	if ttl := b.conf.RetentionFor(name).TTL; ttl > 0 {
+		since = max(since, time.Now().Unix()-ttl)
+	}
+
+	if since > 0 {
+		return strconv.FormatInt(since*1000, 10), nil
+	}
+
+	return "-", nil
+}
+
+// readHistoryRange reads at most count (zero means no limit) stream entries from the range.
+// If reverse is true, entries are read from the end (and from and to must be swapped).
+func (b *Redis) readHistoryRange(name string, from string, to string, count int64, reverse bool) ([]redisHistoryEntry, error) {
+	client, err := b.getClient()
+
+	if err != nil {
+		return nil, err
+	}
+
+	key := historyKeys(name)[0]
+
+	var cmd rueidis.Completed
+
+	switch {
+	case reverse && count > 0:
+		cmd = client.B().Xrevrange().Key(key).End(from).Start(to).Count(count).Build()
+	case reverse:
+		cmd = client.B().Xrevrange().Key(key).End(from).Start(to).Build()
+	case count > 0:
+		cmd = client.B().Xrange().Key(key).Start(from).End(to).Count(count).Build()
+	default:
+		cmd = client.B().Xrange().Key(key).Start(from).End(to).Build()
+	}
+
+	entries, err := client.Do(context.Background(), cmd).AsXRange()
+
+	if err != nil {
+		return nil, errorx.Decorate(err, "failed to read stream history from Redis")
+	}
+
+	epoch := b.Epoch()
+	history := make([]redisHistoryEntry, 0, len(entries))
+
+	for _, entry := range entries {
+		offset, err := strconv.ParseUint(entry.FieldValues["o"], 10, 64)
+
+		if err != nil {
+			return nil, errorx.Decorate(err, "malformed stream entry: %s", entry.ID)
+		}
+
+		msg := common.StreamMessage{
+			Stream: name,
+			Data:   entry.FieldValues["d"],
+			Offset: offset,
+			Epoch:  epoch,
+		}
+
+		if key := entry.FieldValues["k"]; key != "" {
+			msg.Meta = &common.StreamMessageMetadata{CompactionKey: key}
+		}
+
+		history = append(history, redisHistoryEntry{id: entry.ID, msg: msg})
+	}
+
+	return history, nil
+}
+
+// scanHistoryForward reads the stream from the beginning (in batches) and returns
+// at most limit messages with offsets greater than the specified one
+func (b *Redis) scanHistoryForward(name string, start string, offset uint64, limit int) ([]common.StreamMessage, error) {
+	history := []common.StreamMessage{}
+	from := start
+
+	for {
+		entries, err := b.readHistoryRange(name, from, "+", redisHistoryReadBatch, false)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if entry.msg.Offset <= offset {
+				continue
+			}
+
+			history = append(history, entry.msg)
+
+			if len(history) >= limit {
+				return history, nil
+			}
+		}
+
+		if len(entries) < redisHistoryReadBatch {
+			return history, nil
+		}
+
+		from = "(" + entries[len(entries)-1].id
+	}
+}
+
+// scanHistoryBackward reads the stream from the end (in batches) until the specified offset
+// or until limit messages are read (zero means no limit)
+func (b *Redis) scanHistoryBackward(name string, start string, offset uint64, limit int) ([]common.StreamMessage, error) {
+	history := []common.StreamMessage{}
+	from := "+"
+
+	batch := int64(redisHistoryReadBatch)
+
+	if limit > 0 {
+		batch = min(batch, int64(limit)+1)
+	}
+
+	for {
+		entries, err := b.readHistoryRange(name, from, start, batch, true)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if entry.msg.Offset <= offset || (limit > 0 && len(history) >= limit) {
+				slices.Reverse(history)
+				return history, nil
+			}
+
+			history = append(history, entry.msg)
+		}
+
+		if int64(len(entries)) < batch {
+			slices.Reverse(history)
+			return history, nil
+		}
+
+		from = "(" + entries[len(entries)-1].id
+	}
+}
+
+// limitHistory applies history options to the compacted history
+func limitHistory(history []common.StreamMessage, opts *HistoryOptions) []common.StreamMessage {
+	from, to := opts.Bounds(len(history))
+
+	return history[from:to]
+}
+
+// compactHistory removes messages superseded by the later messages with the same compaction key.
+// Superseded entries are deleted from Redis streams on write, except for the first one (to keep the lowest offset),
+// so we must check whether the entries with compaction keys are still the latest ones.
+func (b *Redis) compactHistory(name string, entries []redisHistoryEntry) ([]common.StreamMessage, error) {
+	keys := make([]string, 0)
+
+	for _, entry := range entries {
+		if entry.msg.Meta != nil && entry.msg.Meta.CompactionKey != "" {
+			keys = append(keys, entry.msg.Meta.CompactionKey)
+		}
+	}
+
+	history := make([]common.StreamMessage, 0, len(entries))
+
+	if len(keys) == 0 {
+		for _, entry := range entries {
+			history = append(history, entry.msg)
+		}
+
+		return history, nil
+	}
+
+	client, err := b.getClient()
+
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := client.Do(context.Background(), client.B().Hmget().Key(historyKeys(name)[2]).Field(keys...).Build()).ToArray()
+
+	if err != nil {
+		return nil, errorx.Decorate(err, "failed to read stream history from Redis")
+	}
+
+	latest := make(map[string]string, len(keys))
+
+	for i, key := range keys {
+		if id, err := ids[i].ToString(); err == nil {
+			latest[key] = id
+		}
+	}
+
+	for _, entry := range entries {
+		if entry.msg.Meta != nil && entry.msg.Meta.CompactionKey != "" {
+			if id, ok := latest[entry.msg.Meta.CompactionKey]; ok && id != entry.id {
+				continue
+			}
+		}
+
+		history = append(history, entry.msg)
+	}
+
+	return history, nil
+}
+
+func (b *Redis) calculateEpoch() (string, error) {
+	client, err := b.getClient()
+
+	if err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	maybeNewEpoch, _ := nanoid.Nanoid(4)
+
+	err = client.Do(ctx, client.B().Set().Key(redisEpochKey).Value(maybeNewEpoch).Nx().Build()).Error()
+
+	if err != nil && !rueidis.IsRedisNil(err) {
+		return "", err
+	}
+
+	return client.Do(ctx, client.B().Get().Key(redisEpochKey).Build()).ToString()
+}
+
+func (b *Redis) expireLoop() {
+	ticker := time.NewTicker(redisExpirePeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.shutdownCtx.Done():
+			return
+		case <-ticker.C:
+			b.refreshEpoch()
+			b.expirePresence()
+			b.expireHistoryIndex()
+			b.collectStats()
+		}
+	}
+}
+
+// collectStats reports the number of presence records of the sessions connected to this node
+// (history is stored in Redis and shared by all nodes, so we do not report it here)
+func (b *Redis) collectStats() {
+	if _, ok := b.metrics.(metrics.NoopMetrics); ok {
+		return
+	}
+
+	b.metrics.GaugeSet(metricsPresenceRecordsNum, uint64(b.presence.size()))
+}
+
+// refreshEpoch makes sure we use the same epoch as other nodes
+// (the epoch could be changed via SetEpoch by another node)
+func (b *Redis) refreshEpoch() {
+	client, err := b.getClient()
+
+	if err != nil {
+		return
+	}
+
+	epoch, err := client.Do(context.Background(), client.B().Get().Key(redisEpochKey).Build()).ToString()
+
+	if err != nil {
+		if !rueidis.IsRedisNil(err) {
+			b.log.Warn("failed to retrieve epoch", "error", err)
+		}
+		return
+	}
+
+	if b.Epoch() != epoch {
+		b.log.Warn("epoch updated", "epoch", epoch)
+		b.writeEpoch(epoch)
+	}
+}
+
+// refreshPresenceLoop prolongs presence records and quota slots of the active local sessions
+func (b *Redis) refreshPresenceLoop() {
+	ticker := time.NewTicker(b.presenceRefreshInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.shutdownCtx.Done():
+			return
+		case <-ticker.C:
+			b.refreshPresence()
+			b.refreshSessionsQuotas()
+		}
+	}
+}
+
+func (b *Redis) refreshPresence() {
+	client, err := b.getClient()
+
+	if err != nil {
+		return
+	}
+
+	for sid, streams := range b.presence.snapshot() {
+		if err := b.touchPresence(client, sid, streams); err != nil {
+			b.log.Warn("failed to refresh presence", "sid", sid, "error", err)
+		}
+	}
+}
+
+// touchPresence sets the presence records deadline for the session to now + presence TTL
+func (b *Redis) touchPresence(client rueidis.Client, sid string, streams []string) error {
+	deadline := float64(b.presenceDeadline())
+
+	cmds := make(rueidis.Commands, 0, len(streams))
+
+	for _, stream := range streams {
+		cmds = append(cmds, client.B().Zadd().Key(presenceKeys(stream)[3]).Xx().ScoreMember().ScoreMember(deadline, sid).Build())
+	}
+
+	for _, res := range client.DoMulti(context.Background(), cmds...) {
+		if err := res.Error(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AcquireSessionQuota adds the session to the key's sorted set (scored by deadlines) unless
+// the number of live sessions reached the limit. The check and the insertion are performed atomically via a Lua script.
+func (b *Redis) AcquireSessionQuota(key string, sid string, limit int) (bool, error) {
+	client, err := b.getClient()
+
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now().Unix()
+
+	res, err := redisQuotaAcquireScript.Exec(
+		context.Background(),
+		client,
+		[]string{quotaKey(key)},
+		[]string{
+			sid,
+			strconv.Itoa(limit),
+			strconv.FormatInt(now, 10),
+			strconv.FormatInt(b.presenceDeadline(), 10),
+			strconv.FormatInt(b.conf.PresenceTTL, 10),
+		},
+	).AsInt64()
+
+	if err != nil {
+		b.metrics.CounterIncrement(metricsRedisErrors)
+		return false, errorx.Decorate(err, "failed to acquire sessions quota")
+	}
+
+	if res == 0 {
+		return false, nil
+	}
+
+	b.quotas.add(sid, key)
+
+	return true, nil
+}
+
+func (b *Redis) ReleaseSessionQuota(key string, sid string) error {
+	b.quotas.remove(sid)
+
+	client, err := b.getClient()
+
+	if err != nil {
+		return err
+	}
+
+	err = client.Do(context.Background(), client.B().Zrem().Key(quotaKey(key)).Member(sid).Build()).Error()
+
+	if err != nil {
+		b.metrics.CounterIncrement(metricsRedisErrors)
+		return errorx.Decorate(err, "failed to release sessions quota")
+	}
+
+	return nil
+}
+
+// refreshSessionsQuotas prolongs the quota slots of the active local sessions (and the quota keys themselves)
+func (b *Redis) refreshSessionsQuotas() {
+	client, err := b.getClient()
+
+	if err != nil {
+		return
+	}
+
+	deadline := float64(b.presenceDeadline())
+	ttl := b.conf.PresenceTTL
+
+	cmds := make(rueidis.Commands, 0)
+
+	for sid, key := range b.quotas.snapshot() {
+		cmds = append(
+			cmds,
+			client.B().Zadd().Key(quotaKey(key)).Xx().ScoreMember().ScoreMember(deadline, sid).Build(),
+			client.B().Expire().Key(quotaKey(key)).Seconds(ttl).Build(),
+		)
+	}
+
+	if len(cmds) == 0 {
+		return
+	}
+
+	for _, res := range client.DoMulti(context.Background(), cmds...) {
+		if err := res.Error(); err != nil {
+			b.metrics.CounterIncrement(metricsRedisErrors)
+			b.log.Warn("failed to refresh sessions quota", "error", err)
+			return
+		}
+	}
+}
+
+// expireHistoryIndex removes expired streams from the history index
+func (b *Redis) expireHistoryIndex() {
+	if !b.conf.Wildcards {
+		return
+	}
+
+	client, err := b.getClient()
+
+	if err != nil {
+		return
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+
+	err = client.Do(context.Background(), client.B().Zremrangebyscore().Key(redisHistoryIndexKey).Min("-inf").Max(now).Build()).Error()
+
+	if err != nil {
+		b.log.Warn("failed to expire history index", "error", err)
+	}
+}
+
+// expirePresence removes expired presence records.
+// Only a single node performs expiration at a time (the one acquired the lock for the current period).
+func (b *Redis) expirePresence() {
+	client, err := b.getClient()
+
+	if err != nil {
+		return
+	}
+
+	ctx := context.Background()
+
+	err = client.Do(ctx, client.B().Set().Key(redisPresenceLockKey).Value(b.nodeID).Nx().Px(redisExpirePeriod).Build()).Error()
+
+	if err != nil {
+		if !rueidis.IsRedisNil(err) {
+			b.log.Warn("failed to acquire presence expiration lock", "error", err)
+		}
+		return
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+
+	streams, err := client.Do(ctx, client.B().Zrangebyscore().Key(redisPresenceIndexKey).Min("-inf").Max(now).Limit(0, redisExpireBatchSize).Build()).AsStrSlice()
+
+	if err != nil {
+		b.log.Warn("failed to retrieve presence streams", "error", err)
+		return
+	}
+
+	for _, stream := range streams {
+		b.expireStreamPresence(client, stream, now)
+	}
+}
+
+func (b *Redis) expireStreamPresence(client rueidis.Client, stream string, now string) {
+	ctx := context.Background()
+	keys := presenceKeys(stream)
+
+	res, err := redisPresenceExpireScript.Exec(ctx, client, keys, []string{now}).AsStrSlice()
+
+	if err != nil {
+		b.log.Warn("failed to expire presence", "stream", stream, "error", err)
+		return
+	}
+
+	left, next := res[:len(res)-1], res[len(res)-1]
+
+	if next != "" {
+		score, _ := strconv.ParseFloat(next, 64)
+		client.Do(ctx, client.B().Zadd().Key(redisPresenceIndexKey).Xx().ScoreMember().ScoreMember(score, stream).Build())
+	} else {
+		client.Do(ctx, client.B().Zrem().Key(redisPresenceIndexKey).Member(stream).Build())
+
+		// A new record could be added concurrently, make sure it's indexed
+		count, err := client.Do(ctx, client.B().Zcard().Key(keys[3]).Build()).AsInt64()
+
+		if err == nil && count > 0 {
+			client.Do(ctx, client.B().Zadd().Key(redisPresenceIndexKey).Nx().ScoreMember().ScoreMember(float64(time.Now().Unix()), stream).Build())
+		}
+	}
+
+	if b.broadcaster == nil {
+		return
+	}
+
+	for _, pid := range left {
+		msg := &common.PresenceEvent{Type: common.PresenceLeaveType, ID: pid}
+
+		b.broadcaster.Broadcast(&common.StreamMessage{
+			Stream: stream,
+			Data:   string(utils.ToJSON(msg)),
+			Meta: &common.StreamMessageMetadata{
+				BroadcastType: common.PresenceType,
+				Transient:     true,
+			},
+		})
+	}
+}
+
+func (b *Redis) presenceDeadline() int64 {
+	return time.Now().Unix() + b.conf.PresenceTTL
+}
+
+// Refresh presence records often enough to survive a few failed attempts
+func (b *Redis) presenceRefreshInterval() time.Duration {
+	return max(time.Duration(b.conf.PresenceTTL)*time.Second/3, 100*time.Millisecond)
+}
+
+func (b *Redis) getClient() (rueidis.Client, error) {
+	b.clientMu.RLock()
+	defer b.clientMu.RUnlock()
+
+	if b.client == nil {
+		return nil, errors.New("Redis client is not initialized") // nolint:stylecheck
+	}
+
+	return b.client, nil
+}
+
+// Stream-related keys use hash tags to be stored in the same slot (for Redis Cluster compatibility)
+func historyKeys(stream string) []string {
+	base := redisStreamPrefix + "{" + stream + "}"
+
+	return []string{base, base + ":o", base + ":k"}
+}
+
+func presenceKeys(stream string) []string {
+	base := redisPresencePrefix + "{" + stream + "}"
+
+	return []string{base, base + ":c", base + ":s", base + ":e"}
+}
+
+func quotaKey(key string) string {
+	return redisQuotaPrefix + key
+}
+
+// presenceTracker keeps track of the local sessions presence records
+type presenceTracker struct {
+	sessions map[string]map[string]struct{}
+	mu       sync.Mutex
+}
+
+func newPresenceTracker() *presenceTracker {
+	return &presenceTracker{sessions: make(map[string]map[string]struct{})}
+}
+
+func (t *presenceTracker) add(sid string, stream string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.sessions[sid]; !ok {
+		t.sessions[sid] = make(map[string]struct{})
+	}
+
+	t.sessions[sid][stream] = struct{}{}
+}
+
+func (t *presenceTracker) remove(sid string, stream string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if streams, ok := t.sessions[sid]; ok {
+		delete(streams, stream)
+
+		if len(streams) == 0 {
+			delete(t.sessions, sid)
+		}
+	}
+}
+
+// finish stops tracking the session and returns its streams
+func (t *presenceTracker) finish(sid string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	streams := t.sessions[sid]
+	delete(t.sessions, sid)
+
+	res := make([]string, 0, len(streams))
+
+	for stream := range streams {
+		res = append(res, stream)
+	}
+
+	return res
+}
+
+// size returns the number of tracked presence records (stream-session pairs)
+func (t *presenceTracker) size() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	res := 0
+
+	for _, streams := range t.sessions {
+		res += len(streams)
+	}
+
+	return res
+}
+
+func (t *presenceTracker) snapshot() map[string][]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	res := make(map[string][]string, len(t.sessions))
+
+	for sid, streams := range t.sessions {
+		list := make([]string, 0, len(streams))
+
+		for stream := range streams {
+			list = append(list, stream)
+		}
+
+		res[sid] = list
+	}
+
+	return res
+}
+
+func redisScriptError(err error) error {
+	if rerr, ok := rueidis.IsRedisErr(err); ok {
+		return errors.New(strings.TrimSpace(rerr.Error()))
+	}
+
+	return err
+}
@@ -0,0 +1,88 @@
+package broker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anycable/anycable-go/utils"
+)
+
+// RetentionPolicy describes history retention settings for streams matching the pattern.
+// Zero values fallback to the global settings.
+type RetentionPolicy struct {
+	// Stream name pattern: exact name, prefix (e.g., "chat:*") or glob (e.g., "project:*:cursors")
+	Pattern string `toml:"pattern"`
+	// For how long to keep history in seconds
+	TTL int64 `toml:"ttl"`
+	// Max number of messages to keep in the history
+	Limit int `toml:"limit"`
+	// Max total size of messages to keep in the history (bytes)
+	MaxBytes int64 `toml:"max_bytes"`
+	// Do not store history for matching streams at all
+	Disabled bool `toml:"disabled"`
+}
+
+// Retention contains resolved history settings for a particular stream
+type Retention struct {
+	TTL      int64
+	Limit    int
+	MaxBytes int64
+	Disabled bool
+}
+
+// RetentionFor returns history retention settings for the stream.
+// The first matching policy wins.
+func (c *Config) RetentionFor(stream string) Retention {
+	res := Retention{TTL: c.HistoryTTL, Limit: c.HistoryLimit}
+
+	for _, policy := range c.Retention {
+		if !utils.MatchStreamPattern(policy.Pattern, stream) {
+			continue
+		}
+
+		if policy.Disabled {
+			res.Disabled = true
+			return res
+		}
+
+		if policy.TTL > 0 {
+			res.TTL = policy.TTL
+		}
+
+		if policy.Limit > 0 {
+			res.Limit = policy.Limit
+		}
+
+		res.MaxBytes = policy.MaxBytes
+
+		return res
+	}
+
+	return res
+}
+
+func (p RetentionPolicy) ToToml() string {
+	var result strings.Builder
+
+	result.WriteString("[[broker.retention]]\n")
+	result.WriteString(fmt.Sprintf("pattern = \"%s\"\n", p.Pattern))
+
+	if p.Disabled {
+		result.WriteString("disabled = true\n")
+		return result.String()
+	}
+
+	if p.TTL > 0 {
+		result.WriteString(fmt.Sprintf("ttl = %d\n", p.TTL))
+	}
+
+	if p.Limit > 0 {
+		result.WriteString(fmt.Sprintf("limit = %d\n", p.Limit))
+	}
+
+	if p.MaxBytes > 0 {
+		result.WriteString(fmt.Sprintf("max_bytes = %d\n", p.MaxBytes))
+	}
+
+	return result.String()
+}
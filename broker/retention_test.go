@@ -0,0 +1,46 @@
+package broker
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_RetentionFor(t *testing.T) {
+	config := NewConfig()
+	config.HistoryTTL = 100
+	config.HistoryLimit = 10
+	config.Retention = []RetentionPolicy{
+		{Pattern: "chat:*", TTL: 3600, MaxBytes: 1024},
+		{Pattern: "project:*:cursors", Disabled: true},
+		{Pattern: "project:*", Limit: 1000},
+	}
+
+	assert.Equal(t, Retention{TTL: 100, Limit: 10}, config.RetentionFor("notifications"))
+	assert.Equal(t, Retention{TTL: 3600, Limit: 10, MaxBytes: 1024}, config.RetentionFor("chat:42"))
+	assert.Equal(t, Retention{TTL: 100, Limit: 10, Disabled: true}, config.RetentionFor("project:1:cursors"))
+	assert.Equal(t, Retention{TTL: 100, Limit: 1000}, config.RetentionFor("project:1:tasks"))
+}
+
+func TestConfig_RetentionToToml(t *testing.T) {
+	conf := NewConfig()
+	conf.Retention = []RetentionPolicy{
+		{Pattern: "chat:*", TTL: 3600, Limit: 1000, MaxBytes: 1024},
+		{Pattern: "cursors:*", Disabled: true},
+	}
+
+	tomlStr := "[broker]\n" + conf.ToToml()
+
+	assert.Contains(t, tomlStr, "[[broker.retention]]\npattern = \"chat:*\"")
+
+	var decoded struct {
+		Broker Config `toml:"broker"`
+	}
+
+	_, err := toml.Decode(tomlStr, &decoded)
+	require.NoError(t, err)
+
+	assert.Equal(t, conf, decoded.Broker)
+}
@@ -11,16 +11,20 @@ import (
 	"strings"
 	"time"
 
+	"github.com/anycable/anycable-go/admin"
 	"github.com/anycable/anycable-go/broadcast"
 	"github.com/anycable/anycable-go/broker"
 	"github.com/anycable/anycable-go/common"
 	"github.com/anycable/anycable-go/config"
 	"github.com/anycable/anycable-go/enats"
+	"github.com/anycable/anycable-go/graphql"
 	"github.com/anycable/anycable-go/identity"
 	"github.com/anycable/anycable-go/logger"
+	"github.com/anycable/anycable-go/lp"
 	metricspkg "github.com/anycable/anycable-go/metrics"
 	"github.com/anycable/anycable-go/mrb"
 	"github.com/anycable/anycable-go/node"
+	"github.com/anycable/anycable-go/ocpp"
 	"github.com/anycable/anycable-go/pubsub"
 	"github.com/anycable/anycable-go/router"
 	"github.com/anycable/anycable-go/server"
@@ -144,6 +148,12 @@ func (r *Runner) checkAndSetDefaults() error {
 		r.websocketHandlerFactory = r.defaultWebSocketHandler
 	}
 
+	// Wildcard streams must be handled consistently by all the components
+	r.config.Streams.Wildcards = r.config.App.WildcardStreams
+	r.config.Broker.Wildcards = r.config.App.WildcardStreams
+	r.config.RedisPubSub.Wildcards = r.config.App.WildcardStreams
+	r.config.NATSPubSub.Wildcards = r.config.App.WildcardStreams
+
 	metrics, err := r.initMetrics(&r.config.Metrics)
 
 	if err != nil {
@@ -219,6 +229,53 @@ func (r *Runner) Run() error {
 		wsServer.SetupHandler(r.config.SSE.Path, sseHandler)
 	}
 
+	if r.config.LongPolling.Enabled {
+		r.log.Info(
+			fmt.Sprintf("Handle long polling requests at %s%s (poll_interval: %d, keepalive_timeout: %d)",
+				wsServer.Address(), r.config.LongPolling.Path, r.config.LongPolling.PollInterval, r.config.LongPolling.KeepaliveTimeout),
+		)
+
+		pollHandler, err := r.defaultLongPollingHandler(appNode, wsServer.ShutdownCtx(), r.config)
+
+		if err != nil {
+			return errorx.Decorate(err, "failed to initialize long polling handler")
+		}
+
+		wsServer.SetupHandler(r.config.LongPolling.Path, pollHandler)
+	}
+
+	if r.config.GraphQL.Enabled() {
+		r.log.Info(
+			fmt.Sprintf("Handle GraphQL WebSocket connections at %s%s (channel: %s, action: %s)",
+				wsServer.Address(), r.config.GraphQL.Path, r.config.GraphQL.Channel, r.config.GraphQL.Action),
+		)
+
+		graphqlHandler, err := r.defaultGraphQLHandler(appNode, r.config, r.log)
+
+		if err != nil {
+			return errorx.Decorate(err, "failed to initialize GraphQL handler")
+		}
+
+		wsServer.SetupHandler(r.config.GraphQL.Path, graphqlHandler)
+	}
+
+	if r.config.OCPP.Enabled() {
+		ocppPath := strings.TrimSuffix(r.config.OCPP.Path, "/") + "/"
+
+		r.log.Info(
+			fmt.Sprintf("Handle OCPP v1.6 WebSocket connections at %s%s{station_id}",
+				wsServer.Address(), ocppPath),
+		)
+
+		ocppHandler, err := r.defaultOCPPHandler(appNode, r.config, r.log)
+
+		if err != nil {
+			return errorx.Decorate(err, "failed to initialize OCPP handler")
+		}
+
+		wsServer.SetupHandler(ocppPath, ocppHandler)
+	}
+
 	go r.startWSServer(wsServer)
 	go r.metrics.Run() // nolint:errcheck
 
@@ -273,6 +330,8 @@ func (r *Runner) runNode() (*node.Node, error) {
 		appNode.SetBroker(appBroker)
 	}
 
+	appNode.SetBroadcaster(subscriber)
+
 	disconnector, err := r.disconnectorFactory(appNode, r.config, r.log)
 	if err != nil {
 		return nil, errorx.Decorate(err, "failed to initialize disconnector")
@@ -319,6 +378,17 @@ func (r *Runner) runNode() (*node.Node, error) {
 
 	r.shutdownables = append(r.shutdownables, subscriber)
 
+	if r.config.Admin.Enabled {
+		adminServer := admin.NewServer(appBroker, appNode, &r.config.Admin, r.log)
+
+		err = adminServer.Start(r.errChan)
+		if err != nil {
+			return nil, errorx.Decorate(err, "failed to start admin API")
+		}
+
+		r.shutdownables = append(r.shutdownables, adminServer)
+	}
+
 	if r.broadcastersFactory != nil {
 		broadcasters, berr := r.broadcastersFactory(appNode, r.config, r.log)
 
@@ -458,6 +528,46 @@ func (r *Runner) defaultWebSocketHandler(n *node.Node, c *config.Config, l *slog
 	}), nil
 }
 
+func (r *Runner) defaultGraphQLHandler(n *node.Node, c *config.Config, l *slog.Logger) (http.Handler, error) {
+	extractor := server.DefaultHeadersExtractor{Headers: c.RPC.ProxyHeaders, Cookies: c.RPC.ProxyCookies}
+	return ws.WebsocketHandler([]string{graphql.Protocol}, &extractor, &c.WS, l, func(wsc *websocket.Conn, info *server.RequestInfo, callback func()) error {
+		wrappedConn := ws.NewConnection(wsc)
+
+		session := graphql.NewSession(n, wrappedConn, info, &c.GraphQL, r.sessionOptionsFromParams(info)...)
+
+		return session.Serve(callback)
+	}), nil
+}
+
+func (r *Runner) defaultOCPPHandler(n *node.Node, c *config.Config, l *slog.Logger) (http.Handler, error) {
+	extractor := server.DefaultHeadersExtractor{Headers: c.RPC.ProxyHeaders, Cookies: c.RPC.ProxyCookies}
+	wsHandler := ws.WebsocketHandler([]string{ocpp.Protocol}, &extractor, &c.WS, l, func(wsc *websocket.Conn, info *server.RequestInfo, callback func()) error {
+		wrappedConn := ws.NewConnection(wsc)
+
+		session, err := ocpp.NewSession(n, wrappedConn, info, &c.OCPP, r.sessionOptionsFromParams(info)...)
+
+		if err != nil {
+			return err
+		}
+
+		// Charge point has been rejected
+		if session == nil {
+			return nil
+		}
+
+		return session.Serve(callback)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if ocpp.StationIDFromPath(c.OCPP.Path, req.URL.Path) == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		wsHandler.ServeHTTP(w, req)
+	}), nil
+}
+
 func (r *Runner) defaultSSEHandler(n *node.Node, ctx context.Context, c *config.Config) (http.Handler, error) {
 	extractor := server.DefaultHeadersExtractor{Headers: c.RPC.ProxyHeaders, Cookies: c.RPC.ProxyCookies}
 	handler := sse.SSEHandler(n, ctx, &extractor, &c.SSE, r.log)
@@ -465,6 +575,13 @@ func (r *Runner) defaultSSEHandler(n *node.Node, ctx context.Context, c *config.
 	return handler, nil
 }
 
+func (r *Runner) defaultLongPollingHandler(n *node.Node, ctx context.Context, c *config.Config) (http.Handler, error) {
+	extractor := server.DefaultHeadersExtractor{Headers: c.RPC.ProxyHeaders, Cookies: c.RPC.ProxyCookies}
+	handler := lp.LongPollingHandler(n, ctx, &extractor, &c.LongPolling, r.log)
+
+	return handler, nil
+}
+
 func (r *Runner) initMRuby() string {
 	if mrb.Supported() {
 		var mrbv string
@@ -41,6 +41,29 @@ func (e *Embedded) SSEHandler(ctx context.Context) (http.Handler, error) {
 	return sseHandler, nil
 }
 
+// LongPollingHandler returns an HTTP handler to serve long polling connections via AnyCable.
+// Please, provide your HTTP server's shutdown context to terminate poll sessions gracefully
+// on server shutdown.
+func (e *Embedded) LongPollingHandler(ctx context.Context) (http.Handler, error) {
+	pollHandler, err := e.r.defaultLongPollingHandler(e.n, ctx, e.r.config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pollHandler, nil
+}
+
+// GraphQLHandler returns an HTTP handler to serve GraphQL WebSocket connections (graphql-transport-ws protocol)
+func (e *Embedded) GraphQLHandler() (http.Handler, error) {
+	return e.r.defaultGraphQLHandler(e.n, e.r.config, e.r.log)
+}
+
+// OCPPHandler returns an HTTP handler to serve OCPP v1.6 WebSocket connections (the charge point ID is the last path segment)
+func (e *Embedded) OCPPHandler() (http.Handler, error) {
+	return e.r.defaultOCPPHandler(e.n, e.r.config, e.r.log)
+}
+
 // HTTPBroadcastHandler returns an HTTP handler to process broadcasting requests
 func (e *Embedded) HTTPBroadcastHandler() (http.Handler, error) {
 	broadcaster := broadcast.NewHTTPBroadcaster(e.n, &e.r.config.HTTPBroadcast, e.r.log)
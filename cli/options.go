@@ -66,7 +66,7 @@ func NewConfigFromCLI(args []string, opts ...cliOption) (*config.Config, error,
 	}
 
 	var path, headers, cookieFilter, mtags string
-	var broadcastAdapters string
+	var broadcastAdapters, conflateStreams string
 	var cliInterrupted = true
 	var shouldPrintConfig = false
 	var metricsFilter string
@@ -100,7 +100,7 @@ func NewConfigFromCLI(args []string, opts ...cliOption) (*config.Config, error,
 	}
 	flags = append(flags, serverCLIFlags(&c, &path)...)
 	flags = append(flags, sslCLIFlags(&c)...)
-	flags = append(flags, broadcastCLIFlags(&c, &broadcastAdapters)...)
+	flags = append(flags, broadcastCLIFlags(&c, &broadcastAdapters, &conflateStreams)...)
 	flags = append(flags, brokerCLIFlags(&c)...)
 	flags = append(flags, redisCLIFlags(&c)...)
 	flags = append(flags, httpBroadcastCLIFlags(&c)...)
@@ -111,11 +111,16 @@ func NewConfigFromCLI(args []string, opts ...cliOption) (*config.Config, error,
 	flags = append(flags, metricsCLIFlags(&c, &metricsFilter, &mtags)...)
 	flags = append(flags, wsCLIFlags(&c)...)
 	flags = append(flags, pingCLIFlags(&c)...)
+	flags = append(flags, limitsCLIFlags(&c)...)
 	flags = append(flags, jwtCLIFlags(&c, &jwtIdKey, &jwtIdParam, &jwtIdEnforce)...)
 	flags = append(flags, signedStreamsCLIFlags(&c, &turboRailsKey, &cableReadyKey, &turboRailsClearText, &cableReadyClearText)...)
 	flags = append(flags, statsdCLIFlags(&c)...)
 	flags = append(flags, embeddedNatsCLIFlags(&c, &enatsRoutes, &enatsGateways)...)
 	flags = append(flags, sseCLIFlags(&c)...)
+	flags = append(flags, longPollingCLIFlags(&c)...)
+	flags = append(flags, graphqlCLIFlags(&c)...)
+	flags = append(flags, ocppCLIFlags(&c)...)
+	flags = append(flags, adminCLIFlags(&c)...)
 	flags = append(flags, miscCLIFlags(&c, &presets)...)
 
 	app := &cli.App{
@@ -209,6 +214,10 @@ Use metrics_rotate_interval instead.`)
 		}
 	}
 
+	if conflateStreams != "" {
+		c.App.ConflateStreams = strings.Split(conflateStreams, ",")
+	}
+
 	if metricsFilter != "" {
 		c.Metrics.LogFilter = strings.Split(metricsFilter, ",")
 	}
@@ -247,6 +256,7 @@ Use shutdown_timeout instead.`)
 	// Propagate allowed origins to all the components
 	c.WS.AllowedOrigins = c.Server.AllowedOrigins
 	c.SSE.AllowedOrigins = c.Server.AllowedOrigins
+	c.LongPolling.AllowedOrigins = c.Server.AllowedOrigins
 	c.HTTPBroadcast.CORSHosts = c.Server.AllowedOrigins
 
 	// Propagate Redis and NATS configs to components
@@ -363,6 +373,10 @@ Use broadcast_key instead.`)
 		if c.RPC.Secret == "" {
 			c.RPC.SecretBase = c.Secret
 		}
+
+		if c.Admin.Secret == "" {
+			c.Admin.SecretBase = c.Secret
+		}
 	}
 
 	// Nullify none secrets
@@ -378,6 +392,9 @@ Use broadcast_key instead.`)
 		c.JWT.Secret = ""
 	}
 
+	// Propagate JWT identification parameter to the GraphQL adapter (to read tokens from connection params)
+	c.GraphQL.JWTParam = c.JWT.Param
+
 	if c.RPC.Secret == "none" {
 		c.RPC.Secret = ""
 	}
@@ -395,6 +412,10 @@ Use broadcast_key instead.`)
 		}
 	}
 
+	if c.Admin.Port == 0 {
+		c.Admin.Port = c.Server.Port
+	}
+
 	// Configure public mode and other insecure features
 	if c.PublicMode {
 		c.SkipAuth = true
@@ -445,6 +466,11 @@ const (
 	miscCategoryDescription          = "MISC:"
 	brokerCategoryDescription        = "BROKER:"
 	sseCategoryDescription           = "SERVER-SENT EVENTS:"
+	pollCategoryDescription          = "LONG POLLING:"
+	graphqlCategoryDescription       = "GRAPHQL:"
+	ocppCategoryDescription          = "OCPP:"
+	adminCategoryDescription         = "ADMIN API:"
+	limitsCategoryDescription        = "LIMITS AND QUOTAS:"
 
 	envPrefix = "ANYCABLE_"
 )
@@ -572,7 +598,7 @@ func sslCLIFlags(c *config.Config) []cli.Flag {
 }
 
 // broadcastCLIFlags returns broadcast_adapter flag
-func broadcastCLIFlags(c *config.Config, adapters *string) []cli.Flag {
+func broadcastCLIFlags(c *config.Config, adapters *string, conflateStreams *string) []cli.Flag {
 	return withDefaults(broadcastCategoryDescription, []cli.Flag{
 		&cli.StringFlag{
 			Name:        "broadcast_adapter",
@@ -581,7 +607,7 @@ func broadcastCLIFlags(c *config.Config, adapters *string) []cli.Flag {
 		},
 		&cli.StringFlag{
 			Name:        "broker",
-			Usage:       "Broker engine to use (memory)",
+			Usage:       "Broker engine to use (memory, nats or redis)",
 			Value:       c.Broker.Adapter,
 			Destination: &c.Broker.Adapter,
 		},
@@ -613,6 +639,19 @@ func broadcastCLIFlags(c *config.Config, adapters *string) []cli.Flag {
 			Destination: &c.App.HubGopoolSize,
 			Hidden:      true,
 		},
+
+		&cli.StringFlag{
+			Name:        "conflate_streams",
+			Usage:       "Comma-separated list of streams (or patterns, e.g., \"prices:*\") to deliver only the latest pending message for",
+			Destination: conflateStreams,
+		},
+
+		&cli.BoolFlag{
+			Name:        "wildcard_streams",
+			Usage:       "Enable wildcard streams (e.g., \"project:42:*\" or \"project.42.>\")",
+			Value:       c.App.WildcardStreams,
+			Destination: &c.App.WildcardStreams,
+		},
 	})
 }
 
@@ -631,6 +670,18 @@ func brokerCLIFlags(c *config.Config) []cli.Flag {
 			Value:       c.Broker.HistoryTTL,
 			Destination: &c.Broker.HistoryTTL,
 		},
+		&cli.StringFlag{
+			Name:        "history_dir",
+			Usage:       "Directory to persist streams history to (memory and NATS brokers)",
+			Value:       c.Broker.HistoryDir,
+			Destination: &c.Broker.HistoryDir,
+		},
+		&cli.StringFlag{
+			Name:        "broker_snapshot_path",
+			Usage:       "File to save the in-memory broker state to on shutdown (and restore from on start)",
+			Value:       c.Broker.SnapshotPath,
+			Destination: &c.Broker.SnapshotPath,
+		},
 		&cli.Int64Flag{
 			Name:        "sessions_ttl",
 			Usage:       "TTL for expired/disconnected sessions (seconds)",
@@ -1068,6 +1119,13 @@ func metricsCLIFlags(c *config.Config, filter *string, mtags *string) []cli.Flag
 			Destination: mtags,
 		},
 
+		&cli.IntFlag{
+			Name:        "metrics_top_streams",
+			Usage:       "Track per-stream statistics and report the specified number of the busiest streams (0 — disabled)",
+			Value:       c.Metrics.TopStreams,
+			Destination: &c.Metrics.TopStreams,
+		},
+
 		&cli.IntFlag{
 			Name:        "stats_refresh_interval",
 			Usage:       "How often to refresh the server stats (in seconds)",
@@ -1137,6 +1195,88 @@ func pingCLIFlags(c *config.Config) []cli.Flag {
 			Value:       c.App.PongTimeout,
 			Destination: &c.App.PongTimeout,
 		},
+
+		&cli.IntFlag{
+			Name:        "outbound_queue_size",
+			Usage:       "The max number of pending outgoing messages per client. Zero means no limit",
+			Value:       c.App.OutboundQueueSize,
+			Destination: &c.App.OutboundQueueSize,
+		},
+
+		&cli.StringFlag{
+			Name:        "outbound_queue_policy",
+			Usage:       "What to do when the client's outbound queue is full (drop_oldest, drop_transient, disconnect)",
+			Value:       c.App.OutboundQueuePolicy,
+			Destination: &c.App.OutboundQueuePolicy,
+		},
+	})
+}
+
+// limitsCLIFlags returns CLI flags for incoming commands rate limiting and quotas
+func limitsCLIFlags(c *config.Config) []cli.Flag {
+	return withDefaults(limitsCategoryDescription, []cli.Flag{
+		&cli.IntFlag{
+			Name:        "perform_rate_limit",
+			Usage:       "The max number of perform (message) commands per second per client. Zero means no limit",
+			Value:       c.App.PerformRateLimit,
+			Destination: &c.App.PerformRateLimit,
+		},
+
+		&cli.IntFlag{
+			Name:        "perform_rate_burst",
+			Usage:       "The max burst of perform (message) commands per client (defaults to the rate limit)",
+			Value:       c.App.PerformRateBurst,
+			Destination: &c.App.PerformRateBurst,
+		},
+
+		&cli.IntFlag{
+			Name:        "whisper_rate_limit",
+			Usage:       "The max number of whispers per second per client. Zero means no limit",
+			Value:       c.App.WhisperRateLimit,
+			Destination: &c.App.WhisperRateLimit,
+		},
+
+		&cli.IntFlag{
+			Name:        "whisper_rate_burst",
+			Usage:       "The max burst of whispers per client (defaults to the rate limit)",
+			Value:       c.App.WhisperRateBurst,
+			Destination: &c.App.WhisperRateBurst,
+		},
+
+		&cli.IntFlag{
+			Name:        "presence_rate_limit",
+			Usage:       "The max number of presence commands (presence, join, leave, update) per second per client. Zero means no limit",
+			Value:       c.App.PresenceRateLimit,
+			Destination: &c.App.PresenceRateLimit,
+		},
+
+		&cli.IntFlag{
+			Name:        "presence_rate_burst",
+			Usage:       "The max burst of presence commands per client (defaults to the rate limit)",
+			Value:       c.App.PresenceRateBurst,
+			Destination: &c.App.PresenceRateBurst,
+		},
+
+		&cli.IntFlag{
+			Name:        "rate_limit_max_violations",
+			Usage:       "The number of consecutive rate-limited commands to disconnect the client after. Zero means never disconnect",
+			Value:       c.App.RateLimitMaxViolations,
+			Destination: &c.App.RateLimitMaxViolations,
+		},
+
+		&cli.IntFlag{
+			Name:        "max_sessions_per_identifier",
+			Usage:       "The max number of concurrent sessions with the same identifiers (cluster-wide with a distributed broker). Zero means no limit",
+			Value:       c.App.MaxSessionsPerIdentifier,
+			Destination: &c.App.MaxSessionsPerIdentifier,
+		},
+
+		&cli.IntFlag{
+			Name:        "max_subscriptions_per_session",
+			Usage:       "The max number of channel subscriptions per session. Zero means no limit",
+			Value:       c.App.MaxSubscriptionsPerSession,
+			Destination: &c.App.MaxSubscriptionsPerSession,
+		},
 	})
 }
 
@@ -1308,6 +1448,155 @@ func sseCLIFlags(c *config.Config) []cli.Flag {
 	})
 }
 
+// longPollingCLIFlags returns CLI flags for long polling
+func longPollingCLIFlags(c *config.Config) []cli.Flag {
+	return withDefaults(pollCategoryDescription, []cli.Flag{
+		&cli.BoolFlag{
+			Name:        "poll",
+			Usage:       "Enable long polling endpoint",
+			Value:       c.LongPolling.Enabled,
+			Destination: &c.LongPolling.Enabled,
+		},
+		&cli.StringFlag{
+			Name:        "poll_path",
+			Usage:       "Long polling endpoint path",
+			Value:       c.LongPolling.Path,
+			Destination: &c.LongPolling.Path,
+		},
+		&cli.IntFlag{
+			Name:        "poll_interval",
+			Usage:       "For how long to wait for messages before responding to a poll request (in seconds)",
+			Value:       c.LongPolling.PollInterval,
+			Destination: &c.LongPolling.PollInterval,
+		},
+		&cli.IntFlag{
+			Name:        "poll_flush_interval",
+			Usage:       "For how long to buffer server-to-client messages before flushing them to the client (in milliseconds)",
+			Value:       c.LongPolling.FlushInterval,
+			Destination: &c.LongPolling.FlushInterval,
+		},
+		&cli.Int64Flag{
+			Name:        "poll_max_request_size",
+			Usage:       "Maximum acceptable request body size (in bytes)",
+			Value:       c.LongPolling.MaxRequestSize,
+			Destination: &c.LongPolling.MaxRequestSize,
+		},
+		&cli.IntFlag{
+			Name:        "poll_keepalive_timeout",
+			Usage:       "For how long to keep a poll session alive between requests (in seconds)",
+			Value:       c.LongPolling.KeepaliveTimeout,
+			Destination: &c.LongPolling.KeepaliveTimeout,
+		},
+		&cli.IntFlag{
+			Name:        "poll_max_buffer_size",
+			Usage:       "Maximum number of messages to buffer for a client between poll requests (the client is disconnected when exceeded)",
+			Value:       c.LongPolling.MaxBufferSize,
+			Destination: &c.LongPolling.MaxBufferSize,
+		},
+	})
+}
+
+// graphqlCLIFlags returns CLI flags for GraphQL over WebSocket
+func graphqlCLIFlags(c *config.Config) []cli.Flag {
+	return withDefaults(graphqlCategoryDescription, []cli.Flag{
+		&cli.StringFlag{
+			Name:        "graphql_path",
+			Usage:       "Enable graphql-transport-ws endpoint at the specified path",
+			Value:       c.GraphQL.Path,
+			Destination: &c.GraphQL.Path,
+		},
+		&cli.StringFlag{
+			Name:        "graphql_channel",
+			Usage:       "GraphQL Ruby channel class name",
+			Value:       c.GraphQL.Channel,
+			Destination: &c.GraphQL.Channel,
+		},
+		&cli.StringFlag{
+			Name:        "graphql_action",
+			Usage:       "GraphQL Ruby channel action to execute operations",
+			Value:       c.GraphQL.Action,
+			Destination: &c.GraphQL.Action,
+		},
+		&cli.IntFlag{
+			Name:        "graphql_init_timeout",
+			Usage:       "For how long to wait for the connection_init message (in seconds)",
+			Value:       c.GraphQL.InitTimeout,
+			Destination: &c.GraphQL.InitTimeout,
+		},
+	})
+}
+
+// ocppCLIFlags returns CLI flags for OCPP
+func ocppCLIFlags(c *config.Config) []cli.Flag {
+	return withDefaults(ocppCategoryDescription, []cli.Flag{
+		&cli.StringFlag{
+			Name:        "ocpp_path",
+			Usage:       "Enable OCPP v1.6 endpoint at the specified path prefix (charge point ID is added to it)",
+			Value:       c.OCPP.Path,
+			Destination: &c.OCPP.Path,
+		},
+		&cli.StringFlag{
+			Name:        "ocpp_channel",
+			Usage:       "Action Cable channel class name to handle OCPP messages",
+			Value:       c.OCPP.Channel,
+			Destination: &c.OCPP.Channel,
+		},
+		&cli.BoolFlag{
+			Name:        "ocpp_granular_actions",
+			Usage:       "Call a separate channel action per OCPP command (otherwise, #receive is called)",
+			Value:       c.OCPP.GranularActions,
+			Destination: &c.OCPP.GranularActions,
+		},
+		&cli.IntFlag{
+			Name:        "ocpp_heartbeat_interval",
+			Usage:       "Heartbeat interval to send to charge points on boot (in seconds)",
+			Value:       c.OCPP.HeartbeatInterval,
+			Destination: &c.OCPP.HeartbeatInterval,
+		},
+		&cli.IntFlag{
+			Name:        "ocpp_call_timeout",
+			Usage:       "Time to wait for a charge point to respond to a server-initiated call (in seconds)",
+			Value:       c.OCPP.CallTimeout,
+			Destination: &c.OCPP.CallTimeout,
+		},
+		&cli.IntFlag{
+			Name:        "ocpp_max_pending_calls",
+			Usage:       "Max number of server-initiated calls waiting to be sent to a charge point (only one call can be in flight)",
+			Value:       c.OCPP.MaxPendingCalls,
+			Destination: &c.OCPP.MaxPendingCalls,
+		},
+	})
+}
+
+// adminCLIFlags returns admin API flags
+func adminCLIFlags(c *config.Config) []cli.Flag {
+	return withDefaults(adminCategoryDescription, []cli.Flag{
+		&cli.BoolFlag{
+			Name:        "admin",
+			Usage:       "Enable admin API",
+			Value:       c.Admin.Enabled,
+			Destination: &c.Admin.Enabled,
+		},
+		&cli.IntFlag{
+			Name:        "admin_port",
+			Usage:       "Admin API server port (the main server port is used by default)",
+			Value:       c.Admin.Port,
+			Destination: &c.Admin.Port,
+		},
+		&cli.StringFlag{
+			Name:        "admin_path",
+			Usage:       "Admin API endpoints path prefix",
+			Value:       c.Admin.Path,
+			Destination: &c.Admin.Path,
+		},
+		&cli.StringFlag{
+			Name:        "admin_secret",
+			Usage:       "Admin API authorization secret (auto-generated from the application secret by default)",
+			Destination: &c.Admin.Secret,
+		},
+	})
+}
+
 // miscCLIFlags returns uncategorized flags
 func miscCLIFlags(c *config.Config, presets *string) []cli.Flag {
 	return withDefaults(miscCategoryDescription, []cli.Flag{
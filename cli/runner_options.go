@@ -158,26 +158,51 @@ func WithWebSocketEndpoint(path string, fn websocketHandler) Option {
 
 // WithDefaultBroker is an Option to set Runner broker to default broker from config
 func WithDefaultBroker() Option {
-	return WithBroker(func(br broker.Broadcaster, c *config.Config, l *slog.Logger) (broker.Broker, error) {
-		if c.Broker.Adapter == "" {
-			return broker.NewLegacyBroker(br), nil
+	return func(r *Runner) error {
+		return WithBroker(func(br broker.Broadcaster, c *config.Config, l *slog.Logger) (broker.Broker, error) {
+			return defaultBroker(br, r.Instrumenter(), c, l)
+		})(r)
+	}
+}
+
+func defaultBroker(br broker.Broadcaster, m metrics.Instrumenter, c *config.Config, l *slog.Logger) (broker.Broker, error) {
+	if c.Broker.Adapter == "" {
+		return broker.NewLegacyBroker(br), nil
+	}
+
+	switch c.Broker.Adapter {
+	case "memory":
+		opts := []broker.MemoryOption{broker.WithMemoryInstrumenter(m), broker.WithMemoryLogger(l)}
+
+		if c.Broker.HistoryDir != "" {
+			opts = append(opts, broker.WithMemoryHistory(broker.NewFileBroker(&c.Broker, c.Broker.HistoryDir, l)))
 		}
 
-		switch c.Broker.Adapter {
-		case "memory":
-			b := broker.NewMemoryBroker(br, &c.Broker)
-			return b, nil
-		case "nats":
-			// TODO: Figure out a better place for this hack.
-			// We don't want to enable JetStream by default (if NATS is used only for pub/sub),
-			// currently, we only need it when NATS is used as a broker.
-			c.EmbeddedNats.JetStream = true
-			b := broker.NewNATSBroker(br, &c.Broker, &c.NATS, l)
-			return b, nil
-		default:
-			return nil, errorx.IllegalArgument.New("Unsupported broker adapter: %s", c.Broker.Adapter)
+		if c.Broker.SnapshotPath != "" {
+			opts = append(opts, broker.WithMemorySnapshot(c.Broker.SnapshotPath))
 		}
-	})
+
+		b := broker.NewMemoryBroker(br, &c.Broker, opts...)
+		return b, nil
+	case "nats":
+		// TODO: Figure out a better place for this hack.
+		// We don't want to enable JetStream by default (if NATS is used only for pub/sub),
+		// currently, we only need it when NATS is used as a broker.
+		c.EmbeddedNats.JetStream = true
+		opts := []broker.NATSOption{broker.WithNATSInstrumenter(m)}
+
+		if c.Broker.HistoryDir != "" {
+			opts = append(opts, broker.WithNATSLocalBroker(broker.NewFileBroker(&c.Broker, c.Broker.HistoryDir, l)))
+		}
+
+		b := broker.NewNATSBroker(br, &c.Broker, &c.NATS, l, opts...)
+		return b, nil
+	case "redis":
+		b := broker.NewRedisBroker(br, &c.Broker, &c.Redis, l, broker.WithRedisInstrumenter(m))
+		return b, nil
+	default:
+		return nil, errorx.IllegalArgument.New("Unsupported broker adapter: %s", c.Broker.Adapter)
+	}
 }
 
 // WithTelemetry enables AnyCable telemetry unless ANYCABLE_DISABLE_TELEMETRY is set.
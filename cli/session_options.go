@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/encoders"
 	"github.com/anycable/anycable-go/node"
 	"github.com/anycable/anycable-go/server"
 )
@@ -20,6 +21,15 @@ const (
 func (r *Runner) sessionOptionsFromProtocol(protocol string) []node.SessionOption {
 	opts := []node.SessionOption{}
 
+	switch protocol {
+	case common.ActionCableV1Msgpack, common.ActionCableV1ExtMsgpack:
+		opts = append(opts, node.WithEncoder(encoders.Msgpack{}))
+	case common.ActionCableV1Protobuf:
+		opts = append(opts, node.WithEncoder(encoders.Protobuf{}))
+	case common.ActionCableV1ExtProtobuf:
+		opts = append(opts, node.WithEncoder(encoders.ProtobufV2{}))
+	}
+
 	if common.IsExtendedActionCableProtocol(protocol) {
 		opts = append(opts, node.WithResumable(true))
 
@@ -2,6 +2,7 @@
 package common
 
 import (
+	"bytes"
 	"encoding/json"
 	"log/slog"
 
@@ -30,16 +31,26 @@ func StatusName(status int) string {
 }
 
 const (
-	ActionCableV1JSON    = "actioncable-v1-json"
-	ActionCableV1ExtJSON = "actioncable-v1-ext-json"
+	ActionCableV1JSON       = "actioncable-v1-json"
+	ActionCableV1ExtJSON    = "actioncable-v1-ext-json"
+	ActionCableV1Msgpack    = "actioncable-v1-msgpack"
+	ActionCableV1ExtMsgpack = "actioncable-v1-ext-msgpack"
+	// Incoming and outgoing messages are encoded as action_cable.Message
+	ActionCableV1Protobuf = "actioncable-v1-protobuf"
+	// Incoming messages are encoded as action_cable.Message, outgoing ones as action_cable.Reply
+	ActionCableV1ExtProtobuf = "actioncable-v1-ext-protobuf"
 )
 
 func ActionCableProtocols() []string {
-	return []string{ActionCableV1JSON, ActionCableV1ExtJSON}
+	return []string{
+		ActionCableV1JSON, ActionCableV1ExtJSON,
+		ActionCableV1Msgpack, ActionCableV1ExtMsgpack,
+		ActionCableV1Protobuf, ActionCableV1ExtProtobuf,
+	}
 }
 
 func ActionCableExtendedProtocols() []string {
-	return []string{ActionCableV1ExtJSON}
+	return []string{ActionCableV1ExtJSON, ActionCableV1ExtMsgpack, ActionCableV1ExtProtobuf}
 }
 
 func IsExtendedActionCableProtocol(protocol string) bool {
@@ -66,10 +77,11 @@ const (
 	HistoryConfirmedType = "confirm_history"
 	HistoryRejectedType  = "reject_history"
 
-	PresenceInfoType  = "info"
-	PresenceJoinType  = "join"
-	PresenceLeaveType = "leave"
-	PresenceType      = "presence"
+	PresenceInfoType   = "info"
+	PresenceJoinType   = "join"
+	PresenceLeaveType  = "leave"
+	PresenceUpdateType = "update"
+	PresenceType       = "presence"
 
 	WhisperType = "whisper"
 )
@@ -81,12 +93,24 @@ const (
 	IDLE_TIMEOUT_REASON      = "idle_timeout"
 	NO_PONG_REASON           = "no_pong"
 	UNAUTHORIZED_REASON      = "unauthorized"
+	SLOW_CONSUMER_REASON     = "slow_consumer"
+	RATE_LIMITED_REASON      = "rate_limited"
+	// Sessions quota per identifier exceeded
+	TOO_MANY_SESSIONS_REASON = "too_many_sessions"
+	// Subscriptions quota per session exceeded
+	TOO_MANY_SUBSCRIPTIONS_REASON = "too_many_subscriptions"
+	// Long polling session has expired (or never existed)
+	SESSION_EXPIRED_REASON = "session_expired"
 )
 
 // Reserver state fields
 const (
 	WHISPER_STREAM_STATE  = "$w"
 	PRESENCE_STREAM_STATE = "$p"
+	// Subscription filter set by the controller
+	FILTER_STATE = "$f"
+	// Subscription filter requested by the client
+	CLIENT_FILTER_STATE = "$fc"
 )
 
 // SessionEnv represents the underlying HTTP connection data:
@@ -311,6 +335,10 @@ type HistoryRequest struct {
 	Since int64 `json:"since,omitempty"`
 	// Streams contains the information of last offsets/epoch received for a particular stream
 	Streams map[string]HistoryPosition `json:"streams,omitempty"`
+	// Limit is the max number of messages to return per stream (zero means no limit)
+	Limit int `json:"limit,omitempty"`
+	// Latest defines whether to return the latest Limit messages (skipping the older ones)
+	Latest bool `json:"latest,omitempty"`
 }
 
 func (hr *HistoryRequest) LogValue() slog.Value {
@@ -318,7 +346,14 @@ func (hr *HistoryRequest) LogValue() slog.Value {
 		return slog.StringValue("nil")
 	}
 
-	return slog.GroupValue(slog.Int64("since", hr.Since), slog.Any("streams", hr.Streams))
+	return slog.GroupValue(slog.Int64("since", hr.Since), slog.Any("streams", hr.Streams), slog.Int("limit", hr.Limit), slog.Bool("latest", hr.Latest))
+}
+
+// HistoryCursor is sent along with the history confirmation when the history has been truncated
+type HistoryCursor struct {
+	Truncated bool `json:"truncated"`
+	// Streams contains the positions to request the next page of history from
+	Streams map[string]HistoryPosition `json:"streams,omitempty"`
 }
 
 // Message represents incoming client message
@@ -328,6 +363,8 @@ type Message struct {
 	Data       interface{}    `json:"data,omitempty"`
 	History    HistoryRequest `json:"history,omitempty"`
 	Presence   *PresenceEvent `json:"presence,omitempty"`
+	// Filter is a subscription filter expression requested by the client
+	Filter map[string]interface{} `json:"filter,omitempty"`
 }
 
 func (m *Message) LogValue() slog.Value {
@@ -351,6 +388,12 @@ type StreamMessageMetadata struct {
 	BroadcastType string `json:"broadcast_type,omitempty"`
 	// Transient defines whether this message should be stored in the history
 	Transient bool `json:"transient,omitempty"`
+	// CompactionKey defines the key to compact the stream history by:
+	// only the latest message with the same key is kept in the history
+	CompactionKey string `json:"compaction_key,omitempty"`
+	// Conflate defines whether this message could replace the previous one from the same stream
+	// if it hasn't been delivered to the client yet (latest-value-only delivery)
+	Conflate bool `json:"conflate,omitempty"`
 }
 
 func (smm *StreamMessageMetadata) LogValue() slog.Value {
@@ -456,6 +499,119 @@ func (m *RemoteDisconnectMessage) LogValue() slog.Value {
 	return slog.GroupValue(slog.String("ids", m.Identifier), slog.Bool("reconnect", m.Reconnect))
 }
 
+func (m *RemoteCommandMessage) ToRemoteTransmitMessage() (*RemoteTransmitMessage, error) {
+	tmsg := RemoteTransmitMessage{}
+
+	if err := json.Unmarshal(m.Payload, &tmsg); err != nil {
+		return nil, err
+	}
+
+	return &tmsg, nil
+}
+
+// RemoteTransmitMessage contains a message to be sent directly to all sessions
+// with the specified identifiers (w/o using streams)
+type RemoteTransmitMessage struct {
+	Identifier string `json:"identifier"`
+	// Data is a JSON-encoded message to be transmitted to clients as is
+	Data string `json:"data"`
+}
+
+// UnmarshalJSON accepts data both as a JSON-encoded string and as a raw JSON value
+func (m *RemoteTransmitMessage) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Identifier string          `json:"identifier"`
+		Data       json.RawMessage `json:"data"`
+	}
+
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	m.Identifier = raw.Identifier
+	m.Data = ""
+
+	data := bytes.TrimSpace(raw.Data)
+
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &m.Data)
+	}
+
+	if len(data) > 0 && !bytes.Equal(data, []byte("null")) {
+		m.Data = string(data)
+	}
+
+	return nil
+}
+
+func (m *RemoteTransmitMessage) LogValue() slog.Value {
+	if m == nil {
+		return slog.StringValue("nil")
+	}
+
+	return slog.GroupValue(slog.String("ids", m.Identifier), slog.Any("data", logger.CompactValue(m.Data)))
+}
+
+func (m *RemoteCommandMessage) ToRemoteSubscriptionMessage() (*RemoteSubscriptionMessage, error) {
+	smsg := RemoteSubscriptionMessage{}
+
+	if err := json.Unmarshal(m.Payload, &smsg); err != nil {
+		return nil, err
+	}
+
+	return &smsg, nil
+}
+
+// RemoteSubscriptionMessage contains information required to attach (or detach) a stream
+// to (from) subscriptions of the sessions with the specified identifiers
+type RemoteSubscriptionMessage struct {
+	Identifier string `json:"identifier"`
+	// Channel is a channel identifier to scope the command to (all channels if empty)
+	Channel string `json:"channel,omitempty"`
+	Stream  string `json:"stream,omitempty"`
+}
+
+func (m *RemoteSubscriptionMessage) LogValue() slog.Value {
+	if m == nil {
+		return slog.StringValue("nil")
+	}
+
+	return slog.GroupValue(slog.String("ids", m.Identifier), slog.String("channel", m.Channel), slog.String("stream", m.Stream))
+}
+
+func (m *RemoteCommandMessage) ToRemoteStateMessage() (*RemoteStateMessage, error) {
+	smsg := RemoteStateMessage{}
+
+	if err := json.Unmarshal(m.Payload, &smsg); err != nil {
+		return nil, err
+	}
+
+	return &smsg, nil
+}
+
+// RemoteStateMessage contains connection (cstate) and channel (istate) state updates
+// for the sessions with the specified identifiers
+type RemoteStateMessage struct {
+	Identifier string `json:"identifier"`
+	// Channel is a channel identifier to scope the channel state update to (all channels if empty)
+	Channel string            `json:"channel,omitempty"`
+	CState  map[string]string `json:"cstate,omitempty"`
+	IState  map[string]string `json:"istate,omitempty"`
+}
+
+func (m *RemoteStateMessage) LogValue() slog.Value {
+	if m == nil {
+		return slog.StringValue("nil")
+	}
+
+	return slog.GroupValue(
+		slog.String("ids", m.Identifier),
+		slog.String("channel", m.Channel),
+		slog.Any("cstate", m.CState),
+		slog.Any("istate", m.IState),
+	)
+}
+
 // PingMessage represents a server ping
 type PingMessage struct {
 	Type    string      `json:"type"`
@@ -503,6 +659,7 @@ type Reply struct {
 	Sid         string         `json:"sid,omitempty"`
 	Restored    bool           `json:"restored,omitempty"`
 	RestoredIDs []string       `json:"restored_ids,omitempty"`
+	History     *HistoryCursor `json:"history,omitempty"`
 }
 
 func (r *Reply) LogValue() slog.Value {
@@ -536,6 +693,10 @@ func (r *Reply) LogValue() slog.Value {
 		attrs = append(attrs, slog.String("sid", r.Sid), slog.Bool("restored", r.Restored), slog.Any("restored_ids", r.RestoredIDs))
 	}
 
+	if r.History != nil {
+		attrs = append(attrs, slog.Bool("history_truncated", r.History.Truncated))
+	}
+
 	return slog.GroupValue(attrs...)
 }
 
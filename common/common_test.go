@@ -74,6 +74,38 @@ func TestPubSubMessageFromJSON(t *testing.T) {
 		assert.Equal(t, false, dmsg.Reconnect)
 	})
 
+	t.Run("Remote transmit message", func(t *testing.T) {
+		msg := []byte("{\"command\":\"transmit\",\"payload\":{\"identifier\":\"14\",\"data\":\"{\\\"text\\\":\\\"hi\\\"}\"}}")
+
+		result, err := PubSubMessageFromJSON(msg)
+		assert.Nil(t, err)
+
+		casted := result.(RemoteCommandMessage)
+
+		assert.Equal(t, "transmit", casted.Command)
+
+		tmsg, err := casted.ToRemoteTransmitMessage()
+		assert.Nil(t, err)
+
+		assert.Equal(t, "14", tmsg.Identifier)
+		assert.Equal(t, "{\"text\":\"hi\"}", tmsg.Data)
+	})
+
+	t.Run("Remote transmit message with raw JSON data", func(t *testing.T) {
+		msg := []byte(`{"command":"transmit","payload":{"identifier":"14","data":{"text":"hi"}}}`)
+
+		result, err := PubSubMessageFromJSON(msg)
+		assert.Nil(t, err)
+
+		casted := result.(RemoteCommandMessage)
+
+		tmsg, err := casted.ToRemoteTransmitMessage()
+		assert.Nil(t, err)
+
+		assert.Equal(t, "14", tmsg.Identifier)
+		assert.Equal(t, `{"text":"hi"}`, tmsg.Data)
+	})
+
 	t.Run("Broadcast message", func(t *testing.T) {
 		msg := []byte("{\"stream\":\"bread-test\",\"data\":\"test\"}")
 
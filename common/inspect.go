@@ -0,0 +1,117 @@
+package common
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sort"
+)
+
+// Introspection query kinds
+const (
+	InspectSessionsQuery = "sessions"
+	InspectSessionQuery  = "session"
+	InspectStreamsQuery  = "streams"
+)
+
+// SessionInfo describes a connected session
+type SessionInfo struct {
+	ID          string `json:"sid"`
+	Node        string `json:"node"`
+	Identifiers string `json:"identifiers"`
+	// ConnectedAt is a Unix timestamp (in seconds) of the session creation
+	ConnectedAt int64 `json:"connected_at"`
+	// Channels maps channel identifiers to the subscribed streams
+	Channels map[string][]string `json:"channels"`
+	// Env is only included when requesting a particular session
+	Env *SessionEnvInfo `json:"env,omitempty"`
+}
+
+// SessionEnvInfo contains the session's request information and state
+type SessionEnvInfo struct {
+	URL     string                       `json:"url"`
+	Headers map[string]string            `json:"headers,omitempty"`
+	CState  map[string]string            `json:"cstate,omitempty"`
+	IState  map[string]map[string]string `json:"istate,omitempty"`
+}
+
+// StreamSubscribersInfo contains the number of subscribers for a stream
+type StreamSubscribersInfo struct {
+	Stream      string `json:"stream"`
+	Subscribers int    `json:"subscribers"`
+}
+
+// TopStreamsSubscribers returns up to limit streams with the largest number of subscribers
+func TopStreamsSubscribers(counts map[string]int, limit int) []*StreamSubscribersInfo {
+	res := make([]*StreamSubscribersInfo, 0, len(counts))
+
+	for stream, num := range counts {
+		res = append(res, &StreamSubscribersInfo{Stream: stream, Subscribers: num})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Subscribers == res[j].Subscribers {
+			return res[i].Stream < res[j].Stream
+		}
+
+		return res[i].Subscribers > res[j].Subscribers
+	})
+
+	if len(res) > limit {
+		res = res[:limit]
+	}
+
+	return res
+}
+
+// InspectQuery describes an introspection request
+type InspectQuery struct {
+	Kind       string `json:"kind"`
+	Identifier string `json:"identifier,omitempty"`
+	SID        string `json:"sid,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+}
+
+func (q *InspectQuery) LogValue() slog.Value {
+	if q == nil {
+		return slog.StringValue("nil")
+	}
+
+	return slog.GroupValue(
+		slog.String("kind", q.Kind),
+		slog.String("ids", q.Identifier),
+		slog.String("sid", q.SID),
+		slog.Int("limit", q.Limit),
+	)
+}
+
+// InspectResult contains a single node response to the introspection query
+type InspectResult struct {
+	Node     string                   `json:"node"`
+	Sessions []*SessionInfo           `json:"sessions,omitempty"`
+	Streams  []*StreamSubscribersInfo `json:"streams,omitempty"`
+}
+
+// RemoteInspectMessage is used to perform cluster-wide introspection via pub/sub:
+// a query is sent to all nodes, and each node responds with the result to the request-specific stream
+type RemoteInspectMessage struct {
+	ID    string        `json:"id"`
+	Query *InspectQuery `json:"query,omitempty"`
+}
+
+func (m *RemoteInspectMessage) LogValue() slog.Value {
+	if m == nil {
+		return slog.StringValue("nil")
+	}
+
+	return slog.GroupValue(slog.String("id", m.ID), slog.Any("query", m.Query))
+}
+
+func (m *RemoteCommandMessage) ToRemoteInspectMessage() (*RemoteInspectMessage, error) {
+	imsg := RemoteInspectMessage{}
+
+	if err := json.Unmarshal(m.Payload, &imsg); err != nil {
+		return nil, err
+	}
+
+	return &imsg, nil
+}
@@ -6,14 +6,18 @@ import (
 	"strings"
 
 	"github.com/BurntSushi/toml"
+	"github.com/anycable/anycable-go/admin"
 	"github.com/anycable/anycable-go/broadcast"
 	"github.com/anycable/anycable-go/broker"
 	"github.com/anycable/anycable-go/enats"
+	"github.com/anycable/anycable-go/graphql"
 	"github.com/anycable/anycable-go/identity"
 	"github.com/anycable/anycable-go/logger"
+	"github.com/anycable/anycable-go/lp"
 	"github.com/anycable/anycable-go/metrics"
 	nconfig "github.com/anycable/anycable-go/nats"
 	"github.com/anycable/anycable-go/node"
+	"github.com/anycable/anycable-go/ocpp"
 	"github.com/anycable/anycable-go/pubsub"
 	rconfig "github.com/anycable/anycable-go/redis"
 	"github.com/anycable/anycable-go/rpc"
@@ -57,7 +61,11 @@ type Config struct {
 	JWT                  identity.JWTConfig         `toml:"jwt"`
 	EmbeddedNats         enats.Config               `toml:"embedded_nats"`
 	SSE                  sse.Config                 `toml:"sse"`
+	LongPolling          lp.Config                  `toml:"long_polling"`
+	GraphQL              graphql.Config             `toml:"graphql"`
+	OCPP                 ocpp.Config                `toml:"ocpp"`
 	Streams              streams.Config             `toml:"streams"`
+	Admin                admin.Config               `toml:"admin"`
 
 	ConfigFilePath string
 }
@@ -89,6 +97,10 @@ func NewConfig() Config {
 		JWT:                  identity.NewJWTConfig(""),
 		EmbeddedNats:         enats.NewConfig(),
 		SSE:                  sse.NewConfig(),
+		LongPolling:          lp.NewConfig(),
+		GraphQL:              graphql.NewConfig(),
+		OCPP:                 ocpp.NewConfig(),
+		Admin:                admin.NewConfig(),
 		Streams:              streams.NewConfig(),
 	}
 
@@ -203,6 +215,15 @@ func (c Config) ToToml() string {
 	result.WriteString("# SSE configuration\n[sse]\n")
 	result.WriteString(c.SSE.ToToml())
 
+	result.WriteString("# Long polling configuration\n[long_polling]\n")
+	result.WriteString(c.LongPolling.ToToml())
+
+	result.WriteString("# GraphQL over WebSocket configuration\n[graphql]\n")
+	result.WriteString(c.GraphQL.ToToml())
+
+	result.WriteString("# OCPP configuration\n[ocpp]\n")
+	result.WriteString(c.OCPP.ToToml())
+
 	result.WriteString("# Redis configuration\n[redis]\n")
 	result.WriteString(c.Redis.ToToml())
 
@@ -235,5 +256,8 @@ func (c Config) ToToml() string {
 	result.WriteString("# Embedded NATS configuration\n[embedded_nats]\n")
 	result.WriteString(c.EmbeddedNats.ToToml())
 
+	result.WriteString("# Admin API configuration\n[admin]\n")
+	result.WriteString(c.Admin.ToToml())
+
 	return result.String()
 }
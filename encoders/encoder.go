@@ -9,6 +9,18 @@ type EncodedMessage interface {
 	GetType() string
 }
 
+// TransientMessage is implemented by messages which could be dropped
+// when a client can't keep up with the outgoing messages rate
+type TransientMessage interface {
+	IsTransient() bool
+}
+
+// ConflatableMessage is implemented by messages which could replace
+// the pending (not yet delivered) messages with the same conflation key
+type ConflatableMessage interface {
+	ConflationKey() string
+}
+
 var _ EncodedMessage = (*common.Reply)(nil)
 var _ EncodedMessage = (*common.PingMessage)(nil)
 var _ EncodedMessage = (*common.DisconnectMessage)(nil)
@@ -41,10 +41,16 @@ func (m *EncodingCache) Fetch(
 }
 
 type CachedEncodedMessage struct {
-	target EncodedMessage
-	cache  *EncodingCache
+	target    EncodedMessage
+	cache     *EncodingCache
+	transient bool
+	// conflationKey is used to replace pending messages with the same key
+	conflationKey string
 }
 
+var _ TransientMessage = (*CachedEncodedMessage)(nil)
+var _ ConflatableMessage = (*CachedEncodedMessage)(nil)
+
 func NewCachedEncodedMessage(msg EncodedMessage) *CachedEncodedMessage {
 	return &CachedEncodedMessage{target: msg, cache: NewEncodingCache()}
 }
@@ -53,6 +59,24 @@ func (msg *CachedEncodedMessage) GetType() string {
 	return msg.target.GetType()
 }
 
+// MarkTransient marks the message as non-critical (so it could be dropped for slow clients)
+func (msg *CachedEncodedMessage) MarkTransient() {
+	msg.transient = true
+}
+
+func (msg *CachedEncodedMessage) IsTransient() bool {
+	return msg.transient
+}
+
+// SetConflationKey makes the message replace the pending messages with the same key
+func (msg *CachedEncodedMessage) SetConflationKey(key string) {
+	msg.conflationKey = key
+}
+
+func (msg *CachedEncodedMessage) ConflationKey() string {
+	return msg.conflationKey
+}
+
 func (msg *CachedEncodedMessage) Fetch(id string, callback EncodingFunction) (*ws.SentFrame, error) {
 	return msg.cache.Fetch(msg.target, id, callback)
 }
@@ -0,0 +1,106 @@
+package encoders
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/ws"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const msgpackEncoderID = "msgpack"
+
+func init() {
+	// Raw JSON values (e.g., presence info) must be encoded as structured data, not as binary strings
+	msgpack.Register(json.RawMessage{}, func(enc *msgpack.Encoder, v reflect.Value) error {
+		raw := v.Bytes()
+
+		if len(raw) == 0 {
+			return enc.EncodeNil()
+		}
+
+		var val interface{}
+
+		if err := json.Unmarshal(raw, &val); err != nil {
+			return err
+		}
+
+		return enc.Encode(val)
+	}, nil)
+}
+
+// Msgpack encodes messages using MessagePack (with the same field names as JSON)
+type Msgpack struct {
+}
+
+func (Msgpack) ID() string {
+	return msgpackEncoderID
+}
+
+func (Msgpack) Encode(msg EncodedMessage) (*ws.SentFrame, error) {
+	b, err := marshalMsgpack(msg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ws.SentFrame{FrameType: ws.BinaryFrame, Payload: b}, nil
+}
+
+// EncodeTransmission re-encodes a JSON transmission (e.g., from RPC) using MessagePack
+func (Msgpack) EncodeTransmission(msg string) (*ws.SentFrame, error) {
+	var data interface{}
+
+	if err := json.Unmarshal([]byte(msg), &data); err != nil {
+		return nil, err
+	}
+
+	b, err := marshalMsgpack(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ws.SentFrame{FrameType: ws.BinaryFrame, Payload: b}, nil
+}
+
+func (Msgpack) Decode(raw []byte) (*common.Message, error) {
+	msg := &common.Message{}
+
+	if err := unmarshalMsgpack(raw, msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func marshalMsgpack(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := msgpack.GetEncoder()
+	defer msgpack.PutEncoder(enc)
+
+	enc.Reset(&buf)
+	enc.SetCustomStructTag("json")
+	// JSON numbers are decoded as floats, so we must encode integral values as integers
+	enc.UseCompactInts(true)
+	enc.UseCompactFloats(true)
+
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func unmarshalMsgpack(raw []byte, v interface{}) error {
+	dec := msgpack.GetDecoder()
+	defer msgpack.PutDecoder(dec)
+
+	dec.Reset(bytes.NewReader(raw))
+	dec.SetCustomStructTag("json")
+
+	return dec.Decode(v)
+}
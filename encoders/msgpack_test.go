@@ -0,0 +1,162 @@
+package encoders
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/ws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func decodeMsgpack(t *testing.T, payload []byte) map[string]interface{} {
+	var data map[string]interface{}
+
+	require.NoError(t, msgpack.Unmarshal(payload, &data))
+
+	return data
+}
+
+func TestMsgpackEncoder(t *testing.T) {
+	coder := Msgpack{}
+
+	t.Run(".Encode Reply", func(t *testing.T) {
+		msg := &common.Reply{Identifier: "test_channel", Message: map[string]interface{}{"text": "hello", "count": float64(2)}}
+
+		actual, err := coder.Encode(msg)
+
+		require.NoError(t, err)
+		assert.Equal(t, ws.BinaryFrame, actual.FrameType)
+
+		data := decodeMsgpack(t, actual.Payload)
+
+		assert.Equal(t, map[string]interface{}{
+			"identifier": "test_channel",
+			"message":    map[string]interface{}{"text": "hello", "count": int8(2)},
+		}, data)
+	})
+
+	t.Run(".Encode Reply with stream position and presence", func(t *testing.T) {
+		msg := &common.Reply{
+			Type:       "presence",
+			Identifier: "test_channel",
+			Presence:   &common.PresenceEvent{Type: "join", ID: "42", Info: json.RawMessage(`{"name":"jack"}`)},
+			StreamID:   "chat",
+			Epoch:      "y2023",
+			Offset:     300,
+		}
+
+		actual, err := coder.Encode(msg)
+
+		require.NoError(t, err)
+
+		data := decodeMsgpack(t, actual.Payload)
+
+		assert.Equal(t, "presence", data["type"])
+		assert.Equal(t, "chat", data["stream_id"])
+		assert.Equal(t, "y2023", data["epoch"])
+		assert.EqualValues(t, 300, data["offset"])
+		assert.Equal(t, map[string]interface{}{
+			"type": "join",
+			"id":   "42",
+			"info": map[string]interface{}{"name": "jack"},
+		}, data["presence"])
+	})
+
+	t.Run(".Encode PingMessage", func(t *testing.T) {
+		msg := &common.PingMessage{Type: "ping", Message: int64(1681290000)}
+
+		actual, err := coder.Encode(msg)
+
+		require.NoError(t, err)
+
+		data := decodeMsgpack(t, actual.Payload)
+
+		assert.Equal(t, "ping", data["type"])
+		assert.EqualValues(t, 1681290000, data["message"])
+	})
+
+	t.Run(".Encode DisconnectMessage", func(t *testing.T) {
+		msg := common.NewDisconnectMessage("unauthorized", false)
+
+		actual, err := coder.Encode(msg)
+
+		require.NoError(t, err)
+
+		data := decodeMsgpack(t, actual.Payload)
+
+		assert.Equal(t, map[string]interface{}{
+			"type":      "disconnect",
+			"reason":    "unauthorized",
+			"reconnect": false,
+		}, data)
+	})
+
+	t.Run(".EncodeTransmission", func(t *testing.T) {
+		msg := "{\"type\":\"test\",\"identifier\":\"test_channel\",\"message\":{\"text\":\"hello\",\"count\":2,\"ratio\":0.5}}"
+
+		actual, err := coder.EncodeTransmission(msg)
+
+		require.NoError(t, err)
+		assert.Equal(t, ws.BinaryFrame, actual.FrameType)
+
+		data := decodeMsgpack(t, actual.Payload)
+
+		assert.Equal(t, map[string]interface{}{
+			"type":       "test",
+			"identifier": "test_channel",
+			"message":    map[string]interface{}{"text": "hello", "count": int8(2), "ratio": 0.5},
+		}, data)
+	})
+
+	t.Run(".EncodeTransmission with invalid JSON", func(t *testing.T) {
+		_, err := coder.EncodeTransmission("not a json")
+
+		assert.Error(t, err)
+	})
+
+	t.Run(".Decode", func(t *testing.T) {
+		msg, err := msgpack.Marshal(map[string]interface{}{
+			"command":    "message",
+			"identifier": "test_channel",
+			"data":       "{\"action\":\"speak\"}",
+		})
+		require.NoError(t, err)
+
+		actual, err := coder.Decode(msg)
+
+		require.NoError(t, err)
+		assert.Equal(t, "message", actual.Command)
+		assert.Equal(t, "test_channel", actual.Identifier)
+		assert.Equal(t, "{\"action\":\"speak\"}", actual.Data)
+	})
+
+	t.Run(".Decode with history", func(t *testing.T) {
+		msg, err := msgpack.Marshal(map[string]interface{}{
+			"command":    "history",
+			"identifier": "test_channel",
+			"history": map[string]interface{}{
+				"since": 1681290000,
+				"streams": map[string]interface{}{
+					"chat": map[string]interface{}{"epoch": "y2023", "offset": 42},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		actual, err := coder.Decode(msg)
+
+		require.NoError(t, err)
+		assert.Equal(t, "history", actual.Command)
+		assert.Equal(t, int64(1681290000), actual.History.Since)
+		assert.Equal(t, common.HistoryPosition{Epoch: "y2023", Offset: 42}, actual.History.Streams["chat"])
+	})
+
+	t.Run(".Decode with invalid payload", func(t *testing.T) {
+		_, err := coder.Decode([]byte{0xc1})
+
+		assert.Error(t, err)
+	})
+}
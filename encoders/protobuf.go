@@ -0,0 +1,288 @@
+package encoders
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/anycable/anycable-go/common"
+	pb "github.com/anycable/anycable-go/protos/actioncable"
+	"github.com/anycable/anycable-go/ws"
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	protobufEncoderID   = "protobuf"
+	protobufV2EncoderID = "protobuf_v2"
+
+	// The "presence" command name conflicts with the "presence" message type in the schema
+	presenceCommand = "presence"
+)
+
+// Protobuf encodes messages using Protocol Buffers (see etc/action_cable.proto).
+// Outgoing messages are encoded as action_cable.Message (Action Cable protocol v1).
+// Free-form payloads (data, message, presence info, filter) are carried as JSON-encoded bytes.
+type Protobuf struct {
+}
+
+func (Protobuf) ID() string {
+	return protobufEncoderID
+}
+
+func (Protobuf) Encode(msg EncodedMessage) (*ws.SentFrame, error) {
+	reply, err := replyFromEncodedMessage(msg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeProtobufMessage(reply)
+}
+
+func (Protobuf) EncodeTransmission(msg string) (*ws.SentFrame, error) {
+	reply, err := replyFromTransmission(msg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeProtobufMessage(reply)
+}
+
+func (Protobuf) Decode(raw []byte) (*common.Message, error) {
+	return decodeProtobufMessage(raw)
+}
+
+// ProtobufV2 is similar to Protobuf but encodes outgoing messages as action_cable.Reply
+// (to carry the extended Action Cable protocol fields, such as stream positions and session IDs)
+type ProtobufV2 struct {
+}
+
+func (ProtobufV2) ID() string {
+	return protobufV2EncoderID
+}
+
+func (ProtobufV2) Encode(msg EncodedMessage) (*ws.SentFrame, error) {
+	reply, err := replyFromEncodedMessage(msg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeProtobufReply(reply)
+}
+
+func (ProtobufV2) EncodeTransmission(msg string) (*ws.SentFrame, error) {
+	reply, err := replyFromTransmission(msg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeProtobufReply(reply)
+}
+
+func (ProtobufV2) Decode(raw []byte) (*common.Message, error) {
+	return decodeProtobufMessage(raw)
+}
+
+func replyFromEncodedMessage(msg EncodedMessage) (*common.Reply, error) {
+	switch m := msg.(type) {
+	case *common.Reply:
+		return m, nil
+	case *common.PingMessage:
+		return &common.Reply{Type: m.Type, Message: m.Message}, nil
+	case *common.DisconnectMessage:
+		return &common.Reply{Type: m.Type, Reason: m.Reason, Reconnect: m.Reconnect}, nil
+	default:
+		return nil, fmt.Errorf("unsupported message: %T", msg)
+	}
+}
+
+// replyFromTransmission parses a JSON transmission keeping the message payload as is (so we don't re-encode it)
+func replyFromTransmission(msg string) (*common.Reply, error) {
+	reply := &common.Reply{}
+
+	buf := struct {
+		*common.Reply
+		Message json.RawMessage `json:"message,omitempty"`
+	}{Reply: reply}
+
+	if err := json.Unmarshal([]byte(msg), &buf); err != nil {
+		return nil, err
+	}
+
+	if len(buf.Message) > 0 {
+		reply.Message = buf.Message
+	}
+
+	return reply, nil
+}
+
+func encodeProtobufMessage(reply *common.Reply) (*ws.SentFrame, error) {
+	payload, err := jsonPayload(reply.Message)
+
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &pb.Message{
+		Type:       typeToProto(reply.Type),
+		Identifier: reply.Identifier,
+		Message:    payload,
+		Reason:     reply.Reason,
+		Reconnect:  reply.Reconnect,
+	}
+
+	b, err := proto.Marshal(buf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ws.SentFrame{FrameType: ws.BinaryFrame, Payload: b}, nil
+}
+
+func encodeProtobufReply(reply *common.Reply) (*ws.SentFrame, error) {
+	payload, err := jsonPayload(reply.Message)
+
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &pb.Reply{
+		Type:        typeToProto(reply.Type),
+		Identifier:  reply.Identifier,
+		Message:     payload,
+		Reason:      reply.Reason,
+		Reconnect:   reply.Reconnect,
+		StreamId:    reply.StreamID,
+		Epoch:       reply.Epoch,
+		Offset:      int64(reply.Offset),
+		Sid:         reply.Sid,
+		Restored:    reply.Restored,
+		RestoredIds: reply.RestoredIDs,
+	}
+
+	if reply.Presence != nil {
+		info, err := jsonPayload(reply.Presence.Info)
+
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Presence = &pb.PresenceEvent{Type: reply.Presence.Type, Id: reply.Presence.ID, Info: info}
+	}
+
+	if reply.History != nil {
+		buf.History = &pb.HistoryCursor{Truncated: reply.History.Truncated, Streams: streamPositionsToProto(reply.History.Streams)}
+	}
+
+	b, err := proto.Marshal(buf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ws.SentFrame{FrameType: ws.BinaryFrame, Payload: b}, nil
+}
+
+func decodeProtobufMessage(raw []byte) (*common.Message, error) {
+	buf := &pb.Message{}
+
+	if err := proto.Unmarshal(raw, buf); err != nil {
+		return nil, err
+	}
+
+	msg := &common.Message{
+		Command:    commandFromProto(buf.Command),
+		Identifier: buf.Identifier,
+	}
+
+	if len(buf.Data) > 0 {
+		msg.Data = string(buf.Data)
+
+		// Whispers carry structured data, so we must decode it to be consistent with JSON clients
+		if msg.Command == common.WhisperType {
+			var data interface{}
+
+			if err := json.Unmarshal(buf.Data, &data); err == nil {
+				msg.Data = data
+			}
+		}
+	}
+
+	if buf.History != nil {
+		msg.History = common.HistoryRequest{
+			Since:  buf.History.Since,
+			Limit:  int(buf.History.Limit),
+			Latest: buf.History.Latest,
+		}
+
+		if len(buf.History.Streams) > 0 {
+			msg.History.Streams = make(map[string]common.HistoryPosition, len(buf.History.Streams))
+
+			for name, pos := range buf.History.Streams {
+				msg.History.Streams[name] = common.HistoryPosition{Epoch: pos.Epoch, Offset: uint64(pos.Offset)}
+			}
+		}
+	}
+
+	if buf.Presence != nil {
+		msg.Presence = &common.PresenceEvent{Type: buf.Presence.Type, ID: buf.Presence.Id}
+
+		if len(buf.Presence.Info) > 0 {
+			if err := json.Unmarshal(buf.Presence.Info, &msg.Presence.Info); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(buf.Filter) > 0 {
+		if err := json.Unmarshal(buf.Filter, &msg.Filter); err != nil {
+			return nil, err
+		}
+	}
+
+	return msg, nil
+}
+
+func typeToProto(typ string) pb.Type {
+	if val, ok := pb.Type_value[typ]; ok {
+		return pb.Type(val)
+	}
+
+	return pb.Type_no_type
+}
+
+func commandFromProto(cmd pb.Command) string {
+	switch cmd {
+	case pb.Command_unknown_command:
+		return ""
+	case pb.Command_presence_info:
+		return presenceCommand
+	default:
+		return cmd.String()
+	}
+}
+
+func streamPositionsToProto(streams map[string]common.HistoryPosition) map[string]*pb.StreamHistoryRequest {
+	if len(streams) == 0 {
+		return nil
+	}
+
+	res := make(map[string]*pb.StreamHistoryRequest, len(streams))
+
+	for name, pos := range streams {
+		res[name] = &pb.StreamHistoryRequest{Epoch: pos.Epoch, Offset: int64(pos.Offset)}
+	}
+
+	return res
+}
+
+func jsonPayload(v interface{}) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(v)
+}
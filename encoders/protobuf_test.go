@@ -0,0 +1,271 @@
+package encoders
+
+import (
+	"testing"
+
+	"github.com/anycable/anycable-go/common"
+	pb "github.com/anycable/anycable-go/protos/actioncable"
+	"github.com/anycable/anycable-go/ws"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtobufEncoder(t *testing.T) {
+	coder := Protobuf{}
+
+	t.Run(".Encode Reply", func(t *testing.T) {
+		msg := &common.Reply{Type: "confirm_subscription", Identifier: "test_channel", Message: map[string]interface{}{"text": "hello"}}
+
+		actual, err := coder.Encode(msg)
+
+		require.NoError(t, err)
+		assert.Equal(t, ws.BinaryFrame, actual.FrameType)
+
+		buf := &pb.Message{}
+		require.NoError(t, proto.Unmarshal(actual.Payload, buf))
+
+		assert.Equal(t, pb.Type_confirm_subscription, buf.Type)
+		assert.Equal(t, "test_channel", buf.Identifier)
+		assert.JSONEq(t, `{"text":"hello"}`, string(buf.Message))
+	})
+
+	t.Run(".Encode PingMessage", func(t *testing.T) {
+		msg := &common.PingMessage{Type: "ping", Message: int64(1681290000)}
+
+		actual, err := coder.Encode(msg)
+
+		require.NoError(t, err)
+
+		buf := &pb.Message{}
+		require.NoError(t, proto.Unmarshal(actual.Payload, buf))
+
+		assert.Equal(t, pb.Type_ping, buf.Type)
+		assert.Equal(t, "1681290000", string(buf.Message))
+	})
+
+	t.Run(".Encode DisconnectMessage", func(t *testing.T) {
+		msg := common.NewDisconnectMessage("unauthorized", true)
+
+		actual, err := coder.Encode(msg)
+
+		require.NoError(t, err)
+
+		buf := &pb.Message{}
+		require.NoError(t, proto.Unmarshal(actual.Payload, buf))
+
+		assert.Equal(t, pb.Type_disconnect, buf.Type)
+		assert.Equal(t, "unauthorized", buf.Reason)
+		assert.True(t, buf.Reconnect)
+		assert.Empty(t, buf.Message)
+	})
+
+	t.Run(".EncodeTransmission", func(t *testing.T) {
+		msg := "{\"type\":\"welcome\",\"message\":{\"count\":2}}"
+
+		actual, err := coder.EncodeTransmission(msg)
+
+		require.NoError(t, err)
+		assert.Equal(t, ws.BinaryFrame, actual.FrameType)
+
+		buf := &pb.Message{}
+		require.NoError(t, proto.Unmarshal(actual.Payload, buf))
+
+		assert.Equal(t, pb.Type_welcome, buf.Type)
+		assert.Equal(t, `{"count":2}`, string(buf.Message))
+	})
+
+	t.Run(".EncodeTransmission with invalid JSON", func(t *testing.T) {
+		_, err := coder.EncodeTransmission("not a json")
+
+		assert.Error(t, err)
+	})
+
+	t.Run(".Decode", func(t *testing.T) {
+		raw, err := proto.Marshal(&pb.Message{
+			Command:    pb.Command_message,
+			Identifier: "test_channel",
+			Data:       []byte("{\"action\":\"speak\"}"),
+		})
+		require.NoError(t, err)
+
+		actual, err := coder.Decode(raw)
+
+		require.NoError(t, err)
+		assert.Equal(t, "message", actual.Command)
+		assert.Equal(t, "test_channel", actual.Identifier)
+		assert.Equal(t, "{\"action\":\"speak\"}", actual.Data)
+	})
+
+	t.Run(".Decode with history", func(t *testing.T) {
+		raw, err := proto.Marshal(&pb.Message{
+			Command:    pb.Command_history,
+			Identifier: "test_channel",
+			History: &pb.HistoryRequest{
+				Since: 1681290000,
+				Limit: 10,
+				Streams: map[string]*pb.StreamHistoryRequest{
+					"chat": {Epoch: "y2023", Offset: 42},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		actual, err := coder.Decode(raw)
+
+		require.NoError(t, err)
+		assert.Equal(t, "history", actual.Command)
+		assert.Equal(t, int64(1681290000), actual.History.Since)
+		assert.Equal(t, 10, actual.History.Limit)
+		assert.Equal(t, common.HistoryPosition{Epoch: "y2023", Offset: 42}, actual.History.Streams["chat"])
+	})
+
+	t.Run(".Decode with presence", func(t *testing.T) {
+		raw, err := proto.Marshal(&pb.Message{
+			Command:    pb.Command_join,
+			Identifier: "test_channel",
+			Presence:   &pb.PresenceEvent{Id: "42", Info: []byte(`{"name":"jack"}`)},
+		})
+		require.NoError(t, err)
+
+		actual, err := coder.Decode(raw)
+
+		require.NoError(t, err)
+		assert.Equal(t, "join", actual.Command)
+		assert.Equal(t, "42", actual.Presence.ID)
+		assert.Equal(t, map[string]interface{}{"name": "jack"}, actual.Presence.Info)
+	})
+
+	t.Run(".Decode presence info command", func(t *testing.T) {
+		raw, err := proto.Marshal(&pb.Message{Command: pb.Command_presence_info, Identifier: "test_channel"})
+		require.NoError(t, err)
+
+		actual, err := coder.Decode(raw)
+
+		require.NoError(t, err)
+		assert.Equal(t, "presence", actual.Command)
+	})
+
+	t.Run(".Decode whisper", func(t *testing.T) {
+		raw, err := proto.Marshal(&pb.Message{Command: pb.Command_whisper, Identifier: "test_channel", Data: []byte("{\"typing\":true}")})
+		require.NoError(t, err)
+
+		actual, err := coder.Decode(raw)
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"typing": true}, actual.Data)
+	})
+
+	t.Run(".Decode with filter", func(t *testing.T) {
+		raw, err := proto.Marshal(&pb.Message{
+			Command:    pb.Command_subscribe,
+			Identifier: "test_channel",
+			Filter:     []byte(`{"exclude":["typing"]}`),
+		})
+		require.NoError(t, err)
+
+		actual, err := coder.Decode(raw)
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"exclude": []interface{}{"typing"}}, actual.Filter)
+	})
+
+	t.Run(".Decode with invalid payload", func(t *testing.T) {
+		_, err := coder.Decode([]byte{0xff, 0xff})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestProtobufV2Encoder(t *testing.T) {
+	coder := ProtobufV2{}
+
+	t.Run(".Encode Reply", func(t *testing.T) {
+		msg := &common.Reply{
+			Identifier: "test_channel",
+			Message:    "hello",
+			StreamID:   "chat",
+			Epoch:      "y2023",
+			Offset:     300,
+		}
+
+		actual, err := coder.Encode(msg)
+
+		require.NoError(t, err)
+		assert.Equal(t, ws.BinaryFrame, actual.FrameType)
+
+		buf := &pb.Reply{}
+		require.NoError(t, proto.Unmarshal(actual.Payload, buf))
+
+		assert.Equal(t, pb.Type_no_type, buf.Type)
+		assert.Equal(t, "test_channel", buf.Identifier)
+		assert.Equal(t, `"hello"`, string(buf.Message))
+		assert.Equal(t, "chat", buf.StreamId)
+		assert.Equal(t, "y2023", buf.Epoch)
+		assert.Equal(t, int64(300), buf.Offset)
+	})
+
+	t.Run(".Encode welcome with session ID", func(t *testing.T) {
+		msg := &common.Reply{Type: "welcome", Sid: "s42", Restored: true, RestoredIDs: []string{"a", "b"}}
+
+		actual, err := coder.Encode(msg)
+
+		require.NoError(t, err)
+
+		buf := &pb.Reply{}
+		require.NoError(t, proto.Unmarshal(actual.Payload, buf))
+
+		assert.Equal(t, pb.Type_welcome, buf.Type)
+		assert.Equal(t, "s42", buf.Sid)
+		assert.True(t, buf.Restored)
+		assert.Equal(t, []string{"a", "b"}, buf.RestoredIds)
+	})
+
+	t.Run(".Encode history confirmation with cursor", func(t *testing.T) {
+		msg := &common.Reply{
+			Type:       "confirm_history",
+			Identifier: "test_channel",
+			History: &common.HistoryCursor{
+				Truncated: true,
+				Streams:   map[string]common.HistoryPosition{"chat": {Epoch: "y2023", Offset: 10}},
+			},
+		}
+
+		actual, err := coder.Encode(msg)
+
+		require.NoError(t, err)
+
+		buf := &pb.Reply{}
+		require.NoError(t, proto.Unmarshal(actual.Payload, buf))
+
+		assert.Equal(t, pb.Type_confirm_history, buf.Type)
+		assert.True(t, buf.History.Truncated)
+		assert.Equal(t, "y2023", buf.History.Streams["chat"].Epoch)
+		assert.Equal(t, int64(10), buf.History.Streams["chat"].Offset)
+	})
+
+	t.Run(".Encode DisconnectMessage", func(t *testing.T) {
+		actual, err := coder.Encode(common.NewDisconnectMessage("server_restart", true))
+
+		require.NoError(t, err)
+
+		buf := &pb.Reply{}
+		require.NoError(t, proto.Unmarshal(actual.Payload, buf))
+
+		assert.Equal(t, pb.Type_disconnect, buf.Type)
+		assert.Equal(t, "server_restart", buf.Reason)
+		assert.True(t, buf.Reconnect)
+	})
+
+	t.Run(".EncodeTransmission", func(t *testing.T) {
+		actual, err := coder.EncodeTransmission("{\"type\":\"welcome\",\"sid\":\"s42\"}")
+
+		require.NoError(t, err)
+
+		buf := &pb.Reply{}
+		require.NoError(t, proto.Unmarshal(actual.Payload, buf))
+
+		assert.Equal(t, pb.Type_welcome, buf.Type)
+		assert.Equal(t, "s42", buf.Sid)
+	})
+}
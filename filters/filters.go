@@ -0,0 +1,389 @@
+// Package filters implements server-side subscription filters.
+//
+// A filter is a JSON object mapping JSON paths to conditions, for example:
+//
+//	{"user.role": "admin", "priority": {"gte": 3}, "kind": {"in": ["comment", "reply"]}}
+//
+// All the conditions must match (logical AND). Paths are dot-separated and evaluated
+// against the broadcast data; paths starting with "$meta." are evaluated against the message metadata.
+// A condition could be a scalar (equality check) or an object with operators:
+// "eq", "ne", "in", "nin", "gt", "gte", "lt", "lte".
+package filters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anycable/anycable-go/common"
+)
+
+const (
+	// MetaPrefix is used to reference stream message metadata fields in paths
+	MetaPrefix = "$meta."
+)
+
+type operator int
+
+const (
+	opEq operator = iota
+	opNe
+	opIn
+	opNin
+	opGt
+	opGte
+	opLt
+	opLte
+)
+
+var operators = map[string]operator{
+	"eq":  opEq,
+	"ne":  opNe,
+	"in":  opIn,
+	"nin": opNin,
+	"gt":  opGt,
+	"gte": opGte,
+	"lt":  opLt,
+	"lte": opLte,
+}
+
+type condition struct {
+	path   []string
+	meta   bool
+	op     operator
+	value  interface{}
+	values []interface{}
+	number float64
+}
+
+// Filter is a compiled filter expression.
+// Filters are immutable and safe for concurrent use.
+type Filter struct {
+	conditions []*condition
+}
+
+// Compile parses and validates a filter expression
+func Compile(raw string) (*Filter, error) {
+	var spec map[string]interface{}
+
+	if err := json.Unmarshal([]byte(raw), &spec); err != nil {
+		return nil, fmt.Errorf("filter must be a JSON object: %w", err)
+	}
+
+	return FromMap(spec)
+}
+
+// FromMap builds a filter from the decoded JSON object
+func FromMap(spec map[string]interface{}) (*Filter, error) {
+	f := &Filter{conditions: []*condition{}}
+
+	// Sort paths to make evaluation order deterministic
+	paths := make([]string, 0, len(spec))
+
+	for path := range spec {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		conds, err := compileConditions(path, spec[path])
+
+		if err != nil {
+			return nil, err
+		}
+
+		f.conditions = append(f.conditions, conds...)
+	}
+
+	return f, nil
+}
+
+// And combines filters into a single one matching only when all the filters match.
+// Nil filters are ignored; returns nil if there is nothing to combine.
+func And(list ...*Filter) *Filter {
+	present := make([]*Filter, 0, len(list))
+
+	for _, f := range list {
+		if f != nil {
+			present = append(present, f)
+		}
+	}
+
+	switch len(present) {
+	case 0:
+		return nil
+	case 1:
+		return present[0]
+	}
+
+	res := &Filter{conditions: []*condition{}}
+
+	for _, f := range present {
+		res.conditions = append(res.conditions, f.conditions...)
+	}
+
+	return res
+}
+
+// Match returns true if the message satisfies all the conditions.
+// A nil filter matches everything.
+func (f *Filter) Match(msg *Message) bool {
+	if f == nil {
+		return true
+	}
+
+	for _, cond := range f.conditions {
+		if !cond.match(msg) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func compileConditions(path string, spec interface{}) ([]*condition, error) {
+	if path == "" {
+		return nil, errors.New("filter path must not be empty")
+	}
+
+	meta := false
+
+	if strings.HasPrefix(path, MetaPrefix) {
+		meta = true
+		path = strings.TrimPrefix(path, MetaPrefix)
+	}
+
+	tokens := strings.Split(path, ".")
+
+	for _, token := range tokens {
+		if token == "" {
+			return nil, fmt.Errorf("invalid filter path: %s", path)
+		}
+	}
+
+	ops, ok := spec.(map[string]interface{})
+
+	// Scalar values (and arrays) are compared for equality
+	if !ok {
+		cond, err := compileCondition(tokens, meta, "eq", spec)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return []*condition{cond}, nil
+	}
+
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("no operators specified for path: %s", path)
+	}
+
+	names := make([]string, 0, len(ops))
+
+	for name := range ops {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	conds := make([]*condition, 0, len(ops))
+
+	for _, name := range names {
+		cond, err := compileCondition(tokens, meta, name, ops[name])
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition for path %s: %w", path, err)
+		}
+
+		conds = append(conds, cond)
+	}
+
+	return conds, nil
+}
+
+func compileCondition(path []string, meta bool, name string, value interface{}) (*condition, error) {
+	op, ok := operators[name]
+
+	if !ok {
+		return nil, fmt.Errorf("unknown operator: %s", name)
+	}
+
+	cond := &condition{path: path, meta: meta, op: op, value: value}
+
+	switch op {
+	case opIn, opNin:
+		values, ok := value.([]interface{})
+
+		if !ok {
+			return nil, fmt.Errorf("%s operator expects an array", name)
+		}
+
+		cond.values = values
+	case opGt, opGte, opLt, opLte:
+		num, ok := value.(float64)
+
+		if !ok {
+			return nil, fmt.Errorf("%s operator expects a number", name)
+		}
+
+		cond.number = num
+	}
+
+	return cond, nil
+}
+
+func (c *condition) match(msg *Message) bool {
+	val, found := msg.lookup(c.meta, c.path)
+
+	switch c.op {
+	case opEq:
+		return found && equal(val, c.value)
+	case opNe:
+		return !found || !equal(val, c.value)
+	case opIn:
+		return found && contains(c.values, val)
+	case opNin:
+		return !found || !contains(c.values, val)
+	}
+
+	num, ok := val.(float64)
+
+	if !found || !ok {
+		return false
+	}
+
+	switch c.op {
+	case opGt:
+		return num > c.number
+	case opGte:
+		return num >= c.number
+	case opLt:
+		return num < c.number
+	case opLte:
+		return num <= c.number
+	}
+
+	return false
+}
+
+func contains(values []interface{}, val interface{}) bool {
+	for _, v := range values {
+		if equal(v, val) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func equal(a interface{}, b interface{}) bool {
+	switch av := a.(type) {
+	case string, float64, bool, nil:
+		return a == b
+	case []interface{}:
+		bv, ok := b.([]interface{})
+
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+
+		return true
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+
+		for k, v := range av {
+			if !equal(v, bv[k]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// Message wraps a stream message to be matched against filters.
+// Data and metadata are decoded lazily and only once, so the same wrapper
+// should be used to match the message against multiple filters.
+type Message struct {
+	msg *common.StreamMessage
+
+	data       interface{}
+	dataParsed bool
+
+	meta       interface{}
+	metaParsed bool
+}
+
+// NewMessage creates a new message wrapper
+func NewMessage(msg *common.StreamMessage) *Message {
+	return &Message{msg: msg}
+}
+
+func (m *Message) lookup(meta bool, path []string) (interface{}, bool) {
+	var root interface{}
+
+	if meta {
+		root = m.metaValue()
+	} else {
+		root = m.dataValue()
+	}
+
+	current := root
+
+	for _, key := range path {
+		obj, ok := current.(map[string]interface{})
+
+		if !ok {
+			return nil, false
+		}
+
+		current, ok = obj[key]
+
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+func (m *Message) dataValue() interface{} {
+	if !m.dataParsed {
+		m.dataParsed = true
+
+		// Non-JSON data doesn't match any path
+		_ = json.Unmarshal([]byte(m.msg.Data), &m.data)
+	}
+
+	return m.data
+}
+
+func (m *Message) metaValue() interface{} {
+	if !m.metaParsed {
+		m.metaParsed = true
+
+		if m.msg.Meta != nil {
+			// Use JSON representation to reference fields by their public names
+			if raw, err := json.Marshal(m.msg.Meta); err == nil {
+				_ = json.Unmarshal(raw, &m.meta)
+			}
+		}
+	}
+
+	return m.meta
+}
@@ -0,0 +1,117 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		f, err := Compile(`{"user.role":"admin","priority":{"gte":1,"lt":5},"kind":{"in":["a","b"]},"$meta.broadcast_type":"update"}`)
+
+		require.NoError(t, err)
+		assert.Len(t, f.conditions, 5)
+	})
+
+	invalid := map[string]string{
+		"not an object":      `["a"]`,
+		"malformed":          `{"a":`,
+		"unknown operator":   `{"a":{"like":"b"}}`,
+		"in with scalar":     `{"a":{"in":"b"}}`,
+		"gt with string":     `{"a":{"gt":"1"}}`,
+		"empty path":         `{"":1}`,
+		"empty path segment": `{"a..b":1}`,
+		"no operators":       `{"a":{}}`,
+	}
+
+	for name, raw := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := Compile(raw)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	msg := &common.StreamMessage{
+		Stream: "chat",
+		Data:   `{"user":{"id":42,"role":"admin"},"priority":3,"kind":"comment","tags":["a","b"]}`,
+		Meta:   &common.StreamMessageMetadata{BroadcastType: "update"},
+	}
+
+	cases := map[string]struct {
+		filter   string
+		expected bool
+	}{
+		"equality":            {`{"kind":"comment"}`, true},
+		"equality miss":       {`{"kind":"reply"}`, false},
+		"nested":              {`{"user.role":"admin","user.id":42}`, true},
+		"nested miss":         {`{"user.id":43}`, false},
+		"missing path":        {`{"user.name":"jack"}`, false},
+		"path through scalar": {`{"kind.x":"comment"}`, false},
+		"ne":                  {`{"kind":{"ne":"reply"}}`, true},
+		"ne missing":          {`{"unknown":{"ne":"reply"}}`, true},
+		"in":                  {`{"kind":{"in":["comment","reply"]}}`, true},
+		"in miss":             {`{"kind":{"in":["reply"]}}`, false},
+		"nin":                 {`{"kind":{"nin":["reply"]}}`, true},
+		"nin miss":            {`{"kind":{"nin":["comment"]}}`, false},
+		"range":               {`{"priority":{"gte":3,"lt":5}}`, true},
+		"range miss":          {`{"priority":{"gt":3}}`, false},
+		"lte":                 {`{"priority":{"lte":3}}`, true},
+		"compare non-number":  {`{"kind":{"gt":1}}`, false},
+		"array equality":      {`{"tags":["a","b"]}`, true},
+		"meta":                {`{"$meta.broadcast_type":"update"}`, true},
+		"meta miss":           {`{"$meta.broadcast_type":"create"}`, false},
+		"type mismatch":       {`{"priority":"3"}`, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f, err := Compile(tc.filter)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, f.Match(NewMessage(msg)))
+		})
+	}
+
+	t.Run("non-JSON data", func(t *testing.T) {
+		f, err := Compile(`{"kind":{"ne":"comment"}}`)
+		require.NoError(t, err)
+
+		assert.True(t, f.Match(NewMessage(&common.StreamMessage{Data: "plain text"})))
+
+		f, err = Compile(`{"kind":"comment"}`)
+		require.NoError(t, err)
+
+		assert.False(t, f.Match(NewMessage(&common.StreamMessage{Data: "plain text"})))
+	})
+
+	t.Run("nil filter", func(t *testing.T) {
+		var f *Filter
+
+		assert.True(t, f.Match(NewMessage(msg)))
+	})
+}
+
+func TestAnd(t *testing.T) {
+	assert.Nil(t, And(nil, nil))
+
+	a, err := Compile(`{"kind":"comment"}`)
+	require.NoError(t, err)
+
+	b, err := Compile(`{"priority":{"gt":5}}`)
+	require.NoError(t, err)
+
+	assert.Same(t, a, And(nil, a))
+
+	msg := NewMessage(&common.StreamMessage{Data: `{"kind":"comment","priority":3}`})
+
+	assert.True(t, a.Match(msg))
+	assert.False(t, And(a, b).Match(msg))
+	// And must not modify the original filters
+	assert.True(t, a.Match(msg))
+}
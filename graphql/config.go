@@ -0,0 +1,59 @@
+package graphql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GraphQL over WebSocket configuration
+type Config struct {
+	// Path is the URL path to handle GraphQL WebSocket connections (empty means disabled)
+	Path string `toml:"path"`
+	// Channel is the GraphQL Ruby channel class name
+	Channel string `toml:"channel"`
+	// Action is the GraphQL Ruby channel action to execute operations
+	Action string `toml:"action"`
+	// For how long to wait for the connection_init message (seconds)
+	InitTimeout int `toml:"init_timeout"`
+	// JWT identification parameter name (inherited from the JWT configuration)
+	JWTParam string `toml:"-"`
+}
+
+// NewConfig creates a new Config with default values.
+func NewConfig() Config {
+	return Config{
+		Channel:     "GraphqlChannel",
+		Action:      "execute",
+		InitTimeout: 3,
+	}
+}
+
+// Enabled returns true if the GraphQL endpoint path is configured
+func (c Config) Enabled() bool {
+	return c.Path != ""
+}
+
+// ToToml converts the Config struct to a TOML string representation
+func (c Config) ToToml() string {
+	var result strings.Builder
+
+	result.WriteString("# GraphQL WebSocket endpoint path (graphql-transport-ws protocol)\n")
+	if c.Path != "" {
+		result.WriteString(fmt.Sprintf("path = \"%s\"\n", c.Path))
+	} else {
+		result.WriteString("# path = \"/graphql\"\n")
+	}
+
+	result.WriteString("# GraphQL Ruby channel class name\n")
+	result.WriteString(fmt.Sprintf("channel = \"%s\"\n", c.Channel))
+
+	result.WriteString("# GraphQL Ruby channel action name\n")
+	result.WriteString(fmt.Sprintf("action = \"%s\"\n", c.Action))
+
+	result.WriteString("# For how long to wait for the connection_init message (seconds)\n")
+	result.WriteString(fmt.Sprintf("init_timeout = %d\n", c.InitTimeout))
+
+	result.WriteString("\n")
+
+	return result.String()
+}
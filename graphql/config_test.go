@@ -0,0 +1,39 @@
+package graphql
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_ToToml(t *testing.T) {
+	conf := NewConfig()
+	conf.Path = "/graphql"
+	conf.InitTimeout = 5
+
+	tomlStr := conf.ToToml()
+
+	assert.Contains(t, tomlStr, "path = \"/graphql\"")
+	assert.Contains(t, tomlStr, "channel = \"GraphqlChannel\"")
+	assert.Contains(t, tomlStr, "action = \"execute\"")
+	assert.Contains(t, tomlStr, "init_timeout = 5")
+
+	// Round-trip test
+	conf2 := Config{}
+
+	_, err := toml.Decode(tomlStr, &conf2)
+	require.NoError(t, err)
+
+	assert.Equal(t, conf, conf2)
+}
+
+func TestConfig_ToToml_Disabled(t *testing.T) {
+	conf := NewConfig()
+
+	tomlStr := conf.ToToml()
+
+	assert.Contains(t, tomlStr, "# path = \"/graphql\"")
+	assert.False(t, conf.Enabled())
+}
@@ -0,0 +1,126 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/encoders"
+	"github.com/anycable/anycable-go/ws"
+)
+
+const graphqlEncoderID = "graphql"
+
+// Encoder converts Action Cable messages into graphql-transport-ws messages (and vice versa)
+type Encoder struct {
+}
+
+func (Encoder) ID() string {
+	return graphqlEncoderID
+}
+
+func (e Encoder) Encode(msg encoders.EncodedMessage) (*ws.SentFrame, error) {
+	switch m := msg.(type) {
+	case *Message:
+		return encodeMessage(m)
+	case *common.PingMessage:
+		return encodeMessage(&Message{Type: pingType})
+	case *common.DisconnectMessage:
+		return encodeDisconnect(m.Reason), nil
+	case *common.Reply:
+		return encodeReply(m)
+	default:
+		return nil, errors.New("unsupported message type: " + msg.GetType())
+	}
+}
+
+func (e Encoder) EncodeTransmission(raw string) (*ws.SentFrame, error) {
+	msg := common.Reply{}
+
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		return nil, err
+	}
+
+	return e.Encode(&msg)
+}
+
+func (Encoder) Decode(raw []byte) (*common.Message, error) {
+	msg := Message{}
+
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return nil, err
+	}
+
+	return &common.Message{Command: msg.Type, Identifier: msg.ID, Data: msg.Payload}, nil
+}
+
+func encodeReply(reply *common.Reply) (*ws.SentFrame, error) {
+	switch reply.Type {
+	case common.WelcomeType:
+		return encodeMessage(&Message{Type: connectionAckType})
+	case common.PingType:
+		return encodeMessage(&Message{Type: pingType})
+	case common.DisconnectType:
+		return encodeDisconnect(reply.Reason), nil
+	case common.RejectedType:
+		return encodeOperationError(reply.Identifier, "Subscription rejected")
+	case common.ErrorType:
+		reason := reply.Reason
+
+		if reason == "" {
+			reason = "Operation failed"
+		}
+
+		return encodeOperationError(reply.Identifier, reason)
+	case "":
+		id := operationID(reply.Identifier)
+
+		if id == "" {
+			return nil, nil
+		}
+
+		if payload, ok := nextPayload(reply.Message); ok {
+			return encodeMessage(&Message{Type: nextType, ID: id, Payload: payload})
+		}
+
+		if isFinal(reply.Message) {
+			return encodeMessage(&Message{Type: completeType, ID: id})
+		}
+
+		return nil, nil
+	default:
+		// Subscription confirmations and other Action Cable specific messages
+		// have no counterparts in the protocol
+		return nil, nil
+	}
+}
+
+func encodeOperationError(identifier string, reason string) (*ws.SentFrame, error) {
+	id := operationID(identifier)
+
+	if id == "" {
+		return nil, nil
+	}
+
+	return encodeMessage(newErrorMessage(id, reason))
+}
+
+// Only the connection initialisation timeout has a dedicated close code;
+// other disconnects are performed by the executor or the session itself
+func encodeDisconnect(reason string) *ws.SentFrame {
+	if reason == common.IDLE_TIMEOUT_REASON {
+		return &ws.SentFrame{FrameType: ws.CloseFrame, CloseCode: initTimeoutCode, CloseReason: "Connection initialisation timeout"}
+	}
+
+	return nil
+}
+
+func encodeMessage(msg *Message) (*ws.SentFrame, error) {
+	b, err := json.Marshal(msg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ws.SentFrame{FrameType: ws.TextFrame, Payload: b}, nil
+}
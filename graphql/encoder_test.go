@@ -0,0 +1,127 @@
+package graphql
+
+import (
+	"testing"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/ws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncoder(t *testing.T) {
+	coder := Encoder{}
+	identifier := buildIdentifier("GraphqlChannel", "op-1")
+
+	t.Run(".Encode welcome", func(t *testing.T) {
+		actual, err := coder.Encode(&common.Reply{Type: "welcome", Sid: "s42"})
+
+		require.NoError(t, err)
+		assert.Equal(t, ws.TextFrame, actual.FrameType)
+		assert.Equal(t, `{"type":"connection_ack"}`, string(actual.Payload))
+	})
+
+	t.Run(".Encode PingMessage", func(t *testing.T) {
+		actual, err := coder.Encode(&common.PingMessage{Type: "ping", Message: 1681290000})
+
+		require.NoError(t, err)
+		assert.Equal(t, `{"type":"ping"}`, string(actual.Payload))
+	})
+
+	t.Run(".Encode Message", func(t *testing.T) {
+		actual, err := coder.Encode(&Message{Type: "pong"})
+
+		require.NoError(t, err)
+		assert.Equal(t, `{"type":"pong"}`, string(actual.Payload))
+	})
+
+	t.Run(".Encode idle timeout disconnect", func(t *testing.T) {
+		actual, err := coder.Encode(common.NewDisconnectMessage(common.IDLE_TIMEOUT_REASON, false))
+
+		require.NoError(t, err)
+		assert.Equal(t, ws.CloseFrame, actual.FrameType)
+		assert.Equal(t, 4408, actual.CloseCode)
+	})
+
+	t.Run(".Encode other disconnect", func(t *testing.T) {
+		actual, err := coder.Encode(common.NewDisconnectMessage(common.UNAUTHORIZED_REASON, false))
+
+		require.NoError(t, err)
+		assert.Nil(t, actual)
+	})
+
+	t.Run(".Encode subscription confirmation", func(t *testing.T) {
+		actual, err := coder.Encode(&common.Reply{Type: "confirm_subscription", Identifier: identifier})
+
+		require.NoError(t, err)
+		assert.Nil(t, actual)
+	})
+
+	t.Run(".Encode subscription rejection", func(t *testing.T) {
+		actual, err := coder.Encode(&common.Reply{Type: "reject_subscription", Identifier: identifier})
+
+		require.NoError(t, err)
+		assert.Equal(t, `{"type":"error","id":"op-1","payload":[{"message":"Subscription rejected"}]}`, string(actual.Payload))
+	})
+
+	t.Run(".Encode result", func(t *testing.T) {
+		msg := &common.Reply{
+			Identifier: identifier,
+			Message: map[string]interface{}{
+				"result": map[string]interface{}{"data": map[string]interface{}{"post": "hello"}},
+				"more":   true,
+			},
+		}
+
+		actual, err := coder.Encode(msg)
+
+		require.NoError(t, err)
+		assert.Equal(t, `{"type":"next","id":"op-1","payload":{"data":{"post":"hello"}}}`, string(actual.Payload))
+	})
+
+	t.Run(".Encode empty subscription result", func(t *testing.T) {
+		msg := &common.Reply{
+			Identifier: identifier,
+			Message:    map[string]interface{}{"result": map[string]interface{}{"data": nil}, "more": true},
+		}
+
+		actual, err := coder.Encode(msg)
+
+		require.NoError(t, err)
+		assert.Nil(t, actual)
+	})
+
+	t.Run(".Encode final empty result", func(t *testing.T) {
+		msg := &common.Reply{
+			Identifier: identifier,
+			Message:    map[string]interface{}{"more": false},
+		}
+
+		actual, err := coder.Encode(msg)
+
+		require.NoError(t, err)
+		assert.Equal(t, `{"type":"complete","id":"op-1"}`, string(actual.Payload))
+	})
+
+	t.Run(".EncodeTransmission", func(t *testing.T) {
+		actual, err := coder.EncodeTransmission(`{"identifier":"{\"channel\":\"GraphqlChannel\",\"channelId\":\"op-1\"}","message":{"result":{"errors":[{"message":"boom"}]},"more":false}}`)
+
+		require.NoError(t, err)
+		assert.Equal(t, `{"type":"next","id":"op-1","payload":{"errors":[{"message":"boom"}]}}`, string(actual.Payload))
+	})
+
+	t.Run(".Decode", func(t *testing.T) {
+		actual, err := coder.Decode([]byte(`{"type":"subscribe","id":"op-1","payload":{"query":"{ post }"}}`))
+
+		require.NoError(t, err)
+		assert.Equal(t, "subscribe", actual.Command)
+		assert.Equal(t, "op-1", actual.Identifier)
+		assert.Equal(t, map[string]interface{}{"query": "{ post }"}, actual.Data)
+	})
+
+	t.Run(".Decode with invalid payload", func(t *testing.T) {
+		_, err := coder.Decode([]byte("not a json"))
+
+		assert.Error(t, err)
+	})
+}
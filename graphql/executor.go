@@ -0,0 +1,260 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/node"
+)
+
+// Executor maps graphql-transport-ws messages to the node API:
+// connection_init authenticates the session, subscribe subscribes to the GraphQL channel and executes the operation,
+// complete unsubscribes from the channel.
+// NOTE: Executor keeps per-connection state, so each session must have its own executor.
+type Executor struct {
+	node   *node.Node
+	config *Config
+
+	mu          sync.Mutex
+	initialized bool
+	operations  map[string]struct{}
+}
+
+var _ node.Executor = (*Executor)(nil)
+
+// NewExecutor creates a new executor for a single GraphQL session
+func NewExecutor(n *node.Node, config *Config) *Executor {
+	return &Executor{
+		node:       n,
+		config:     config,
+		operations: make(map[string]struct{}),
+	}
+}
+
+func (ex *Executor) HandleCommand(s *node.Session, msg *common.Message) error {
+	switch msg.Command {
+	case connectionInitType:
+		return ex.connectionInit(s, msg)
+	case pingType:
+		s.Send(&Message{Type: pongType})
+		return nil
+	case pongType:
+		return nil
+	case subscribeType:
+		return ex.subscribe(s, msg)
+	case completeType:
+		return ex.complete(s, msg)
+	default:
+		s.Disconnect("Invalid message received", badRequestCode)
+		return fmt.Errorf("unknown message type: %s", msg.Command)
+	}
+}
+
+func (ex *Executor) Disconnect(s *node.Session) error {
+	return ex.node.Disconnect(s)
+}
+
+func (ex *Executor) connectionInit(s *node.Session, msg *common.Message) error {
+	ex.mu.Lock()
+
+	if ex.initialized {
+		ex.mu.Unlock()
+		s.Disconnect("Too many initialisation requests", tooManyInitCode)
+		return errors.New("connection has been already initialised")
+	}
+
+	ex.initialized = true
+	ex.mu.Unlock()
+
+	if msg.Data != nil {
+		ex.setConnectionParams(s, msg.Data)
+	}
+
+	res, err := ex.node.Authenticate(s, node.WithDisconnectOnFailure(false))
+
+	if err != nil {
+		return err
+	}
+
+	if res.Status != common.SUCCESS {
+		s.Disconnect("Forbidden", forbiddenCode)
+	}
+
+	return nil
+}
+
+// Connection params are passed to the RPC server as a JSON-encoded header;
+// the JWT token is extracted from the params and passed as a header, too
+func (ex *Executor) setConnectionParams(s *node.Session, params interface{}) {
+	env := s.GetEnv()
+
+	headers := make(map[string]string)
+
+	if env.Headers != nil {
+		for k, v := range *env.Headers {
+			headers[k] = v
+		}
+	}
+
+	if encoded, err := json.Marshal(params); err == nil {
+		headers[connectionParamsHeader] = string(encoded)
+	}
+
+	if ex.config.JWTParam != "" {
+		if data, ok := params.(map[string]interface{}); ok {
+			if token, ok := data[ex.config.JWTParam].(string); ok {
+				headers["x-"+strings.ToLower(ex.config.JWTParam)] = token
+			}
+		}
+	}
+
+	env.Headers = &headers
+}
+
+func (ex *Executor) subscribe(s *node.Session, msg *common.Message) error {
+	if !s.IsConnected() {
+		s.Disconnect("Unauthorized", unauthorizedCode)
+		return errors.New("connection is not initialised")
+	}
+
+	id := msg.Identifier
+
+	if id == "" {
+		s.Disconnect("Operation ID is missing", badRequestCode)
+		return errors.New("operation ID is missing")
+	}
+
+	// Operations are executed via channel actions, so they share the perform rate limit
+	if !ex.node.CheckRateLimit(s, "message") {
+		s.Send(newErrorMessage(id, "Rate limit exceeded"))
+		return nil
+	}
+
+	ex.mu.Lock()
+
+	if _, ok := ex.operations[id]; ok {
+		ex.mu.Unlock()
+		s.Disconnect(fmt.Sprintf("Subscriber for %s already exists", id), subscriberExistsCode)
+		return fmt.Errorf("operation already exists: %s", id)
+	}
+
+	ex.operations[id] = struct{}{}
+	ex.mu.Unlock()
+
+	identifier := buildIdentifier(ex.config.Channel, id)
+
+	res, err := ex.node.Subscribe(s, &common.Message{Command: "subscribe", Identifier: identifier})
+
+	if err != nil || res == nil || res.Status != common.SUCCESS {
+		ex.forget(id)
+		return err
+	}
+
+	data, err := ex.buildPerformData(msg.Data)
+
+	if err != nil {
+		s.Send(newErrorMessage(id, "Invalid operation payload"))
+		ex.unsubscribe(s, id) // nolint:errcheck
+		return err
+	}
+
+	res, err = ex.node.Perform(s, &common.Message{Command: "message", Identifier: identifier, Data: data})
+
+	if err != nil {
+		s.Send(newErrorMessage(id, "Operation failed"))
+		ex.unsubscribe(s, id) // nolint:errcheck
+		return err
+	}
+
+	if res != nil && isCompleted(res.Transmissions) {
+		s.Send(&Message{Type: completeType, ID: id})
+		return ex.unsubscribe(s, id)
+	}
+
+	return nil
+}
+
+func (ex *Executor) complete(s *node.Session, msg *common.Message) error {
+	// Completing an unknown (or already completed) operation is a no-op
+	if !ex.has(msg.Identifier) {
+		return nil
+	}
+
+	return ex.unsubscribe(s, msg.Identifier)
+}
+
+func (ex *Executor) unsubscribe(s *node.Session, id string) error {
+	ex.forget(id)
+
+	_, err := ex.node.Unsubscribe(s, &common.Message{Command: "unsubscribe", Identifier: buildIdentifier(ex.config.Channel, id)})
+
+	return err
+}
+
+func (ex *Executor) buildPerformData(payload interface{}) (string, error) {
+	data := make(map[string]interface{})
+
+	if payload != nil {
+		params, ok := payload.(map[string]interface{})
+
+		if !ok {
+			return "", fmt.Errorf("operation payload must be an object, got %v", payload)
+		}
+
+		for k, v := range params {
+			data[k] = v
+		}
+	}
+
+	data["action"] = ex.config.Action
+
+	b, err := json.Marshal(data)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+func (ex *Executor) has(id string) bool {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+
+	_, ok := ex.operations[id]
+
+	return ok
+}
+
+func (ex *Executor) forget(id string) {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+
+	delete(ex.operations, id)
+}
+
+// isCompleted returns true if the operation result is final (e.g., queries and mutations).
+// Empty final results are turned into complete messages by the encoder, so we only check non-empty ones.
+func isCompleted(transmissions []string) bool {
+	for _, raw := range transmissions {
+		var reply common.Reply
+
+		if err := json.Unmarshal([]byte(raw), &reply); err != nil {
+			continue
+		}
+
+		if reply.Type != "" {
+			continue
+		}
+
+		if _, ok := nextPayload(reply.Message); ok && isFinal(reply.Message) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,324 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/anycable/anycable-go/broker"
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/metrics"
+	"github.com/anycable/anycable-go/mocks"
+	"github.com/anycable/anycable-go/node"
+	"github.com/anycable/anycable-go/pubsub"
+	"github.com/anycable/anycable-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor(t *testing.T) {
+	appNode, controller := buildNode()
+
+	go appNode.Start()                           // nolint: errcheck
+	defer appNode.Shutdown(context.Background()) // nolint: errcheck
+
+	conf := NewConfig()
+	conf.JWTParam = "jid"
+
+	identifier := buildIdentifier("GraphqlChannel", "op-1")
+
+	controller.
+		On("Shutdown").
+		Return(nil)
+
+	controller.
+		On("Disconnect", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	controller.
+		On("Authenticate", "failed", mock.Anything).
+		Return(&common.ConnectResult{Status: common.FAILURE, Transmissions: []string{`{"type":"disconnect","reason":"unauthorized","reconnect":false}`}}, nil)
+
+	controller.
+		On("Authenticate", mock.Anything, mock.Anything).
+		Return(&common.ConnectResult{Status: common.SUCCESS, Identifier: "ids", Transmissions: []string{`{"type":"welcome"}`}}, nil)
+
+	controller.
+		On("Subscribe", mock.Anything, mock.Anything, "ids", identifier).
+		Return(&common.CommandResult{Status: common.SUCCESS, Transmissions: []string{`{"type":"confirm_subscription","identifier":` + jsonString(identifier) + `}`}}, nil)
+
+	controller.
+		On("Unsubscribe", mock.Anything, mock.Anything, "ids", identifier).
+		Return(&common.CommandResult{Status: common.SUCCESS}, nil)
+
+	t.Run("connection_init", func(t *testing.T) {
+		conn := newTestConnection()
+		session := NewSession(appNode, conn, requestInfo("init"), &conf)
+
+		require.NoError(t, session.ReadMessage([]byte(`{"type":"connection_init","payload":{"jid":"secret","token":"42"}}`)))
+
+		assert.Equal(t, `{"type":"connection_ack"}`, conn.receive(t))
+		assert.True(t, session.IsConnected())
+
+		headers := *session.GetEnv().Headers
+		assert.Equal(t, `{"jid":"secret","token":"42"}`, headers["x-apollo-connection"])
+		assert.Equal(t, "secret", headers["x-jid"])
+	})
+
+	t.Run("connection_init when authentication fails", func(t *testing.T) {
+		conn := newTestConnection()
+		session := NewSession(appNode, conn, requestInfo("failed"), &conf)
+
+		require.NoError(t, session.ReadMessage([]byte(`{"type":"connection_init"}`)))
+
+		assert.Equal(t, 4403, conn.closedWith(t))
+		assert.False(t, session.IsConnected())
+	})
+
+	t.Run("connection_init twice", func(t *testing.T) {
+		conn := newTestConnection()
+		session := NewSession(appNode, conn, requestInfo("twice"), &conf)
+
+		require.NoError(t, session.ReadMessage([]byte(`{"type":"connection_init"}`)))
+		assert.Equal(t, `{"type":"connection_ack"}`, conn.receive(t))
+
+		require.NoError(t, session.ReadMessage([]byte(`{"type":"connection_init"}`)))
+		assert.Equal(t, 4429, conn.closedWith(t))
+	})
+
+	t.Run("connection_init timeout", func(t *testing.T) {
+		conn := newTestConnection()
+		timeoutConf := conf
+		timeoutConf.InitTimeout = 0
+
+		NewSession(appNode, conn, requestInfo("timeout"), &timeoutConf)
+
+		assert.Equal(t, 4408, conn.closedWith(t))
+	})
+
+	t.Run("ping", func(t *testing.T) {
+		conn := newTestConnection()
+		session := NewSession(appNode, conn, requestInfo("ping"), &conf)
+
+		require.NoError(t, session.ReadMessage([]byte(`{"type":"ping"}`)))
+
+		assert.Equal(t, `{"type":"pong"}`, conn.receive(t))
+	})
+
+	t.Run("unknown message type", func(t *testing.T) {
+		conn := newTestConnection()
+		session := NewSession(appNode, conn, requestInfo("unknown"), &conf)
+
+		require.NoError(t, session.ReadMessage([]byte(`{"type":"start","id":"op-1"}`)))
+
+		assert.Equal(t, 4400, conn.closedWith(t))
+	})
+
+	t.Run("subscribe before connection_init", func(t *testing.T) {
+		conn := newTestConnection()
+		session := NewSession(appNode, conn, requestInfo("not_initialized"), &conf)
+
+		require.NoError(t, session.ReadMessage([]byte(`{"type":"subscribe","id":"op-1","payload":{"query":"{ post }"}}`)))
+
+		assert.Equal(t, 4401, conn.closedWith(t))
+	})
+
+	t.Run("subscribe with query", func(t *testing.T) {
+		controller.
+			On("Perform", "query", mock.Anything, "ids", identifier, `{"action":"execute","query":"{ post }"}`).
+			Return(&common.CommandResult{
+				Status:        common.SUCCESS,
+				Transmissions: []string{`{"identifier":` + jsonString(identifier) + `,"message":{"result":{"data":{"post":"hello"}},"more":false}}`},
+			}, nil)
+
+		conn := newTestConnection()
+		session := NewSession(appNode, conn, requestInfo("query"), &conf)
+
+		require.NoError(t, session.ReadMessage([]byte(`{"type":"connection_init"}`)))
+		assert.Equal(t, `{"type":"connection_ack"}`, conn.receive(t))
+
+		require.NoError(t, session.ReadMessage([]byte(`{"type":"subscribe","id":"op-1","payload":{"query":"{ post }"}}`)))
+
+		assert.Equal(t, `{"type":"next","id":"op-1","payload":{"data":{"post":"hello"}}}`, conn.receive(t))
+		assert.Equal(t, `{"type":"complete","id":"op-1"}`, conn.receive(t))
+
+		controller.AssertCalled(t, "Unsubscribe", "query", mock.Anything, "ids", identifier)
+	})
+
+	t.Run("subscribe with subscription and complete", func(t *testing.T) {
+		controller.
+			On("Perform", "subscription", mock.Anything, "ids", identifier, mock.Anything).
+			Return(&common.CommandResult{
+				Status:        common.SUCCESS,
+				Streams:       []string{"graphql-subscription:42"},
+				Transmissions: []string{`{"identifier":` + jsonString(identifier) + `,"message":{"result":{"data":null},"more":true}}`},
+			}, nil)
+
+		conn := newTestConnection()
+		session := NewSession(appNode, conn, requestInfo("subscription"), &conf)
+
+		require.NoError(t, session.ReadMessage([]byte(`{"type":"connection_init"}`)))
+		assert.Equal(t, `{"type":"connection_ack"}`, conn.receive(t))
+
+		require.NoError(t, session.ReadMessage([]byte(`{"type":"subscribe","id":"op-1","payload":{"query":"subscription { postCreated }"}}`)))
+		conn.assertNoFrames(t)
+
+		session.Send(&common.Reply{Identifier: identifier, Message: map[string]interface{}{"result": map[string]interface{}{"data": "new post"}, "more": true}})
+		assert.Equal(t, `{"type":"next","id":"op-1","payload":{"data":"new post"}}`, conn.receive(t))
+
+		require.NoError(t, session.ReadMessage([]byte(`{"type":"subscribe","id":"op-1","payload":{"query":"subscription { postCreated }"}}`)))
+		assert.Equal(t, 4409, conn.closedWith(t))
+	})
+
+	t.Run("complete", func(t *testing.T) {
+		controller.
+			On("Perform", "complete", mock.Anything, "ids", identifier, mock.Anything).
+			Return(&common.CommandResult{Status: common.SUCCESS, Streams: []string{"graphql-subscription:42"}}, nil)
+
+		conn := newTestConnection()
+		session := NewSession(appNode, conn, requestInfo("complete"), &conf)
+
+		require.NoError(t, session.ReadMessage([]byte(`{"type":"connection_init"}`)))
+		assert.Equal(t, `{"type":"connection_ack"}`, conn.receive(t))
+
+		require.NoError(t, session.ReadMessage([]byte(`{"type":"subscribe","id":"op-1","payload":{"query":"subscription { postCreated }"}}`)))
+		require.NoError(t, session.ReadMessage([]byte(`{"type":"complete","id":"op-1"}`)))
+
+		controller.AssertCalled(t, "Unsubscribe", "complete", mock.Anything, "ids", identifier)
+
+		// Operation ID can be reused after completion
+		require.NoError(t, session.ReadMessage([]byte(`{"type":"subscribe","id":"op-1","payload":{"query":"subscription { postCreated }"}}`)))
+		conn.assertNoFrames(t)
+	})
+}
+
+func TestExecutorRateLimits(t *testing.T) {
+	appNode, controller := buildNode(func(c *node.Config) {
+		c.PerformRateLimit = 1
+		c.RateLimitMaxViolations = 0
+	})
+
+	go appNode.Start()                           // nolint: errcheck
+	defer appNode.Shutdown(context.Background()) // nolint: errcheck
+
+	conf := NewConfig()
+
+	controller.
+		On("Shutdown").
+		Return(nil)
+
+	controller.
+		On("Disconnect", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	controller.
+		On("Authenticate", mock.Anything, mock.Anything).
+		Return(&common.ConnectResult{Status: common.SUCCESS, Identifier: "ids", Transmissions: []string{`{"type":"welcome"}`}}, nil)
+
+	controller.
+		On("Subscribe", mock.Anything, mock.Anything, "ids", mock.Anything).
+		Return(&common.CommandResult{Status: common.SUCCESS}, nil)
+
+	controller.
+		On("Perform", "limited", mock.Anything, "ids", mock.Anything, mock.Anything).
+		Return(&common.CommandResult{Status: common.SUCCESS}, nil)
+
+	conn := newTestConnection()
+	session := NewSession(appNode, conn, requestInfo("limited"), &conf)
+
+	require.NoError(t, session.ReadMessage([]byte(`{"type":"connection_init"}`)))
+	assert.Equal(t, `{"type":"connection_ack"}`, conn.receive(t))
+
+	require.NoError(t, session.ReadMessage([]byte(`{"type":"subscribe","id":"op-1","payload":{"query":"subscription { postCreated }"}}`)))
+	conn.assertNoFrames(t)
+
+	require.NoError(t, session.ReadMessage([]byte(`{"type":"subscribe","id":"op-2","payload":{"query":"subscription { postCreated }"}}`)))
+	assert.Equal(t, `{"type":"error","id":"op-2","payload":[{"message":"Rate limit exceeded"}]}`, conn.receive(t))
+
+	controller.AssertNumberOfCalls(t, "Perform", 1)
+}
+
+type testConnection struct {
+	frames    chan string
+	closeCode chan int
+}
+
+func newTestConnection() *testConnection {
+	return &testConnection{frames: make(chan string, 10), closeCode: make(chan int, 1)}
+}
+
+func (c *testConnection) Write(msg []byte, deadline time.Time) error {
+	c.frames <- string(msg)
+	return nil
+}
+
+func (c *testConnection) WriteBinary(msg []byte, deadline time.Time) error {
+	return errors.New("unsupported")
+}
+
+func (c *testConnection) Read() ([]byte, error) {
+	return nil, errors.New("unsupported")
+}
+
+func (c *testConnection) Close(code int, reason string) {
+	select {
+	case c.closeCode <- code:
+	default:
+	}
+}
+
+func (c *testConnection) receive(t *testing.T) string {
+	select {
+	case msg := <-c.frames:
+		return msg
+	case <-time.After(time.Second):
+		require.Fail(t, "Timeout waiting for a message")
+		return ""
+	}
+}
+
+func (c *testConnection) assertNoFrames(t *testing.T) {
+	select {
+	case msg := <-c.frames:
+		assert.Fail(t, "Unexpected message", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func (c *testConnection) closedWith(t *testing.T) int {
+	select {
+	case code := <-c.closeCode:
+		return code
+	case <-time.After(time.Second):
+		require.Fail(t, "Timeout waiting for connection to be closed")
+		return 0
+	}
+}
+
+func requestInfo(uid string) *server.RequestInfo {
+	return &server.RequestInfo{UID: uid, URL: "/graphql", Headers: &map[string]string{}}
+}
+
+func jsonString(val string) string {
+	b, _ := json.Marshal(val) // nolint:errchkjson
+	return string(b)
+}
+
+func buildNode(opts ...func(*node.Config)) (*node.Node, *mocks.Controller) {
+	controller := &mocks.Controller{}
+	config := node.NewConfig()
+	config.HubGopoolSize = 2
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	n := node.NewNode(&config, node.WithController(controller), node.WithInstrumenter(metrics.NewMetrics(nil, 10, slog.Default())))
+	n.SetBroker(broker.NewLegacyBroker(pubsub.NewLegacySubscriber(n)))
+	n.SetDisconnector(node.NewNoopDisconnector())
+	return n, controller
+}
@@ -0,0 +1,125 @@
+package graphql
+
+import (
+	"encoding/json"
+)
+
+// Protocol is the GraphQL over WebSocket subprotocol name (https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md)
+const Protocol = "graphql-transport-ws"
+
+// Message types
+const (
+	connectionInitType = "connection_init"
+	connectionAckType  = "connection_ack"
+	pingType           = "ping"
+	pongType           = "pong"
+	subscribeType      = "subscribe"
+	nextType           = "next"
+	errorType          = "error"
+	completeType       = "complete"
+)
+
+// Close codes
+const (
+	badRequestCode       = 4400
+	unauthorizedCode     = 4401
+	forbiddenCode        = 4403
+	initTimeoutCode      = 4408
+	subscriberExistsCode = 4409
+	tooManyInitCode      = 4429
+)
+
+// connectionParamsHeader is used to pass connection_init payload to the RPC server
+const connectionParamsHeader = "x-apollo-connection"
+
+// Message represents a graphql-transport-ws protocol message
+type Message struct {
+	Type    string      `json:"type"`
+	ID      string      `json:"id,omitempty"`
+	Payload interface{} `json:"payload,omitempty"`
+}
+
+func (m *Message) GetType() string {
+	return m.Type
+}
+
+func newErrorMessage(id string, reason string) *Message {
+	return &Message{
+		Type:    errorType,
+		ID:      id,
+		Payload: []map[string]string{{"message": reason}},
+	}
+}
+
+type channelIdentifier struct {
+	Channel   string `json:"channel"`
+	ChannelID string `json:"channelId"`
+}
+
+// buildIdentifier returns an Action Cable channel identifier for the operation
+func buildIdentifier(channel string, id string) string {
+	b, _ := json.Marshal(&channelIdentifier{Channel: channel, ChannelID: id}) // nolint:errchkjson
+
+	return string(b)
+}
+
+// operationID extracts the operation ID from the Action Cable channel identifier
+func operationID(identifier string) string {
+	var ci channelIdentifier
+
+	if err := json.Unmarshal([]byte(identifier), &ci); err != nil {
+		return ""
+	}
+
+	return ci.ChannelID
+}
+
+// GraphQL Ruby channel transmits execution results in the following form:
+//
+//	{"result": {"data": {...}, "errors": [...]}, "more": true}
+//
+// nextPayload returns the execution result if it contains data or errors
+// (subscriptions are acknowledged with empty results which must not be sent to clients).
+func nextPayload(msg interface{}) (interface{}, bool) {
+	data, ok := msg.(map[string]interface{})
+
+	if !ok {
+		return msg, msg != nil
+	}
+
+	raw, ok := data["result"]
+
+	if !ok {
+		if _, hasMore := data["more"]; hasMore {
+			return nil, false
+		}
+
+		// Not a GraphQL Ruby channel payload, deliver as is
+		return msg, true
+	}
+
+	result, ok := raw.(map[string]interface{})
+
+	if !ok {
+		return nil, false
+	}
+
+	if result["data"] == nil && result["errors"] == nil {
+		return nil, false
+	}
+
+	return result, true
+}
+
+// isFinal returns true if there will be no more results for the operation
+func isFinal(msg interface{}) bool {
+	data, ok := msg.(map[string]interface{})
+
+	if !ok {
+		return false
+	}
+
+	more, ok := data["more"].(bool)
+
+	return ok && !more
+}
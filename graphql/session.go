@@ -0,0 +1,24 @@
+package graphql
+
+import (
+	"time"
+
+	"github.com/anycable/anycable-go/node"
+	"github.com/anycable/anycable-go/server"
+)
+
+// NewSession creates a new session speaking the graphql-transport-ws protocol.
+// The session is authenticated only after the connection_init message is received
+// (and disconnected if it's not received within the configured timeout).
+func NewSession(n *node.Node, conn node.Connection, info *server.RequestInfo, config *Config, opts ...node.SessionOption) *node.Session {
+	deadline := time.Now().Add(time.Duration(config.InitTimeout) * time.Second)
+
+	opts = append(
+		opts,
+		node.WithEncoder(Encoder{}),
+		node.WithExecutor(NewExecutor(n, config)),
+		node.WithHandshakeMessageDeadline(deadline),
+	)
+
+	return node.NewSession(n, conn, info.URL, info.Headers, info.UID, opts...)
+}
@@ -7,15 +7,16 @@ import (
 
 	"github.com/anycable/anycable-go/common"
 	"github.com/anycable/anycable-go/encoders"
+	"github.com/anycable/anycable-go/filters"
 )
 
 // Gate plays the role of a shard for the hub.
 // It keeps subscriptions for some streams (a particular shard) and is used
 // to broadcast messages to all subscribers of these streams.
 type Gate struct {
-	// Maps streams to sessions with identifiers
-	// stream -> session -> identifier -> true
-	streams map[string]map[HubSession]map[string]bool
+	// Maps streams to sessions with identifiers and subscription filters
+	// stream -> session -> identifier -> filter (nil if not filtered)
+	streams map[string]map[HubSession]map[string]*filters.Filter
 
 	// Maps sessions to identifiers to streams
 	// session -> identifier -> [stream]
@@ -24,6 +25,11 @@ type Gate struct {
 	// This channel is used as a broadcast queue
 	sender chan *common.StreamMessage
 
+	// Delivery statistics per stream (only collected if trackStats is true)
+	stats      map[string]*streamCounters
+	statsMu    sync.Mutex
+	trackStats bool
+
 	mu  sync.RWMutex
 	log *slog.Logger
 }
@@ -31,10 +37,11 @@ type Gate struct {
 // NewGate creates a new gate.
 func NewGate(ctx context.Context, l *slog.Logger) *Gate {
 	g := Gate{
-		streams:         make(map[string]map[HubSession]map[string]bool),
+		streams:         make(map[string]map[HubSession]map[string]*filters.Filter),
 		sessionsStreams: make(map[HubSession]map[string][]string),
 		// Use a buffered channel to avoid blocking
 		sender: make(chan *common.StreamMessage, 256),
+		stats:  make(map[string]*streamCounters),
 		log:    l,
 	}
 
@@ -63,19 +70,32 @@ func (g *Gate) Broadcast(streamMsg *common.StreamMessage) {
 }
 
 // Subscribe adds a session to the stream.
-func (g *Gate) Subscribe(session HubSession, stream string, identifier string) {
+// If filter is not nil, only messages matching it are sent to the session.
+func (g *Gate) Subscribe(session HubSession, stream string, identifier string, filter *filters.Filter) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
 	if _, ok := g.streams[stream]; !ok {
-		g.streams[stream] = make(map[HubSession]map[string]bool)
+		g.streams[stream] = make(map[HubSession]map[string]*filters.Filter)
 	}
 
 	if _, ok := g.streams[stream][session]; !ok {
-		g.streams[stream][session] = make(map[string]bool)
+		g.streams[stream][session] = make(map[string]*filters.Filter)
 	}
 
-	g.streams[stream][session][identifier] = true
+	g.streams[stream][session][identifier] = filter
+}
+
+// UpdateFilter replaces the filter of the existing session's subscription to the stream.
+func (g *Gate) UpdateFilter(session HubSession, stream string, identifier string, filter *filters.Filter) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if ids, ok := g.streams[stream][session]; ok {
+		if _, ok := ids[identifier]; ok {
+			ids[identifier] = filter
+		}
+	}
 }
 
 // Unsubscribe removes a session from the stream.
@@ -109,6 +129,10 @@ func (g *Gate) Unsubscribe(session HubSession, stream string, identifier string)
 
 		if len(g.streams[stream]) == 0 {
 			delete(g.streams, stream)
+
+			g.statsMu.Lock()
+			delete(g.stats, stream)
+			g.statsMu.Unlock()
 		}
 	}
 }
@@ -121,6 +145,31 @@ func (g *Gate) Size() int {
 	return len(g.streams)
 }
 
+// StreamsStats appends delivery statistics for all the gate's streams to the provided slice
+func (g *Gate) StreamsStats(dest []*StreamStats) []*StreamStats {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	g.statsMu.Lock()
+	defer g.statsMu.Unlock()
+
+	for stream, sessions := range g.streams {
+		dest = append(dest, g.stats[stream].toStats(stream, len(sessions)))
+	}
+
+	return dest
+}
+
+// Subscribers adds the number of subscribed sessions for each stream to the provided map
+func (g *Gate) Subscribers(dest map[string]int) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	for stream, sessions := range g.streams {
+		dest[stream] += len(sessions)
+	}
+}
+
 func (g *Gate) broadcastLoop(ctx context.Context) {
 	for {
 		select {
@@ -143,12 +192,30 @@ func (g *Gate) performBroadcast(streamMsg *common.StreamMessage) {
 	streamSessions := streamSessionsSnapshot(g.streams[stream])
 	g.mu.RUnlock()
 
+	if len(streamSessions) == 0 {
+		return
+	}
+
+	filterable := filters.NewMessage(streamMsg)
+
+	deliveries := 0
+
+	if g.trackStats {
+		defer func() {
+			g.trackBroadcast(stream, len(streamMsg.Data), deliveries)
+		}()
+	}
+
 	for session, ids := range streamSessions {
 		if streamMsg.Meta != nil && streamMsg.Meta.ExcludeSocket == session.GetID() {
 			continue
 		}
 
-		for _, id := range ids {
+		for id, filter := range ids {
+			if !filter.Match(filterable) {
+				continue
+			}
+
 			if msg, ok := buf[id]; ok {
 				bdata = msg
 			} else {
@@ -157,31 +224,64 @@ func (g *Gate) performBroadcast(streamMsg *common.StreamMessage) {
 			}
 
 			session.Send(bdata)
+			deliveries++
 		}
 	}
 }
 
+func (g *Gate) trackBroadcast(stream string, size int, deliveries int) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	// Stream could have been removed during the broadcast
+	if _, ok := g.streams[stream]; !ok {
+		return
+	}
+
+	g.statsMu.Lock()
+	defer g.statsMu.Unlock()
+
+	counters, ok := g.stats[stream]
+
+	if !ok {
+		counters = &streamCounters{}
+		g.stats[stream] = counters
+	}
+
+	counters.track(size, deliveries)
+}
+
 func buildMessage(msg *common.StreamMessage, identifier string) encoders.EncodedMessage {
-	reply := msg.ToReplyFor(identifier)
+	return buildReplyMessage(msg, msg.ToReplyFor(identifier), identifier)
+}
+
+func buildReplyMessage(msg *common.StreamMessage, reply *common.Reply, identifier string) encoders.EncodedMessage {
+	cached := encoders.NewCachedEncodedMessage(reply)
 
 	if msg.Meta != nil {
 		reply.Type = msg.Meta.BroadcastType
+
+		if msg.Meta.Transient {
+			cached.MarkTransient()
+		}
+
+		// Messages are conflated per subscription (stream + channel identifier)
+		if msg.Meta.Conflate {
+			cached.SetConflationKey(msg.Stream + "\x00" + identifier)
+		}
 	}
 
-	return encoders.NewCachedEncodedMessage(reply)
+	return cached
 }
 
-func streamSessionsSnapshot[T comparable](src map[T]map[string]bool) map[T][]string {
-	dest := make(map[T][]string)
+func streamSessionsSnapshot[T comparable](src map[T]map[string]*filters.Filter) map[T]map[string]*filters.Filter {
+	dest := make(map[T]map[string]*filters.Filter, len(src))
 
 	for k, v := range src {
-		dest[k] = make([]string, len(v))
-
-		i := 0
+		dest[k] = make(map[string]*filters.Filter, len(v))
 
-		for id := range v {
-			dest[k][i] = id
-			i++
+		for id, filter := range v {
+			dest[k][id] = filter
 		}
 	}
 
@@ -19,7 +19,7 @@ func TestUnsubscribe(t *testing.T) {
 
 	session := NewMockSession("123")
 
-	gate.Subscribe(session, "test", "test_channel")
+	gate.Subscribe(session, "test", "test_channel", nil)
 
 	assert.Equal(t, 1, gate.Size())
 
@@ -39,7 +39,7 @@ func TestShutdown(t *testing.T) {
 
 	session := NewMockSession("123")
 
-	gate.Subscribe(session, "test", "test_channel")
+	gate.Subscribe(session, "test", "test_channel", nil)
 
 	gate.Broadcast(&common.StreamMessage{Stream: "test", Data: "1"})
 
@@ -8,6 +8,7 @@ import (
 
 	"github.com/anycable/anycable-go/common"
 	"github.com/anycable/anycable-go/encoders"
+	"github.com/anycable/anycable-go/filters"
 	"github.com/anycable/anycable-go/utils"
 )
 
@@ -57,6 +58,13 @@ type Hub struct {
 	gates    []*Gate
 	gatesNum int
 
+	// Gate for wildcard subscriptions
+	patterns *PatternGate
+	// Whether wildcard streams are enabled (otherwise, they're treated as regular streams)
+	wildcards bool
+	// Whether to collect per-stream delivery statistics
+	streamsStats bool
+
 	// Registered sessions
 	sessions map[string]*HubSessionInfo
 
@@ -90,11 +98,27 @@ type Hub struct {
 	mu sync.RWMutex
 }
 
+type HubOption func(*Hub)
+
+// WithWildcardStreams enables wildcard streams support (see utils.StreamPatternDelimiter)
+func WithWildcardStreams(val bool) HubOption {
+	return func(h *Hub) {
+		h.wildcards = val
+	}
+}
+
+// WithStreamsStats enables per-stream delivery statistics (see StreamsStats)
+func WithStreamsStats(val bool) HubOption {
+	return func(h *Hub) {
+		h.streamsStats = val
+	}
+}
+
 // NewHub builds new hub instance
-func NewHub(poolSize int, l *slog.Logger) *Hub {
+func NewHub(poolSize int, l *slog.Logger, opts ...HubOption) *Hub {
 	ctx, doneFn := context.WithCancel(context.Background())
 
-	return &Hub{
+	h := &Hub{
 		broadcast:   make(chan *common.StreamMessage, 256),
 		disconnect:  make(chan *common.RemoteDisconnectMessage, 128),
 		register:    make(chan HubRegistration, 2048),
@@ -102,11 +126,24 @@ func NewHub(poolSize int, l *slog.Logger) *Hub {
 		identifiers: make(map[string]map[string]bool),
 		gates:       buildGates(ctx, poolSize, l),
 		gatesNum:    poolSize,
+		patterns:    NewPatternGate(ctx, poolSize, l.With("component", "hub", "gate", "patterns")),
 		pool:        utils.NewGoPool("remote commands", 256),
 		doneFn:      doneFn,
 		shutdown:    make(chan struct{}),
 		log:         l.With("component", "hub"),
 	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	for _, gate := range h.gates {
+		gate.trackStats = h.streamsStats
+	}
+
+	h.patterns.trackStats = h.streamsStats
+
+	return h
 }
 
 // Run makes hub active
@@ -184,9 +221,34 @@ func (h *Hub) StreamsSize() int {
 	for _, gate := range h.gates {
 		size += gate.Size()
 	}
+	size += h.patterns.Size()
 	return size
 }
 
+// StreamsSubscribers returns the number of subscribed sessions for each stream
+func (h *Hub) StreamsSubscribers() map[string]int {
+	res := make(map[string]int)
+
+	for _, gate := range h.gates {
+		gate.Subscribers(res)
+	}
+
+	h.patterns.Subscribers(res)
+
+	return res
+}
+
+// StreamsStats returns delivery statistics for all the active streams
+func (h *Hub) StreamsStats() []*StreamStats {
+	res := make([]*StreamStats, 0)
+
+	for _, gate := range h.gates {
+		res = gate.StreamsStats(res)
+	}
+
+	return h.patterns.StreamsStats(res)
+}
+
 func (h *Hub) AddSession(session HubSession) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -245,7 +307,7 @@ func (h *Hub) unsubscribeSessionFromAllChannels(session HubSession) {
 		for _, streamInfo := range sessionInfo.streams {
 			stream, identifier := streamInfo[0], streamInfo[1]
 
-			h.gates[index(stream, h.gatesNum)].Unsubscribe(session, stream, identifier)
+			h.gateFor(stream).Unsubscribe(session, stream, identifier)
 		}
 	}
 }
@@ -261,7 +323,7 @@ func (h *Hub) UnsubscribeSessionFromChannel(session HubSession, targetIdentifier
 			stream, identifier := streamInfo[0], streamInfo[1]
 
 			if targetIdentifier == identifier {
-				h.gates[index(stream, h.gatesNum)].Unsubscribe(session, stream, identifier)
+				h.gateFor(stream).Unsubscribe(session, stream, identifier)
 				sessionInfo.RemoveStream(stream, identifier)
 			}
 		}
@@ -271,7 +333,13 @@ func (h *Hub) UnsubscribeSessionFromChannel(session HubSession, targetIdentifier
 }
 
 func (h *Hub) SubscribeSession(session HubSession, stream string, identifier string) {
-	h.gates[index(stream, h.gatesNum)].Subscribe(session, stream, identifier)
+	h.SubscribeSessionWithFilter(session, stream, identifier, nil)
+}
+
+// SubscribeSessionWithFilter subscribes a session to the stream;
+// only messages matching the filter are delivered (nil filter matches everything)
+func (h *Hub) SubscribeSessionWithFilter(session HubSession, stream string, identifier string, filter *filters.Filter) {
+	h.gateFor(stream).Subscribe(session, stream, identifier, filter)
 
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -287,8 +355,28 @@ func (h *Hub) SubscribeSession(session HubSession, stream string, identifier str
 	h.log.With("sid", sid).Debug("subscribed", "identifier", identifier, "stream", stream)
 }
 
+// UpdateSessionFilter replaces the filter for all the session's streams subscribed with the identifier
+func (h *Hub) UpdateSessionFilter(session HubSession, identifier string, filter *filters.Filter) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	sid := session.GetID()
+
+	if sessionInfo, ok := h.sessions[sid]; ok {
+		for _, streamInfo := range sessionInfo.streams {
+			stream, id := streamInfo[0], streamInfo[1]
+
+			if id == identifier {
+				h.gateFor(stream).UpdateFilter(session, stream, identifier, filter)
+			}
+		}
+	}
+
+	h.log.With("sid", sid).Debug("filter updated", "identifier", identifier)
+}
+
 func (h *Hub) UnsubscribeSession(session HubSession, stream string, identifier string) {
-	h.gates[index(stream, h.gatesNum)].Unsubscribe(session, stream, identifier)
+	h.gateFor(stream).Unsubscribe(session, stream, identifier)
 
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -304,6 +392,24 @@ func (h *Hub) UnsubscribeSession(session HubSession, stream string, identifier s
 
 func (h *Hub) broadcastToStream(streamMsg *common.StreamMessage) {
 	h.gates[index(streamMsg.Stream, h.gatesNum)].Broadcast(streamMsg)
+	h.patterns.Broadcast(streamMsg)
+}
+
+// streamsGate is implemented by gates keeping streams subscriptions
+type streamsGate interface {
+	Subscribe(session HubSession, stream string, identifier string, filter *filters.Filter)
+	UpdateFilter(session HubSession, stream string, identifier string, filter *filters.Filter)
+	Unsubscribe(session HubSession, stream string, identifier string)
+}
+
+// gateFor returns the gate responsible for the stream: wildcard streams are kept in the patterns gate,
+// other streams are sharded between gates
+func (h *Hub) gateFor(stream string) streamsGate {
+	if h.wildcards && utils.IsStreamPattern(stream) {
+		return h.patterns
+	}
+
+	return h.gates[index(stream, h.gatesNum)]
 }
 
 func (h *Hub) disconnectSessions(identifier string, reconnect bool) {
@@ -349,6 +455,40 @@ func (h *Hub) FindByIdentifier(id string) HubSession {
 	return nil
 }
 
+// FindByID returns a session by its ID
+func (h *Hub) FindByID(sid string) HubSession {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if info, ok := h.sessions[sid]; ok {
+		return info.session
+	}
+
+	return nil
+}
+
+// FindAllByIdentifier returns all the sessions with the specified identifiers
+func (h *Hub) FindAllByIdentifier(id string) []HubSession {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ids, ok := h.identifiers[id]
+
+	if !ok {
+		return nil
+	}
+
+	sessions := make([]HubSession, 0, len(ids))
+
+	for id := range ids {
+		if info, ok := h.sessions[id]; ok {
+			sessions = append(sessions, info.session)
+		}
+	}
+
+	return sessions
+}
+
 func (h *Hub) Sessions() []HubSession {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
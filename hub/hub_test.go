@@ -12,6 +12,7 @@ import (
 
 	"github.com/anycable/anycable-go/common"
 	"github.com/anycable/anycable-go/encoders"
+	"github.com/anycable/anycable-go/filters"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -287,6 +288,238 @@ func TestBroadcastMessage(t *testing.T) {
 	})
 }
 
+func TestBroadcastToPatterns(t *testing.T) {
+	hub := NewHub(2, slog.Default(), WithWildcardStreams(true))
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	session := NewMockSession("123")
+	hub.AddSession(session)
+	hub.SubscribeSession(session, "project:42:*", "project_channel")
+	hub.SubscribeSession(session, "project:*:tasks", "project_channel")
+
+	session2 := NewMockSession("321")
+	hub.AddSession(session2)
+	hub.SubscribeSession(session2, "project:42:tasks", "tasks_channel")
+	hub.SubscribeSession(session2, "project.>", "dots_channel")
+
+	assert.Equal(t, 4, hub.StreamsSize())
+
+	t.Run("Broadcast to matching patterns", func(t *testing.T) {
+		hub.BroadcastMessage(&common.StreamMessage{Stream: "project:42:tasks", Data: "\"ciao\""})
+
+		msg, err := session.Read()
+		require.NoError(t, err)
+		assert.Equal(t, "{\"identifier\":\"project_channel\",\"message\":\"ciao\",\"stream_id\":\"project:42:tasks\"}", string(msg))
+
+		// Only once for multiple matching patterns
+		_, err = session.Read()
+		require.Error(t, err)
+
+		msg, err = session2.Read()
+		require.NoError(t, err)
+		assert.Equal(t, "{\"identifier\":\"tasks_channel\",\"message\":\"ciao\"}", string(msg))
+
+		_, err = session2.Read()
+		require.Error(t, err)
+	})
+
+	t.Run("Broadcast to dot-separated patterns", func(t *testing.T) {
+		hub.BroadcastMessage(&common.StreamMessage{Stream: "project.1.comments", Data: "\"hi\""})
+
+		msg, err := session2.Read()
+		require.NoError(t, err)
+		assert.Equal(t, "{\"identifier\":\"dots_channel\",\"message\":\"hi\",\"stream_id\":\"project.1.comments\"}", string(msg))
+
+		_, err = session.Read()
+		require.Error(t, err)
+	})
+
+	t.Run("Unsubscribe from pattern", func(t *testing.T) {
+		hub.UnsubscribeSessionFromChannel(session, "project_channel")
+
+		assert.Equal(t, 2, hub.StreamsSize())
+
+		hub.BroadcastMessage(&common.StreamMessage{Stream: "project:42:comments", Data: "\"ciao\""})
+
+		_, err := session.Read()
+		require.Error(t, err)
+	})
+}
+
+func TestBroadcastToPatternsDisabled(t *testing.T) {
+	hub := NewHub(2, slog.Default())
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	session := NewMockSession("123")
+	hub.AddSession(session)
+	hub.SubscribeSession(session, "project:42:*", "project_channel")
+
+	hub.BroadcastMessage(&common.StreamMessage{Stream: "project:42:tasks", Data: "\"ciao\""})
+
+	_, err := session.Read()
+	require.Error(t, err)
+
+	// Wildcard-like streams are regular streams when wildcards are disabled
+	hub.BroadcastMessage(&common.StreamMessage{Stream: "project:42:*", Data: "\"ciao\""})
+
+	msg, err := session.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "{\"identifier\":\"project_channel\",\"message\":\"ciao\"}", string(msg))
+}
+
+func TestBroadcastWithFilters(t *testing.T) {
+	hub := NewHub(2, slog.Default(), WithWildcardStreams(true))
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	adminFilter, err := filters.Compile(`{"role":"admin"}`)
+	require.NoError(t, err)
+
+	urgentFilter, err := filters.Compile(`{"priority":{"gte":5}}`)
+	require.NoError(t, err)
+
+	session := NewMockSession("123")
+	hub.AddSession(session)
+	hub.SubscribeSessionWithFilter(session, "notifications", "admin_channel", adminFilter)
+	hub.SubscribeSession(session, "notifications", "all_channel")
+
+	session2 := NewMockSession("321")
+	hub.AddSession(session2)
+	hub.SubscribeSessionWithFilter(session2, "notifications:*", "urgent_channel", urgentFilter)
+
+	t.Run("Broadcast to filtered subscriptions", func(t *testing.T) {
+		hub.BroadcastMessage(&common.StreamMessage{Stream: "notifications", Data: `{"role":"admin"}`})
+
+		received := []string{}
+
+		for i := 0; i < 2; i++ {
+			msg, err := session.Read()
+			require.NoError(t, err)
+
+			received = append(received, string(msg))
+		}
+
+		assert.ElementsMatch(
+			t,
+			[]string{
+				"{\"identifier\":\"admin_channel\",\"message\":{\"role\":\"admin\"}}",
+				"{\"identifier\":\"all_channel\",\"message\":{\"role\":\"admin\"}}",
+			},
+			received,
+		)
+
+		hub.BroadcastMessage(&common.StreamMessage{Stream: "notifications", Data: `{"role":"user"}`})
+
+		msg, err := session.Read()
+		require.NoError(t, err)
+		assert.Equal(t, "{\"identifier\":\"all_channel\",\"message\":{\"role\":\"user\"}}", string(msg))
+
+		_, err = session.Read()
+		require.Error(t, err)
+	})
+
+	t.Run("Broadcast to filtered pattern subscriptions", func(t *testing.T) {
+		hub.BroadcastMessage(&common.StreamMessage{Stream: "notifications:1", Data: `{"priority":1}`})
+
+		_, err := session2.Read()
+		require.Error(t, err)
+
+		hub.BroadcastMessage(&common.StreamMessage{Stream: "notifications:1", Data: `{"priority":7}`})
+
+		msg, err := session2.Read()
+		require.NoError(t, err)
+		assert.Equal(t, "{\"identifier\":\"urgent_channel\",\"message\":{\"priority\":7},\"stream_id\":\"notifications:1\"}", string(msg))
+	})
+}
+
+func TestStreamsSubscribers(t *testing.T) {
+	hub := NewHub(2, slog.Default())
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	session := NewMockSession("123")
+	hub.AddSession(session)
+	hub.SubscribeSession(session, "chat", "chat_channel")
+	hub.SubscribeSession(session, "chat", "chat_channel_2")
+	hub.SubscribeSession(session, "news:*", "news_channel")
+
+	session2 := NewMockSession("321")
+	hub.AddSession(session2)
+	hub.SubscribeSession(session2, "chat", "chat_channel")
+
+	assert.Equal(t, map[string]int{"chat": 2, "news:*": 1}, hub.StreamsSubscribers())
+
+	assert.Same(t, session, hub.FindByID("123"))
+	assert.Nil(t, hub.FindByID("unknown"))
+}
+
+func TestStreamsStats(t *testing.T) {
+	hub := NewHub(2, slog.Default(), WithWildcardStreams(true), WithStreamsStats(true))
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	session := NewMockSession("123")
+	hub.AddSession(session)
+	hub.SubscribeSession(session, "chat", "chat_channel")
+	hub.SubscribeSession(session, "news:*", "news_channel")
+
+	session2 := NewMockSession("321")
+	hub.AddSession(session2)
+	hub.SubscribeSession(session2, "chat", "chat_channel")
+
+	hub.BroadcastMessage(&common.StreamMessage{Stream: "chat", Data: "\"hello\""})
+	hub.BroadcastMessage(&common.StreamMessage{Stream: "news:1", Data: "\"hi\""})
+
+	statsFor := func(stream string) *StreamStats {
+		for _, stats := range hub.StreamsStats() {
+			if stats.Stream == stream {
+				return stats
+			}
+		}
+
+		return nil
+	}
+
+	assert.Eventually(t, func() bool {
+		chat := statsFor("chat")
+		news := statsFor("news:*")
+
+		return chat != nil && chat.Messages == 1 && news != nil && news.Messages == 1
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, &StreamStats{Stream: "chat", Subscribers: 2, Messages: 1, Bytes: 14}, statsFor("chat"))
+	assert.Equal(t, &StreamStats{Stream: "news:*", Subscribers: 1, Messages: 1, Bytes: 4}, statsFor("news:*"))
+
+	hub.UnsubscribeSession(session, "news:*", "news_channel")
+
+	assert.Nil(t, statsFor("news:*"))
+}
+
+func TestStreamsStatsDisabled(t *testing.T) {
+	hub := NewHub(2, slog.Default())
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	session := NewMockSession("123")
+	hub.AddSession(session)
+	hub.SubscribeSession(session, "chat", "chat_channel")
+
+	hub.BroadcastMessage(&common.StreamMessage{Stream: "chat", Data: "\"hello\""})
+
+	_, err := session.Read()
+	require.NoError(t, err)
+
+	assert.Equal(t, []*StreamStats{{Stream: "chat", Subscribers: 1}}, hub.StreamsStats())
+}
+
 func TestBroadcastOrder(t *testing.T) {
 	hub := NewHub(10, slog.Default())
 
@@ -346,6 +579,22 @@ func TestBuildMessageString(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func TestBuildMessageWithMeta(t *testing.T) {
+	msg := buildMessage(&common.StreamMessage{Stream: "prices", Data: "1", Meta: &common.StreamMessageMetadata{Conflate: true, Transient: true}}, "chat")
+
+	cached, ok := msg.(*encoders.CachedEncodedMessage)
+	require.True(t, ok)
+
+	assert.True(t, cached.IsTransient())
+	assert.Equal(t, "prices\x00chat", cached.ConflationKey())
+
+	msg = buildMessage(&common.StreamMessage{Stream: "prices", Data: "1"}, "chat")
+	cached = msg.(*encoders.CachedEncodedMessage)
+
+	assert.False(t, cached.IsTransient())
+	assert.Equal(t, "", cached.ConflationKey())
+}
+
 type benchmarkConfig struct {
 	hubPoolSize       int
 	totalStreams      int
@@ -0,0 +1,267 @@
+package hub
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/encoders"
+	"github.com/anycable/anycable-go/filters"
+	"github.com/anycable/anycable-go/utils"
+)
+
+// PatternGate keeps wildcard subscriptions (e.g., "project:42:*" or "project.42.>")
+// and broadcasts messages to all subscribers of the matching patterns.
+// Patterns are stored in tries (one per delimiter), so matching doesn't require scanning all the patterns.
+type PatternGate struct {
+	// Maps patterns to sessions with identifiers
+	// pattern -> session -> identifier -> filter (nil if not filtered)
+	patterns map[string]map[HubSession]map[string]*filters.Filter
+
+	// Patterns tries by delimiter
+	tries map[byte]*streamsTrie
+
+	// Broadcast queues; messages are sharded by stream to keep their order within a stream
+	// and to fan out different streams concurrently
+	senders []chan *common.StreamMessage
+
+	// Delivery statistics per pattern (only collected if trackStats is true)
+	stats      map[string]*streamCounters
+	statsMu    sync.Mutex
+	trackStats bool
+
+	mu  sync.RWMutex
+	log *slog.Logger
+}
+
+// NewPatternGate creates a new pattern gate with the specified number of broadcasting goroutines.
+func NewPatternGate(ctx context.Context, poolSize int, l *slog.Logger) *PatternGate {
+	g := PatternGate{
+		patterns: make(map[string]map[HubSession]map[string]*filters.Filter),
+		tries:    make(map[byte]*streamsTrie),
+		senders:  make([]chan *common.StreamMessage, max(poolSize, 1)),
+		stats:    make(map[string]*streamCounters),
+		log:      l,
+	}
+
+	for i := range g.senders {
+		// Use a buffered channel to avoid blocking
+		g.senders[i] = make(chan *common.StreamMessage, 256)
+		go g.broadcastLoop(ctx, g.senders[i])
+	}
+
+	return &g
+}
+
+// Broadcast sends a message to all subscribers of the patterns matching the stream.
+func (g *PatternGate) Broadcast(streamMsg *common.StreamMessage) {
+	g.mu.RLock()
+	if len(g.patterns) == 0 {
+		g.mu.RUnlock()
+		return
+	}
+	g.mu.RUnlock()
+
+	g.log.Debug("schedule broadcast", "stream", streamMsg.Stream)
+
+	g.senders[index(streamMsg.Stream, len(g.senders))] <- streamMsg
+}
+
+// Subscribe adds a session to the pattern.
+func (g *PatternGate) Subscribe(session HubSession, pattern string, identifier string, filter *filters.Filter) {
+	delim, ok := utils.StreamPatternDelimiter(pattern)
+
+	if !ok {
+		g.log.Warn("not a wildcard stream", "stream", pattern)
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.patterns[pattern]; !ok {
+		g.patterns[pattern] = make(map[HubSession]map[string]*filters.Filter)
+
+		if _, ok := g.tries[delim]; !ok {
+			g.tries[delim] = newStreamsTrie(delim)
+		}
+
+		g.tries[delim].Insert(pattern)
+	}
+
+	if _, ok := g.patterns[pattern][session]; !ok {
+		g.patterns[pattern][session] = make(map[string]*filters.Filter)
+	}
+
+	g.patterns[pattern][session][identifier] = filter
+}
+
+// UpdateFilter replaces the filter of the existing session's subscription to the pattern.
+func (g *PatternGate) UpdateFilter(session HubSession, pattern string, identifier string, filter *filters.Filter) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if ids, ok := g.patterns[pattern][session]; ok {
+		if _, ok := ids[identifier]; ok {
+			ids[identifier] = filter
+		}
+	}
+}
+
+// Unsubscribe removes a session from the pattern.
+func (g *PatternGate) Unsubscribe(session HubSession, pattern string, identifier string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.patterns[pattern][session][identifier]; !ok {
+		return
+	}
+
+	delete(g.patterns[pattern][session], identifier)
+
+	if len(g.patterns[pattern][session]) == 0 {
+		delete(g.patterns[pattern], session)
+
+		if len(g.patterns[pattern]) == 0 {
+			delete(g.patterns, pattern)
+
+			g.statsMu.Lock()
+			delete(g.stats, pattern)
+			g.statsMu.Unlock()
+
+			delim, _ := utils.StreamPatternDelimiter(pattern)
+
+			if trie, ok := g.tries[delim]; ok {
+				trie.Remove(pattern)
+			}
+		}
+	}
+}
+
+// Size returns a number of uniq patterns
+func (g *PatternGate) Size() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return len(g.patterns)
+}
+
+// StreamsStats appends delivery statistics for all the patterns to the provided slice
+func (g *PatternGate) StreamsStats(dest []*StreamStats) []*StreamStats {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	g.statsMu.Lock()
+	defer g.statsMu.Unlock()
+
+	for pattern, sessions := range g.patterns {
+		dest = append(dest, g.stats[pattern].toStats(pattern, len(sessions)))
+	}
+
+	return dest
+}
+
+// Subscribers adds the number of subscribed sessions for each pattern to the provided map
+func (g *PatternGate) Subscribers(dest map[string]int) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	for pattern, sessions := range g.patterns {
+		dest[pattern] += len(sessions)
+	}
+}
+
+func (g *PatternGate) broadcastLoop(ctx context.Context, sender chan *common.StreamMessage) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-sender:
+			g.performBroadcast(msg)
+		}
+	}
+}
+
+func (g *PatternGate) performBroadcast(streamMsg *common.StreamMessage) {
+	buf := make(map[string](encoders.EncodedMessage))
+
+	var bdata encoders.EncodedMessage
+
+	// A session could be subscribed to multiple matching patterns with the same identifier,
+	// we must deliver the message only once
+	recipients := make(map[HubSession]map[string]bool)
+
+	filterable := filters.NewMessage(streamMsg)
+
+	// Number of deliveries per matching pattern (for stats)
+	deliveries := make(map[string]int)
+
+	g.mu.RLock()
+	for _, trie := range g.tries {
+		for _, pattern := range trie.Match(streamMsg.Stream) {
+			deliveries[pattern] = 0
+
+			for session, ids := range g.patterns[pattern] {
+				if _, ok := recipients[session]; !ok {
+					recipients[session] = make(map[string]bool)
+				}
+
+				for id, filter := range ids {
+					if filter.Match(filterable) {
+						recipients[session][id] = true
+						deliveries[pattern]++
+					}
+				}
+			}
+		}
+	}
+
+	// Track stats while holding the lock to make sure patterns are still present
+	if g.trackStats {
+		g.trackBroadcast(deliveries, len(streamMsg.Data))
+	}
+	g.mu.RUnlock()
+
+	for session, ids := range recipients {
+		if streamMsg.Meta != nil && streamMsg.Meta.ExcludeSocket == session.GetID() {
+			continue
+		}
+
+		for id := range ids {
+			if msg, ok := buf[id]; ok {
+				bdata = msg
+			} else {
+				bdata = buildPatternMessage(streamMsg, id)
+				buf[id] = bdata
+			}
+
+			session.Send(bdata)
+		}
+	}
+}
+
+func (g *PatternGate) trackBroadcast(deliveries map[string]int, size int) {
+	g.statsMu.Lock()
+	defer g.statsMu.Unlock()
+
+	for pattern, num := range deliveries {
+		counters, ok := g.stats[pattern]
+
+		if !ok {
+			counters = &streamCounters{}
+			g.stats[pattern] = counters
+		}
+
+		counters.track(size, num)
+	}
+}
+
+// buildPatternMessage builds a message for a wildcard subscriber;
+// the stream name is always included, so clients could tell the origin of the message
+func buildPatternMessage(msg *common.StreamMessage, identifier string) encoders.EncodedMessage {
+	reply := msg.ToReplyFor(identifier)
+	reply.StreamID = msg.Stream
+
+	return buildReplyMessage(msg, reply, identifier)
+}
@@ -0,0 +1,32 @@
+package hub
+
+// StreamStats contains delivery statistics for a stream (or a wildcard pattern)
+type StreamStats struct {
+	Stream      string
+	Subscribers int
+	// Messages is the total number of messages broadcasted to the stream
+	Messages uint64
+	// Bytes is the total number of bytes fanned out to subscribers (message size multiplied by the number of recipients)
+	Bytes uint64
+}
+
+type streamCounters struct {
+	messages uint64
+	bytes    uint64
+}
+
+func (c *streamCounters) track(size int, deliveries int) {
+	c.messages++
+	c.bytes += uint64(size) * uint64(deliveries)
+}
+
+func (c *streamCounters) toStats(stream string, subscribers int) *StreamStats {
+	stats := &StreamStats{Stream: stream, Subscribers: subscribers}
+
+	if c != nil {
+		stats.Messages = c.messages
+		stats.Bytes = c.bytes
+	}
+
+	return stats
+}
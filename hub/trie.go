@@ -0,0 +1,135 @@
+package hub
+
+import (
+	"strings"
+
+	"github.com/anycable/anycable-go/utils"
+)
+
+type trieNode struct {
+	children map[string]*trieNode
+	// pattern is set for nodes terminating a pattern
+	pattern string
+}
+
+func newTrieNode() *trieNode {
+	return &trieNode{children: make(map[string]*trieNode)}
+}
+
+// streamsTrie stores wildcard stream patterns split into tokens by the delimiter,
+// so matching a stream name takes O(tokens) steps (not depending on the number of patterns)
+type streamsTrie struct {
+	delim string
+	root  *trieNode
+	size  int
+}
+
+func newStreamsTrie(delim byte) *streamsTrie {
+	return &streamsTrie{delim: string(delim), root: newTrieNode()}
+}
+
+// Insert adds a pattern to the trie. Returns false if the pattern is already present.
+func (t *streamsTrie) Insert(pattern string) bool {
+	node := t.root
+
+	for _, token := range strings.Split(pattern, t.delim) {
+		child, ok := node.children[token]
+
+		if !ok {
+			child = newTrieNode()
+			node.children[token] = child
+		}
+
+		node = child
+	}
+
+	if node.pattern != "" {
+		return false
+	}
+
+	node.pattern = pattern
+	t.size++
+
+	return true
+}
+
+// Remove deletes a pattern from the trie and prunes empty branches
+func (t *streamsTrie) Remove(pattern string) bool {
+	tokens := strings.Split(pattern, t.delim)
+	path := make([]*trieNode, 0, len(tokens)+1)
+
+	node := t.root
+	path = append(path, node)
+
+	for _, token := range tokens {
+		child, ok := node.children[token]
+
+		if !ok {
+			return false
+		}
+
+		node = child
+		path = append(path, node)
+	}
+
+	if node.pattern == "" {
+		return false
+	}
+
+	node.pattern = ""
+	t.size--
+
+	for i := len(tokens) - 1; i >= 0; i-- {
+		child := path[i+1]
+
+		if child.pattern != "" || len(child.children) > 0 {
+			break
+		}
+
+		delete(path[i].children, tokens[i])
+	}
+
+	return true
+}
+
+// Match returns all the patterns matching the stream name
+func (t *streamsTrie) Match(stream string) []string {
+	if t.size == 0 {
+		return nil
+	}
+
+	res := []string{}
+
+	t.match(t.root, strings.Split(stream, t.delim), &res)
+
+	return res
+}
+
+func (t *streamsTrie) match(node *trieNode, tokens []string, res *[]string) {
+	if len(tokens) == 0 {
+		if node.pattern != "" {
+			*res = append(*res, node.pattern)
+		}
+
+		return
+	}
+
+	if child, ok := node.children[tokens[0]]; ok {
+		t.match(child, tokens[1:], res)
+	}
+
+	if child, ok := node.children[utils.StreamWildcard]; ok && tokens[0] != utils.StreamWildcard {
+		t.match(child, tokens[1:], res)
+	}
+
+	// Tail wildcard matches the rest of the tokens (one or more);
+	// a single literal ">" token has been already matched above
+	if child, ok := node.children[utils.StreamTailWildcard]; ok && child.pattern != "" && (len(tokens) > 1 || tokens[0] != utils.StreamTailWildcard) {
+		*res = append(*res, child.pattern)
+	}
+}
+
+// Size returns the number of patterns in the trie
+func (t *streamsTrie) Size() int {
+	return t.size
+}
@@ -0,0 +1,41 @@
+package hub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamsTrie(t *testing.T) {
+	trie := newStreamsTrie(':')
+
+	assert.True(t, trie.Insert("project:42:*"))
+	assert.True(t, trie.Insert("project:*:tasks"))
+	assert.True(t, trie.Insert("project:>"))
+	assert.True(t, trie.Insert("chat:*:messages:>"))
+	assert.False(t, trie.Insert("project:42:*"))
+
+	assert.Equal(t, 4, trie.Size())
+
+	assert.ElementsMatch(t, []string{"project:42:*", "project:*:tasks", "project:>"}, trie.Match("project:42:tasks"))
+	assert.ElementsMatch(t, []string{"project:42:*", "project:>"}, trie.Match("project:42:comments"))
+	assert.ElementsMatch(t, []string{"project:>"}, trie.Match("project:42:tasks:1"))
+	assert.ElementsMatch(t, []string{"chat:*:messages:>"}, trie.Match("chat:1:messages:2:3"))
+	assert.Empty(t, trie.Match("chat:1:messages"))
+	assert.Empty(t, trie.Match("project"))
+	assert.Empty(t, trie.Match("notifications"))
+
+	assert.True(t, trie.Remove("project:>"))
+	assert.False(t, trie.Remove("project:>"))
+	assert.False(t, trie.Remove("project:1:*"))
+
+	assert.ElementsMatch(t, []string{"project:42:*", "project:*:tasks"}, trie.Match("project:42:tasks"))
+	assert.Empty(t, trie.Match("project:42:tasks:1"))
+
+	assert.True(t, trie.Remove("project:42:*"))
+	assert.True(t, trie.Remove("project:*:tasks"))
+	assert.True(t, trie.Remove("chat:*:messages:>"))
+
+	assert.Equal(t, 0, trie.Size())
+	assert.Empty(t, trie.root.children)
+}
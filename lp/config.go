@@ -0,0 +1,72 @@
+package lp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Long polling configuration
+type Config struct {
+	Enabled bool `toml:"enabled"`
+	// Path is the URL path to handle long polling requests
+	Path string `toml:"path"`
+	// For how long to hold a poll request if there are no messages for the client (seconds)
+	PollInterval int `toml:"interval"`
+	// For how long to buffer server-to-client messages before responding to the poll request (milliseconds)
+	FlushInterval int `toml:"flush_interval"`
+	// The max size of the request body (bytes)
+	MaxRequestSize int64 `toml:"max_request_size"`
+	// For how long to keep a session alive between poll requests (seconds)
+	KeepaliveTimeout int `toml:"keepalive_timeout"`
+	// The max number of messages to buffer for a client between poll requests
+	MaxBufferSize int `toml:"max_buffer_size"`
+	// Allowed origins for CORS (inherited from the server configuration)
+	AllowedOrigins string `toml:"-"`
+}
+
+// NewConfig creates a new Config with default values.
+func NewConfig() Config {
+	return Config{
+		Enabled:          false,
+		Path:             "/lp",
+		PollInterval:     15,
+		FlushInterval:    500,
+		MaxRequestSize:   65536, // 64 kB
+		KeepaliveTimeout: 5,
+		MaxBufferSize:    1024,
+	}
+}
+
+// ToToml converts the Config struct to a TOML string representation
+func (c Config) ToToml() string {
+	var result strings.Builder
+
+	result.WriteString("# Enable long polling support\n")
+	if c.Enabled {
+		result.WriteString("enabled = true\n")
+	} else {
+		result.WriteString("# enabled = true\n")
+	}
+
+	result.WriteString("# Long polling endpoint path\n")
+	result.WriteString(fmt.Sprintf("path = \"%s\"\n", c.Path))
+
+	result.WriteString("# For how long to wait for messages before responding to a poll request (seconds)\n")
+	result.WriteString(fmt.Sprintf("interval = %d\n", c.PollInterval))
+
+	result.WriteString("# For how long to buffer messages before flushing them to the client (milliseconds)\n")
+	result.WriteString(fmt.Sprintf("flush_interval = %d\n", c.FlushInterval))
+
+	result.WriteString("# The max size of a request body (bytes)\n")
+	result.WriteString(fmt.Sprintf("max_request_size = %d\n", c.MaxRequestSize))
+
+	result.WriteString("# For how long to keep a poll session alive between requests (seconds)\n")
+	result.WriteString(fmt.Sprintf("keepalive_timeout = %d\n", c.KeepaliveTimeout))
+
+	result.WriteString("# The max number of messages to buffer between poll requests (the client is disconnected when exceeded)\n")
+	result.WriteString(fmt.Sprintf("max_buffer_size = %d\n", c.MaxBufferSize))
+
+	result.WriteString("\n")
+
+	return result.String()
+}
@@ -0,0 +1,128 @@
+package lp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/anycable/anycable-go/node"
+)
+
+var ErrBufferOverflow = errors.New("long polling buffer overflow")
+
+// Connection buffers server-to-client messages between poll requests
+type Connection struct {
+	buffer  [][]byte
+	maxSize int
+
+	// ready is notified when new messages are added to the buffer
+	ready chan struct{}
+
+	ctx      context.Context
+	cancelFn context.CancelFunc
+
+	done bool
+
+	mu sync.Mutex
+}
+
+var _ node.Connection = (*Connection)(nil)
+
+// NewConnection creates a new long polling connection with the specified buffer size limit (0 means no limit)
+func NewConnection(maxSize int) *Connection {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Connection{
+		buffer:   make([][]byte, 0),
+		maxSize:  maxSize,
+		ready:    make(chan struct{}, 1),
+		ctx:      ctx,
+		cancelFn: cancel,
+	}
+}
+
+func (c *Connection) Read() ([]byte, error) {
+	return nil, errors.New("unsupported")
+}
+
+func (c *Connection) Write(msg []byte, deadline time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done {
+		return nil
+	}
+
+	if c.maxSize > 0 && len(c.buffer) >= c.maxSize {
+		return ErrBufferOverflow
+	}
+
+	// Message could be reused by the caller, so we must copy it
+	buf := make([]byte, len(msg))
+	copy(buf, msg)
+
+	c.buffer = append(c.buffer, buf)
+
+	select {
+	case c.ready <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (c *Connection) WriteBinary(msg []byte, deadline time.Time) error {
+	return errors.New("unsupported")
+}
+
+// Close marks the connection as closed, pending messages could still be flushed
+func (c *Connection) Close(code int, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done {
+		return
+	}
+
+	c.done = true
+
+	c.cancelFn()
+}
+
+// Context is done when the connection is closed
+func (c *Connection) Context() context.Context {
+	return c.ctx
+}
+
+// Ready returns a channel notified when new messages are available
+func (c *Connection) Ready() <-chan struct{} {
+	return c.ready
+}
+
+// Flush returns all the buffered messages and resets the buffer
+func (c *Connection) Flush() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	messages := c.buffer
+	c.buffer = make([][]byte, 0)
+
+	return messages
+}
+
+// Pending returns the number of buffered messages
+func (c *Connection) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.buffer)
+}
+
+// IsClosed returns true if the connection has been closed
+func (c *Connection) IsClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.done
+}
@@ -0,0 +1,57 @@
+package lp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnection_Write(t *testing.T) {
+	c := NewConnection(2)
+
+	require.NoError(t, c.Write([]byte("hello"), time.Now()))
+
+	select {
+	case <-c.Ready():
+	default:
+		assert.Fail(t, "ready notification expected")
+	}
+
+	require.NoError(t, c.Write([]byte("world"), time.Now()))
+
+	assert.Equal(t, 2, c.Pending())
+
+	t.Run("when buffer is full", func(t *testing.T) {
+		err := c.Write([]byte("overflow"), time.Now())
+		assert.ErrorIs(t, err, ErrBufferOverflow)
+	})
+
+	messages := c.Flush()
+	assert.Equal(t, [][]byte{[]byte("hello"), []byte("world")}, messages)
+	assert.Equal(t, 0, c.Pending())
+}
+
+func TestConnection_Close(t *testing.T) {
+	c := NewConnection(0)
+
+	require.NoError(t, c.Write([]byte("bye"), time.Now()))
+
+	c.Close(1000, "Closed")
+
+	select {
+	case <-c.Context().Done():
+	default:
+		assert.Fail(t, "context must be done")
+	}
+
+	assert.True(t, c.IsClosed())
+
+	// Pending messages could be flushed, but no new messages are accepted
+	require.NoError(t, c.Write([]byte("ignored"), time.Now()))
+	assert.Equal(t, [][]byte{[]byte("bye")}, c.Flush())
+
+	// Calling close twice is fine
+	c.Close(1000, "Closed")
+}
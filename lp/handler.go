@@ -0,0 +1,250 @@
+package lp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/node"
+	"github.com/anycable/anycable-go/server"
+	"github.com/anycable/anycable-go/version"
+	"github.com/anycable/anycable-go/ws"
+
+	nanoid "github.com/matoous/go-nanoid"
+)
+
+const pollIDHeader = "X-AnyCable-Poll-ID"
+
+// LongPollingHandler generates a new http handler for long polling connections
+func LongPollingHandler(n *node.Node, shutdownCtx context.Context, headersExtractor server.HeadersExtractor, config *Config, l *slog.Logger) http.Handler {
+	var allowedHosts []string
+
+	if config.AllowedOrigins == "" {
+		allowedHosts = []string{}
+	} else {
+		allowedHosts = strings.Split(config.AllowedOrigins, ",")
+	}
+
+	poller := NewPoller(n, config, l)
+	go poller.Run(shutdownCtx)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Write CORS headers
+		server.WriteCORSHeaders(w, r, allowedHosts)
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", w.Header().Get("Access-Control-Allow-Headers")+", "+pollIDHeader)
+		w.Header().Set("Access-Control-Expose-Headers", pollIDHeader)
+
+		// Respond to preflight requests
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("X-AnyCable-Version", version.Version())
+		w.Header().Set("Content-Type", "application/jsonl; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "private, no-cache, no-store, must-revalidate, max-age=0") // HTTP 1.1
+		w.Header().Set("Pragma", "no-cache")                                                       // HTTP 1.0
+		w.Header().Set("Expire", "0")
+
+		id := r.Header.Get(pollIDHeader)
+
+		if id == "" {
+			// New sessions could only be created via POST requests
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			poller.connect(w, r, headersExtractor)
+			return
+		}
+
+		poller.poll(w, r, id)
+	})
+}
+
+// connect authenticates a new session and processes the commands sent along the request
+func (p *Poller) connect(w http.ResponseWriter, r *http.Request, headersExtractor server.HeadersExtractor) {
+	info, err := server.NewRequestInfo(r, headersExtractor)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	sessionCtx := p.log.With("sid", info.UID)
+
+	commands, err := p.readCommands(w, r)
+	if err != nil {
+		sessionCtx.Debug("failed to read commands", "error", err)
+		writeRequestError(w, err)
+		return
+	}
+
+	conn := NewConnection(p.config.MaxBufferSize)
+	// Responses act as heartbeats, so we don't need pings
+	session := node.NewSession(p.node, conn, info.URL, info.Headers, info.UID, node.WithPingInterval(0))
+
+	res, err := p.node.Authenticate(session)
+
+	if err != nil {
+		sessionCtx.Error("failed to authenticate session", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if res.Status != common.SUCCESS {
+		sessionCtx.Debug("authentication failed")
+		writeMessages(w, http.StatusUnauthorized, p.collect(r.Context(), conn, time.Duration(p.config.FlushInterval)*time.Millisecond))
+		return
+	}
+
+	id, err := nanoid.Nanoid()
+	if err != nil {
+		sessionCtx.Error("failed to generate poll ID", "error", err)
+		session.DisconnectNow("Closed", ws.CloseInternalServerErr)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ps := newPollSession(id, session, conn)
+	p.add(ps)
+
+	sessionCtx.Debug("session established")
+
+	p.handleCommands(ps, commands)
+
+	w.Header().Set(pollIDHeader, id)
+
+	if !ps.acquire() {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	defer ps.release()
+
+	p.respond(w, r, ps, time.Duration(p.config.FlushInterval)*time.Millisecond)
+}
+
+// poll processes the commands (if any) and waits for the messages for the existing session
+func (p *Poller) poll(w http.ResponseWriter, r *http.Request, id string) {
+	ps := p.get(id)
+
+	if ps == nil {
+		p.metrics.CounterIncrement(metricsStaleRequests)
+		p.log.Debug("stale poll request", "poll_id", id)
+
+		msg, _ := json.Marshal(common.NewDisconnectMessage(common.SESSION_EXPIRED_REASON, true)) // nolint:errchkjson
+		writeMessages(w, http.StatusUnauthorized, [][]byte{msg})
+		return
+	}
+
+	ps.touch()
+
+	commands, err := p.readCommands(w, r)
+	if err != nil {
+		ps.session.Log.Debug("failed to read commands", "error", err)
+		writeRequestError(w, err)
+		return
+	}
+
+	p.handleCommands(ps, commands)
+
+	if !ps.acquire() {
+		// Only a single poll request could wait for messages at a time;
+		// commands could be sent via POST requests concurrently
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusConflict)
+		}
+		return
+	}
+
+	defer ps.release()
+
+	p.respond(w, r, ps, time.Duration(p.config.PollInterval)*time.Second)
+}
+
+func (p *Poller) respond(w http.ResponseWriter, r *http.Request, ps *pollSession, timeout time.Duration) {
+	messages := p.collect(r.Context(), ps.conn, timeout)
+
+	// Session has been closed, and the client has received all the pending messages
+	if ps.conn.IsClosed() && ps.conn.Pending() == 0 {
+		p.remove(ps.id)
+	}
+
+	writeMessages(w, http.StatusOK, messages)
+}
+
+func (p *Poller) handleCommands(ps *pollSession, commands [][]byte) {
+	for _, cmd := range commands {
+		if err := ps.session.ReadMessage(cmd); err != nil {
+			ps.session.Log.Debug("failed to process command", "error", err)
+		}
+	}
+}
+
+var errRequestTooLarge = errors.New("request body is too large")
+
+// readCommands reads JSONL-encoded commands from the request body
+func (p *Poller) readCommands(w http.ResponseWriter, r *http.Request) ([][]byte, error) {
+	if r.Method != http.MethodPost || r.Body == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, p.config.MaxRequestSize))
+
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, errRequestTooLarge
+		}
+
+		return nil, err
+	}
+
+	commands := make([][]byte, 0)
+
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+
+		if len(line) == 0 {
+			continue
+		}
+
+		commands = append(commands, line)
+	}
+
+	return commands, nil
+}
+
+func writeRequestError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errRequestTooLarge) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	} else {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func writeMessages(w http.ResponseWriter, status int, messages [][]byte) {
+	w.WriteHeader(status)
+
+	for _, msg := range messages {
+		w.Write(msg)          // nolint:errcheck
+		w.Write([]byte("\n")) // nolint:errcheck
+	}
+}
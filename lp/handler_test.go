@@ -0,0 +1,339 @@
+package lp
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anycable/anycable-go/broker"
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/metrics"
+	"github.com/anycable/anycable-go/mocks"
+	"github.com/anycable/anycable-go/node"
+	"github.com/anycable/anycable-go/pubsub"
+	"github.com/anycable/anycable-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLongPollingHandler(t *testing.T) {
+	appNode, controller := buildNode()
+
+	go appNode.Start()                           // nolint: errcheck
+	defer appNode.Shutdown(context.Background()) // nolint: errcheck
+
+	conf := NewConfig()
+	conf.PollInterval = 1
+	conf.FlushInterval = 50
+	conf.KeepaliveTimeout = 1
+	conf.MaxRequestSize = 1024
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler := LongPollingHandler(appNode, ctx, &server.DefaultHeadersExtractor{}, &conf, slog.Default())
+
+	controller.
+		On("Shutdown").
+		Return(nil)
+
+	controller.
+		On("Disconnect", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	t.Run("OPTIONS", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("OPTIONS", "/", nil)
+
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "GET, POST, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "X-AnyCable-Poll-ID")
+		assert.Equal(t, "X-AnyCable-Poll-ID", w.Header().Get("Access-Control-Expose-Headers"))
+	})
+
+	t.Run("non-GET/OPTIONS/POST", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/", nil)
+
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+
+	t.Run("GET without poll ID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("when authentication fails", func(t *testing.T) {
+		defer assertNoSessions(t, appNode)
+
+		controller.
+			On("Authenticate", "sid-fail", mock.Anything).
+			Return(&common.ConnectResult{
+				Status:        common.FAILURE,
+				Transmissions: []string{`{"type":"disconnect","reason":"unauthorized"}`},
+			}, nil)
+
+		req, _ := http.NewRequest("POST", "/", nil)
+		req.Header.Set("X-Request-ID", "sid-fail")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, w.Header().Get("X-AnyCable-Poll-ID"))
+		assert.Equal(t, `{"type":"disconnect","reason":"unauthorized"}`+"\n", w.Body.String())
+	})
+
+	t.Run("when request is too large", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/", strings.NewReader(strings.Repeat("x", 2048)))
+		req.Header.Set("X-Request-ID", "sid-large")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("with unknown poll ID", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("X-AnyCable-Poll-ID", "unknown")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, `{"type":"disconnect","reason":"session_expired","reconnect":true}`+"\n", w.Body.String())
+		assert.Equal(t, uint64(1), appNode.Instrumenter().(*metrics.Metrics).Counter(metricsStaleRequests).Value())
+	})
+
+	t.Run("connect, subscribe, poll and expire", func(t *testing.T) {
+		controller.
+			On("Authenticate", "sid-poll", mock.Anything).
+			Return(&common.ConnectResult{
+				Identifier:    "se2023",
+				Status:        common.SUCCESS,
+				Transmissions: []string{`{"type":"welcome"}`},
+			}, nil)
+
+		controller.
+			On("Subscribe", "sid-poll", mock.Anything, "se2023", "chat_1").
+			Return(&common.CommandResult{
+				Status:        common.SUCCESS,
+				Transmissions: []string{`{"type":"confirm_subscription","identifier":"chat_1"}`},
+				Streams:       []string{"messages_1"},
+			}, nil)
+
+		body := `{"command":"subscribe","identifier":"chat_1"}` + "\n"
+		req, _ := http.NewRequest("POST", "/", strings.NewReader(body))
+		req.Header.Set("X-Request-ID", "sid-poll")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+
+		pollID := w.Header().Get("X-AnyCable-Poll-ID")
+		require.NotEmpty(t, pollID)
+
+		assert.Equal(
+			t,
+			`{"type":"welcome"}`+"\n"+`{"type":"confirm_subscription","identifier":"chat_1"}`+"\n",
+			w.Body.String(),
+		)
+
+		assert.Equal(t, uint64(1), appNode.Instrumenter().(*metrics.Metrics).Gauge(metricsClientsNum).Value())
+
+		// Poll without messages
+		req, _ = http.NewRequest("GET", "/", nil)
+		req.Header.Set("X-AnyCable-Poll-ID", pollID)
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Body.String())
+
+		// Poll with a broadcast
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			appNode.Broadcast(&common.StreamMessage{Stream: "messages_1", Data: `{"content":"hello"}`})
+		}()
+
+		req, _ = http.NewRequest("GET", "/", nil)
+		req.Header.Set("X-AnyCable-Poll-ID", pollID)
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `{"identifier":"chat_1","message":{"content":"hello"}}`+"\n", w.Body.String())
+
+		// Messages are buffered between polls
+		appNode.Broadcast(&common.StreamMessage{Stream: "messages_1", Data: `{"content":"one"}`})
+		appNode.Broadcast(&common.StreamMessage{Stream: "messages_1", Data: `{"content":"two"}`})
+
+		time.Sleep(100 * time.Millisecond)
+
+		req, _ = http.NewRequest("GET", "/", nil)
+		req.Header.Set("X-AnyCable-Poll-ID", pollID)
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(
+			t,
+			`{"identifier":"chat_1","message":{"content":"one"}}`+"\n"+`{"identifier":"chat_1","message":{"content":"two"}}`+"\n",
+			w.Body.String(),
+		)
+
+		// The session must expire if not polled
+		assertNoSessions(t, appNode)
+
+		req, _ = http.NewRequest("GET", "/", nil)
+		req.Header.Set("X-AnyCable-Poll-ID", pollID)
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, uint64(0), appNode.Instrumenter().(*metrics.Metrics).Gauge(metricsClientsNum).Value())
+	})
+}
+
+func TestPollerBufferOverflow(t *testing.T) {
+	appNode, controller := buildNode()
+
+	go appNode.Start()                           // nolint: errcheck
+	defer appNode.Shutdown(context.Background()) // nolint: errcheck
+
+	conf := NewConfig()
+	conf.FlushInterval = 50
+	conf.MaxBufferSize = 2
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler := LongPollingHandler(appNode, ctx, &server.DefaultHeadersExtractor{}, &conf, slog.Default())
+
+	controller.
+		On("Shutdown").
+		Return(nil)
+
+	controller.
+		On("Disconnect", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	controller.
+		On("Authenticate", "sid-overflow", mock.Anything).
+		Return(&common.ConnectResult{
+			Identifier:    "se2023",
+			Status:        common.SUCCESS,
+			Transmissions: []string{`{"type":"welcome"}`},
+		}, nil)
+
+	controller.
+		On("Subscribe", "sid-overflow", mock.Anything, "se2023", "chat_1").
+		Return(&common.CommandResult{
+			Status:        common.SUCCESS,
+			Transmissions: []string{`{"type":"confirm_subscription","identifier":"chat_1"}`},
+			Streams:       []string{"messages_1"},
+		}, nil)
+
+	body := `{"command":"subscribe","identifier":"chat_1"}` + "\n"
+	req, _ := http.NewRequest("POST", "/", strings.NewReader(body))
+	req.Header.Set("X-Request-ID", "sid-overflow")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	pollID := w.Header().Get("X-AnyCable-Poll-ID")
+
+	for i := 0; i < 3; i++ {
+		appNode.Broadcast(&common.StreamMessage{Stream: "messages_1", Data: `{"content":"hello"}`})
+	}
+
+	// Overflowing client is disconnected
+	assertNoSessions(t, appNode)
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("X-AnyCable-Poll-ID", pollID)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	// Buffered messages are delivered
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, strings.Count(w.Body.String(), "\n"))
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("X-AnyCable-Poll-ID", pollID)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// This a helper method to ensure no sessions left after test (so no global state is left).
+// Session may be removed from the hub asynchrounously, so we need to wait for it.
+func assertNoSessions(t *testing.T, n *node.Node) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			if n.Size() == 0 {
+				close(done)
+				return
+			}
+
+			time.Sleep(100 * time.Millisecond)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		require.Fail(t, "Timeout waiting for sessions to be removed")
+	case <-done:
+	}
+}
+
+type immediateDisconnector struct {
+	n *node.Node
+}
+
+func (d *immediateDisconnector) Enqueue(s *node.Session) error {
+	return d.n.DisconnectNow(s)
+}
+
+func (immediateDisconnector) Run() error                         { return nil }
+func (immediateDisconnector) Shutdown(ctx context.Context) error { return nil }
+func (immediateDisconnector) Size() int                          { return 0 }
+
+func buildNode() (*node.Node, *mocks.Controller) {
+	controller := &mocks.Controller{}
+	config := node.NewConfig()
+	config.HubGopoolSize = 2
+	n := node.NewNode(&config, node.WithController(controller), node.WithInstrumenter(metrics.NewMetrics(nil, 10, slog.Default())))
+	n.SetBroker(broker.NewLegacyBroker(pubsub.NewLegacySubscriber(n)))
+	n.SetDisconnector(&immediateDisconnector{n})
+	return n, controller
+}
@@ -0,0 +1,232 @@
+package lp
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/metrics"
+	"github.com/anycable/anycable-go/node"
+	"github.com/anycable/anycable-go/ws"
+)
+
+const (
+	metricsClientsNum    = "long_poll_clients_num"
+	metricsStaleRequests = "long_poll_stale_requests_total"
+)
+
+// pollSession wraps a node session with the long polling state
+type pollSession struct {
+	id      string
+	session *node.Session
+	conn    *Connection
+
+	mu           sync.Mutex
+	polling      bool
+	lastActivity time.Time
+}
+
+func newPollSession(id string, session *node.Session, conn *Connection) *pollSession {
+	return &pollSession{id: id, session: session, conn: conn, lastActivity: time.Now()}
+}
+
+// acquire marks the session as being polled.
+// Returns false if there is another poll request in progress.
+func (ps *pollSession) acquire() bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.polling {
+		return false
+	}
+
+	ps.polling = true
+	ps.lastActivity = time.Now()
+
+	return true
+}
+
+func (ps *pollSession) release() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.polling = false
+	ps.lastActivity = time.Now()
+}
+
+func (ps *pollSession) touch() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.lastActivity = time.Now()
+}
+
+// isIdle returns true if the session is not being polled and hasn't been active since the deadline
+func (ps *pollSession) isIdle(deadline time.Time) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	return !ps.polling && ps.lastActivity.Before(deadline)
+}
+
+// Poller keeps track of long polling sessions and expires idle ones
+type Poller struct {
+	node    *node.Node
+	config  *Config
+	metrics metrics.Instrumenter
+
+	sessions map[string]*pollSession
+	mu       sync.RWMutex
+
+	log *slog.Logger
+}
+
+// NewPoller creates a new Poller for the node
+func NewPoller(n *node.Node, config *Config, l *slog.Logger) *Poller {
+	p := &Poller{
+		node:     n,
+		config:   config,
+		metrics:  n.Instrumenter(),
+		sessions: make(map[string]*pollSession),
+		log:      l.With("context", "long_polling"),
+	}
+
+	p.metrics.RegisterGauge(metricsClientsNum, "The number of active long polling clients")
+	p.metrics.RegisterCounter(metricsStaleRequests, "The total number of requests for expired long polling sessions")
+
+	return p
+}
+
+// Run checks for idle sessions periodically until the context is done.
+// When the context is done, all the sessions are disconnected.
+func (p *Poller) Run(ctx context.Context) {
+	interval := time.Duration(p.config.KeepaliveTimeout) * time.Second / 2
+
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.Shutdown()
+			return
+		case now := <-ticker.C:
+			p.expire(now)
+		}
+	}
+}
+
+// Shutdown disconnects all the sessions
+func (p *Poller) Shutdown() {
+	p.mu.RLock()
+	sessions := make([]*pollSession, 0, len(p.sessions))
+	for _, ps := range p.sessions {
+		sessions = append(sessions, ps)
+	}
+	p.mu.RUnlock()
+
+	for _, ps := range sessions {
+		ps.session.DisconnectWithMessage(
+			common.NewDisconnectMessage(common.SERVER_RESTART_REASON, true),
+			common.SERVER_RESTART_REASON,
+		)
+	}
+}
+
+// Size returns the number of tracked sessions
+func (p *Poller) Size() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return len(p.sessions)
+}
+
+func (p *Poller) add(ps *pollSession) {
+	p.mu.Lock()
+	p.sessions[ps.id] = ps
+	size := len(p.sessions)
+	p.mu.Unlock()
+
+	p.metrics.GaugeSet(metricsClientsNum, uint64(size))
+}
+
+func (p *Poller) get(id string) *pollSession {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.sessions[id]
+}
+
+func (p *Poller) remove(id string) {
+	p.mu.Lock()
+	delete(p.sessions, id)
+	size := len(p.sessions)
+	p.mu.Unlock()
+
+	p.metrics.GaugeSet(metricsClientsNum, uint64(size))
+}
+
+// expire disconnects and removes sessions which haven't been polled within the keepalive timeout
+func (p *Poller) expire(now time.Time) {
+	deadline := now.Add(-time.Duration(p.config.KeepaliveTimeout) * time.Second)
+
+	p.mu.RLock()
+	idle := make([]*pollSession, 0)
+	for _, ps := range p.sessions {
+		if ps.isIdle(deadline) {
+			idle = append(idle, ps)
+		}
+	}
+	p.mu.RUnlock()
+
+	for _, ps := range idle {
+		p.log.Debug("session expired", "sid", ps.session.GetID())
+
+		p.remove(ps.id)
+		ps.session.DisconnectNow("Session expired", ws.CloseNormalClosure)
+	}
+}
+
+// collect waits for messages to arrive (at most for the specified timeout)
+// and returns them after the flush interval.
+// Returns nil if the request has been terminated (so the messages stay in the buffer).
+func (p *Poller) collect(ctx context.Context, conn *Connection, timeout time.Duration) [][]byte {
+	// Drain the stale notification first, so we don't miss the messages written after the check
+	select {
+	case <-conn.Ready():
+	default:
+	}
+
+	if conn.Pending() == 0 {
+		pollTimer := time.NewTimer(timeout)
+		defer pollTimer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-conn.Context().Done():
+			return conn.Flush()
+		case <-pollTimer.C:
+			return conn.Flush()
+		case <-conn.Ready():
+		}
+	}
+
+	flushTimer := time.NewTimer(time.Duration(p.config.FlushInterval) * time.Millisecond)
+	defer flushTimer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-conn.Context().Done():
+	case <-flushTimer.C:
+	}
+
+	return conn.Flush()
+}
@@ -18,6 +18,8 @@ type Config struct {
 	Port      int               `toml:"port"`
 	Tags      map[string]string `toml:"tags"`
 	Statsd    StatsdConfig      `toml:"statsd"`
+	// Number of the busiest streams to report statistics for (0 means disabled)
+	TopStreams int `toml:"top_streams"`
 }
 
 // NewConfig creates an empty Config struct
@@ -94,6 +96,13 @@ func (c Config) ToToml() string {
 		result.WriteString("# tags.key = \"value\"\n")
 	}
 
+	result.WriteString("# Number of the busiest streams to report per-stream statistics for (0 — disabled)\n")
+	if c.TopStreams > 0 {
+		result.WriteString(fmt.Sprintf("top_streams = %d\n", c.TopStreams))
+	} else {
+		result.WriteString("# top_streams = 10\n")
+	}
+
 	result.WriteString("# StatsD configuration\n")
 	var prefix = "# "
 	if c.Statsd.Enabled() {
@@ -39,6 +39,7 @@ func TestConfig_ToToml(t *testing.T) {
 	conf.Host = "example.com"
 	conf.Port = 9090
 	conf.Tags = map[string]string{"env": "prod", "region": "us-west"}
+	conf.TopStreams = 5
 
 	tomlStr := conf.ToToml()
 
@@ -49,6 +50,7 @@ func TestConfig_ToToml(t *testing.T) {
 	assert.Contains(t, tomlStr, "log_filter = [ \"metric1\", \"metric2\" ]")
 	assert.Contains(t, tomlStr, "tags.env = \"prod\"")
 	assert.Contains(t, tomlStr, "tags.region = \"us-west\"")
+	assert.Contains(t, tomlStr, "top_streams = 5")
 
 	// Round-trip test
 	conf2 := NewConfig()
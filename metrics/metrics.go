@@ -42,6 +42,7 @@ type Metrics struct {
 	tags           map[string]string
 	counters       map[string]*Counter
 	gauges         map[string]*Gauge
+	streams        streamsTracker
 	shutdownCh     chan struct{}
 	closed         bool
 	log            *slog.Logger
@@ -77,6 +78,8 @@ func NewFromConfig(config *Config, l *slog.Logger) (*Metrics, error) {
 		instance.tags = config.Tags
 	}
 
+	instance.streams.limit = config.TopStreams
+
 	if config.HTTPEnabled() {
 		if config.Host != "" && config.Host != server.Host {
 			srv, err := server.NewServer(config.Host, strconv.Itoa(config.Port), server.SSL, 0)
@@ -94,6 +97,10 @@ func NewFromConfig(config *Config, l *slog.Logger) (*Metrics, error) {
 
 		instance.httpPath = config.HTTP
 		instance.server.SetupHandler(instance.httpPath, http.HandlerFunc(instance.PrometheusHandler))
+
+		if config.TopStreams > 0 {
+			instance.server.SetupHandler(instance.httpPath+"/streams", http.HandlerFunc(instance.StreamsHandler))
+		}
 	}
 
 	return instance, nil
@@ -133,7 +140,12 @@ func (m *Metrics) Run() error {
 		}
 	}
 
-	if len(m.writers) == 0 {
+	m.mu.RLock()
+	streamsEnabled := m.streamsStatsEnabled()
+	m.mu.RUnlock()
+
+	// Streams stats must be rotated to calculate rates even if there are no writers
+	if len(m.writers) == 0 && !streamsEnabled {
 		m.log.Debug("no metrics writers, disabling metrics rotation")
 		return nil
 	}
@@ -285,4 +297,6 @@ func (m *Metrics) rotate() {
 	for _, c := range m.counters {
 		c.UpdateDelta()
 	}
+
+	m.rotateStreams(time.Now())
 }
@@ -51,9 +51,30 @@ func (p *BasePrinter) Stop() {
 func (p *BasePrinter) Write(m *Metrics) error {
 	snapshot := m.IntervalSnapshot()
 	p.Print(snapshot)
+	p.PrintStreams(m.TopStreams())
 	return nil
 }
 
+// PrintStreams logs the busiest streams stats
+func (p *BasePrinter) PrintStreams(streams []*StreamStats) {
+	if len(streams) == 0 {
+		return
+	}
+
+	fields := make([]interface{}, 0, len(streams))
+
+	for _, s := range streams {
+		fields = append(fields, slog.Group(
+			s.Stream,
+			"subscribers", s.Subscribers,
+			"messages_per_second", s.MessagesRate,
+			"bytes_per_second", s.BytesRate,
+		))
+	}
+
+	p.log.Info("top streams", fields...)
+}
+
 // Print logs stats data using global logger with info level
 func (p *BasePrinter) Print(snapshot map[string]uint64) {
 	// Sort keys to provide deterministic output
@@ -37,6 +37,8 @@ func (m *Metrics) Prometheus() string {
 		buf.WriteString(name + tags + " " + strconv.FormatUint(gauge.Value(), 10) + "\n")
 	})
 
+	m.prometheusStreams(&buf)
+
 	return buf.String()
 }
 
@@ -62,3 +64,18 @@ func toPromTags(tags map[string]string) string {
 
 	return fmt.Sprintf("{%s}", strings.Join(buf, ", "))
 }
+
+// toPromTagsWith returns tags with an additional label (which value is escaped)
+func toPromTagsWith(tags map[string]string, key string, value string) string {
+	buf := make([]string, 0, len(tags)+1)
+
+	for k, v := range tags {
+		buf = append(buf, fmt.Sprintf("%s=\"%s\"", k, v))
+	}
+
+	buf = append(buf, fmt.Sprintf("%s=\"%s\"", key, promLabelEscaper.Replace(value)))
+
+	return fmt.Sprintf("{%s}", strings.Join(buf, ", "))
+}
+
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
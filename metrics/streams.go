@@ -0,0 +1,184 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StreamStats contains delivery statistics for a stream
+type StreamStats struct {
+	Stream      string `json:"stream"`
+	Subscribers uint64 `json:"subscribers"`
+	// Messages is the total number of messages broadcasted to the stream
+	Messages uint64 `json:"messages"`
+	// Bytes is the total number of bytes fanned out to the stream subscribers
+	Bytes uint64 `json:"bytes"`
+	// Rates are calculated for the last rotation interval
+	MessagesRate float64 `json:"messages_per_second"`
+	BytesRate    float64 `json:"bytes_per_second"`
+}
+
+// StreamsStatsProvider returns the current (cumulative) statistics for all active streams
+type StreamsStatsProvider func() []*StreamStats
+
+// StreamsStatsRegistry is implemented by instrumenters supporting per-stream statistics
+type StreamsStatsRegistry interface {
+	RegisterStreamsStats(provider StreamsStatsProvider)
+	// TopStreamsEnabled returns true if per-stream statistics must be collected
+	TopStreamsEnabled() bool
+}
+
+var _ StreamsStatsRegistry = (*Metrics)(nil)
+
+type streamsTracker struct {
+	provider StreamsStatsProvider
+	limit    int
+
+	// Totals from the previous rotation used to calculate rates
+	prev    map[string]*StreamStats
+	prevAt  time.Time
+	top     []*StreamStats
+	rotated bool
+}
+
+// RegisterStreamsStats sets the per-stream statistics provider.
+// Statistics are only collected if the top streams limit is configured.
+func (m *Metrics) RegisterStreamsStats(provider StreamsStatsProvider) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.streams.provider = provider
+}
+
+// TopStreamsEnabled returns true if the top streams limit is configured
+func (m *Metrics) TopStreamsEnabled() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.streams.limit > 0
+}
+
+// TopStreams returns the statistics for the busiest streams (calculated during the latest rotation)
+func (m *Metrics) TopStreams() []*StreamStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.streams.top
+}
+
+func (m *Metrics) streamsStatsEnabled() bool {
+	return m.streams.provider != nil && m.streams.limit > 0
+}
+
+// rotateStreams calculates rates since the previous rotation and picks the top streams.
+// Must be called under the lock.
+func (m *Metrics) rotateStreams(now time.Time) {
+	if !m.streamsStatsEnabled() {
+		return
+	}
+
+	current := m.streams.provider()
+	elapsed := now.Sub(m.streams.prevAt).Seconds()
+
+	prev := make(map[string]*StreamStats, len(current))
+
+	for _, stats := range current {
+		prev[stats.Stream] = &StreamStats{Stream: stats.Stream, Messages: stats.Messages, Bytes: stats.Bytes}
+
+		if !m.streams.rotated || elapsed <= 0 {
+			continue
+		}
+
+		var prevMessages, prevBytes uint64
+
+		// Counters could be reset if a stream has been removed and re-added
+		if old, ok := m.streams.prev[stats.Stream]; ok && old.Messages <= stats.Messages && old.Bytes <= stats.Bytes {
+			prevMessages = old.Messages
+			prevBytes = old.Bytes
+		}
+
+		stats.MessagesRate = float64(stats.Messages-prevMessages) / elapsed
+		stats.BytesRate = float64(stats.Bytes-prevBytes) / elapsed
+	}
+
+	m.streams.top = topStreams(current, m.streams.limit)
+	m.streams.prev = prev
+	m.streams.prevAt = now
+	m.streams.rotated = true
+}
+
+// topStreams returns the busiest streams: the ones with the highest fan-out rate
+// (and the largest number of subscribers)
+func topStreams(list []*StreamStats, limit int) []*StreamStats {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].BytesRate != list[j].BytesRate {
+			return list[i].BytesRate > list[j].BytesRate
+		}
+
+		if list[i].Subscribers != list[j].Subscribers {
+			return list[i].Subscribers > list[j].Subscribers
+		}
+
+		return list[i].Stream < list[j].Stream
+	})
+
+	if len(list) > limit {
+		list = list[:limit]
+	}
+
+	return list
+}
+
+type streamsResponse struct {
+	Streams []*StreamStats `json:"streams"`
+}
+
+// StreamsHandler serves top streams statistics as JSON
+func (m *Metrics) StreamsHandler(w http.ResponseWriter, r *http.Request) {
+	streams := m.TopStreams()
+
+	if streams == nil {
+		streams = []*StreamStats{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(&streamsResponse{Streams: streams}); err != nil {
+		m.log.Debug("failed to write streams stats", "error", err)
+	}
+}
+
+func (m *Metrics) prometheusStreams(buf *strings.Builder) {
+	streams := m.TopStreams()
+
+	if len(streams) == 0 {
+		return
+	}
+
+	metrics := []struct {
+		name  string
+		kind  string
+		desc  string
+		value func(s *StreamStats) uint64
+	}{
+		{"stream_subscribers", "gauge", "The number of stream subscribers (top streams only)", func(s *StreamStats) uint64 { return s.Subscribers }},
+		{"stream_messages_total", "counter", "The total number of messages broadcasted to the stream (top streams only)", func(s *StreamStats) uint64 { return s.Messages }},
+		{"stream_bytes_total", "counter", "The total number of bytes fanned out to the stream subscribers (top streams only)", func(s *StreamStats) uint64 { return s.Bytes }},
+	}
+
+	for _, metric := range metrics {
+		name := prometheusNamespace + `_` + metric.name
+
+		buf.WriteString("\n# HELP " + name + " " + metric.desc + "\n")
+		buf.WriteString("# TYPE " + name + " " + metric.kind + "\n")
+
+		for _, stats := range streams {
+			tags := toPromTagsWith(m.tags, "stream", stats.Stream)
+			buf.WriteString(name + tags + " " + strconv.FormatUint(metric.value(stats), 10) + "\n")
+		}
+	}
+}
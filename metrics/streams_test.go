@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type streamsStub struct {
+	stats map[string]*StreamStats
+}
+
+func (s *streamsStub) provide() []*StreamStats {
+	res := make([]*StreamStats, 0, len(s.stats))
+
+	for _, st := range s.stats {
+		copied := *st
+		res = append(res, &copied)
+	}
+
+	return res
+}
+
+func TestStreamsStats(t *testing.T) {
+	m := NewMetrics(nil, 10, slog.Default())
+	m.streams.limit = 2
+
+	assert.True(t, m.TopStreamsEnabled())
+
+	stub := &streamsStub{stats: map[string]*StreamStats{
+		"chat":    {Stream: "chat", Subscribers: 10, Messages: 1, Bytes: 100},
+		"news":    {Stream: "news", Subscribers: 3, Messages: 1, Bytes: 30},
+		"private": {Stream: "private", Subscribers: 1},
+	}}
+
+	m.RegisterStreamsStats(stub.provide)
+
+	start := time.Now()
+
+	m.rotateStreams(start)
+
+	top := m.TopStreams()
+	require.Len(t, top, 2)
+
+	// No rates yet, sorted by subscribers
+	assert.Equal(t, "chat", top[0].Stream)
+	assert.Equal(t, "news", top[1].Stream)
+	assert.Equal(t, float64(0), top[0].BytesRate)
+
+	stub.stats["news"].Messages = 21
+	stub.stats["news"].Bytes = 630
+	stub.stats["chat"].Messages = 11
+	stub.stats["chat"].Bytes = 1100
+
+	m.rotateStreams(start.Add(10 * time.Second))
+
+	top = m.TopStreams()
+	require.Len(t, top, 2)
+
+	assert.Equal(t, "chat", top[0].Stream)
+	assert.Equal(t, float64(1), top[0].MessagesRate)
+	assert.Equal(t, float64(100), top[0].BytesRate)
+
+	assert.Equal(t, "news", top[1].Stream)
+	assert.Equal(t, float64(2), top[1].MessagesRate)
+	assert.Equal(t, float64(60), top[1].BytesRate)
+	assert.Equal(t, uint64(630), top[1].Bytes)
+
+	t.Run("prometheus", func(t *testing.T) {
+		m.tags = map[string]string{"env": "test"}
+		defer func() { m.tags = nil }()
+
+		actual := m.Prometheus()
+
+		assert.Contains(t, actual, `
+# HELP anycable_go_stream_subscribers The number of stream subscribers (top streams only)
+# TYPE anycable_go_stream_subscribers gauge
+anycable_go_stream_subscribers{env="test", stream="chat"} 10
+anycable_go_stream_subscribers{env="test", stream="news"} 3
+`)
+
+		assert.Contains(t, actual, `anycable_go_stream_bytes_total{env="test", stream="news"} 630`)
+		assert.Contains(t, actual, `anycable_go_stream_messages_total{env="test", stream="chat"} 11`)
+		assert.NotContains(t, actual, `stream="private"`)
+	})
+
+	t.Run("handler", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/metrics/streams", nil)
+		w := httptest.NewRecorder()
+
+		m.StreamsHandler(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		var resp streamsResponse
+
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Streams, 2)
+		assert.Equal(t, "chat", resp.Streams[0].Stream)
+		assert.Equal(t, float64(100), resp.Streams[0].BytesRate)
+	})
+}
+
+func TestStreamsStatsDisabled(t *testing.T) {
+	m := NewMetrics(nil, 10, slog.Default())
+
+	m.RegisterStreamsStats(func() []*StreamStats {
+		return []*StreamStats{{Stream: "chat", Subscribers: 1}}
+	})
+
+	m.rotate()
+
+	assert.False(t, m.TopStreamsEnabled())
+	assert.Empty(t, m.TopStreams())
+	assert.NotContains(t, m.Prometheus(), "anycable_go_stream_subscribers")
+
+	w := httptest.NewRecorder()
+	m.StreamsHandler(w, httptest.NewRequest("GET", "/metrics/streams", nil))
+
+	assert.JSONEq(t, `{"streams":[]}`, w.Body.String())
+}
+
+func TestToPromTagsWith(t *testing.T) {
+	assert.Equal(t, `{stream="chat:\"1\"\\2"}`, toPromTagsWith(nil, "stream", `chat:"1"\2`))
+}
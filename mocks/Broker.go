@@ -98,9 +98,16 @@ func (_m *Broker) HandleCommand(msg *common.RemoteCommandMessage) {
 	_m.Called(msg)
 }
 
-// HistoryFrom provides a mock function with given fields: stream, epoch, offset
-func (_m *Broker) HistoryFrom(stream string, epoch string, offset uint64) ([]common.StreamMessage, error) {
-	ret := _m.Called(stream, epoch, offset)
+// HistoryFrom provides a mock function with given fields: stream, epoch, offset, opts
+func (_m *Broker) HistoryFrom(stream string, epoch string, offset uint64, opts ...broker.HistoryOption) ([]common.StreamMessage, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, stream, epoch, offset)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
 
 	if len(ret) == 0 {
 		panic("no return value specified for HistoryFrom")
@@ -108,19 +115,19 @@ func (_m *Broker) HistoryFrom(stream string, epoch string, offset uint64) ([]com
 
 	var r0 []common.StreamMessage
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string, uint64) ([]common.StreamMessage, error)); ok {
-		return rf(stream, epoch, offset)
+	if rf, ok := ret.Get(0).(func(string, string, uint64, ...broker.HistoryOption) ([]common.StreamMessage, error)); ok {
+		return rf(stream, epoch, offset, opts...)
 	}
-	if rf, ok := ret.Get(0).(func(string, string, uint64) []common.StreamMessage); ok {
-		r0 = rf(stream, epoch, offset)
+	if rf, ok := ret.Get(0).(func(string, string, uint64, ...broker.HistoryOption) []common.StreamMessage); ok {
+		r0 = rf(stream, epoch, offset, opts...)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]common.StreamMessage)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string, uint64) error); ok {
-		r1 = rf(stream, epoch, offset)
+	if rf, ok := ret.Get(1).(func(string, string, uint64, ...broker.HistoryOption) error); ok {
+		r1 = rf(stream, epoch, offset, opts...)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -128,9 +135,16 @@ func (_m *Broker) HistoryFrom(stream string, epoch string, offset uint64) ([]com
 	return r0, r1
 }
 
-// HistorySince provides a mock function with given fields: stream, ts
-func (_m *Broker) HistorySince(stream string, ts int64) ([]common.StreamMessage, error) {
-	ret := _m.Called(stream, ts)
+// HistorySince provides a mock function with given fields: stream, ts, opts
+func (_m *Broker) HistorySince(stream string, ts int64, opts ...broker.HistoryOption) ([]common.StreamMessage, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, stream, ts)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
 
 	if len(ret) == 0 {
 		panic("no return value specified for HistorySince")
@@ -138,19 +152,19 @@ func (_m *Broker) HistorySince(stream string, ts int64) ([]common.StreamMessage,
 
 	var r0 []common.StreamMessage
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, int64) ([]common.StreamMessage, error)); ok {
-		return rf(stream, ts)
+	if rf, ok := ret.Get(0).(func(string, int64, ...broker.HistoryOption) ([]common.StreamMessage, error)); ok {
+		return rf(stream, ts, opts...)
 	}
-	if rf, ok := ret.Get(0).(func(string, int64) []common.StreamMessage); ok {
-		r0 = rf(stream, ts)
+	if rf, ok := ret.Get(0).(func(string, int64, ...broker.HistoryOption) []common.StreamMessage); ok {
+		r0 = rf(stream, ts, opts...)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]common.StreamMessage)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string, int64) error); ok {
-		r1 = rf(stream, ts)
+	if rf, ok := ret.Get(1).(func(string, int64, ...broker.HistoryOption) error); ok {
+		r1 = rf(stream, ts, opts...)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -255,6 +269,36 @@ func (_m *Broker) PresenceRemove(stream string, sid string) (*common.PresenceEve
 	return r0, r1
 }
 
+// PresenceUpdate provides a mock function with given fields: stream, sid, info
+func (_m *Broker) PresenceUpdate(stream string, sid string, info interface{}) (*common.PresenceEvent, error) {
+	ret := _m.Called(stream, sid, info)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PresenceUpdate")
+	}
+
+	var r0 *common.PresenceEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string, interface{}) (*common.PresenceEvent, error)); ok {
+		return rf(stream, sid, info)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, interface{}) *common.PresenceEvent); ok {
+		r0 = rf(stream, sid, info)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*common.PresenceEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, interface{}) error); ok {
+		r1 = rf(stream, sid, info)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RestoreSession provides a mock function with given fields: from
 func (_m *Broker) RestoreSession(from string) ([]byte, error) {
 	ret := _m.Called(from)
@@ -67,6 +67,11 @@ func (c *MockController) Subscribe(sid string, env *common.SessionEnv, id string
 		res.Streams = []string{"stream"}
 	}
 
+	if channel == "with_filter" {
+		res.Streams = []string{"stream"}
+		res.IState = map[string]string{common.FILTER_STATE: `{"visible":true}`}
+	}
+
 	return res, nil
 }
 
@@ -257,6 +257,7 @@ func TestIntegrationHistory_Memory(t *testing.T) {
 	node, controller := setupIntegrationNode()
 
 	bconf := broker.NewConfig()
+	bconf.Wildcards = true
 
 	subscriber := pubsub.NewLegacySubscriber(node)
 
@@ -283,6 +284,7 @@ func TestIntegrationHistory_NATS(t *testing.T) {
 	node, controller := setupIntegrationNode()
 
 	bconf := broker.NewConfig()
+	bconf.Wildcards = true
 
 	nconfig := natsconfig.NewNATSConfig()
 	nconfig.Servers = addr
@@ -303,6 +305,9 @@ func TestIntegrationHistory_NATS(t *testing.T) {
 
 func sharedIntegrationHistory(t *testing.T, node *Node, controller *mocks.Controller) {
 	node.HandleBroadcast([]byte(`{"stream": "messages_1","data":"Lorenzo: Ciao"}`))
+	node.HandleBroadcast([]byte(`{"stream": "project:1:tasks","data":"task 1"}`))
+	node.HandleBroadcast([]byte(`{"stream": "project:1:comments","data":"comment 1"}`))
+	node.HandleBroadcast([]byte(`{"stream": "project:2:tasks","data":"task 2"}`))
 
 	// Use sleep to make sure Since option works (and we don't want
 	// to hack broker internals to update stream messages timestamps)
@@ -391,6 +396,45 @@ func sharedIntegrationHistory(t *testing.T, node *Node, controller *mocks.Contro
 		assertReceive(t, session, `{"identifier":"chat_1","message":"100+ new notifications","stream_id":"presence_1","epoch":"2022","offset":5}`)
 		assertReceive(t, session, `{"type":"confirm_history","identifier":"chat_1"}`)
 	})
+
+	t.Run("Subscribe to wildcard stream with history", func(t *testing.T) {
+		session := requireAuthenticatedSession(t, node, "carol")
+
+		controller.
+			On("Subscribe", "carol", mock.Anything, "carol", "project_1").
+			Return(&common.CommandResult{
+				Status:        common.SUCCESS,
+				Streams:       []string{"project:1:*"},
+				Transmissions: []string{`{"type":"confirm","identifier":"project_1"}`},
+			}, nil)
+
+		_, err := node.Subscribe(
+			session,
+			&common.Message{
+				Identifier: "project_1",
+				Command:    "subscribe",
+				History: common.HistoryRequest{
+					Since: ts - 10,
+				},
+			})
+
+		require.NoError(t, err)
+
+		requireReceive(t, session, `{"type":"confirm","identifier":"project_1"}`)
+
+		msgs, err := readMessages(session.conn, 2)
+		require.NoError(t, err)
+
+		assert.Contains(t, msgs, `{"identifier":"project_1","message":"task 1","stream_id":"project:1:tasks","epoch":"2022","offset":1}`)
+		assert.Contains(t, msgs, `{"identifier":"project_1","message":"comment 1","stream_id":"project:1:comments","epoch":"2022","offset":1}`)
+
+		assertReceive(t, session, `{"type":"confirm_history","identifier":"project_1"}`)
+
+		node.HandleBroadcast([]byte(`{"stream": "project:2:comments","data":"comment 2"}`))
+		node.HandleBroadcast([]byte(`{"stream": "project:1:comments","data":"comment 3"}`))
+
+		assertReceive(t, session, `{"identifier":"project_1","message":"comment 3","stream_id":"project:1:comments","epoch":"2022","offset":2}`)
+	})
 }
 
 // A test to verify the presence flow.
@@ -530,6 +574,38 @@ func sharedIntegrationPresence(t *testing.T, node *Node, controller *mocks.Contr
 		assertReceive(t, mia, `{"type":"presence","identifier":"chat_1","message":{"type":"info","total":1,"records":[{"id":"13","info":{"name":"Mia"}}]}}`)
 	})
 
+	t.Run("Update", func(t *testing.T) {
+		sasha, mia, cleanup := setupSessions()
+		defer cleanup()
+
+		err := node.PresenceJoin(sasha, &common.Message{Identifier: "chat_1", Presence: &common.PresenceEvent{ID: "42", Info: map[string]interface{}{"name": "Sasha"}}})
+		require.NoError(t, err)
+
+		assertReceive(t, mia, `{"type":"presence","identifier":"chat_1","message":{"id":"42","info":{"name":"Sasha"},"type":"join"}}`)
+		assertReceive(t, sasha, `{"type":"presence","identifier":"chat_1","message":{"id":"42","info":{"name":"Sasha"},"type":"join"}}`)
+
+		err = node.HandleCommand(sasha, &common.Message{Identifier: "chat_1", Command: "update", Presence: &common.PresenceEvent{Info: map[string]interface{}{"name": "Sasha", "status": "typing"}}})
+		require.NoError(t, err)
+
+		assertReceive(t, mia, `{"type":"presence","identifier":"chat_1","message":{"id":"42","info":{"name":"Sasha","status":"typing"},"type":"update"}}`)
+		assertReceive(t, sasha, `{"type":"presence","identifier":"chat_1","message":{"id":"42","info":{"name":"Sasha","status":"typing"},"type":"update"}}`)
+
+		err = node.Presence(mia, &common.Message{Identifier: "chat_1", Command: "presence"})
+		require.NoError(t, err)
+
+		assertReceive(t, mia, `{"type":"presence","identifier":"chat_1","message":{"type":"info","total":1,"records":[{"id":"42","info":{"name":"Sasha","status":"typing"}}]}}`)
+
+		// Cannot update presence without joining first
+		err = node.PresenceUpdate(mia, &common.Message{Identifier: "chat_1", Presence: &common.PresenceEvent{Info: "away"}})
+		require.Error(t, err)
+
+		err = node.PresenceLeave(sasha, &common.Message{Identifier: "chat_1"})
+		require.NoError(t, err)
+
+		assertReceive(t, mia, `{"type":"presence","identifier":"chat_1","message":{"id":"42","type":"leave"}}`)
+		assertReceive(t, sasha, `{"type":"presence","identifier":"chat_1","message":{"id":"42","type":"leave"}}`)
+	})
+
 	t.Run("Presence expiration", func(t *testing.T) {
 		sasha, mia, cleanup := setupSessions()
 		defer cleanup()
@@ -564,6 +640,7 @@ func setupIntegrationNode() (*Node, *mocks.Controller) {
 	config := NewConfig()
 	config.HubGopoolSize = 2
 	config.DisconnectMode = DISCONNECT_MODE_NEVER
+	config.WildcardStreams = true
 
 	controller := &mocks.Controller{}
 	controller.On("Shutdown").Return(nil)
@@ -13,6 +13,14 @@ const (
 
 var DISCONNECT_MODES = []string{DISCONNECT_MODE_ALWAYS, DISCONNECT_MODE_AUTO, DISCONNECT_MODE_NEVER}
 
+const (
+	OUTBOUND_QUEUE_POLICY_DROP_OLDEST    = "drop_oldest"
+	OUTBOUND_QUEUE_POLICY_DROP_TRANSIENT = "drop_transient"
+	OUTBOUND_QUEUE_POLICY_DISCONNECT     = "disconnect"
+)
+
+var OUTBOUND_QUEUE_POLICIES = []string{OUTBOUND_QUEUE_POLICY_DROP_OLDEST, OUTBOUND_QUEUE_POLICY_DROP_TRANSIENT, OUTBOUND_QUEUE_POLICY_DISCONNECT}
+
 // Config contains general application/node settings
 type Config struct {
 	// Define when to invoke Disconnect callback
@@ -31,6 +39,33 @@ type Config struct {
 	PongTimeout int `toml:"pong_timeout"`
 	// For how long to wait for disconnect callbacks to be processed before exiting (seconds)
 	ShutdownTimeout int `toml:"shutdown_timeout"`
+	// The max number of pending outgoing messages per session
+	OutboundQueueSize int `toml:"outbound_queue_size"`
+	// What to do when the session's outgoing messages queue is full (drop_oldest, drop_transient, disconnect)
+	OutboundQueuePolicy string `toml:"outbound_queue_policy"`
+	// Stream name patterns to use latest-value-only delivery for (pending messages are replaced by newer ones)
+	ConflateStreams []string `toml:"conflate_streams"`
+	// Treat stream names with wildcard tokens (e.g., "project:42:*" or "project.42.>") as patterns
+	WildcardStreams bool `toml:"wildcard_streams"`
+	// The max number of `message` (perform) commands per second per session (0 means no limit)
+	PerformRateLimit int `toml:"perform_rate_limit"`
+	// The max burst of `message` commands (defaults to the rate limit)
+	PerformRateBurst int `toml:"perform_rate_burst"`
+	// The max number of whispers per second per session (0 means no limit)
+	WhisperRateLimit int `toml:"whisper_rate_limit"`
+	// The max burst of whispers (defaults to the rate limit)
+	WhisperRateBurst int `toml:"whisper_rate_burst"`
+	// The max number of presence commands (presence, join, leave, update) per second per session (0 means no limit)
+	PresenceRateLimit int `toml:"presence_rate_limit"`
+	// The max burst of presence commands (defaults to the rate limit)
+	PresenceRateBurst int `toml:"presence_rate_burst"`
+	// The number of consecutive rate-limited commands after which the session is disconnected (0 means never disconnect)
+	RateLimitMaxViolations int `toml:"rate_limit_max_violations"`
+	// The max number of concurrent sessions with the same identifiers (0 means no limit).
+	// The limit is enforced cluster-wide when a distributed broker is used
+	MaxSessionsPerIdentifier int `toml:"max_sessions_per_identifier"`
+	// The max number of channel subscriptions per session (0 means no limit)
+	MaxSubscriptionsPerSession int `toml:"max_subscriptions_per_session"`
 }
 
 // NewConfig builds a new config
@@ -43,6 +78,9 @@ func NewConfig() Config {
 		PingTimestampPrecision:     "s",
 		DisconnectMode:             DISCONNECT_MODE_AUTO,
 		ShutdownTimeout:            30,
+		OutboundQueueSize:          256,
+		OutboundQueuePolicy:        OUTBOUND_QUEUE_POLICY_DISCONNECT,
+		RateLimitMaxViolations:     10,
 	}
 }
 
@@ -68,6 +106,48 @@ func (c Config) ToToml() string {
 	result.WriteString("# Graceful shutdown period (seconds)\n")
 	result.WriteString(fmt.Sprintf("shutdown_timeout = %d\n", c.ShutdownTimeout))
 
+	result.WriteString("# The max number of pending outgoing messages per client\n")
+	result.WriteString(fmt.Sprintf("outbound_queue_size = %d\n", c.OutboundQueueSize))
+
+	result.WriteString("# What to do when a client can't keep up with outgoing messages (drop_oldest, drop_transient, disconnect)\n")
+	result.WriteString(fmt.Sprintf("outbound_queue_policy = \"%s\"\n", c.OutboundQueuePolicy))
+
+	result.WriteString("# Streams to deliver only the latest pending message for (exact names or patterns, e.g., \"prices:*\")\n")
+	if len(c.ConflateStreams) > 0 {
+		result.WriteString(fmt.Sprintf("conflate_streams = [ \"%s\" ]\n", strings.Join(c.ConflateStreams, "\", \"")))
+	} else {
+		result.WriteString("# conflate_streams = []\n")
+	}
+
+	result.WriteString("# Enable wildcard streams (e.g., \"project:42:*\" or \"project.42.>\")\n")
+	if c.WildcardStreams {
+		result.WriteString("wildcard_streams = true\n")
+	} else {
+		result.WriteString("# wildcard_streams = true\n")
+	}
+
+	result.WriteString("# Per-session rate limits for incoming commands (per second, 0 — no limit) and bursts (defaults to the rate limit)\n")
+	writeRateLimit(&result, "perform", c.PerformRateLimit, c.PerformRateBurst)
+	writeRateLimit(&result, "whisper", c.WhisperRateLimit, c.WhisperRateBurst)
+	writeRateLimit(&result, "presence", c.PresenceRateLimit, c.PresenceRateBurst)
+
+	result.WriteString("# The number of consecutive rate-limited commands to disconnect the client after (0 — never disconnect)\n")
+	result.WriteString(fmt.Sprintf("rate_limit_max_violations = %d\n", c.RateLimitMaxViolations))
+
+	result.WriteString("# The max number of concurrent sessions per identifier (0 — no limit; cluster-wide with a distributed broker)\n")
+	if c.MaxSessionsPerIdentifier > 0 {
+		result.WriteString(fmt.Sprintf("max_sessions_per_identifier = %d\n", c.MaxSessionsPerIdentifier))
+	} else {
+		result.WriteString("# max_sessions_per_identifier = 100\n")
+	}
+
+	result.WriteString("# The max number of channel subscriptions per session (0 — no limit)\n")
+	if c.MaxSubscriptionsPerSession > 0 {
+		result.WriteString(fmt.Sprintf("max_subscriptions_per_session = %d\n", c.MaxSubscriptionsPerSession))
+	} else {
+		result.WriteString("# max_subscriptions_per_session = 100\n")
+	}
+
 	result.WriteString("# How often to refresh system-wide metrics (seconds)\n")
 	result.WriteString(fmt.Sprintf("stats_refresh_interval = %d\n", c.StatsRefreshInterval))
 
@@ -81,3 +161,17 @@ func (c Config) ToToml() string {
 
 	return result.String()
 }
+
+func writeRateLimit(result *strings.Builder, kind string, limit int, burst int) {
+	if limit > 0 {
+		result.WriteString(fmt.Sprintf("%s_rate_limit = %d\n", kind, limit))
+	} else {
+		result.WriteString(fmt.Sprintf("# %s_rate_limit = 10\n", kind))
+	}
+
+	if burst > 0 {
+		result.WriteString(fmt.Sprintf("%s_rate_burst = %d\n", kind, burst))
+	} else {
+		result.WriteString(fmt.Sprintf("# %s_rate_burst = 20\n", kind))
+	}
+}
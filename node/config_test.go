@@ -14,6 +14,12 @@ func TestConfig_ToToml(t *testing.T) {
 	conf.HubGopoolSize = 100
 	conf.PingTimestampPrecision = "ns"
 	conf.ShutdownDisconnectPoolSize = 1024
+	conf.OutboundQueuePolicy = "drop_transient"
+	conf.ConflateStreams = []string{"prices:*", "cursors"}
+	conf.PerformRateLimit = 10
+	conf.PerformRateBurst = 30
+	conf.WhisperRateLimit = 5
+	conf.MaxSubscriptionsPerSession = 50
 
 	tomlStr := conf.ToToml()
 
@@ -22,6 +28,17 @@ func TestConfig_ToToml(t *testing.T) {
 	assert.Contains(t, tomlStr, "ping_timestamp_precision = \"ns\"")
 	assert.Contains(t, tomlStr, "# pong_timeout = 6")
 	assert.Contains(t, tomlStr, "shutdown_disconnect_gopool_size = 1024")
+	assert.Contains(t, tomlStr, "outbound_queue_size = 256")
+	assert.Contains(t, tomlStr, "outbound_queue_policy = \"drop_transient\"")
+	assert.Contains(t, tomlStr, "conflate_streams = [ \"prices:*\", \"cursors\" ]")
+	assert.Contains(t, tomlStr, "perform_rate_limit = 10")
+	assert.Contains(t, tomlStr, "perform_rate_burst = 30")
+	assert.Contains(t, tomlStr, "whisper_rate_limit = 5")
+	assert.Contains(t, tomlStr, "# whisper_rate_burst = 20")
+	assert.Contains(t, tomlStr, "# presence_rate_limit = 10")
+	assert.Contains(t, tomlStr, "rate_limit_max_violations = 10")
+	assert.Contains(t, tomlStr, "# max_sessions_per_identifier = 100")
+	assert.Contains(t, tomlStr, "max_subscriptions_per_session = 50")
 
 	// Round-trip test
 	conf2 := NewConfig()
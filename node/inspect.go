@@ -0,0 +1,277 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anycable/anycable-go/common"
+	nanoid "github.com/matoous/go-nanoid"
+)
+
+const (
+	inspectCommand = "inspect"
+	// Nodes respond to cluster-wide queries via a dedicated stream, so only the requesting node receives the results
+	inspectReplyPrefix = "$inspect:"
+	// The number of node responses to buffer per request
+	inspectRepliesBuffer = 64
+	// How long to wait for more responses after receiving the last one
+	inspectIdleTimeout = 200 * time.Millisecond
+
+	defaultInspectStreamsLimit = 10
+	filteredHeaderValue        = "[FILTERED]"
+)
+
+// Headers which values must not be exposed via introspection
+var sensitiveHeaders = []string{"cookie", "authorization"}
+
+// Inspect returns information about the sessions and streams of this node
+func (n *Node) Inspect(query *common.InspectQuery) (*common.InspectResult, error) {
+	return n.inspect(query, false)
+}
+
+// inspect performs the query; cluster-wide results contain no sessions env and include all the streams
+// (so the subscribers counts could be merged correctly)
+func (n *Node) inspect(query *common.InspectQuery, cluster bool) (*common.InspectResult, error) {
+	res := &common.InspectResult{Node: n.id}
+
+	switch query.Kind {
+	case common.InspectSessionsQuery:
+		sessions := n.remoteSessions(query.Identifier)
+
+		res.Sessions = make([]*common.SessionInfo, 0, len(sessions))
+
+		for _, s := range sessions {
+			res.Sessions = append(res.Sessions, n.sessionInfo(s, false))
+		}
+	case common.InspectSessionQuery:
+		if s, ok := n.hub.FindByID(query.SID).(*Session); ok {
+			res.Sessions = []*common.SessionInfo{n.sessionInfo(s, !cluster)}
+		}
+	case common.InspectStreamsQuery:
+		counts := n.hub.StreamsSubscribers()
+		limit := query.Limit
+
+		if cluster {
+			limit = len(counts)
+		} else if limit <= 0 {
+			limit = defaultInspectStreamsLimit
+		}
+
+		res.Streams = common.TopStreamsSubscribers(counts, limit)
+	default:
+		return nil, fmt.Errorf("unknown inspect query: %s", query.Kind)
+	}
+
+	return res, nil
+}
+
+// InspectCluster sends the introspection query to all the nodes in the cluster (including this one)
+// and collects the results until no more responses arrive (or the context is done)
+func (n *Node) InspectCluster(ctx context.Context, query *common.InspectQuery) ([]*common.InspectResult, error) {
+	// Validate query locally before sending it to other nodes
+	if _, err := n.Inspect(query); err != nil {
+		return nil, err
+	}
+
+	if n.broadcaster == nil {
+		res, err := n.inspect(query, true)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return []*common.InspectResult{res}, nil
+	}
+
+	id, err := nanoid.Nanoid()
+
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(&common.RemoteInspectMessage{ID: id, Query: query})
+
+	if err != nil {
+		return nil, err
+	}
+
+	replies := make(chan *common.InspectResult, inspectRepliesBuffer)
+	stream := inspectReplyPrefix + id
+
+	n.inspectionsMu.Lock()
+	n.inspections[id] = replies
+	n.inspectionsMu.Unlock()
+
+	n.broadcaster.Subscribe(stream)
+
+	defer func() {
+		n.broadcaster.Unsubscribe(stream)
+
+		n.inspectionsMu.Lock()
+		delete(n.inspections, id)
+		n.inspectionsMu.Unlock()
+	}()
+
+	n.broadcaster.BroadcastCommand(&common.RemoteCommandMessage{Command: inspectCommand, Payload: payload})
+
+	results := []*common.InspectResult{}
+
+	// We don't know how many nodes are there, so we stop waiting when nodes stop responding
+	var idle <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return results, nil
+		case <-idle:
+			return results, nil
+		case res := <-replies:
+			results = append(results, res)
+
+			// Session IDs are unique, no need to wait for other nodes
+			if query.Kind == common.InspectSessionQuery && len(res.Sessions) > 0 {
+				return results, nil
+			}
+
+			idle = time.After(inspectIdleTimeout)
+		}
+	}
+}
+
+func (n *Node) handleRemoteInspect(msg *common.RemoteInspectMessage) {
+	if msg.Query == nil || msg.ID == "" || n.broadcaster == nil {
+		return
+	}
+
+	res, err := n.inspect(msg.Query, true)
+
+	if err != nil {
+		n.log.Warn("failed to perform remote inspect", "query", msg.Query, "error", err)
+		return
+	}
+
+	payload, err := json.Marshal(res)
+
+	if err != nil {
+		n.log.Error("failed to encode inspect result", "error", err)
+		return
+	}
+
+	n.broadcaster.Broadcast(&common.StreamMessage{Stream: inspectReplyPrefix + msg.ID, Data: string(payload)})
+}
+
+func (n *Node) handleInspectReply(msg *common.StreamMessage) {
+	id := strings.TrimPrefix(msg.Stream, inspectReplyPrefix)
+
+	n.inspectionsMu.Lock()
+	replies, ok := n.inspections[id]
+	n.inspectionsMu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	var res common.InspectResult
+
+	if err := json.Unmarshal([]byte(msg.Data), &res); err != nil {
+		n.log.Warn("failed to decode inspect result", "error", err)
+		return
+	}
+
+	select {
+	case replies <- &res:
+	default:
+		n.log.Warn("too many inspect results, dropping", "id", id)
+	}
+}
+
+func isInspectReplyStream(stream string) bool {
+	return strings.HasPrefix(stream, inspectReplyPrefix)
+}
+
+// isInspectMessage returns true if the pub/sub message is a part of the cluster-wide introspection
+// (such messages could only be sent by nodes themselves)
+func isInspectMessage(msg interface{}) bool {
+	switch v := msg.(type) {
+	case common.StreamMessage:
+		return isInspectReplyStream(v.Stream)
+	case []*common.StreamMessage:
+		for _, el := range v {
+			if isInspectReplyStream(el.Stream) {
+				return true
+			}
+		}
+	case common.RemoteCommandMessage:
+		return v.Command == inspectCommand
+	}
+
+	return false
+}
+
+func (n *Node) sessionInfo(s *Session, withEnv bool) *common.SessionInfo {
+	info := &common.SessionInfo{
+		ID:          s.GetID(),
+		Node:        n.id,
+		Identifiers: s.GetIdentifiers(),
+		ConnectedAt: s.connectedAt.Unix(),
+		Channels:    s.subscriptions.ToMap(),
+	}
+
+	if !withEnv {
+		return info
+	}
+
+	s.smu.Lock()
+	defer s.smu.Unlock()
+
+	env := &common.SessionEnvInfo{
+		URL:    s.env.URL,
+		CState: make(map[string]string),
+		IState: make(map[string]map[string]string),
+	}
+
+	if s.env.Headers != nil {
+		env.Headers = make(map[string]string, len(*s.env.Headers))
+
+		for k, v := range *s.env.Headers {
+			if isSensitiveHeader(k) {
+				v = filteredHeaderValue
+			}
+
+			env.Headers[k] = v
+		}
+	}
+
+	if s.env.ConnectionState != nil {
+		for k, v := range *s.env.ConnectionState {
+			env.CState[k] = v
+		}
+	}
+
+	if s.env.ChannelStates != nil {
+		for id, state := range *s.env.ChannelStates {
+			env.IState[id] = make(map[string]string, len(state))
+
+			for k, v := range state {
+				env.IState[id][k] = v
+			}
+		}
+	}
+
+	info.Env = env
+
+	return info
+}
+
+func isSensitiveHeader(name string) bool {
+	for _, h := range sensitiveHeaders {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,137 @@
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	node := NewMockNode()
+	node.id = "node-1"
+
+	go node.hub.Run()
+	defer node.hub.Shutdown()
+
+	session := NewMockSessionWithEnv("14", node, "ws://test.anycable.io/cable", &map[string]string{"cookie": "token=secret", "x-api-version": "2"})
+	session.SetIdentifiers("user:1")
+	node.hub.AddSession(session)
+
+	session2 := NewMockSession("15", node)
+	session2.SetIdentifiers("user:1")
+	node.hub.AddSession(session2)
+
+	_, err := node.Subscribe(session, &common.Message{Identifier: "with_stream"})
+	require.NoError(t, err)
+
+	_, err = node.Subscribe(session2, &common.Message{Identifier: "with_stream"})
+	require.NoError(t, err)
+
+	node.hub.SubscribeSession(session, "news", "with_stream")
+
+	session.env.MergeConnectionState(&map[string]string{"locale": "en"})
+
+	t.Run("Sessions by identifier", func(t *testing.T) {
+		res, err := node.Inspect(&common.InspectQuery{Kind: common.InspectSessionsQuery, Identifier: "user:1"})
+		require.NoError(t, err)
+
+		assert.Equal(t, "node-1", res.Node)
+		require.Len(t, res.Sessions, 2)
+
+		for _, info := range res.Sessions {
+			assert.Equal(t, "user:1", info.Identifiers)
+			assert.Equal(t, "node-1", info.Node)
+			assert.Equal(t, map[string][]string{"with_stream": {"stream"}}, info.Channels)
+			assert.Nil(t, info.Env)
+		}
+	})
+
+	t.Run("Session details", func(t *testing.T) {
+		res, err := node.Inspect(&common.InspectQuery{Kind: common.InspectSessionQuery, SID: "14"})
+		require.NoError(t, err)
+
+		require.Len(t, res.Sessions, 1)
+
+		info := res.Sessions[0]
+
+		assert.Equal(t, "14", info.ID)
+		require.NotNil(t, info.Env)
+		assert.Equal(t, "ws://test.anycable.io/cable", info.Env.URL)
+		assert.Equal(t, map[string]string{"cookie": "[FILTERED]", "x-api-version": "2"}, info.Env.Headers)
+		assert.Equal(t, map[string]string{"locale": "en"}, info.Env.CState)
+
+		res, err = node.Inspect(&common.InspectQuery{Kind: common.InspectSessionQuery, SID: "unknown"})
+		require.NoError(t, err)
+		assert.Empty(t, res.Sessions)
+	})
+
+	t.Run("Streams subscribers", func(t *testing.T) {
+		res, err := node.Inspect(&common.InspectQuery{Kind: common.InspectStreamsQuery, Limit: 1})
+		require.NoError(t, err)
+
+		assert.Equal(t, []*common.StreamSubscribersInfo{{Stream: "stream", Subscribers: 2}}, res.Streams)
+	})
+
+	t.Run("Unknown query", func(t *testing.T) {
+		_, err := node.Inspect(&common.InspectQuery{Kind: "nodes"})
+		require.Error(t, err)
+	})
+
+	t.Run("Cluster-wide", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		start := time.Now()
+
+		results, err := node.InspectCluster(ctx, &common.InspectQuery{Kind: common.InspectSessionsQuery, Identifier: "user:1"})
+		require.NoError(t, err)
+
+		assert.Less(t, time.Since(start), time.Second)
+
+		require.Len(t, results, 1)
+		assert.Equal(t, "node-1", results[0].Node)
+		assert.Len(t, results[0].Sessions, 2)
+
+		assert.Empty(t, node.inspections)
+	})
+
+	t.Run("Cluster-wide session details", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		results, err := node.InspectCluster(ctx, &common.InspectQuery{Kind: common.InspectSessionQuery, SID: "14"})
+		require.NoError(t, err)
+
+		require.Len(t, results, 1)
+		require.Len(t, results[0].Sessions, 1)
+		assert.Nil(t, results[0].Sessions[0].Env)
+	})
+
+	t.Run("Cluster-wide streams subscribers", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		results, err := node.InspectCluster(ctx, &common.InspectQuery{Kind: common.InspectStreamsQuery, Limit: 1})
+		require.NoError(t, err)
+
+		require.Len(t, results, 1)
+		assert.Equal(t, []*common.StreamSubscribersInfo{{Stream: "stream", Subscribers: 2}, {Stream: "news", Subscribers: 1}}, results[0].Streams)
+	})
+
+	t.Run("Inspect commands from broadcasters are ignored", func(t *testing.T) {
+		subscriber := &mocks.Subscriber{}
+		node.SetBroadcaster(subscriber)
+		defer node.SetBroadcaster(nil)
+
+		node.HandlePubSub([]byte(`{"command":"inspect","payload":{"id":"42","query":{"kind":"sessions","identifier":"user:1"}}}`))
+		node.HandleBroadcast([]byte(`{"command":"inspect","payload":{"id":"42","query":{"kind":"sessions","identifier":"user:1"}}}`))
+
+		subscriber.AssertNotCalled(t, "Broadcast", mock.Anything)
+	})
+}
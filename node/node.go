@@ -12,6 +12,7 @@ import (
 
 	"github.com/anycable/anycable-go/broker"
 	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/filters"
 	"github.com/anycable/anycable-go/hub"
 	"github.com/anycable/anycable-go/logger"
 	"github.com/anycable/anycable-go/metrics"
@@ -33,12 +34,25 @@ const (
 	metricsFailedCommandReceived = "failed_client_msg_total"
 	metricsBroadcastMsg          = "broadcast_msg_total"
 	metricsUnknownBroadcast      = "failed_broadcast_msg_total"
+	metricsRemoteCommandsMsg     = "remote_commands_total"
 
 	metricsSentMsg    = "server_msg_total"
 	metricsFailedSent = "failed_server_msg_total"
 
 	metricsDataSent     = "data_sent_total"
 	metricsDataReceived = "data_rcvd_total"
+
+	metricsOutboundPending   = "outbound_queue_pending_num"
+	metricsOutboundMaxDepth  = "outbound_queue_max_depth"
+	metricsOutboundDropped   = "outbound_queue_dropped_total"
+	metricsSlowConsumers     = "slow_consumer_disconnects_total"
+	metricsOutboundConflated = "outbound_conflated_total"
+
+	metricsRateLimited            = "rate_limited_total"
+	metricsRateLimitedDisconnects = "rate_limit_disconnects_total"
+
+	metricsSessionsQuotaExceeded      = "sessions_quota_exceeded_total"
+	metricsSubscriptionsQuotaExceeded = "subscriptions_quota_exceeded_total"
 )
 
 // AppNode describes a basic node interface
@@ -71,12 +85,20 @@ type Node struct {
 	config       *Config
 	hub          *hub.Hub
 	broker       broker.Broker
+	broadcaster  broker.Broadcaster
 	controller   Controller
 	disconnector Disconnector
 	shutdownCh   chan struct{}
 	shutdownMu   sync.Mutex
 	closed       bool
 	log          *slog.Logger
+
+	// Pending cluster-wide introspection requests
+	inspections   map[string]chan *common.InspectResult
+	inspectionsMu sync.Mutex
+
+	// Sessions quota used when no distributed broker is configured
+	localQuota *broker.LocalSessionsQuota
 }
 
 var _ AppNode = (*Node)(nil)
@@ -110,8 +132,10 @@ func WithID(id string) NodeOption {
 // NewNode builds new node struct
 func NewNode(config *Config, opts ...NodeOption) *Node {
 	n := &Node{
-		config:     config,
-		shutdownCh: make(chan struct{}),
+		config:      config,
+		shutdownCh:  make(chan struct{}),
+		inspections: make(map[string]chan *common.InspectResult),
+		localQuota:  broker.NewLocalSessionsQuota(),
 	}
 
 	for _, opt := range opts {
@@ -123,7 +147,18 @@ func NewNode(config *Config, opts ...NodeOption) *Node {
 		n.log = slog.With("context", "node")
 	}
 
-	n.hub = hub.NewHub(config.HubGopoolSize, n.log)
+	streamsStats := false
+
+	if registry, ok := n.metrics.(metrics.StreamsStatsRegistry); ok {
+		streamsStats = registry.TopStreamsEnabled()
+	}
+
+	n.hub = hub.NewHub(
+		config.HubGopoolSize,
+		n.log,
+		hub.WithWildcardStreams(config.WildcardStreams),
+		hub.WithStreamsStats(streamsStats),
+	)
 
 	if n.metrics != nil {
 		n.registerMetrics()
@@ -154,6 +189,11 @@ func (n *Node) SetBroker(b broker.Broker) {
 	n.broker = b
 }
 
+// SetBroadcaster sets the pub/sub broadcaster used to communicate with other nodes directly (e.g., for cluster-wide introspection)
+func (n *Node) SetBroadcaster(b broker.Broadcaster) {
+	n.broadcaster = b
+}
+
 // Return current instrumenter for the node
 func (n *Node) Instrumenter() metrics.Instrumenter {
 	return n.metrics
@@ -166,6 +206,19 @@ func (n *Node) HandleCommand(s *Session, msg *common.Message) (err error) {
 
 	s.keepalive()
 
+	if allowed, disconnected := n.checkRateLimit(s, msg.Command); !allowed {
+		if !disconnected {
+			s.Send(&common.Reply{
+				Type:       common.ErrorType,
+				Identifier: msg.Identifier,
+				Reason:     common.RATE_LIMITED_REASON,
+			})
+		}
+
+		s.resetPong()
+		return
+	}
+
 	switch msg.Command {
 	case "pong":
 	case "subscribe":
@@ -182,6 +235,8 @@ func (n *Node) HandleCommand(s *Session, msg *common.Message) (err error) {
 		err = n.PresenceJoin(s, msg)
 	case "leave":
 		err = n.PresenceLeave(s, msg)
+	case "update":
+		err = n.PresenceUpdate(s, msg)
 	case "whisper":
 		err = n.Whisper(s, msg)
 	default:
@@ -195,6 +250,38 @@ func (n *Node) HandleCommand(s *Session, msg *common.Message) (err error) {
 	return
 }
 
+// CheckRateLimit returns false if the command (e.g., "message" or "whisper") exceeds the session's rate limit.
+// Sessions staying over the limit are disconnected. Protocol executors must notify clients about rejected commands themselves.
+func (n *Node) CheckRateLimit(s *Session, command string) bool {
+	allowed, _ := n.checkRateLimit(s, command)
+
+	return allowed
+}
+
+// checkRateLimit returns false if the command exceeds the session's rate limit;
+// the second value is true if the session has been disconnected for staying over the limit
+func (n *Node) checkRateLimit(s *Session, command string) (bool, bool) {
+	allowed, exceeded := s.rateLimiter.allow(command, time.Now())
+
+	if allowed {
+		return true, false
+	}
+
+	n.metrics.CounterIncrement(metricsRateLimited)
+
+	if exceeded {
+		n.metrics.CounterIncrement(metricsRateLimitedDisconnects)
+		s.Log.Warn("disconnecting session exceeding rate limit", "command", command)
+
+		s.DisconnectWithMessage(common.NewDisconnectMessage(common.RATE_LIMITED_REASON, false), common.RATE_LIMITED_REASON)
+		return false, true
+	}
+
+	s.Log.Debug("command rate limited", "command", command)
+
+	return false, false
+}
+
 // HandleBroadcast parses incoming broadcast message, record it and re-transmit to other nodes
 func (n *Node) HandleBroadcast(raw []byte) {
 	msg, err := common.PubSubMessageFromJSON(raw)
@@ -205,6 +292,11 @@ func (n *Node) HandleBroadcast(raw []byte) {
 		return
 	}
 
+	if isInspectMessage(msg) {
+		n.log.Warn("introspection messages can only be sent by nodes", "data", logger.CompactValue(raw))
+		return
+	}
+
 	switch v := msg.(type) {
 	case common.StreamMessage:
 		n.log.Debug("handle broadcast message", "payload", &v)
@@ -230,6 +322,11 @@ func (n *Node) HandlePubSub(raw []byte) {
 		return
 	}
 
+	if isInspectMessage(msg) {
+		n.log.Warn("introspection messages can only be sent by nodes", "data", logger.CompactValue(raw))
+		return
+	}
+
 	switch v := msg.(type) {
 	case common.StreamMessage:
 		n.Broadcast(&v)
@@ -345,6 +442,10 @@ func (n *Node) Authenticate(s *Session, options ...AuthOption) (*common.ConnectR
 	}
 
 	if res.Status == common.SUCCESS {
+		if !n.acquireSessionQuota(s, res.Identifier) {
+			return n.rejectOverQuota(s, res), nil
+		}
+
 		n.Authenticated(s, res.Identifier)
 	} else {
 		if res.Status == common.FAILURE {
@@ -396,10 +497,17 @@ func (n *Node) TryRestoreSession(s *Session) (restored bool) {
 		return false
 	}
 
+	// Fallback to authentication if the quota is exceeded (so the application is notified about the rejected session)
+	if ids, ierr := identifiersFromCache(cached_session); ierr == nil && !n.acquireSessionQuota(s, ids) {
+		s.Log.Debug("sessions quota exceeded, skip restoring", "old_sid", prev_sid)
+		return false
+	}
+
 	err = s.RestoreFromCache(cached_session)
 
 	if err != nil {
 		s.Log.Error("failed to restore session from cache", "old_sid", prev_sid, "error", err)
+		n.releaseSessionQuota(s)
 		return false
 	}
 
@@ -410,9 +518,16 @@ func (n *Node) TryRestoreSession(s *Session) (restored bool) {
 
 	// Resubscribe to streams
 	for identifier, channel_streams := range s.subscriptions.channels {
+		filter, ferr := n.subscriptionFilter(s, identifier)
+
+		if ferr != nil {
+			s.Log.Error("invalid subscription filter", "identifier", identifier, "error", ferr)
+			continue
+		}
+
 		for stream := range channel_streams {
 			streamId := n.broker.Subscribe(stream)
-			n.hub.SubscribeSession(s, streamId, identifier)
+			n.hub.SubscribeSessionWithFilter(s, streamId, identifier, filter)
 		}
 	}
 
@@ -442,6 +557,35 @@ func (n *Node) Subscribe(s *Session, msg *common.Message) (*common.CommandResult
 		return nil, fmt.Errorf("already subscribed to %s", msg.Identifier)
 	}
 
+	if n.subscriptionsQuotaExceeded(s) {
+		s.smu.Unlock()
+
+		n.metrics.CounterIncrement(metricsSubscriptionsQuotaExceeded)
+
+		res := &common.CommandResult{Status: common.FAILURE}
+
+		s.Send(&common.Reply{
+			Type:       common.RejectedType,
+			Identifier: msg.Identifier,
+			Reason:     common.TOO_MANY_SUBSCRIPTIONS_REASON,
+		})
+
+		return res, fmt.Errorf("subscriptions quota exceeded for %s", msg.Identifier)
+	}
+
+	var clientFilter string
+
+	if msg.Filter != nil {
+		filter, ferr := n.encodeClientFilter(msg.Filter)
+
+		if ferr != nil {
+			s.smu.Unlock()
+			return nil, errorx.Decorate(ferr, "invalid filter for %s", msg.Identifier)
+		}
+
+		clientFilter = filter
+	}
+
 	res, err := n.controller.Subscribe(s.GetID(), s.env, s.GetIdentifiers(), msg.Identifier)
 
 	s.Log.Debug("controller subscribe", "response", res, "err", err)
@@ -455,6 +599,11 @@ func (n *Node) Subscribe(s *Session, msg *common.Message) (*common.CommandResult
 	} else if res.Status == common.SUCCESS {
 		confirmed = true
 		s.subscriptions.AddChannel(msg.Identifier)
+
+		if clientFilter != "" {
+			s.env.MergeChannelState(msg.Identifier, &map[string]string{common.CLIENT_FILTER_STATE: clientFilter})
+		}
+
 		s.Log.Debug("subscribed", "identifier", msg.Identifier)
 	} else {
 		s.Log.Debug("subscription rejected", "identifier", msg.Identifier)
@@ -602,7 +751,19 @@ func (n *Node) History(s *Session, msg *common.Message) error {
 		return fmt.Errorf("history request is missing, got %v", msg)
 	}
 
-	backlog, err := n.retreiveHistory(&history, subscriptionStreams)
+	// History must be filtered the same way as broadcasts
+	filter, err := n.subscriptionFilter(s, msg.Identifier)
+
+	if err != nil {
+		s.Send(&common.Reply{
+			Type:       common.HistoryRejectedType,
+			Identifier: msg.Identifier,
+		})
+
+		return errorx.Decorate(err, "invalid subscription filter")
+	}
+
+	backlog, cursor, err := n.retreiveHistory(&history, subscriptionStreams, filter)
 
 	if err != nil {
 		s.Send(&common.Reply{
@@ -613,50 +774,178 @@ func (n *Node) History(s *Session, msg *common.Message) error {
 		return err
 	}
 
-	for _, el := range backlog {
-		s.Send(el.ToReplyFor(msg.Identifier))
+	for i := range backlog {
+		s.Send(backlog[i].ToReplyFor(msg.Identifier))
 	}
 
 	s.Send(&common.Reply{
 		Type:       common.HistoryConfirmedType,
 		Identifier: msg.Identifier,
+		History:    cursor,
 	})
 
 	return nil
 }
 
-func (n *Node) retreiveHistory(history *common.HistoryRequest, streams []string) (backlog []common.StreamMessage, err error) {
+// retreiveHistory returns the streams backlog (matching the filter) and the cursor to fetch the next page of history from
+// (if the backlog has been truncated). The limit applies to the whole backlog, not to every stream.
+func (n *Node) retreiveHistory(history *common.HistoryRequest, streams []string, filter *filters.Filter) (backlog []common.StreamMessage, cursor *common.HistoryCursor, err error) {
 	backlog = []common.StreamMessage{}
+	limit := history.Limit
 
-	for _, stream := range streams {
-		if history.Streams != nil {
-			pos, ok := history.Streams[stream]
+	opts := []broker.HistoryOption{}
 
-			if ok {
-				streamBacklog, err := n.broker.HistoryFrom(stream, pos.Epoch, pos.Offset)
+	// We request one more message to find out whether there are more messages to fetch
+	if limit > 0 {
+		if history.Latest {
+			opts = append(opts, broker.WithLatestHistory(limit+1))
+		} else {
+			opts = append(opts, broker.WithHistoryLimit(limit+1))
+		}
+	}
 
-				if err != nil {
-					return nil, err
-				}
+	truncated := false
+	// The last fetched messages of the streams with more messages to fetch
+	// (we continue from them even if they've been filtered out)
+	scanned := make(map[string]common.StreamMessage)
 
-				backlog = append(backlog, streamBacklog...)
+	for _, stream := range n.expandStreams(streams) {
+		var streamBacklog []common.StreamMessage
 
-				continue
+		if pos, ok := history.Streams[stream]; ok {
+			streamBacklog, err = n.broker.HistoryFrom(stream, pos.Epoch, pos.Offset, opts...)
+		} else if history.Since > 0 {
+			streamBacklog, err = n.broker.HistorySince(stream, history.Since, opts...)
+		} else {
+			continue
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// The stream has more messages to fetch; the limit is applied after filtering
+		if limit > 0 && len(streamBacklog) > limit {
+			truncated = true
+
+			if history.Latest {
+				// Older messages are skipped, there is no next page
+				streamBacklog = streamBacklog[1:]
+			} else {
+				scanned[stream] = streamBacklog[len(streamBacklog)-1]
 			}
 		}
 
-		if history.Since > 0 {
-			streamBacklog, err := n.broker.HistorySince(stream, history.Since)
+		for i := range streamBacklog {
+			if filter.Match(filters.NewMessage(&streamBacklog[i])) {
+				backlog = append(backlog, streamBacklog[i])
+			}
+		}
+	}
 
-			if err != nil {
-				return nil, err
+	// Streams which have messages dropped due to the limit
+	dropped := make(map[string]bool)
+
+	if limit > 0 && len(backlog) > limit {
+		truncated = true
+
+		if history.Latest {
+			backlog = backlog[len(backlog)-limit:]
+		} else {
+			for _, el := range backlog[limit:] {
+				dropped[el.Stream] = true
 			}
 
-			backlog = append(backlog, streamBacklog...)
+			backlog = backlog[:limit]
+		}
+	}
+
+	if !truncated {
+		return backlog, nil, nil
+	}
+
+	cursor = &common.HistoryCursor{Truncated: true}
+
+	// Older messages are skipped, there is no next page
+	if history.Latest {
+		return backlog, cursor, nil
+	}
+
+	positions := make(map[string]common.HistoryPosition)
+
+	// Continue from the last delivered messages
+	for _, el := range backlog {
+		positions[el.Stream] = common.HistoryPosition{Epoch: el.Epoch, Offset: el.Offset}
+	}
+
+	// Skip the fetched messages which have been filtered out (unless some messages were dropped)
+	for stream, last := range scanned {
+		if !dropped[stream] {
+			positions[stream] = common.HistoryPosition{Epoch: last.Epoch, Offset: last.Offset}
+		}
+	}
+
+	// Nothing has been delivered from these streams, so we continue from the requested position
+	for stream := range dropped {
+		if _, ok := positions[stream]; ok {
+			continue
+		}
+
+		if pos, ok := history.Streams[stream]; ok {
+			positions[stream] = pos
+		}
+	}
+
+	if len(positions) > 0 {
+		cursor.Streams = positions
+	}
+
+	return backlog, cursor, nil
+}
+
+// expandStreams replaces wildcard streams with the matching streams known to the broker
+// (so we can retrieve history for them)
+func (n *Node) expandStreams(streams []string) []string {
+	if !n.config.WildcardStreams {
+		return streams
+	}
+
+	res := make([]string, 0, len(streams))
+	seen := make(map[string]bool, len(streams))
+
+	add := func(stream string) {
+		if !seen[stream] {
+			seen[stream] = true
+			res = append(res, stream)
 		}
 	}
 
-	return backlog, nil
+	for _, stream := range streams {
+		if !utils.IsStreamPattern(stream) {
+			add(stream)
+			continue
+		}
+
+		matcher, ok := n.broker.(broker.StreamsMatcher)
+
+		if !ok {
+			n.log.Debug("broker doesn't support wildcard streams, skip history", "stream", stream)
+			continue
+		}
+
+		matching, err := matcher.MatchStreams(stream)
+
+		if err != nil {
+			n.log.Warn("failed to resolve wildcard stream", "stream", stream, "error", err)
+			continue
+		}
+
+		for _, name := range matching {
+			add(name)
+		}
+	}
+
+	return res
 }
 
 // Whisper broadcasts the message to the specified whispering stream to
@@ -775,18 +1064,63 @@ func (n *Node) PresenceLeave(s *Session, msg *common.Message) error {
 	return n.handlePresenceReply(s, msg.Identifier, common.PresenceLeaveType, msg.Presence)
 }
 
+// PresenceUpdate updates the session's presence info for the specified identifier
+func (n *Node) PresenceUpdate(s *Session, msg *common.Message) error {
+	s.smu.Lock()
+
+	if ok := s.subscriptions.HasChannel(msg.Identifier); !ok {
+		s.smu.Unlock()
+		return fmt.Errorf("unknown subscription %s", msg.Identifier)
+	}
+
+	s.smu.Unlock()
+
+	return n.handlePresenceReply(s, msg.Identifier, common.PresenceUpdateType, msg.Presence)
+}
+
 // Broadcast message to stream (locally)
 func (n *Node) Broadcast(msg *common.StreamMessage) {
+	if isInspectReplyStream(msg.Stream) {
+		n.handleInspectReply(msg)
+		return
+	}
+
 	n.metrics.CounterIncrement(metricsBroadcastMsg)
 	n.log.Debug("incoming broadcast message", "payload", msg)
-	n.hub.BroadcastMessage(msg)
+	n.hub.BroadcastMessage(n.withConflation(msg))
+}
+
+// withConflation marks messages for streams configured as conflatable
+func (n *Node) withConflation(msg *common.StreamMessage) *common.StreamMessage {
+	if len(n.config.ConflateStreams) == 0 || (msg.Meta != nil && msg.Meta.Conflate) {
+		return msg
+	}
+
+	for _, pattern := range n.config.ConflateStreams {
+		if utils.MatchStreamPattern(pattern, msg.Stream) {
+			// Messages could be shared with other components (e.g., broker), so we must copy them
+			conflated := *msg
+			meta := common.StreamMessageMetadata{}
+
+			if msg.Meta != nil {
+				meta = *msg.Meta
+			}
+
+			meta.Conflate = true
+			conflated.Meta = &meta
+
+			return &conflated
+		}
+	}
+
+	return msg
 }
 
 // Execute remote command (locally)
 func (n *Node) ExecuteRemoteCommand(msg *common.RemoteCommandMessage) {
-	// TODO: Add remote commands metrics
-	// n.metrics.CounterIncrement(metricsRemoteCommandsMsg)
-	switch msg.Command { // nolint:gocritic
+	n.metrics.CounterIncrement(metricsRemoteCommandsMsg)
+
+	switch msg.Command {
 	case "disconnect":
 		dmsg, err := msg.ToRemoteDisconnectMessage()
 		if err != nil {
@@ -797,6 +1131,50 @@ func (n *Node) ExecuteRemoteCommand(msg *common.RemoteCommandMessage) {
 		n.log.Debug("incoming remote command", "command", dmsg)
 
 		n.RemoteDisconnect(dmsg)
+	case "transmit":
+		tmsg, err := msg.ToRemoteTransmitMessage()
+		if err != nil {
+			n.log.Warn("failed to parse remote transmit command", "data", msg, "error", err)
+			return
+		}
+
+		n.log.Debug("incoming remote command", "command", tmsg)
+
+		n.RemoteTransmit(tmsg)
+	case "subscribe", "unsubscribe":
+		smsg, err := msg.ToRemoteSubscriptionMessage()
+		if err != nil {
+			n.log.Warn("failed to parse remote subscription command", "data", msg, "error", err)
+			return
+		}
+
+		n.log.Debug("incoming remote command", "command", msg.Command, "payload", smsg)
+
+		if msg.Command == "subscribe" {
+			n.RemoteSubscribe(smsg)
+		} else {
+			n.RemoteUnsubscribe(smsg)
+		}
+	case "update_state":
+		smsg, err := msg.ToRemoteStateMessage()
+		if err != nil {
+			n.log.Warn("failed to parse remote state command", "data", msg, "error", err)
+			return
+		}
+
+		n.log.Debug("incoming remote command", "command", smsg)
+
+		n.RemoteUpdateState(smsg)
+	case inspectCommand:
+		imsg, err := msg.ToRemoteInspectMessage()
+		if err != nil {
+			n.log.Warn("failed to parse remote inspect command", "data", msg, "error", err)
+			return
+		}
+
+		n.log.Debug("incoming remote command", "command", imsg)
+
+		n.handleRemoteInspect(imsg)
 	}
 }
 
@@ -807,6 +1185,7 @@ func (n *Node) Disconnect(s *Session) error {
 	}
 
 	n.broker.FinishPresence(s.GetID()) // nolint:errcheck
+	n.releaseSessionQuota(s)
 
 	if n.IsShuttingDown() {
 		// Make sure session is removed from hub, so we don't try to send
@@ -858,6 +1237,135 @@ func (n *Node) RemoteDisconnect(msg *common.RemoteDisconnectMessage) {
 	n.hub.RemoteDisconnect(msg)
 }
 
+// RemoteTransmit sends a message to all sessions with the specified identifiers
+func (n *Node) RemoteTransmit(msg *common.RemoteTransmitMessage) {
+	for _, s := range n.remoteSessions(msg.Identifier) {
+		s.SendJSONTransmission(msg.Data)
+	}
+}
+
+// RemoteSubscribe attaches a stream to the subscriptions of the sessions with the specified identifiers
+func (n *Node) RemoteSubscribe(msg *common.RemoteSubscriptionMessage) {
+	if msg.Stream == "" {
+		n.log.Warn("remote subscribe requires a stream", "command", msg)
+		return
+	}
+
+	for _, s := range n.remoteSessions(msg.Identifier) {
+		isDirty := false
+
+		for _, identifier := range remoteChannels(s, msg.Channel) {
+			if s.subscriptions.HasChannelStream(identifier, msg.Stream) {
+				continue
+			}
+
+			isDirty = n.handleCommandReply(s, &common.Message{Identifier: identifier}, &common.CommandResult{Streams: []string{msg.Stream}}) || isDirty
+		}
+
+		if isDirty {
+			n.commitRemoteChanges(s)
+		}
+	}
+}
+
+// RemoteUnsubscribe detaches a stream from the subscriptions of the sessions with the specified identifiers.
+// If no stream is specified, the channel subscription is removed, and the client is notified via the "unsubscribed" message.
+func (n *Node) RemoteUnsubscribe(msg *common.RemoteSubscriptionMessage) {
+	if msg.Stream == "" && msg.Channel == "" {
+		n.log.Warn("remote unsubscribe requires a stream or a channel", "command", msg)
+		return
+	}
+
+	for _, s := range n.remoteSessions(msg.Identifier) {
+		if msg.Stream == "" {
+			if s.subscriptions.HasChannel(msg.Channel) {
+				// Unsubscribing performs an RPC call, we shouldn't block pub/sub processing
+				go n.unsubscribeRemotely(s, msg.Channel)
+			}
+
+			continue
+		}
+
+		isDirty := false
+
+		for _, identifier := range remoteChannels(s, msg.Channel) {
+			if !s.subscriptions.HasChannelStream(identifier, msg.Stream) {
+				continue
+			}
+
+			isDirty = n.handleCommandReply(s, &common.Message{Identifier: identifier}, &common.CommandResult{StoppedStreams: []string{msg.Stream}}) || isDirty
+		}
+
+		if isDirty {
+			n.commitRemoteChanges(s)
+		}
+	}
+}
+
+// RemoteUpdateState updates connection and channel states of the sessions with the specified identifiers
+func (n *Node) RemoteUpdateState(msg *common.RemoteStateMessage) {
+	for _, s := range n.remoteSessions(msg.Identifier) {
+		isDirty := false
+
+		if len(msg.CState) > 0 {
+			isDirty = n.handleCallReply(s, &common.CallResult{CState: msg.CState})
+		}
+
+		if len(msg.IState) > 0 {
+			for _, identifier := range remoteChannels(s, msg.Channel) {
+				isDirty = n.handleCommandReply(s, &common.Message{Identifier: identifier}, &common.CommandResult{IState: msg.IState}) || isDirty
+			}
+		}
+
+		if isDirty {
+			n.commitRemoteChanges(s)
+		}
+	}
+}
+
+func (n *Node) unsubscribeRemotely(s *Session, identifier string) {
+	if _, err := n.Unsubscribe(s, &common.Message{Identifier: identifier}); err != nil {
+		s.Log.Warn("remote unsubscribe failed", "identifier", identifier, "error", err)
+		return
+	}
+
+	s.Send(&common.Reply{Type: common.UnsubscribedType, Identifier: identifier})
+}
+
+func (n *Node) commitRemoteChanges(s *Session) {
+	if s.IsResumeable() {
+		if berr := n.broker.CommitSession(s.GetID(), s); berr != nil {
+			s.Log.Error("failed to persist session in cache", "error", berr)
+		}
+	}
+}
+
+func (n *Node) remoteSessions(identifier string) []*Session {
+	hubSessions := n.hub.FindAllByIdentifier(identifier)
+	sessions := make([]*Session, 0, len(hubSessions))
+
+	for _, hs := range hubSessions {
+		if s, ok := hs.(*Session); ok {
+			sessions = append(sessions, s)
+		}
+	}
+
+	return sessions
+}
+
+// remoteChannels returns the session's channels a remote command must be applied to
+func remoteChannels(s *Session, channel string) []string {
+	if channel == "" {
+		return s.subscriptions.Channels()
+	}
+
+	if s.subscriptions.HasChannel(channel) {
+		return []string{channel}
+	}
+
+	return nil
+}
+
 // Interest is represented as a int; -1 indicates no interest, 0 indicates lack of such information,
 // and 1 indicates interest.
 func (n *Node) markDisconnectable(s *Session, interest int) {
@@ -908,22 +1416,48 @@ func (n *Node) handleCommandReply(s *Session, msg *common.Message, reply *common
 		}
 	}
 
-	if reply.Streams != nil {
-		isDirty = true
-
-		for _, stream := range reply.Streams {
-			streamId := n.broker.Subscribe(stream)
-			n.hub.SubscribeSession(s, streamId, msg.Identifier)
-			s.subscriptions.AddChannelStream(msg.Identifier, streamId)
-		}
-	}
+	filterChanged := false
 
+	// Channel state must be updated before subscribing to streams,
+	// since it may contain the subscription filter
 	if reply.IState != nil {
 		isDirty = true
 
 		s.smu.Lock()
 		s.env.MergeChannelState(msg.Identifier, &reply.IState)
 		s.smu.Unlock()
+
+		_, filterChanged = reply.IState[common.FILTER_STATE]
+	}
+
+	if reply.Streams != nil || filterChanged {
+		isDirty = true
+
+		filter, ferr := n.subscriptionFilter(s, msg.Identifier)
+
+		if ferr != nil {
+			// Do not fallback to unfiltered delivery to avoid leaking messages
+			s.Log.Error("invalid subscription filter, streams are not subscribed", "identifier", msg.Identifier, "error", ferr)
+
+			if filterChanged {
+				n.hub.UnsubscribeSessionFromChannel(s, msg.Identifier)
+
+				for _, stream := range s.subscriptions.RemoveChannelStreams(msg.Identifier) {
+					n.broker.Unsubscribe(stream)
+				}
+			}
+		} else {
+			// Already subscribed streams must use the new filter, too
+			if filterChanged {
+				n.hub.UpdateSessionFilter(s, msg.Identifier, filter)
+			}
+
+			for _, stream := range reply.Streams {
+				streamId := n.broker.Subscribe(stream)
+				n.hub.SubscribeSessionWithFilter(s, streamId, msg.Identifier, filter)
+				s.subscriptions.AddChannelStream(msg.Identifier, streamId)
+			}
+		}
 	}
 
 	isConnectionDirty := n.handleCallReply(s, reply.ToCallResult())
@@ -934,6 +1468,49 @@ func (n *Node) handleCommandReply(s *Session, msg *common.Message, reply *common
 	return isDirty || isConnectionDirty
 }
 
+// subscriptionFilter compiles the subscription filter from the channel state:
+// the controller-provided filter and the client-requested filter are combined (both must match)
+func (n *Node) subscriptionFilter(s *Session, identifier string) (*filters.Filter, error) {
+	s.smu.Lock()
+	serverFilter := s.env.GetChannelStateField(identifier, common.FILTER_STATE)
+	clientFilter := s.env.GetChannelStateField(identifier, common.CLIENT_FILTER_STATE)
+	s.smu.Unlock()
+
+	var list []*filters.Filter
+
+	for _, raw := range []string{serverFilter, clientFilter} {
+		if raw == "" {
+			continue
+		}
+
+		filter, err := filters.Compile(raw)
+
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, filter)
+	}
+
+	return filters.And(list...), nil
+}
+
+// encodeClientFilter validates the client-requested filter and returns its JSON representation
+// to be stored in the channel state
+func (n *Node) encodeClientFilter(spec map[string]interface{}) (string, error) {
+	if _, err := filters.FromMap(spec); err != nil {
+		return "", err
+	}
+
+	raw, err := json.Marshal(spec)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(raw), nil
+}
+
 func (n *Node) handleCallReply(s *Session, reply *common.CallResult) bool {
 	isDirty := false
 
@@ -983,6 +1560,11 @@ func (n *Node) handlePresenceReply(s *Session, identifier string, event string,
 		msg, err = n.broker.PresenceAdd(stream, sid, presence.ID, presence.Info)
 	} else if event == common.PresenceLeaveType {
 		msg, err = n.broker.PresenceRemove(stream, sid)
+	} else if event == common.PresenceUpdateType {
+		if presence == nil {
+			return errors.New("presence data is missing")
+		}
+		msg, err = n.broker.PresenceUpdate(stream, sid, presence.Info)
 	} else {
 		return fmt.Errorf("unknown presence event: %s", event)
 	}
@@ -1087,6 +1669,19 @@ func (n *Node) collectStatsOnce() {
 	n.metrics.GaugeSet(metricsUniqClientsNum, uint64(n.hub.UniqSize()))
 	n.metrics.GaugeSet(metricsStreamsNum, uint64(n.hub.StreamsSize()))
 	n.metrics.GaugeSet(metricsDisconnectQueue, uint64(n.disconnector.Size()))
+
+	var pending, maxDepth int
+
+	for _, hs := range n.hub.Sessions() {
+		if s, ok := hs.(*Session); ok {
+			depth := s.sendQueue.len()
+			pending += depth
+			maxDepth = max(maxDepth, depth)
+		}
+	}
+
+	n.metrics.GaugeSet(metricsOutboundPending, uint64(pending))
+	n.metrics.GaugeSet(metricsOutboundMaxDepth, uint64(maxDepth))
 }
 
 func (n *Node) registerMetrics() {
@@ -1097,16 +1692,49 @@ func (n *Node) registerMetrics() {
 	n.metrics.RegisterGauge(metricsUniqClientsNum, "The number of unique clients (with respect to connection identifiers)")
 	n.metrics.RegisterGauge(metricsStreamsNum, "The number of active broadcasting streams")
 	n.metrics.RegisterGauge(metricsDisconnectQueue, "The size of delayed disconnect")
+	n.metrics.RegisterGauge(metricsOutboundPending, "The total number of pending outgoing messages")
+	n.metrics.RegisterGauge(metricsOutboundMaxDepth, "The max number of pending outgoing messages per client")
 
 	n.metrics.RegisterCounter(metricsFailedAuths, "The total number of failed authentication attempts")
 	n.metrics.RegisterCounter(metricsReceivedMsg, "The total number of received messages from clients")
 	n.metrics.RegisterCounter(metricsFailedCommandReceived, "The total number of unrecognized messages received from clients")
 	n.metrics.RegisterCounter(metricsBroadcastMsg, "The total number of messages received through PubSub (for broadcast)")
 	n.metrics.RegisterCounter(metricsUnknownBroadcast, "The total number of unrecognized messages received through PubSub")
+	n.metrics.RegisterCounter(metricsRemoteCommandsMsg, "The total number of remote commands received through PubSub")
 
 	n.metrics.RegisterCounter(metricsSentMsg, "The total number of messages sent to clients")
 	n.metrics.RegisterCounter(metricsFailedSent, "The total number of messages failed to send to clients")
 
 	n.metrics.RegisterCounter(metricsDataSent, "The total amount of bytes sent to clients")
 	n.metrics.RegisterCounter(metricsDataReceived, "The total amount of bytes received from clients")
+
+	n.metrics.RegisterCounter(metricsOutboundDropped, "The total number of outgoing messages dropped due to slow clients")
+	n.metrics.RegisterCounter(metricsSlowConsumers, "The total number of clients disconnected due to outbound queue overflow")
+	n.metrics.RegisterCounter(metricsOutboundConflated, "The total number of pending outgoing messages replaced by newer ones (conflated)")
+
+	n.metrics.RegisterCounter(metricsRateLimited, "The total number of client commands rejected due to rate limits")
+	n.metrics.RegisterCounter(metricsRateLimitedDisconnects, "The total number of clients disconnected for exceeding rate limits")
+
+	n.metrics.RegisterCounter(metricsSessionsQuotaExceeded, "The total number of connections rejected due to the sessions per identifier quota")
+	n.metrics.RegisterCounter(metricsSubscriptionsQuotaExceeded, "The total number of subscriptions rejected due to the subscriptions per session quota")
+
+	if registry, ok := n.metrics.(metrics.StreamsStatsRegistry); ok {
+		registry.RegisterStreamsStats(n.streamsStats)
+	}
+}
+
+func (n *Node) streamsStats() []*metrics.StreamStats {
+	stats := n.hub.StreamsStats()
+	res := make([]*metrics.StreamStats, len(stats))
+
+	for i, s := range stats {
+		res[i] = &metrics.StreamStats{
+			Stream:      s.Stream,
+			Subscribers: uint64(s.Subscribers),
+			Messages:    s.Messages,
+			Bytes:       s.Bytes,
+		}
+	}
+
+	return res
 }
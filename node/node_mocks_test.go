@@ -9,7 +9,6 @@ import (
 	"github.com/anycable/anycable-go/metrics"
 	"github.com/anycable/anycable-go/mocks"
 	"github.com/anycable/anycable-go/pubsub"
-	"github.com/anycable/anycable-go/ws"
 )
 
 // NewMockNode build new node with mock controller
@@ -18,7 +17,9 @@ func NewMockNode() *Node {
 	config := NewConfig()
 	config.HubGopoolSize = 2
 	node := NewNode(&config, WithInstrumenter(metrics.NewMetrics(nil, 10, slog.Default())), WithController(&controller))
-	node.SetBroker(broker.NewLegacyBroker(pubsub.NewLegacySubscriber(node)))
+	subscriber := pubsub.NewLegacySubscriber(node)
+	node.SetBroker(broker.NewLegacyBroker(subscriber))
+	node.SetBroadcaster(subscriber)
 	dconfig := NewDisconnectQueueConfig()
 	dconfig.Rate = 1
 	node.SetDisconnector(NewDisconnectQueue(node, &dconfig, slog.Default()))
@@ -34,7 +35,7 @@ func NewMockSession(uid string, node *Node, opts ...SessionOption) *Session {
 		Log:           slog.With("sid", uid),
 		subscriptions: NewSubscriptionState(),
 		env:           common.NewSessionEnv("/cable-test", &map[string]string{}),
-		sendCh:        make(chan *ws.SentFrame, 256),
+		sendQueue:     newOutboundQueue(256, OUTBOUND_QUEUE_POLICY_DISCONNECT),
 		encoder:       encoders.JSON{},
 		metrics:       metrics.NoopMetrics{},
 	}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/anycable/anycable-go/common"
 	"github.com/anycable/anycable-go/encoders"
+	"github.com/anycable/anycable-go/metrics"
 	"github.com/anycable/anycable-go/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -124,6 +125,79 @@ func TestSubscribe(t *testing.T) {
 		assert.Error(t, err)
 	})
 
+	t.Run("Subscription with a filter", func(t *testing.T) {
+		session := NewMockSession("16", node)
+
+		node.hub.AddSession(session)
+		defer node.hub.RemoveSession(session)
+
+		_, err := node.Subscribe(session, &common.Message{Identifier: "with_filter", Filter: map[string]interface{}{"kind": map[string]interface{}{"in": []interface{}{"a", "b"}}}})
+		require.NoError(t, err)
+
+		_, err = session.conn.Read()
+		require.NoError(t, err)
+
+		assert.Equal(t, `{"visible":true}`, session.env.GetChannelStateField("with_filter", common.FILTER_STATE))
+		assert.Equal(t, `{"kind":{"in":["a","b"]}}`, session.env.GetChannelStateField("with_filter", common.CLIENT_FILTER_STATE))
+
+		// Filtered out by the controller filter
+		node.hub.BroadcastMessage(&common.StreamMessage{Stream: "stream", Data: `{"visible":false,"kind":"a"}`})
+		// Filtered out by the client filter
+		node.hub.BroadcastMessage(&common.StreamMessage{Stream: "stream", Data: `{"visible":true,"kind":"c"}`})
+		node.hub.BroadcastMessage(&common.StreamMessage{Stream: "stream", Data: `{"visible":true,"kind":"b"}`})
+
+		msg, err := session.conn.Read()
+		require.NoError(t, err)
+
+		assert.JSONEq(t, `{"identifier":"with_filter","message":{"visible":true,"kind":"b"}}`, string(msg))
+	})
+
+	t.Run("Subscription filter update", func(t *testing.T) {
+		session := NewMockSession("18", node)
+
+		node.hub.AddSession(session)
+		defer node.hub.RemoveSession(session)
+
+		_, err := node.Subscribe(session, &common.Message{Identifier: "with_filter"})
+		require.NoError(t, err)
+
+		_, err = session.conn.Read()
+		require.NoError(t, err)
+
+		node.handleCommandReply(session, &common.Message{Identifier: "with_filter"}, &common.CommandResult{
+			IState: map[string]string{common.FILTER_STATE: `{"visible":false}`},
+		})
+
+		node.hub.BroadcastMessage(&common.StreamMessage{Stream: "stream", Data: `{"visible":true}`})
+		node.hub.BroadcastMessage(&common.StreamMessage{Stream: "stream", Data: `{"visible":false}`})
+
+		msg, err := session.conn.Read()
+		require.NoError(t, err)
+
+		assert.JSONEq(t, `{"identifier":"with_filter","message":{"visible":false}}`, string(msg))
+
+		// Invalid filter stops delivery
+		node.handleCommandReply(session, &common.Message{Identifier: "with_filter"}, &common.CommandResult{
+			IState: map[string]string{common.FILTER_STATE: `{"visible":{"like":true}}`},
+		})
+
+		node.hub.BroadcastMessage(&common.StreamMessage{Stream: "stream", Data: `{"visible":false}`})
+
+		_, err = session.conn.Read()
+		require.Error(t, err)
+
+		assert.Empty(t, session.subscriptions.StreamsFor("with_filter"))
+	})
+
+	t.Run("Subscription with an invalid filter", func(t *testing.T) {
+		session := NewMockSession("17", node)
+
+		_, err := node.Subscribe(session, &common.Message{Identifier: "with_stream_2", Filter: map[string]interface{}{"kind": map[string]interface{}{"like": "a"}}})
+		assert.Error(t, err)
+
+		assert.False(t, session.subscriptions.HasChannel("with_stream_2"))
+	})
+
 	t.Run("Rejected subscription", func(t *testing.T) {
 		session := NewMockSession("15", node)
 
@@ -306,6 +380,55 @@ func TestWhisper(t *testing.T) {
 	})
 }
 
+func TestHandleCommandRateLimits(t *testing.T) {
+	node := NewMockNode()
+	node.config.PerformRateLimit = 1
+	node.config.RateLimitMaxViolations = 2
+
+	session := NewMockSession("14", node, func(s *Session) {
+		s.rateLimiter = newCommandsLimiter(node.config)
+	})
+	session.Connected = true
+	session.closed = false
+	session.subscriptions.AddChannel("test_channel")
+
+	node.hub.AddSession(session)
+	defer node.hub.RemoveSession(session)
+
+	go node.hub.Run()
+	defer node.hub.Shutdown()
+
+	err := node.HandleCommand(session, &common.Message{Command: "message", Identifier: "test_channel", Data: "action"})
+	require.NoError(t, err)
+
+	msg, err := session.conn.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "action", string(msg))
+
+	err = node.HandleCommand(session, &common.Message{Command: "message", Identifier: "test_channel", Data: "action"})
+	require.NoError(t, err)
+
+	msg, err = session.conn.Read()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"error","identifier":"test_channel","reason":"rate_limited"}`, string(msg))
+
+	assert.Equal(t, uint64(1), node.metrics.(*metrics.Metrics).Counter(metricsRateLimited).Value())
+
+	// Non-limited commands are still processed
+	err = node.HandleCommand(session, &common.Message{Command: "pong"})
+	require.NoError(t, err)
+
+	err = node.HandleCommand(session, &common.Message{Command: "message", Identifier: "test_channel", Data: "action"})
+	require.NoError(t, err)
+
+	msg, err = session.conn.Read()
+	require.NoError(t, err)
+	assert.Equal(t, string(toJSON(common.NewDisconnectMessage("rate_limited", false))), string(msg))
+
+	assert.True(t, session.IsClosed())
+	assert.Equal(t, uint64(1), node.metrics.(*metrics.Metrics).Counter(metricsRateLimitedDisconnects).Value())
+}
+
 func TestStreamSubscriptionRaceConditions(t *testing.T) {
 	node := NewMockNode()
 	session := NewMockSession("14", node)
@@ -366,6 +489,90 @@ func TestDisconnect(t *testing.T) {
 	})
 }
 
+func TestHistoryWithFilter(t *testing.T) {
+	node := NewMockNode()
+
+	broker := &mocks.Broker{}
+	node.SetBroker(broker)
+
+	session := NewMockSession("14", node)
+
+	session.subscriptions.AddChannel("test_channel")
+	session.subscriptions.AddChannelStream("test_channel", "streamo")
+	session.env.MergeChannelState("test_channel", &map[string]string{common.FILTER_STATE: `{"visible":true}`})
+
+	ts := int64(100200)
+
+	broker.
+		On("HistorySince", "streamo", ts).
+		Return([]common.StreamMessage{
+			{Stream: "streamo", Data: `{"visible":false}`, Offset: 22, Epoch: "test"},
+			{Stream: "streamo", Data: `{"visible":true}`, Offset: 23, Epoch: "test"},
+		}, nil)
+
+	err := node.History(session, &common.Message{Identifier: "test_channel", History: common.HistoryRequest{Since: ts}})
+	require.NoError(t, err)
+
+	msg, err := session.conn.Read()
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"identifier":"test_channel","message":{"visible":true},"stream_id":"streamo","epoch":"test","offset":23}`, string(msg))
+
+	ack, err := session.conn.Read()
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"type":"confirm_history","identifier":"test_channel"}`, string(ack))
+}
+
+func TestHistoryLimit(t *testing.T) {
+	node := NewMockNode()
+
+	broker := &mocks.Broker{}
+	node.SetBroker(broker)
+
+	session := NewMockSession("14", node)
+
+	session.subscriptions.AddChannel("test_channel")
+	session.subscriptions.AddChannelStream("test_channel", "streamo")
+	session.subscriptions.AddChannelStream("test_channel", "emptissimo")
+	session.env.MergeChannelState("test_channel", &map[string]string{common.FILTER_STATE: `{"visible":true}`})
+
+	ts := int64(100200)
+
+	broker.
+		On("HistorySince", "streamo", ts, mock.Anything).
+		Return([]common.StreamMessage{
+			{Stream: "streamo", Data: `{"visible":true,"n":1}`, Offset: 22, Epoch: "test"},
+			{Stream: "streamo", Data: `{"visible":false,"n":2}`, Offset: 23, Epoch: "test"},
+			{Stream: "streamo", Data: `{"visible":true,"n":3}`, Offset: 24, Epoch: "test"},
+		}, nil)
+	broker.
+		On("HistorySince", "emptissimo", ts, mock.Anything).
+		Return([]common.StreamMessage{
+			{Stream: "emptissimo", Data: `{"visible":true,"n":4}`, Offset: 2, Epoch: "test"},
+		}, nil)
+
+	err := node.History(session, &common.Message{Identifier: "test_channel", History: common.HistoryRequest{Since: ts, Limit: 2}})
+	require.NoError(t, err)
+
+	// Filtered out messages do not count towards the limit, which applies to the whole reply
+	msg, err := session.conn.Read()
+	require.NoError(t, err)
+	assert.Contains(t, string(msg), `"offset":22`)
+
+	msg, err = session.conn.Read()
+	require.NoError(t, err)
+	assert.Contains(t, string(msg), `"offset":24`)
+
+	ack, err := session.conn.Read()
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"type":"confirm_history","identifier":"test_channel","history":{"truncated":true,"streams":{"streamo":{"epoch":"test","offset":24}}}}`, string(ack))
+
+	_, err = session.conn.Read()
+	require.Error(t, err)
+}
+
 func TestHistory(t *testing.T) {
 	node := NewMockNode()
 
@@ -574,6 +781,79 @@ func TestHistory(t *testing.T) {
 		require.Error(t, err)
 	})
 
+	t.Run("Truncated history with Limit", func(t *testing.T) {
+		ts = 200000
+
+		broker.
+			On("HistoryFrom", "streamo", "test", uint64(21), mock.Anything).
+			Return(stream, nil)
+		broker.
+			On("HistorySince", "emptissimo", ts, mock.Anything).
+			Return(nil, nil)
+
+		err := node.History(
+			session,
+			&common.Message{
+				Identifier: "test_channel",
+				History: common.HistoryRequest{
+					Since: ts,
+					Limit: 1,
+					Streams: map[string]common.HistoryPosition{
+						"streamo": {Epoch: "test", Offset: 21},
+					},
+				},
+			},
+		)
+		require.NoError(t, err)
+
+		received, err := session.conn.Read()
+		require.NoError(t, err)
+
+		assert.Equal(t, "{\"identifier\":\"test_channel\",\"message\":\"ciao\",\"stream_id\":\"streamo\",\"epoch\":\"test\",\"offset\":22}", string(received))
+
+		ack, err := session.conn.Read()
+		require.NoError(t, err)
+
+		assert.Equal(t, `{"type":"confirm_history","identifier":"test_channel","history":{"truncated":true,"streams":{"streamo":{"epoch":"test","offset":22}}}}`, string(ack))
+
+		_, err = session.conn.Read()
+		require.Error(t, err)
+	})
+
+	t.Run("Latest history with Limit", func(t *testing.T) {
+		ts = 200050
+
+		broker.
+			On("HistorySince", "streamo", ts, mock.Anything).
+			Return(stream, nil)
+		broker.
+			On("HistorySince", "emptissimo", ts, mock.Anything).
+			Return(nil, nil)
+
+		err := node.History(
+			session,
+			&common.Message{
+				Identifier: "test_channel",
+				History: common.HistoryRequest{
+					Since:  ts,
+					Limit:  1,
+					Latest: true,
+				},
+			},
+		)
+		require.NoError(t, err)
+
+		received, err := session.conn.Read()
+		require.NoError(t, err)
+
+		assert.Equal(t, "{\"identifier\":\"test_channel\",\"message\":\"buona sera\",\"stream_id\":\"streamo\",\"epoch\":\"test\",\"offset\":23}", string(received))
+
+		ack, err := session.conn.Read()
+		require.NoError(t, err)
+
+		assert.Equal(t, `{"type":"confirm_history","identifier":"test_channel","history":{"truncated":true}}`, string(ack))
+	})
+
 	t.Run("Error retrieving history", func(t *testing.T) {
 		ts = 200100
 
@@ -786,6 +1066,24 @@ func TestBroadcasting(t *testing.T) {
 	})
 }
 
+func TestBroadcastConflation(t *testing.T) {
+	node := NewMockNode()
+	node.config.ConflateStreams = []string{"prices:*"}
+
+	meta := &common.StreamMessageMetadata{BroadcastType: "price"}
+	msg := &common.StreamMessage{Stream: "prices:btc", Data: "1", Meta: meta}
+
+	conflated := node.withConflation(msg)
+
+	require.NotSame(t, msg, conflated)
+	assert.True(t, conflated.Meta.Conflate)
+	assert.Equal(t, "price", conflated.Meta.BroadcastType)
+	assert.False(t, meta.Conflate)
+
+	msg = &common.StreamMessage{Stream: "news", Data: "1"}
+	assert.Same(t, msg, node.withConflation(msg))
+}
+
 func TestHandlePubSubWithCommand(t *testing.T) {
 	node := NewMockNode()
 
@@ -805,6 +1103,133 @@ func TestHandlePubSubWithCommand(t *testing.T) {
 	assert.True(t, session.closed)
 }
 
+func TestHandlePubSubWithTransmitCommand(t *testing.T) {
+	node := NewMockNode()
+
+	go node.hub.Run()
+	defer node.hub.Shutdown()
+
+	session := NewMockSession("14", node)
+	node.hub.AddSession(session)
+
+	session2 := NewMockSession("15", node)
+	session2.SetIdentifiers("14")
+	node.hub.AddSession(session2)
+
+	session3 := NewMockSession("16", node)
+	node.hub.AddSession(session3)
+
+	node.HandlePubSub([]byte(`{"command":"transmit","payload":{"identifier":"14","data":"{\"type\":\"notification\",\"text\":\"hi\"}"}}`))
+
+	expected := `{"type":"notification","text":"hi"}`
+
+	msg, err := session.conn.Read()
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(msg))
+
+	msg, err = session2.conn.Read()
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(msg))
+
+	_, err = session3.conn.Read()
+	assert.Error(t, err)
+
+	node.HandlePubSub([]byte(`{"command":"transmit","payload":{"identifier":"14","data":{"type":"notification","text":"hi"}}}`))
+
+	msg, err = session.conn.Read()
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(msg))
+
+	assert.Equal(t, uint64(2), node.metrics.(*metrics.Metrics).Counter(metricsRemoteCommandsMsg).Value())
+	assert.Equal(t, uint64(0), node.metrics.(*metrics.Metrics).Counter(metricsBroadcastMsg).Value())
+}
+
+func TestHandlePubSubWithSubscriptionCommands(t *testing.T) {
+	node := NewMockNode()
+
+	go node.hub.Run()
+	defer node.hub.Shutdown()
+
+	session := NewMockSession("14", node)
+	node.hub.AddSession(session)
+
+	session2 := NewMockSession("15", node)
+	node.hub.AddSession(session2)
+
+	for _, s := range []*Session{session, session2} {
+		_, err := node.Subscribe(s, &common.Message{Identifier: "test_channel"})
+		require.NoError(t, err)
+
+		_, err = node.Subscribe(s, &common.Message{Identifier: "chat_channel"})
+		require.NoError(t, err)
+
+		_, err = readMessages(s.conn, 2)
+		require.NoError(t, err)
+	}
+
+	t.Run("Subscribe to a stream", func(t *testing.T) {
+		node.HandlePubSub([]byte(`{"command":"subscribe","payload":{"identifier":"14","channel":"test_channel","stream":"project:1"}}`))
+
+		assert.Equal(t, []string{"project:1"}, session.subscriptions.StreamsFor("test_channel"))
+		assert.Empty(t, session.subscriptions.StreamsFor("chat_channel"))
+		assert.Empty(t, session2.subscriptions.StreamsFor("test_channel"))
+
+		node.hub.BroadcastMessage(&common.StreamMessage{Stream: "project:1", Data: "42"})
+
+		msg, err := session.conn.Read()
+		require.NoError(t, err)
+		assert.Equal(t, `{"identifier":"test_channel","message":42}`, string(msg))
+
+		_, err = session2.conn.Read()
+		assert.Error(t, err)
+	})
+
+	t.Run("Subscribe to a stream for all channels", func(t *testing.T) {
+		node.HandlePubSub([]byte(`{"command":"subscribe","payload":{"identifier":"14","stream":"project:2"}}`))
+
+		assert.ElementsMatch(t, []string{"project:1", "project:2"}, session.subscriptions.StreamsFor("test_channel"))
+		assert.Equal(t, []string{"project:2"}, session.subscriptions.StreamsFor("chat_channel"))
+	})
+
+	t.Run("Unsubscribe from a stream", func(t *testing.T) {
+		node.HandlePubSub([]byte(`{"command":"unsubscribe","payload":{"identifier":"14","stream":"project:2"}}`))
+
+		assert.Equal(t, []string{"project:1"}, session.subscriptions.StreamsFor("test_channel"))
+		assert.Empty(t, session.subscriptions.StreamsFor("chat_channel"))
+
+		node.hub.BroadcastMessage(&common.StreamMessage{Stream: "project:2", Data: "42"})
+
+		_, err := session.conn.Read()
+		assert.Error(t, err)
+	})
+
+	t.Run("Update state", func(t *testing.T) {
+		node.HandlePubSub([]byte(`{"command":"update_state","payload":{"identifier":"14","channel":"chat_channel","cstate":{"role":"guest"},"istate":{"room":"1"}}}`))
+
+		assert.Equal(t, "guest", session.env.GetConnectionStateField("role"))
+		assert.Equal(t, "1", session.env.GetChannelStateField("chat_channel", "room"))
+		assert.Equal(t, "", session.env.GetChannelStateField("test_channel", "room"))
+		assert.Equal(t, "", session2.env.GetConnectionStateField("role"))
+	})
+
+	t.Run("Unsubscribe from a channel", func(t *testing.T) {
+		node.HandlePubSub([]byte(`{"command":"unsubscribe","payload":{"identifier":"14","channel":"test_channel"}}`))
+
+		msg, err := session.conn.Read()
+		require.NoError(t, err)
+		assert.Equal(t, `{"type":"unsubscribed","identifier":"test_channel"}`, string(msg))
+
+		assert.False(t, session.subscriptions.HasChannel("test_channel"))
+		assert.True(t, session.subscriptions.HasChannel("chat_channel"))
+		assert.True(t, session2.subscriptions.HasChannel("test_channel"))
+
+		node.hub.BroadcastMessage(&common.StreamMessage{Stream: "project:1", Data: "42"})
+
+		_, err = session.conn.Read()
+		assert.Error(t, err)
+	})
+}
+
 func TestLookupSession(t *testing.T) {
 	node := NewMockNode()
 
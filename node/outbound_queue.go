@@ -0,0 +1,214 @@
+package node
+
+import (
+	"sync"
+
+	"github.com/anycable/anycable-go/ws"
+)
+
+type outboundFrame struct {
+	frame     *ws.SentFrame
+	transient bool
+	// key is used to conflate frames (only the latest frame with the same key is kept)
+	key string
+}
+
+// outboundQueue is a bounded FIFO queue of frames to be written to the client connection.
+// When the queue is full, the overflow policy defines which frames to drop (or whether the client must be disconnected).
+// Control frames (close frames) are never dropped and do not count towards the limit.
+type outboundQueue struct {
+	frames []*outboundFrame
+	size   int
+	policy string
+
+	// overflowed is set when the client must be disconnected,
+	// new data frames are ignored from this moment
+	overflowed bool
+	closed     bool
+
+	ready chan struct{}
+	mu    sync.Mutex
+}
+
+func newOutboundQueue(size int, policy string) *outboundQueue {
+	return &outboundQueue{
+		frames: make([]*outboundFrame, 0, min(size, 16)),
+		size:   size,
+		policy: policy,
+		ready:  make(chan struct{}, 1),
+	}
+}
+
+// enqueue replaces a pending frame with the same conflation key or adds the frame to the queue
+// (atomically, so concurrent frames with the same key couldn't be both appended).
+// Returns true if the frame has been conflated; otherwise, returns the results of push.
+func (q *outboundQueue) enqueue(frame *ws.SentFrame, transient bool, key string) (bool, int, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.replaceFrame(frame, transient, key) {
+		return true, 0, true
+	}
+
+	dropped, ok := q.pushFrame(frame, transient, key)
+
+	return false, dropped, ok
+}
+
+// push adds a frame to the queue. Returns the number of dropped frames and false if the queue overflowed
+// and the client must be disconnected
+func (q *outboundQueue) push(frame *ws.SentFrame, transient bool, key string) (int, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.pushFrame(frame, transient, key)
+}
+
+func (q *outboundQueue) pushFrame(frame *ws.SentFrame, transient bool, key string) (int, bool) {
+	if q.closed {
+		return 0, true
+	}
+
+	if frame.FrameType == ws.CloseFrame {
+		q.append(frame, false, "")
+		return 0, true
+	}
+
+	if q.overflowed {
+		return 1, true
+	}
+
+	if q.size <= 0 || len(q.frames) < q.size {
+		q.append(frame, transient, key)
+		return 0, true
+	}
+
+	switch q.policy {
+	case OUTBOUND_QUEUE_POLICY_DROP_OLDEST:
+		q.remove(0)
+		q.append(frame, transient, key)
+
+		return 1, true
+	case OUTBOUND_QUEUE_POLICY_DROP_TRANSIENT:
+		if transient {
+			return 1, true
+		}
+
+		for i, f := range q.frames {
+			if f.transient {
+				q.remove(i)
+				q.append(frame, transient, key)
+
+				return 1, true
+			}
+		}
+	}
+
+	// Pending frames are dropped, so a disconnect notice could be delivered right away
+	dropped := len(q.frames) + 1
+	q.frames = q.frames[:0]
+	q.overflowed = true
+
+	return dropped, false
+}
+
+// pushControl adds a frame to the queue bypassing the limit
+func (q *outboundQueue) pushControl(frame *ws.SentFrame) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+
+	q.append(frame, false, "")
+}
+
+// replace substitutes a pending frame with the same conflation key with the new one.
+// Returns false if there is no such frame.
+func (q *outboundQueue) replace(frame *ws.SentFrame, transient bool, key string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.replaceFrame(frame, transient, key)
+}
+
+func (q *outboundQueue) replaceFrame(frame *ws.SentFrame, transient bool, key string) bool {
+	if key == "" || q.closed || q.overflowed {
+		return false
+	}
+
+	for _, f := range q.frames {
+		if f.key == key {
+			f.frame = frame
+			f.transient = transient
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// pop returns the next frame to write; it blocks until a frame is available.
+// Returns false when the queue is closed and drained.
+func (q *outboundQueue) pop() (*ws.SentFrame, bool) {
+	for {
+		q.mu.Lock()
+
+		if len(q.frames) > 0 {
+			f := q.frames[0]
+			q.remove(0)
+			q.mu.Unlock()
+
+			return f.frame, true
+		}
+
+		if q.closed {
+			q.mu.Unlock()
+			return nil, false
+		}
+
+		q.mu.Unlock()
+
+		<-q.ready
+	}
+}
+
+// len returns the number of pending frames
+func (q *outboundQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.frames)
+}
+
+func (q *outboundQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+
+	q.closed = true
+	q.notify()
+}
+
+func (q *outboundQueue) append(frame *ws.SentFrame, transient bool, key string) {
+	q.frames = append(q.frames, &outboundFrame{frame: frame, transient: transient, key: key})
+	q.notify()
+}
+
+func (q *outboundQueue) remove(i int) {
+	copy(q.frames[i:], q.frames[i+1:])
+	q.frames[len(q.frames)-1] = nil
+	q.frames = q.frames[:len(q.frames)-1]
+}
+
+func (q *outboundQueue) notify() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,168 @@
+package node
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/anycable/anycable-go/ws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func textFrame(payload string) *ws.SentFrame {
+	return &ws.SentFrame{FrameType: ws.TextFrame, Payload: []byte(payload)}
+}
+
+func drainQueue(q *outboundQueue) []string {
+	res := []string{}
+
+	for q.len() > 0 {
+		frame, _ := q.pop()
+		res = append(res, string(frame.Payload))
+	}
+
+	return res
+}
+
+func TestOutboundQueue_DropOldest(t *testing.T) {
+	q := newOutboundQueue(2, OUTBOUND_QUEUE_POLICY_DROP_OLDEST)
+
+	for _, payload := range []string{"a", "b"} {
+		dropped, ok := q.push(textFrame(payload), false, "")
+		require.True(t, ok)
+		assert.Equal(t, 0, dropped)
+	}
+
+	dropped, ok := q.push(textFrame("c"), false, "")
+	require.True(t, ok)
+	assert.Equal(t, 1, dropped)
+
+	assert.Equal(t, []string{"b", "c"}, drainQueue(q))
+}
+
+func TestOutboundQueue_DropTransient(t *testing.T) {
+	q := newOutboundQueue(2, OUTBOUND_QUEUE_POLICY_DROP_TRANSIENT)
+
+	q.push(textFrame("a"), false, "")
+	q.push(textFrame("whisper"), true, "")
+
+	// Incoming transient message is dropped
+	dropped, ok := q.push(textFrame("typing"), true, "")
+	require.True(t, ok)
+	assert.Equal(t, 1, dropped)
+
+	// Queued transient message is evicted
+	dropped, ok = q.push(textFrame("b"), false, "")
+	require.True(t, ok)
+	assert.Equal(t, 1, dropped)
+
+	assert.Equal(t, 2, q.len())
+
+	// No transient messages left, so we cannot keep up
+	dropped, ok = q.push(textFrame("c"), false, "")
+	require.False(t, ok)
+	assert.Equal(t, 3, dropped)
+	assert.Equal(t, 0, q.len())
+}
+
+func TestOutboundQueue_Disconnect(t *testing.T) {
+	q := newOutboundQueue(1, OUTBOUND_QUEUE_POLICY_DISCONNECT)
+
+	_, ok := q.push(textFrame("a"), false, "")
+	require.True(t, ok)
+
+	dropped, ok := q.push(textFrame("b"), true, "")
+	require.False(t, ok)
+	assert.Equal(t, 2, dropped)
+
+	// Data frames are ignored after overflow
+	dropped, ok = q.push(textFrame("c"), false, "")
+	require.True(t, ok)
+	assert.Equal(t, 1, dropped)
+
+	q.pushControl(textFrame("disconnect"))
+
+	// Close frames are always accepted
+	_, ok = q.push(&ws.SentFrame{FrameType: ws.CloseFrame}, false, "")
+	require.True(t, ok)
+
+	assert.Equal(t, 2, q.len())
+
+	frame, ok := q.pop()
+	require.True(t, ok)
+	assert.Equal(t, "disconnect", string(frame.Payload))
+
+	frame, ok = q.pop()
+	require.True(t, ok)
+	assert.Equal(t, ws.CloseFrame, frame.FrameType)
+}
+
+func TestOutboundQueue_Close(t *testing.T) {
+	q := newOutboundQueue(10, OUTBOUND_QUEUE_POLICY_DISCONNECT)
+
+	done := make(chan struct{})
+
+	go func() {
+		frame, ok := q.pop()
+		assert.True(t, ok)
+		assert.Equal(t, "a", string(frame.Payload))
+
+		_, ok = q.pop()
+		assert.False(t, ok)
+
+		close(done)
+	}()
+
+	q.push(textFrame("a"), false, "")
+	q.close()
+
+	<-done
+
+	_, ok := q.push(textFrame("b"), false, "")
+	assert.True(t, ok)
+	assert.Equal(t, 0, q.len())
+}
+
+func TestOutboundQueue_Replace(t *testing.T) {
+	q := newOutboundQueue(10, OUTBOUND_QUEUE_POLICY_DISCONNECT)
+
+	assert.False(t, q.replace(textFrame("price:1"), false, "prices"))
+
+	q.push(textFrame("price:1"), false, "prices")
+	q.push(textFrame("news:1"), false, "")
+
+	assert.True(t, q.replace(textFrame("price:2"), false, "prices"))
+	assert.True(t, q.replace(textFrame("price:3"), false, "prices"))
+	assert.False(t, q.replace(textFrame("news:2"), false, ""))
+
+	assert.Equal(t, []string{"price:3", "news:1"}, drainQueue(q))
+
+	// Delivered frames are not replaced
+	assert.False(t, q.replace(textFrame("price:4"), false, "prices"))
+}
+
+func TestOutboundQueue_Enqueue(t *testing.T) {
+	q := newOutboundQueue(10, OUTBOUND_QUEUE_POLICY_DISCONNECT)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			q.enqueue(textFrame("price"), false, "prices")
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, 1, q.len())
+
+	conflated, dropped, ok := q.enqueue(textFrame("news"), false, "")
+
+	assert.False(t, conflated)
+	assert.Equal(t, 0, dropped)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"price", "news"}, drainQueue(q))
+}
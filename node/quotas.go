@@ -0,0 +1,96 @@
+package node
+
+import (
+	"github.com/anycable/anycable-go/broker"
+	"github.com/anycable/anycable-go/common"
+)
+
+// acquireSessionQuota returns false if the number of sessions with the same identifiers
+// reached the configured limit. Otherwise, a quota slot is reserved for the session
+// (cluster-wide, if a distributed broker is used).
+func (n *Node) acquireSessionQuota(s *Session, ids string) bool {
+	limit := n.config.MaxSessionsPerIdentifier
+
+	// Anonymous connections are not limited
+	if limit <= 0 || ids == "" {
+		return true
+	}
+
+	ok, err := n.sessionsQuota().AcquireSessionQuota(ids, s.GetID(), limit)
+
+	if err != nil {
+		s.Log.Error("failed to acquire sessions quota", "error", err)
+		return true
+	}
+
+	if !ok {
+		return false
+	}
+
+	s.smu.Lock()
+	s.quotaKey = ids
+	s.smu.Unlock()
+
+	return true
+}
+
+// releaseSessionQuota frees the quota slot occupied by the session
+func (n *Node) releaseSessionQuota(s *Session) {
+	s.smu.Lock()
+	key := s.quotaKey
+	s.quotaKey = ""
+	s.smu.Unlock()
+
+	if key == "" {
+		return
+	}
+
+	if err := n.sessionsQuota().ReleaseSessionQuota(key, s.GetID()); err != nil {
+		s.Log.Error("failed to release sessions quota", "error", err)
+	}
+}
+
+// sessionsQuota returns the broker's quota if it's shared between nodes; otherwise, sessions are tracked locally
+func (n *Node) sessionsQuota() broker.SessionsQuota {
+	if n.clusterQuotasEnabled() {
+		if q, ok := n.broker.(broker.SessionsQuota); ok {
+			return q
+		}
+	}
+
+	return n.localQuota
+}
+
+// rejectOverQuota disconnects the authenticated session exceeding the sessions quota
+func (n *Node) rejectOverQuota(s *Session, res *common.ConnectResult) *common.ConnectResult {
+	n.metrics.CounterIncrement(metricsSessionsQuotaExceeded)
+	s.Log.Warn("sessions quota exceeded", "ids", res.Identifier)
+
+	// The session has been accepted by the application, so we must notify it about disconnection
+	s.SetIdentifiers(res.Identifier)
+	n.markDisconnectable(s, res.DisconnectInterest)
+
+	if s.IsDisconnectable() {
+		if err := n.disconnector.Enqueue(s); err != nil {
+			s.Log.Error("failed to enqueue disconnect", "error", err)
+		}
+	}
+
+	s.DisconnectWithMessage(common.NewDisconnectMessage(common.TOO_MANY_SESSIONS_REASON, false), common.TOO_MANY_SESSIONS_REASON)
+
+	return &common.ConnectResult{Status: common.FAILURE, Identifier: res.Identifier}
+}
+
+// subscriptionsQuotaExceeded returns true if the session can't subscribe to more channels.
+// Must be called under the session's state lock.
+func (n *Node) subscriptionsQuotaExceeded(s *Session) bool {
+	limit := n.config.MaxSubscriptionsPerSession
+
+	return limit > 0 && len(s.subscriptions.Channels()) >= limit
+}
+
+func (n *Node) clusterQuotasEnabled() bool {
+	d, ok := n.broker.(broker.Distributed)
+
+	return ok && d.IsDistributed()
+}
@@ -0,0 +1,151 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/anycable/anycable-go/broker"
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/metrics"
+	"github.com/anycable/anycable-go/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// distributedMemory emulates a distributed broker by sharing the same memory broker between nodes
+type distributedMemory struct {
+	*broker.Memory
+}
+
+func (distributedMemory) IsDistributed() bool {
+	return true
+}
+
+func TestSessionsQuota(t *testing.T) {
+	node := NewMockNode()
+	node.config.MaxSessionsPerIdentifier = 2
+
+	go node.hub.Run()
+	defer node.hub.Shutdown()
+
+	first := NewMockSessionWithEnv("1", node, "/cable", &map[string]string{"id": "john"})
+	second := NewMockSessionWithEnv("2", node, "/cable", &map[string]string{"id": "john"})
+	other := NewMockSessionWithEnv("3", node, "/cable", &map[string]string{"id": "jack"})
+
+	for _, s := range []*Session{first, second, other} {
+		res, err := node.Authenticate(s)
+		require.NoError(t, err)
+		assert.Equal(t, common.SUCCESS, res.Status)
+
+		_, err = s.conn.Read()
+		require.NoError(t, err)
+	}
+
+	session := NewMockSessionWithEnv("4", node, "/cable", &map[string]string{"id": "john"})
+
+	res, err := node.Authenticate(session)
+	require.NoError(t, err)
+	assert.Equal(t, common.FAILURE, res.Status)
+	assert.False(t, session.Connected)
+
+	msg, err := session.conn.Read()
+	require.NoError(t, err)
+	assert.Equal(t, string(toJSON(common.NewDisconnectMessage(common.TOO_MANY_SESSIONS_REASON, false))), string(msg))
+
+	assert.Equal(t, 3, node.hub.Size())
+	assert.Equal(t, uint64(1), node.metrics.(*metrics.Metrics).Counter(metricsSessionsQuotaExceeded).Value())
+
+	t.Run("when a session is gone", func(t *testing.T) {
+		require.NoError(t, node.Disconnect(first))
+
+		session := NewMockSessionWithEnv("5", node, "/cable", &map[string]string{"id": "john"})
+
+		res, err := node.Authenticate(session)
+		require.NoError(t, err)
+		assert.Equal(t, common.SUCCESS, res.Status)
+	})
+
+	t.Run("anonymous sessions are not limited", func(t *testing.T) {
+		for _, sid := range []string{"a1", "a2", "a3"} {
+			session := NewMockSessionWithEnv(sid, node, "/cable", &map[string]string{})
+
+			res, err := node.Authenticate(session)
+			require.NoError(t, err)
+			assert.Equal(t, common.SUCCESS, res.Status)
+		}
+	})
+}
+
+func TestSessionsQuotaCluster(t *testing.T) {
+	nodeA := NewMockNode()
+	nodeB := NewMockNode()
+
+	bconf := broker.NewConfig()
+	br := distributedMemory{broker.NewMemoryBroker(pubsub.NewLegacySubscriber(nodeA), &bconf)}
+
+	for _, n := range []*Node{nodeA, nodeB} {
+		n.config.MaxSessionsPerIdentifier = 1
+		n.SetBroker(br)
+
+		go n.hub.Run()
+		defer n.hub.Shutdown()
+	}
+
+	sessionA := NewMockSessionWithEnv("1", nodeA, "/cable", &map[string]string{"id": "john"})
+
+	res, err := nodeA.Authenticate(sessionA)
+	require.NoError(t, err)
+	require.Equal(t, common.SUCCESS, res.Status)
+
+	sessionB := NewMockSessionWithEnv("2", nodeB, "/cable", &map[string]string{"id": "john"})
+
+	res, err = nodeB.Authenticate(sessionB)
+	require.NoError(t, err)
+	assert.Equal(t, common.FAILURE, res.Status)
+
+	ok, err := br.AcquireSessionQuota("john", "probe", 1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, nodeA.Disconnect(sessionA))
+
+	sessionB = NewMockSessionWithEnv("3", nodeB, "/cable", &map[string]string{"id": "john"})
+
+	res, err = nodeB.Authenticate(sessionB)
+	require.NoError(t, err)
+	assert.Equal(t, common.SUCCESS, res.Status)
+}
+
+func TestSubscriptionsQuota(t *testing.T) {
+	node := NewMockNode()
+	node.config.MaxSubscriptionsPerSession = 1
+
+	session := NewMockSession("14", node)
+
+	go node.hub.Run()
+	defer node.hub.Shutdown()
+
+	res, err := node.Subscribe(session, &common.Message{Identifier: "test_channel"})
+	require.NoError(t, err)
+	assert.Equal(t, common.SUCCESS, res.Status)
+
+	_, err = session.conn.Read()
+	require.NoError(t, err)
+
+	res, err = node.Subscribe(session, &common.Message{Identifier: "another_channel"})
+	require.Error(t, err)
+	assert.Equal(t, common.FAILURE, res.Status)
+
+	msg, err := session.conn.Read()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"reject_subscription","identifier":"another_channel","reason":"too_many_subscriptions"}`, string(msg))
+
+	assert.Equal(t, []string{"test_channel"}, session.subscriptions.Channels())
+	assert.Equal(t, uint64(1), node.metrics.(*metrics.Metrics).Counter(metricsSubscriptionsQuotaExceeded).Value())
+
+	_, err = node.Unsubscribe(session, &common.Message{Identifier: "test_channel"})
+	require.NoError(t, err)
+
+	res, err = node.Subscribe(session, &common.Message{Identifier: "another_channel"})
+	require.NoError(t, err)
+	assert.Equal(t, common.SUCCESS, res.Status)
+}
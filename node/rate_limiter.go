@@ -0,0 +1,128 @@
+package node
+
+import (
+	"sync"
+	"time"
+)
+
+// Command budgets (rate limits are tracked separately for each of them)
+const (
+	performBudget  = "perform"
+	whisperBudget  = "whisper"
+	presenceBudget = "presence"
+)
+
+// commandBudgets maps client commands to the rate limiting budgets.
+// Commands not listed here are not rate limited.
+var commandBudgets = map[string]string{
+	"message":  performBudget,
+	"whisper":  whisperBudget,
+	"presence": presenceBudget,
+	"join":     presenceBudget,
+	"leave":    presenceBudget,
+	"update":   presenceBudget,
+}
+
+// tokenBucket implements a classic token bucket algorithm:
+// the bucket is refilled at the specified rate (tokens per second) up to the burst size,
+// and each command consumes a token
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate int, burst int) *tokenBucket {
+	if burst <= 0 {
+		burst = rate
+	}
+
+	return &tokenBucket{rate: float64(rate), burst: float64(burst), tokens: float64(burst)}
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+// commandsLimiter tracks incoming commands rate for a session
+type commandsLimiter struct {
+	buckets map[string]*tokenBucket
+	// The number of consecutive rejected commands to disconnect the session after
+	maxViolations int
+	violations    int
+
+	mu sync.Mutex
+}
+
+// newCommandsLimiter returns a limiter for the configured budgets or nil if rate limiting is disabled
+func newCommandsLimiter(c *Config) *commandsLimiter {
+	buckets := make(map[string]*tokenBucket)
+
+	if c.PerformRateLimit > 0 {
+		buckets[performBudget] = newTokenBucket(c.PerformRateLimit, c.PerformRateBurst)
+	}
+
+	if c.WhisperRateLimit > 0 {
+		buckets[whisperBudget] = newTokenBucket(c.WhisperRateLimit, c.WhisperRateBurst)
+	}
+
+	if c.PresenceRateLimit > 0 {
+		buckets[presenceBudget] = newTokenBucket(c.PresenceRateLimit, c.PresenceRateBurst)
+	}
+
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	return &commandsLimiter{buckets: buckets, maxViolations: c.RateLimitMaxViolations}
+}
+
+// allow returns true if the command fits the budget.
+// The second return value is true when the session exceeded the max number of consecutive violations
+// and must be disconnected.
+func (l *commandsLimiter) allow(command string, now time.Time) (bool, bool) {
+	if l == nil {
+		return true, false
+	}
+
+	budget, ok := commandBudgets[command]
+
+	if !ok {
+		return true, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, ok := l.buckets[budget]
+
+	if !ok {
+		return true, false
+	}
+
+	if bucket.allow(now) {
+		l.violations = 0
+		return true, false
+	}
+
+	l.violations++
+
+	return false, l.maxViolations > 0 && l.violations >= l.maxViolations
+}
@@ -0,0 +1,115 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(2, 3)
+	now := time.Now()
+
+	assert.True(t, bucket.allow(now))
+	assert.True(t, bucket.allow(now))
+	assert.True(t, bucket.allow(now))
+	assert.False(t, bucket.allow(now))
+
+	// Refilled with a single token
+	now = now.Add(500 * time.Millisecond)
+
+	assert.True(t, bucket.allow(now))
+	assert.False(t, bucket.allow(now))
+
+	// Never refilled beyond the burst size
+	now = now.Add(time.Minute)
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, bucket.allow(now))
+	}
+
+	assert.False(t, bucket.allow(now))
+}
+
+func TestCommandsLimiter(t *testing.T) {
+	t.Run("when disabled", func(t *testing.T) {
+		config := NewConfig()
+		limiter := newCommandsLimiter(&config)
+
+		assert.Nil(t, limiter)
+
+		allowed, exceeded := limiter.allow("message", time.Now())
+
+		assert.True(t, allowed)
+		assert.False(t, exceeded)
+	})
+
+	t.Run("with separate budgets", func(t *testing.T) {
+		config := NewConfig()
+		config.PerformRateLimit = 1
+		config.PresenceRateLimit = 1
+		config.PresenceRateBurst = 2
+
+		limiter := newCommandsLimiter(&config)
+		require.NotNil(t, limiter)
+
+		now := time.Now()
+
+		allowed, _ := limiter.allow("message", now)
+		assert.True(t, allowed)
+
+		allowed, _ = limiter.allow("message", now)
+		assert.False(t, allowed)
+
+		// Presence commands share the budget
+		allowed, _ = limiter.allow("join", now)
+		assert.True(t, allowed)
+
+		allowed, _ = limiter.allow("update", now)
+		assert.True(t, allowed)
+
+		allowed, _ = limiter.allow("leave", now)
+		assert.False(t, allowed)
+
+		// Whispers are not limited
+		for i := 0; i < 10; i++ {
+			allowed, _ = limiter.allow("whisper", now)
+			assert.True(t, allowed)
+		}
+
+		// Other commands are never limited
+		allowed, _ = limiter.allow("subscribe", now)
+		assert.True(t, allowed)
+	})
+
+	t.Run("with max violations", func(t *testing.T) {
+		config := NewConfig()
+		config.WhisperRateLimit = 1
+		config.RateLimitMaxViolations = 2
+
+		limiter := newCommandsLimiter(&config)
+		now := time.Now()
+
+		allowed, exceeded := limiter.allow("whisper", now)
+		assert.True(t, allowed)
+		assert.False(t, exceeded)
+
+		allowed, exceeded = limiter.allow("whisper", now)
+		assert.False(t, allowed)
+		assert.False(t, exceeded)
+
+		// Successful command resets violations
+		allowed, _ = limiter.allow("whisper", now.Add(time.Second))
+		assert.True(t, allowed)
+
+		allowed, exceeded = limiter.allow("whisper", now.Add(time.Second))
+		assert.False(t, allowed)
+		assert.False(t, exceeded)
+
+		allowed, exceeded = limiter.allow("whisper", now.Add(time.Second))
+		assert.False(t, allowed)
+		assert.True(t, exceeded)
+	})
+}
@@ -45,7 +45,10 @@ type Session struct {
 	// Mutex for protocol-related state (env, subscriptions)
 	smu sync.Mutex
 
-	sendCh chan *ws.SentFrame
+	sendQueue *outboundQueue
+
+	// Incoming commands rate limiter (nil if rate limiting is disabled)
+	rateLimiter *commandsLimiter
 
 	pingTimer    *time.Timer
 	pingInterval time.Duration
@@ -60,6 +63,11 @@ type Session struct {
 	resumable bool
 	prevSid   string
 
+	// The key of the sessions quota slot occupied by the session
+	quotaKey string
+
+	connectedAt time.Time
+
 	Connected bool
 	// Could be used to store arbitrary data within a session
 	InternalState map[string]interface{}
@@ -140,9 +148,11 @@ func NewSession(node *Node, conn Connection, url string, headers *map[string]str
 		metrics:                node.metrics,
 		env:                    common.NewSessionEnv(url, headers),
 		subscriptions:          NewSubscriptionState(),
-		sendCh:                 make(chan *ws.SentFrame, 256),
+		sendQueue:              newOutboundQueue(node.config.OutboundQueueSize, node.config.OutboundQueuePolicy),
+		rateLimiter:            newCommandsLimiter(node.config),
 		closed:                 false,
 		Connected:              false,
+		connectedAt:            time.Now(),
 		pingInterval:           time.Duration(node.config.PingInterval) * time.Second,
 		pingTimestampPrecision: node.config.PingTimestampPrecision,
 		// Use JSON by default
@@ -329,7 +339,13 @@ func (s *Session) Serve(callback func()) error {
 
 // SendMessages waits for incoming messages and send them to the client connection
 func (s *Session) SendMessages() {
-	for message := range s.sendCh {
+	for {
+		message, ok := s.sendQueue.pop()
+
+		if !ok {
+			return
+		}
+
 		err := s.writeFrame(message)
 
 		if message.FrameType == ws.CloseFrame {
@@ -376,7 +392,18 @@ func (s *Session) ReadMessage(message []byte) error {
 func (s *Session) Send(msg encoders.EncodedMessage) {
 	if b, err := s.encodeMessage(msg); err == nil {
 		if b != nil {
-			s.sendFrame(b)
+			transient := false
+			key := ""
+
+			if tmsg, ok := msg.(encoders.TransientMessage); ok {
+				transient = tmsg.IsTransient()
+			}
+
+			if cmsg, ok := msg.(encoders.ConflatableMessage); ok {
+				key = cmsg.ConflationKey()
+			}
+
+			s.enqueueFrame(b, transient, key)
 		}
 	} else {
 		s.Log.Warn("failed to encode message", "data", msg, "error", err)
@@ -414,6 +441,12 @@ func (s *Session) DisconnectWithMessage(msg encoders.EncodedMessage, code string
 		wsCode = ws.CloseGoingAway
 	case common.REMOTE_DISCONNECT_REASON:
 		reason = "Closed remotely"
+	case common.SLOW_CONSUMER_REASON:
+		reason = "Slow consumer"
+	case common.RATE_LIMITED_REASON:
+		reason = "Rate limit exceeded"
+	case common.TOO_MANY_SESSIONS_REASON:
+		reason = "Too many sessions"
 	}
 
 	s.Disconnect(reason, wsCode)
@@ -447,6 +480,19 @@ func (s *Session) ToCacheEntry() ([]byte, error) {
 	return json.Marshal(&entry)
 }
 
+// identifiersFromCache returns the session identifiers stored in the cache entry
+func identifiersFromCache(cached []byte) (string, error) {
+	var entry struct {
+		Identifiers string `json:"ids"`
+	}
+
+	if err := json.Unmarshal(cached, &entry); err != nil {
+		return "", err
+	}
+
+	return entry.Identifiers, nil
+}
+
 func (s *Session) RestoreFromCache(cached []byte) error {
 	var entry cacheEntry
 
@@ -511,12 +557,7 @@ func (s *Session) disconnectNow(reason string, code int) {
 		CloseCode:   code,
 	})
 
-	s.mu.Lock()
-	if s.sendCh != nil {
-		close(s.sendCh)
-		s.sendCh = nil
-	}
-	s.mu.Unlock()
+	s.sendQueue.close()
 
 	s.close()
 }
@@ -557,25 +598,38 @@ func (s *Session) sendClose(reason string, code int) {
 }
 
 func (s *Session) sendFrame(message *ws.SentFrame) {
-	s.mu.Lock()
+	s.enqueueFrame(message, false, "")
+}
 
-	if s.sendCh == nil {
-		s.mu.Unlock()
+func (s *Session) enqueueFrame(message *ws.SentFrame, transient bool, key string) {
+	// The previous message with the same key hasn't been sent yet, so we can replace it
+	conflated, dropped, ok := s.sendQueue.enqueue(message, transient, key)
+
+	if conflated {
+		s.metrics.CounterIncrement(metricsOutboundConflated)
 		return
 	}
 
-	select {
-	case s.sendCh <- message:
-	default:
-		if s.sendCh != nil {
-			close(s.sendCh)
-			defer s.Disconnect("Write failed", ws.CloseAbnormalClosure)
-		}
+	if dropped > 0 {
+		s.metrics.CounterAdd(metricsOutboundDropped, uint64(dropped))
+	}
+
+	if !ok {
+		s.handleSlowConsumer()
+	}
+}
 
-		s.sendCh = nil
+// handleSlowConsumer is called when the client can't keep up with the outgoing messages
+// and the outbound queue overflow policy requires disconnecting it
+func (s *Session) handleSlowConsumer() {
+	s.metrics.CounterIncrement(metricsSlowConsumers)
+	s.Log.Warn("disconnecting slow consumer")
+
+	if b, err := s.encodeMessage(common.NewDisconnectMessage(common.SLOW_CONSUMER_REASON, true)); err == nil && b != nil {
+		s.sendQueue.pushControl(b)
 	}
 
-	s.mu.Unlock()
+	s.Disconnect("Slow consumer", ws.CloseNormalClosure)
 }
 
 func (s *Session) writeFrame(message *ws.SentFrame) error {
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/encoders"
 	"github.com/anycable/anycable-go/ws"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -219,3 +220,60 @@ func TestMarkDisconnectable(t *testing.T) {
 
 	assert.True(t, session.IsDisconnectable())
 }
+
+func TestSessionSlowConsumer(t *testing.T) {
+	node := NewMockNode()
+	session := NewMockSession("123", node)
+	session.sendQueue = newOutboundQueue(2, OUTBOUND_QUEUE_POLICY_DISCONNECT)
+	session.closed = false
+	session.Connected = true
+
+	go session.SendMessages()
+
+	// Mock connection buffers 10 messages, so the writer is blocked after that
+	for i := 1; i <= 20; i++ {
+		session.Send(&common.Reply{Type: "message", Message: i})
+	}
+
+	var disconnectMsg string
+
+	for {
+		msg, err := session.conn.Read()
+		require.NoError(t, err)
+
+		if string(msg) == "" {
+			break
+		}
+
+		disconnectMsg = string(msg)
+	}
+
+	assert.Equal(t, `{"type":"disconnect","reason":"slow_consumer","reconnect":true}`, disconnectMsg)
+	assert.False(t, session.IsConnected())
+}
+
+func TestSessionConflation(t *testing.T) {
+	node := NewMockNode()
+	session := NewMockSession("123", node)
+
+	for i := 1; i <= 3; i++ {
+		msg := encoders.NewCachedEncodedMessage(&common.Reply{Identifier: "prices", Message: i})
+		msg.SetConflationKey("prices")
+
+		session.Send(msg)
+	}
+
+	session.Send(&common.Reply{Identifier: "news", Message: "hello"})
+
+	msg := encoders.NewCachedEncodedMessage(&common.Reply{Identifier: "prices", Message: 4})
+	msg.SetConflationKey("prices")
+	session.Send(msg)
+
+	assert.Equal(t, 2, session.sendQueue.len())
+
+	frame, _ := session.sendQueue.pop()
+	assert.Equal(t, `{"identifier":"prices","message":4}`, string(frame.Payload))
+
+	frame, _ = session.sendQueue.pop()
+	assert.Equal(t, `{"identifier":"news","message":"hello"}`, string(frame.Payload))
+}
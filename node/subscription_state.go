@@ -68,6 +68,14 @@ func (st *SubscriptionState) ToMap() map[string][]string {
 	return res
 }
 
+func (st *SubscriptionState) HasChannelStream(id string, stream string) bool {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	_, ok := st.channels[id][stream]
+	return ok
+}
+
 func (st *SubscriptionState) AddChannelStream(id string, stream string) {
 	st.mu.Lock()
 	defer st.mu.Unlock()
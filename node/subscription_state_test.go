@@ -71,3 +71,14 @@ func TestSubscriptionStreamsFor(t *testing.T) {
 	subscriptions.RemoveChannelStream("presence_1", "t")
 	assert.Equal(t, []string{"y"}, subscriptions.StreamsFor("presence_1"))
 }
+
+func TestSubscriptionStateHasChannelStream(t *testing.T) {
+	subscriptions := NewSubscriptionState()
+
+	subscriptions.AddChannel("chat_1")
+	subscriptions.AddChannelStream("chat_1", "messages_1")
+
+	assert.True(t, subscriptions.HasChannelStream("chat_1", "messages_1"))
+	assert.False(t, subscriptions.HasChannelStream("chat_1", "messages_2"))
+	assert.False(t, subscriptions.HasChannelStream("chat_2", "messages_1"))
+}
@@ -0,0 +1,69 @@
+package ocpp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OCPP configuration
+type Config struct {
+	// Path is the URL path prefix to handle OCPP connections (empty means disabled)
+	Path string `toml:"path"`
+	// Channel is the Action Cable channel class name to handle OCPP messages
+	Channel string `toml:"channel"`
+	// GranularActions defines whether to call a separate channel action per OCPP command (or use #receive)
+	GranularActions bool `toml:"granular_actions"`
+	// HeartbeatInterval is the heartbeat interval sent to charge points on boot (seconds)
+	HeartbeatInterval int `toml:"heartbeat_interval"`
+	// CallTimeout is the time to wait for a charge point to respond to a server-initiated call (seconds)
+	CallTimeout int `toml:"call_timeout"`
+	// MaxPendingCalls is the max number of server-initiated calls waiting to be sent to a charge point
+	MaxPendingCalls int `toml:"max_pending_calls"`
+}
+
+// NewConfig creates a new Config with default values.
+func NewConfig() Config {
+	return Config{
+		Channel:           "OCPPChannel",
+		GranularActions:   true,
+		HeartbeatInterval: 60,
+		CallTimeout:       30,
+		MaxPendingCalls:   10,
+	}
+}
+
+// Enabled returns true if the OCPP endpoint path is configured
+func (c Config) Enabled() bool {
+	return c.Path != ""
+}
+
+// ToToml converts the Config struct to a TOML string representation
+func (c Config) ToToml() string {
+	var result strings.Builder
+
+	result.WriteString("# OCPP endpoint path prefix (charge point ID is added to it: <path>/<id>)\n")
+	if c.Path != "" {
+		result.WriteString(fmt.Sprintf("path = \"%s\"\n", c.Path))
+	} else {
+		result.WriteString("# path = \"/ocpp\"\n")
+	}
+
+	result.WriteString("# Action Cable channel class name\n")
+	result.WriteString(fmt.Sprintf("channel = \"%s\"\n", c.Channel))
+
+	result.WriteString("# Whether to call a separate channel action per OCPP command (otherwise, #receive is called)\n")
+	result.WriteString(fmt.Sprintf("granular_actions = %t\n", c.GranularActions))
+
+	result.WriteString("# Heartbeat interval to send to charge points on boot (seconds)\n")
+	result.WriteString(fmt.Sprintf("heartbeat_interval = %d\n", c.HeartbeatInterval))
+
+	result.WriteString("# Time to wait for a charge point to respond to a server-initiated call (seconds)\n")
+	result.WriteString(fmt.Sprintf("call_timeout = %d\n", c.CallTimeout))
+
+	result.WriteString("# Max number of server-initiated calls waiting to be sent to a charge point\n")
+	result.WriteString(fmt.Sprintf("max_pending_calls = %d\n", c.MaxPendingCalls))
+
+	result.WriteString("\n")
+
+	return result.String()
+}
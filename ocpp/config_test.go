@@ -0,0 +1,32 @@
+package ocpp
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_ToToml(t *testing.T) {
+	conf := NewConfig()
+	conf.Path = "/ocpp"
+	conf.GranularActions = false
+
+	tomlStr := conf.ToToml()
+
+	assert.Contains(t, tomlStr, "path = \"/ocpp\"")
+	assert.Contains(t, tomlStr, "channel = \"OCPPChannel\"")
+	assert.Contains(t, tomlStr, "granular_actions = false")
+	assert.Contains(t, tomlStr, "heartbeat_interval = 60")
+	assert.Contains(t, tomlStr, "call_timeout = 30")
+	assert.Contains(t, tomlStr, "max_pending_calls = 10")
+
+	// Round-trip test
+	conf2 := Config{}
+
+	_, err := toml.Decode(tomlStr, &conf2)
+	require.NoError(t, err)
+
+	assert.Equal(t, conf, conf2)
+}
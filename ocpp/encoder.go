@@ -0,0 +1,135 @@
+package ocpp
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/encoders"
+	"github.com/anycable/anycable-go/ws"
+	nanoid "github.com/matoous/go-nanoid"
+)
+
+const (
+	ocppEncoderID = "ocpp"
+
+	malformedCommand = "malformed"
+)
+
+// Encoder converts Action Cable messages into OCPP-J messages (and vice versa).
+// Channel messages with the "Ack" and "Error" commands are sent as CALLRESULT and CALLERROR messages correspondingly,
+// other commands are sent as CALL messages (and tracked to correlate responses).
+// Malformed incoming frames are passed to the executor to respond with CALLERROR.
+//
+// NOTE: Encoder is bound to a session (since it tracks server-initiated calls),
+// so it has a unique ID to avoid sharing encoded broadcasts between sessions.
+type Encoder struct {
+	id    string
+	calls *pendingCalls
+}
+
+func (e Encoder) ID() string {
+	return e.id
+}
+
+func (e Encoder) Encode(msg encoders.EncodedMessage) (*ws.SentFrame, error) {
+	switch m := msg.(type) {
+	case *Message:
+		return encodeMessage(m)
+	case *common.Reply:
+		return e.encodeReply(m)
+	default:
+		// Pings and disconnect notifications have no counterparts in the protocol
+		return nil, nil
+	}
+}
+
+func (e Encoder) EncodeTransmission(raw string) (*ws.SentFrame, error) {
+	msg := common.Reply{}
+
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		return nil, err
+	}
+
+	return e.Encode(&msg)
+}
+
+func (Encoder) Decode(raw []byte) (*common.Message, error) {
+	msg, err := parseMessage(raw)
+
+	if err != nil {
+		malformed := newMalformedMessage(raw, err)
+
+		return &common.Message{Command: malformedCommand, Identifier: malformed.ID, Data: malformed}, nil
+	}
+
+	return &common.Message{Command: msg.GetType(), Identifier: msg.ID, Data: msg}, nil
+}
+
+func (e Encoder) encodeReply(reply *common.Reply) (*ws.SentFrame, error) {
+	// Welcome messages, subscription confirmations, etc.
+	if reply.Type != "" {
+		return nil, nil
+	}
+
+	data, ok := reply.Message.(map[string]interface{})
+
+	if !ok {
+		return nil, errors.New("OCPP message must be an object")
+	}
+
+	command, _ := data["command"].(string)
+	id, _ := data["id"].(string)
+	payload := data["payload"]
+
+	switch command {
+	case "":
+		return nil, errors.New("OCPP message command is missing")
+	case ackCommand:
+		return encodeMessage(&Message{Type: callResultType, ID: id, Payload: payload})
+	case errorCommand:
+		code, _ := data["code"].(string)
+		description, _ := data["message"].(string)
+
+		if code == "" {
+			code = internalErrorCode
+		}
+
+		return encodeMessage(&Message{Type: callErrorType, ID: id, ErrorCode: code, ErrorDescription: description, Payload: payload})
+	default:
+		if id == "" {
+			genid, err := nanoid.Nanoid()
+
+			if err != nil {
+				return nil, err
+			}
+
+			id = genid
+		}
+
+		msg := &Message{Type: callType, ID: id, Action: command, Payload: payload}
+
+		now, err := e.calls.push(msg)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Another call is in flight, so this one is sent later
+		if !now {
+			return nil, nil
+		}
+
+		return encodeMessage(msg)
+	}
+}
+
+func encodeMessage(msg *Message) (*ws.SentFrame, error) {
+	b, err := json.Marshal(msg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ws.SentFrame{FrameType: ws.TextFrame, Payload: b}, nil
+}
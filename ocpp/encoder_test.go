@@ -0,0 +1,226 @@
+package ocpp
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/ws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncoder(t *testing.T) {
+	calls := newPendingCalls(time.Minute, 10)
+	coder := Encoder{id: "ocpp/42", calls: calls}
+
+	t.Run(".Encode Message", func(t *testing.T) {
+		actual, err := coder.Encode(&Message{Type: callResultType, ID: "1", Payload: map[string]interface{}{"status": "Accepted"}})
+
+		require.NoError(t, err)
+		assert.Equal(t, ws.TextFrame, actual.FrameType)
+		assert.Equal(t, `[3,"1",{"status":"Accepted"}]`, string(actual.Payload))
+	})
+
+	t.Run(".Encode Message without payload", func(t *testing.T) {
+		actual, err := coder.Encode(&Message{Type: callErrorType, ID: "1", ErrorCode: "InternalError", ErrorDescription: "Oops"})
+
+		require.NoError(t, err)
+		assert.Equal(t, `[4,"1","InternalError","Oops",{}]`, string(actual.Payload))
+	})
+
+	t.Run(".Encode ping", func(t *testing.T) {
+		actual, err := coder.Encode(&common.PingMessage{Type: "ping", Message: 1681290000})
+
+		require.NoError(t, err)
+		assert.Nil(t, actual)
+	})
+
+	t.Run(".Encode welcome", func(t *testing.T) {
+		actual, err := coder.Encode(&common.Reply{Type: "welcome"})
+
+		require.NoError(t, err)
+		assert.Nil(t, actual)
+	})
+
+	t.Run(".Encode Ack", func(t *testing.T) {
+		msg := &common.Reply{Message: map[string]interface{}{"command": "Ack", "id": "2", "payload": map[string]interface{}{"transactionId": 42}}}
+
+		actual, err := coder.Encode(msg)
+
+		require.NoError(t, err)
+		assert.Equal(t, `[3,"2",{"transactionId":42}]`, string(actual.Payload))
+	})
+
+	t.Run(".Encode Error", func(t *testing.T) {
+		msg := &common.Reply{Message: map[string]interface{}{"command": "Error", "id": "2", "code": "NotSupported", "message": "Unknown action"}}
+
+		actual, err := coder.Encode(msg)
+
+		require.NoError(t, err)
+		assert.Equal(t, `[4,"2","NotSupported","Unknown action",{}]`, string(actual.Payload))
+	})
+
+	t.Run(".Encode remote command", func(t *testing.T) {
+		msg := &common.Reply{Message: map[string]interface{}{"command": "TriggerMessage", "id": "3", "payload": map[string]interface{}{"requestedMessage": "BootNotification"}}}
+
+		actual, err := coder.Encode(msg)
+
+		require.NoError(t, err)
+		assert.Equal(t, `[2,"3","TriggerMessage",{"requestedMessage":"BootNotification"}]`, string(actual.Payload))
+
+		action, ok := calls.take("3")
+		assert.True(t, ok)
+		assert.Equal(t, "TriggerMessage", action)
+	})
+
+	t.Run(".Encode remote command without ID", func(t *testing.T) {
+		msg := &common.Reply{Message: map[string]interface{}{"command": "Reset", "payload": map[string]interface{}{"type": "Soft"}}}
+
+		actual, err := coder.Encode(msg)
+
+		require.NoError(t, err)
+
+		var frame []interface{}
+		require.NoError(t, json.Unmarshal(actual.Payload, &frame))
+
+		require.Len(t, frame, 4)
+		assert.Equal(t, "Reset", frame[2])
+
+		id := frame[1].(string)
+		assert.NotEmpty(t, id)
+
+		_, ok := calls.take(id)
+		assert.True(t, ok)
+	})
+
+	t.Run(".Encode message without command", func(t *testing.T) {
+		_, err := coder.Encode(&common.Reply{Message: map[string]interface{}{"id": "4"}})
+
+		assert.Error(t, err)
+	})
+
+	t.Run(".EncodeTransmission", func(t *testing.T) {
+		actual, err := coder.EncodeTransmission(`{"identifier":"test","message":{"command":"Ack","id":"5","payload":{"status":"Rejected"}}}`)
+
+		require.NoError(t, err)
+		assert.Equal(t, `[3,"5",{"status":"Rejected"}]`, string(actual.Payload))
+	})
+
+	t.Run(".Decode CALL", func(t *testing.T) {
+		actual, err := coder.Decode([]byte(`[2,"19223201","Authorize",{"idTag":"B4F62CEF"}]`))
+
+		require.NoError(t, err)
+		assert.Equal(t, "call", actual.Command)
+		assert.Equal(t, "19223201", actual.Identifier)
+
+		msg := actual.Data.(*Message)
+		assert.Equal(t, "Authorize", msg.Action)
+		assert.Equal(t, map[string]interface{}{"idTag": "B4F62CEF"}, msg.Payload)
+	})
+
+	t.Run(".Decode CALLRESULT", func(t *testing.T) {
+		actual, err := coder.Decode([]byte(`[3,"3",{"status":"Accepted"}]`))
+
+		require.NoError(t, err)
+		assert.Equal(t, "result", actual.Command)
+		assert.Equal(t, map[string]interface{}{"status": "Accepted"}, actual.Data.(*Message).Payload)
+	})
+
+	t.Run(".Decode CALLERROR", func(t *testing.T) {
+		actual, err := coder.Decode([]byte(`[4,"3","NotImplemented","Unknown action",{}]`))
+
+		require.NoError(t, err)
+		assert.Equal(t, "error", actual.Command)
+
+		msg := actual.Data.(*Message)
+		assert.Equal(t, "NotImplemented", msg.ErrorCode)
+		assert.Equal(t, "Unknown action", msg.ErrorDescription)
+	})
+
+	t.Run(".Decode with invalid payload", func(t *testing.T) {
+		for raw, id := range map[string]string{
+			`{"command":"subscribe"}`: "-1",
+			`[2,"1","Heartbeat"]`:     "1",
+			`[5,"2",{}]`:              "2",
+			`[2,3,"Heartbeat",{}]`:    "-1",
+		} {
+			actual, err := coder.Decode([]byte(raw))
+
+			require.NoError(t, err)
+			assert.Equal(t, malformedCommand, actual.Command)
+			assert.Equal(t, id, actual.Data.(*malformedMessage).ID, raw)
+		}
+	})
+}
+
+func TestStationIDFromPath(t *testing.T) {
+	assert.Equal(t, "CP001", StationIDFromPath("/ocpp", "/ocpp/CP001"))
+	assert.Equal(t, "CP001", StationIDFromPath("/ocpp/", "/ocpp/CP001/"))
+	assert.Equal(t, "", StationIDFromPath("/ocpp", "/ocpp/"))
+	assert.Equal(t, "", StationIDFromPath("/ocpp", "/ocpp/CP001/status"))
+	assert.Equal(t, "", StationIDFromPath("/ocpp", "/cable"))
+}
+
+func TestUnderscore(t *testing.T) {
+	assert.Equal(t, "start_transaction", underscore("StartTransaction"))
+	assert.Equal(t, "authorize", underscore("Authorize"))
+	assert.Equal(t, "data_transfer", underscore("DataTransfer"))
+	assert.Equal(t, "diagnostics_status_notification", underscore("DiagnosticsStatusNotification"))
+}
+
+func TestPendingCalls(t *testing.T) {
+	sent := make(chan *Message, 10)
+
+	calls := newPendingCalls(200*time.Millisecond, 1)
+	calls.onSend(func(msg *Message) { sent <- msg })
+
+	now, err := calls.push(&Message{Type: callType, ID: "1", Action: "Reset"})
+	require.NoError(t, err)
+	assert.True(t, now)
+
+	now, err = calls.push(&Message{Type: callType, ID: "2", Action: "TriggerMessage"})
+	require.NoError(t, err)
+	assert.False(t, now)
+
+	_, err = calls.push(&Message{Type: callType, ID: "3", Action: "UnlockConnector"})
+	assert.ErrorIs(t, err, errTooManyPendingCalls)
+
+	_, ok := calls.take("2")
+	assert.False(t, ok)
+
+	action, ok := calls.take("1")
+	assert.True(t, ok)
+	assert.Equal(t, "Reset", action)
+
+	next := <-sent
+	assert.Equal(t, "2", next.ID)
+
+	t.Run("when call times out", func(t *testing.T) {
+		now, err := calls.push(&Message{Type: callType, ID: "4", Action: "Reset"})
+		require.NoError(t, err)
+		assert.False(t, now)
+
+		select {
+		case next := <-sent:
+			assert.Equal(t, "4", next.ID)
+		case <-time.After(time.Second):
+			require.Fail(t, "Timeout waiting for a queued call")
+		}
+
+		_, ok := calls.take("2")
+		assert.False(t, ok)
+	})
+
+	t.Run("when closed", func(t *testing.T) {
+		calls.close()
+
+		now, err := calls.push(&Message{Type: callType, ID: "5", Action: "Reset"})
+		require.NoError(t, err)
+		assert.False(t, now)
+
+		_, ok := calls.take("4")
+		assert.False(t, ok)
+	})
+}
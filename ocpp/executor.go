@@ -0,0 +1,178 @@
+package ocpp
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/node"
+)
+
+// Executor maps OCPP messages to the node API: CALL messages from charge points are turned into
+// channel actions (via Perform), responses to server-initiated calls are passed to the #ack and #error actions.
+// BootNotification and Heartbeat calls are acknowledged by the executor itself.
+type Executor struct {
+	node       *node.Node
+	config     *Config
+	identifier string
+	calls      *pendingCalls
+}
+
+var _ node.Executor = (*Executor)(nil)
+
+func (ex *Executor) HandleCommand(s *node.Session, msg *common.Message) error {
+	if malformed, ok := msg.Data.(*malformedMessage); ok {
+		s.Send(&Message{Type: callErrorType, ID: malformed.ID, ErrorCode: formationViolationCode, ErrorDescription: "Malformed message"})
+		return malformed
+	}
+
+	m, ok := msg.Data.(*Message)
+
+	if !ok {
+		return fmt.Errorf("unsupported OCPP message: %v", msg.Data)
+	}
+
+	switch m.Type {
+	case callType:
+		return ex.handleCall(s, m)
+	case callResultType:
+		return ex.handleResult(s, m)
+	case callErrorType:
+		return ex.handleError(s, m)
+	default:
+		return fmt.Errorf("unknown OCPP message type: %d", m.Type)
+	}
+}
+
+func (ex *Executor) Disconnect(s *node.Session) error {
+	ex.calls.close()
+
+	return ex.node.Disconnect(s)
+}
+
+func (ex *Executor) handleCall(s *node.Session, msg *Message) error {
+	switch msg.Action {
+	case bootNotificationAction:
+		s.Send(&Message{Type: callResultType, ID: msg.ID, Payload: map[string]interface{}{
+			"status":      "Accepted",
+			"currentTime": currentTime(),
+			"interval":    ex.config.HeartbeatInterval,
+		}})
+		return nil
+	case heartbeatAction:
+		s.Send(&Message{Type: callResultType, ID: msg.ID, Payload: map[string]interface{}{
+			"currentTime": currentTime(),
+		}})
+		return nil
+	}
+
+	if !ex.node.CheckRateLimit(s, "message") {
+		s.Send(&Message{Type: callErrorType, ID: msg.ID, ErrorCode: genericErrorCode, ErrorDescription: "Rate limit exceeded"})
+		return nil
+	}
+
+	res, err := ex.perform(s, underscore(msg.Action), map[string]interface{}{
+		"id":      msg.ID,
+		"command": msg.Action,
+		"payload": msg.Payload,
+	})
+
+	if err != nil {
+		s.Send(&Message{Type: callErrorType, ID: msg.ID, ErrorCode: internalErrorCode, ErrorDescription: "Failed to process the request"})
+		return err
+	}
+
+	// The channel has responded explicitly
+	if res != nil && isAcknowledged(res.Transmissions, msg.ID) {
+		return nil
+	}
+
+	if res != nil && res.Status != common.SUCCESS {
+		s.Send(&Message{Type: callErrorType, ID: msg.ID, ErrorCode: genericErrorCode, ErrorDescription: "Request rejected"})
+		return nil
+	}
+
+	s.Send(&Message{Type: callResultType, ID: msg.ID, Payload: map[string]interface{}{"status": "Accepted"}})
+
+	return nil
+}
+
+func (ex *Executor) handleResult(s *node.Session, msg *Message) error {
+	action, _ := ex.calls.take(msg.ID)
+
+	// Responses can't be rejected, so we just drop them
+	if !ex.node.CheckRateLimit(s, "message") {
+		return nil
+	}
+
+	_, err := ex.perform(s, "ack", map[string]interface{}{
+		"id":      msg.ID,
+		"command": ackCommand,
+		"call":    action,
+		"payload": msg.Payload,
+	})
+
+	return err
+}
+
+func (ex *Executor) handleError(s *node.Session, msg *Message) error {
+	action, _ := ex.calls.take(msg.ID)
+
+	if !ex.node.CheckRateLimit(s, "message") {
+		return nil
+	}
+
+	_, err := ex.perform(s, "error", map[string]interface{}{
+		"id":      msg.ID,
+		"command": errorCommand,
+		"call":    action,
+		"code":    msg.ErrorCode,
+		"message": msg.ErrorDescription,
+		"payload": msg.Payload,
+	})
+
+	return err
+}
+
+func (ex *Executor) perform(s *node.Session, action string, data map[string]interface{}) (*common.CommandResult, error) {
+	if !ex.config.GranularActions {
+		action = "receive"
+	}
+
+	data["action"] = action
+
+	b, err := json.Marshal(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ex.node.Perform(s, &common.Message{Command: "message", Identifier: ex.identifier, Data: string(b)})
+}
+
+// isAcknowledged returns true if transmissions contain a response (Ack or Error) to the specified call
+func isAcknowledged(transmissions []string, id string) bool {
+	for _, raw := range transmissions {
+		var reply struct {
+			Message struct {
+				Command string `json:"command"`
+				ID      string `json:"id"`
+			} `json:"message"`
+		}
+
+		if err := json.Unmarshal([]byte(raw), &reply); err != nil {
+			continue
+		}
+
+		if reply.Message.ID == id && (reply.Message.Command == ackCommand || reply.Message.Command == errorCommand) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func currentTime() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
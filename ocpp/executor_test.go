@@ -0,0 +1,304 @@
+package ocpp
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/anycable/anycable-go/broker"
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/metrics"
+	"github.com/anycable/anycable-go/mocks"
+	"github.com/anycable/anycable-go/node"
+	"github.com/anycable/anycable-go/pubsub"
+	"github.com/anycable/anycable-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession(t *testing.T) {
+	appNode, controller := buildNode()
+
+	go appNode.Start()                           // nolint: errcheck
+	defer appNode.Shutdown(context.Background()) // nolint: errcheck
+
+	conf := NewConfig()
+	conf.Path = "/ocpp"
+
+	identifier := `{"channel":"OCPPChannel","id":"CP001"}`
+
+	controller.
+		On("Shutdown").
+		Return(nil)
+
+	controller.
+		On("Disconnect", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	controller.
+		On("Authenticate", "rejected", mock.Anything).
+		Return(&common.ConnectResult{Status: common.FAILURE, Transmissions: []string{`{"type":"disconnect","reason":"unauthorized","reconnect":false}`}}, nil)
+
+	controller.
+		On("Authenticate", mock.Anything, mock.Anything).
+		Return(&common.ConnectResult{Status: common.SUCCESS, Identifier: "ids", Transmissions: []string{`{"type":"welcome"}`}}, nil)
+
+	controller.
+		On("Subscribe", mock.Anything, mock.Anything, "ids", identifier).
+		Return(&common.CommandResult{Status: common.SUCCESS, Streams: []string{"ev/CP001"}, Transmissions: []string{`{"type":"confirm_subscription","identifier":"{\"channel\":\"OCPPChannel\",\"id\":\"CP001\"}"}`}}, nil)
+
+	t.Run("when charge point is rejected", func(t *testing.T) {
+		conn := mocks.NewMockConnection()
+
+		session, err := NewSession(appNode, conn, requestInfo("rejected", "/ocpp/CP001"), &conf)
+
+		require.NoError(t, err)
+		assert.Nil(t, session)
+		controller.AssertNotCalled(t, "Subscribe", "rejected", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("without charge point ID", func(t *testing.T) {
+		_, err := NewSession(appNode, mocks.NewMockConnection(), requestInfo("no_id", "/ocpp/"), &conf)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("BootNotification and Heartbeat", func(t *testing.T) {
+		conn := mocks.NewMockConnection()
+
+		session, err := NewSession(appNode, conn, requestInfo("boot", "/ocpp/CP001"), &conf)
+		require.NoError(t, err)
+		require.NotNil(t, session)
+
+		controller.AssertCalled(t, "Subscribe", "boot", mock.Anything, "ids", identifier)
+
+		require.NoError(t, session.ReadMessage([]byte(`[2,"1","BootNotification",{"chargePointVendor":"AnyCable","chargePointModel":"EV-42"}]`)))
+
+		msg := receive(t, conn)
+		assert.Contains(t, msg, `[3,"1",{`)
+		assert.Contains(t, msg, `"status":"Accepted"`)
+		assert.Contains(t, msg, `"interval":60`)
+
+		require.NoError(t, session.ReadMessage([]byte(`[2,"2","Heartbeat",{}]`)))
+
+		assert.Contains(t, receive(t, conn), `[3,"2",{"currentTime":`)
+
+		controller.AssertNotCalled(t, "Perform", "boot", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("CALL with default acknowledgement", func(t *testing.T) {
+		controller.
+			On("Perform", "status", mock.Anything, "ids", identifier, `{"action":"status_notification","command":"StatusNotification","id":"10","payload":{"status":"Available"}}`).
+			Return(&common.CommandResult{Status: common.SUCCESS}, nil)
+
+		conn := mocks.NewMockConnection()
+
+		session, err := NewSession(appNode, conn, requestInfo("status", "/ocpp/CP001"), &conf)
+		require.NoError(t, err)
+
+		require.NoError(t, session.ReadMessage([]byte(`[2,"10","StatusNotification",{"status":"Available"}]`)))
+
+		assert.Equal(t, `[3,"10",{"status":"Accepted"}]`, receive(t, conn))
+	})
+
+	t.Run("CALL with custom acknowledgement", func(t *testing.T) {
+		controller.
+			On("Perform", "authorize", mock.Anything, "ids", identifier, mock.Anything).
+			Return(&common.CommandResult{
+				Status:        common.SUCCESS,
+				Transmissions: []string{`{"identifier":"{\"channel\":\"OCPPChannel\",\"id\":\"CP001\"}","message":{"command":"Ack","id":"11","payload":{"idTagInfo":{"status":"Blocked"}}}}`},
+			}, nil)
+
+		conn := mocks.NewMockConnection()
+
+		session, err := NewSession(appNode, conn, requestInfo("authorize", "/ocpp/CP001"), &conf)
+		require.NoError(t, err)
+
+		require.NoError(t, session.ReadMessage([]byte(`[2,"11","Authorize",{"idTag":"B4F62CEF"}]`)))
+
+		assert.Equal(t, `[3,"11",{"idTagInfo":{"status":"Blocked"}}]`, receive(t, conn))
+		assertNoFrames(t, conn)
+	})
+
+	t.Run("CALL when rejected", func(t *testing.T) {
+		controller.
+			On("Perform", "rejected_call", mock.Anything, "ids", identifier, mock.Anything).
+			Return(&common.CommandResult{Status: common.FAILURE}, nil)
+
+		conn := mocks.NewMockConnection()
+
+		session, err := NewSession(appNode, conn, requestInfo("rejected_call", "/ocpp/CP001"), &conf)
+		require.NoError(t, err)
+
+		require.NoError(t, session.ReadMessage([]byte(`[2,"14","MeterValues",{}]`)))
+
+		assert.Equal(t, `[4,"14","GenericError","Request rejected",{}]`, receive(t, conn))
+		assertNoFrames(t, conn)
+	})
+
+	t.Run("CALL when RPC fails", func(t *testing.T) {
+		controller.
+			On("Perform", "failure", mock.Anything, "ids", identifier, mock.Anything).
+			Return(nil, errors.New("RPC is unavailable"))
+
+		conn := mocks.NewMockConnection()
+
+		session, err := NewSession(appNode, conn, requestInfo("failure", "/ocpp/CP001"), &conf)
+		require.NoError(t, err)
+
+		require.NoError(t, session.ReadMessage([]byte(`[2,"12","DataTransfer",{}]`)))
+
+		assert.Equal(t, `[4,"12","InternalError","Failed to process the request",{}]`, receive(t, conn))
+	})
+
+	t.Run("remote command and response", func(t *testing.T) {
+		controller.
+			On("Perform", "remote", mock.Anything, "ids", identifier, `{"action":"ack","call":"TriggerMessage","command":"Ack","id":"r1","payload":{"status":"Accepted"}}`).
+			Return(&common.CommandResult{Status: common.SUCCESS}, nil)
+
+		conn := mocks.NewMockConnection()
+
+		session, err := NewSession(appNode, conn, requestInfo("remote", "/ocpp/CP001"), &conf)
+		require.NoError(t, err)
+
+		appNode.Broadcast(&common.StreamMessage{Stream: "ev/CP001", Data: `{"command":"TriggerMessage","id":"r1","payload":{"requestedMessage":"StatusNotification"}}`})
+
+		assert.Equal(t, `[2,"r1","TriggerMessage",{"requestedMessage":"StatusNotification"}]`, receive(t, conn))
+
+		require.NoError(t, session.ReadMessage([]byte(`[3,"r1",{"status":"Accepted"}]`)))
+
+		controller.AssertCalled(t, "Perform", "remote", mock.Anything, "ids", identifier, mock.Anything)
+	})
+
+	t.Run("remote commands are sent one at a time", func(t *testing.T) {
+		controller.
+			On("Perform", "sequential", mock.Anything, "ids", identifier, mock.Anything).
+			Return(&common.CommandResult{Status: common.SUCCESS}, nil)
+
+		conn := mocks.NewMockConnection()
+
+		session, err := NewSession(appNode, conn, requestInfo("sequential", "/ocpp/CP001"), &conf)
+		require.NoError(t, err)
+
+		appNode.Broadcast(&common.StreamMessage{Stream: "ev/CP001", Data: `{"command":"Reset","id":"q1","payload":{"type":"Soft"}}`})
+		appNode.Broadcast(&common.StreamMessage{Stream: "ev/CP001", Data: `{"command":"TriggerMessage","id":"q2","payload":{"requestedMessage":"Heartbeat"}}`})
+
+		assert.Equal(t, `[2,"q1","Reset",{"type":"Soft"}]`, receive(t, conn))
+		assertNoFrames(t, conn)
+
+		require.NoError(t, session.ReadMessage([]byte(`[3,"q1",{"status":"Accepted"}]`)))
+
+		assert.Equal(t, `[2,"q2","TriggerMessage",{"requestedMessage":"Heartbeat"}]`, receive(t, conn))
+	})
+
+	t.Run("malformed message", func(t *testing.T) {
+		conn := mocks.NewMockConnection()
+
+		session, err := NewSession(appNode, conn, requestInfo("malformed", "/ocpp/CP001"), &conf)
+		require.NoError(t, err)
+
+		require.NoError(t, session.ReadMessage([]byte(`[2,"13","Authorize"]`)))
+
+		assert.Equal(t, `[4,"13","FormationViolation","Malformed message",{}]`, receive(t, conn))
+		controller.AssertNotCalled(t, "Perform", "malformed", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("error response with single action", func(t *testing.T) {
+		singleConf := conf
+		singleConf.GranularActions = false
+
+		controller.
+			On("Perform", "single", mock.Anything, "ids", identifier, `{"action":"receive","call":"","code":"NotSupported","command":"Error","id":"r2","message":"Unknown","payload":{}}`).
+			Return(&common.CommandResult{Status: common.SUCCESS}, nil)
+
+		conn := mocks.NewMockConnection()
+
+		session, err := NewSession(appNode, conn, requestInfo("single", "/ocpp/CP001"), &singleConf)
+		require.NoError(t, err)
+
+		require.NoError(t, session.ReadMessage([]byte(`[4,"r2","NotSupported","Unknown",{}]`)))
+
+		controller.AssertCalled(t, "Perform", "single", mock.Anything, "ids", identifier, mock.Anything)
+	})
+}
+
+func TestSessionRateLimits(t *testing.T) {
+	appNode, controller := buildNode(func(c *node.Config) {
+		c.PerformRateLimit = 1
+		c.RateLimitMaxViolations = 0
+	})
+
+	go appNode.Start()                           // nolint: errcheck
+	defer appNode.Shutdown(context.Background()) // nolint: errcheck
+
+	conf := NewConfig()
+	conf.Path = "/ocpp"
+
+	identifier := `{"channel":"OCPPChannel","id":"CP001"}`
+
+	controller.
+		On("Shutdown").
+		Return(nil)
+
+	controller.
+		On("Disconnect", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	controller.
+		On("Authenticate", mock.Anything, mock.Anything).
+		Return(&common.ConnectResult{Status: common.SUCCESS, Identifier: "ids", Transmissions: []string{`{"type":"welcome"}`}}, nil)
+
+	controller.
+		On("Subscribe", mock.Anything, mock.Anything, "ids", identifier).
+		Return(&common.CommandResult{Status: common.SUCCESS}, nil)
+
+	controller.
+		On("Perform", "limited", mock.Anything, "ids", identifier, mock.Anything).
+		Return(&common.CommandResult{Status: common.SUCCESS}, nil)
+
+	conn := mocks.NewMockConnection()
+
+	session, err := NewSession(appNode, conn, requestInfo("limited", "/ocpp/CP001"), &conf)
+	require.NoError(t, err)
+
+	require.NoError(t, session.ReadMessage([]byte(`[2,"1","DataTransfer",{}]`)))
+	assert.Equal(t, `[3,"1",{"status":"Accepted"}]`, receive(t, conn))
+
+	require.NoError(t, session.ReadMessage([]byte(`[2,"2","DataTransfer",{}]`)))
+	assert.Equal(t, `[4,"2","GenericError","Rate limit exceeded",{}]`, receive(t, conn))
+
+	controller.AssertNumberOfCalls(t, "Perform", 1)
+}
+
+func receive(t *testing.T, conn mocks.MockConnection) string {
+	msg, err := conn.Read()
+	require.NoError(t, err)
+
+	return string(msg)
+}
+
+func assertNoFrames(t *testing.T, conn mocks.MockConnection) {
+	msg, err := conn.Read()
+	assert.Error(t, err, "Unexpected message: %s", msg)
+}
+
+func requestInfo(uid string, path string) *server.RequestInfo {
+	return &server.RequestInfo{UID: uid, URL: "ws://localhost:8080" + path, Headers: &map[string]string{}}
+}
+
+func buildNode(opts ...func(*node.Config)) (*node.Node, *mocks.Controller) {
+	controller := &mocks.Controller{}
+	config := node.NewConfig()
+	config.HubGopoolSize = 2
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	n := node.NewNode(&config, node.WithController(controller), node.WithInstrumenter(metrics.NewMetrics(nil, 10, slog.Default())))
+	n.SetBroker(broker.NewLegacyBroker(pubsub.NewLegacySubscriber(n)))
+	n.SetDisconnector(node.NewNoopDisconnector())
+	return n, controller
+}
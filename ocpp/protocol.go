@@ -0,0 +1,372 @@
+package ocpp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Protocol is the OCPP-J 1.6 WebSocket subprotocol name
+const Protocol = "ocpp1.6"
+
+// Message type IDs
+const (
+	callType       = 2
+	callResultType = 3
+	callErrorType  = 4
+)
+
+// Commands used by Action Cable channels to send responses to charge points
+const (
+	ackCommand   = "Ack"
+	errorCommand = "Error"
+)
+
+// Actions handled by AnyCable without calling RPC
+const (
+	bootNotificationAction = "BootNotification"
+	heartbeatAction        = "Heartbeat"
+)
+
+// CALLERROR codes
+const (
+	internalErrorCode      = "InternalError"
+	genericErrorCode       = "GenericError"
+	formationViolationCode = "FormationViolation"
+)
+
+// Message represents an OCPP-J message: CALL, CALLRESULT or CALLERROR
+type Message struct {
+	Type             int
+	ID               string
+	Action           string
+	Payload          interface{}
+	ErrorCode        string
+	ErrorDescription string
+}
+
+func (m *Message) GetType() string {
+	switch m.Type {
+	case callType:
+		return "call"
+	case callResultType:
+		return "result"
+	case callErrorType:
+		return "error"
+	default:
+		return ""
+	}
+}
+
+// MarshalJSON encodes the message as an OCPP-J array
+func (m *Message) MarshalJSON() ([]byte, error) {
+	payload := m.Payload
+
+	// OCPP requires payloads to be objects
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+
+	switch m.Type {
+	case callType:
+		return json.Marshal([]interface{}{m.Type, m.ID, m.Action, payload})
+	case callResultType:
+		return json.Marshal([]interface{}{m.Type, m.ID, payload})
+	case callErrorType:
+		return json.Marshal([]interface{}{m.Type, m.ID, m.ErrorCode, m.ErrorDescription, payload})
+	default:
+		return nil, fmt.Errorf("unknown OCPP message type: %d", m.Type)
+	}
+}
+
+// malformedMessage represents an incoming frame which couldn't be parsed
+type malformedMessage struct {
+	// ID is the message ID (if it could be extracted from the frame)
+	ID  string
+	err error
+}
+
+func (m *malformedMessage) Error() string {
+	return m.err.Error()
+}
+
+// Unknown message IDs are reported as "-1" (as OCPP 2.0.1 prescribes)
+const unknownMessageID = "-1"
+
+// newMalformedMessage tries to extract the message ID from the raw frame to respond with CALLERROR
+func newMalformedMessage(raw []byte, err error) *malformedMessage {
+	var frame []json.RawMessage
+	var id string
+
+	if json.Unmarshal(raw, &frame) != nil || len(frame) < 2 || json.Unmarshal(frame[1], &id) != nil || id == "" {
+		id = unknownMessageID
+	}
+
+	return &malformedMessage{ID: id, err: err}
+}
+
+func parseMessage(raw []byte) (*Message, error) {
+	var frame []json.RawMessage
+
+	if err := json.Unmarshal(raw, &frame); err != nil {
+		return nil, err
+	}
+
+	if len(frame) < 3 {
+		return nil, errors.New("malformed OCPP message")
+	}
+
+	msg := &Message{}
+
+	if err := json.Unmarshal(frame[0], &msg.Type); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(frame[1], &msg.ID); err != nil {
+		return nil, err
+	}
+
+	var payload json.RawMessage
+
+	switch msg.Type {
+	case callType:
+		if len(frame) != 4 {
+			return nil, errors.New("malformed OCPP call")
+		}
+
+		if err := json.Unmarshal(frame[2], &msg.Action); err != nil {
+			return nil, err
+		}
+
+		payload = frame[3]
+	case callResultType:
+		payload = frame[2]
+	case callErrorType:
+		if len(frame) != 5 {
+			return nil, errors.New("malformed OCPP error")
+		}
+
+		if err := json.Unmarshal(frame[2], &msg.ErrorCode); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(frame[3], &msg.ErrorDescription); err != nil {
+			return nil, err
+		}
+
+		payload = frame[4]
+	default:
+		return nil, fmt.Errorf("unknown OCPP message type: %d", msg.Type)
+	}
+
+	if err := json.Unmarshal(payload, &msg.Payload); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+var errTooManyPendingCalls = errors.New("too many pending OCPP calls")
+
+// pendingCalls keeps track of server-initiated calls to correlate them with charge point responses.
+// OCPP-J allows only one call in flight, so the subsequent calls are queued (up to the limit) and sent
+// once the charge point responds to the previous one (or the call times out).
+type pendingCalls struct {
+	mu       sync.Mutex
+	inflight *Message
+	queue    []*Message
+	timer    *time.Timer
+	timeout  time.Duration
+	limit    int
+	closed   bool
+	// send is used to deliver queued calls
+	send func(msg *Message)
+}
+
+func newPendingCalls(timeout time.Duration, limit int) *pendingCalls {
+	return &pendingCalls{timeout: timeout, limit: limit}
+}
+
+// onSend sets a callback to deliver queued calls to the charge point
+func (p *pendingCalls) onSend(fn func(msg *Message)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.send = fn
+}
+
+// push registers a new call and returns true if it must be sent right away
+// (otherwise, the call is queued and sent later)
+func (p *pendingCalls) push(msg *Message) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return false, nil
+	}
+
+	if p.inflight == nil {
+		p.start(msg)
+		return true, nil
+	}
+
+	if len(p.queue) >= p.limit {
+		return false, errTooManyPendingCalls
+	}
+
+	p.queue = append(p.queue, msg)
+
+	return false, nil
+}
+
+// take completes the in-flight call with the specified ID (if any) and returns its action
+func (p *pendingCalls) take(id string) (string, bool) {
+	p.mu.Lock()
+
+	if p.inflight == nil || p.inflight.ID != id {
+		p.mu.Unlock()
+		return "", false
+	}
+
+	action := p.inflight.Action
+	next, send := p.advance()
+	p.mu.Unlock()
+
+	if next != nil {
+		send(next)
+	}
+
+	return action, true
+}
+
+// close stops tracking calls (pending calls are dropped)
+func (p *pendingCalls) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	p.inflight = nil
+	p.queue = nil
+
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+}
+
+// expire drops the in-flight call if the charge point hasn't responded in time
+func (p *pendingCalls) expire(msg *Message) {
+	p.mu.Lock()
+
+	if p.inflight != msg {
+		p.mu.Unlock()
+		return
+	}
+
+	next, send := p.advance()
+	p.mu.Unlock()
+
+	if next != nil {
+		send(next)
+	}
+}
+
+// start marks the call as in-flight. Must be called under the lock.
+func (p *pendingCalls) start(msg *Message) {
+	p.inflight = msg
+
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+
+	if p.timeout > 0 {
+		p.timer = time.AfterFunc(p.timeout, func() { p.expire(msg) })
+	}
+}
+
+// advance completes the in-flight call and starts the next queued one (if any).
+// Must be called under the lock; the returned call must be sent after releasing the lock.
+func (p *pendingCalls) advance() (*Message, func(msg *Message)) {
+	p.inflight = nil
+
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+
+	if p.closed || p.send == nil || len(p.queue) == 0 {
+		return nil, nil
+	}
+
+	next := p.queue[0]
+	p.queue = p.queue[1:]
+	p.start(next)
+
+	return next, p.send
+}
+
+// StationIDFromPath returns the charge point ID from the request path (<prefix>/<id>)
+// or an empty string if the path doesn't match.
+func StationIDFromPath(prefix string, path string) string {
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+
+	if !strings.HasPrefix(path, prefix) {
+		return ""
+	}
+
+	id := strings.TrimSuffix(strings.TrimPrefix(path, prefix), "/")
+
+	if strings.Contains(id, "/") {
+		return ""
+	}
+
+	return id
+}
+
+func stationIDFromURL(prefix string, rawURL string) string {
+	u, err := url.Parse(rawURL)
+
+	if err != nil {
+		return ""
+	}
+
+	return StationIDFromPath(prefix, u.Path)
+}
+
+type channelIdentifier struct {
+	Channel string `json:"channel"`
+	ID      string `json:"id"`
+}
+
+// buildIdentifier returns an Action Cable channel identifier for the charge point
+func buildIdentifier(channel string, id string) string {
+	b, _ := json.Marshal(&channelIdentifier{Channel: channel, ID: id}) // nolint:errchkjson
+
+	return string(b)
+}
+
+// underscore converts OCPP action names into Ruby method names (e.g., StartTransaction -> start_transaction)
+func underscore(action string) string {
+	var result strings.Builder
+
+	runes := []rune(action)
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+				result.WriteRune('_')
+			}
+
+			result.WriteRune(unicode.ToLower(r))
+		} else {
+			result.WriteRune(r)
+		}
+	}
+
+	return result.String()
+}
@@ -0,0 +1,63 @@
+package ocpp
+
+import (
+	"time"
+
+	"github.com/anycable/anycable-go/common"
+	"github.com/anycable/anycable-go/node"
+	"github.com/anycable/anycable-go/server"
+	"github.com/anycable/anycable-go/ws"
+	"github.com/joomcode/errorx"
+)
+
+// NewSession creates a new session for the OCPP charge point, authenticates it and subscribes to the OCPP channel.
+// Returns nil if the charge point has been rejected (the session is disconnected in this case).
+func NewSession(n *node.Node, conn node.Connection, info *server.RequestInfo, config *Config, opts ...node.SessionOption) (*node.Session, error) {
+	stationID := stationIDFromURL(config.Path, info.URL)
+
+	if stationID == "" {
+		return nil, errorx.IllegalArgument.New("charge point ID is missing: %s", info.URL)
+	}
+
+	calls := newPendingCalls(time.Duration(config.CallTimeout)*time.Second, config.MaxPendingCalls)
+	identifier := buildIdentifier(config.Channel, stationID)
+
+	executor := &Executor{node: n, config: config, identifier: identifier, calls: calls}
+
+	opts = append(
+		opts,
+		node.WithEncoder(Encoder{id: ocppEncoderID + "/" + info.UID, calls: calls}),
+		node.WithExecutor(executor),
+		// OCPP relies on heartbeats sent by charge points
+		node.WithPingInterval(0),
+	)
+
+	session := node.NewSession(n, conn, info.URL, info.Headers, info.UID, opts...)
+
+	// Queued server-initiated calls are sent once the charge point responds to the previous one
+	calls.onSend(func(msg *Message) { session.Send(msg) })
+
+	res, err := n.Authenticate(session)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Status != common.SUCCESS {
+		return nil, nil
+	}
+
+	sres, err := n.Subscribe(session, &common.Message{Command: "subscribe", Identifier: identifier})
+
+	if err != nil {
+		session.Disconnect("Subscription failed", ws.CloseInternalServerErr)
+		return nil, errorx.Decorate(err, "failed to subscribe charge point %s", stationID)
+	}
+
+	if sres.Status != common.SUCCESS {
+		session.Disconnect("Subscription rejected", ws.CloseNormalClosure)
+		return nil, nil
+	}
+
+	return session, nil
+}
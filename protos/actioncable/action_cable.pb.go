@@ -0,0 +1,660 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: action_cable.proto
+
+package actioncable
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type Type int32
+
+const (
+	Type_no_type              Type = 0
+	Type_welcome              Type = 1
+	Type_disconnect           Type = 2
+	Type_ping                 Type = 3
+	Type_confirm_subscription Type = 4
+	Type_reject_subscription  Type = 5
+	Type_confirm_history      Type = 6
+	Type_reject_history       Type = 7
+	Type_presence             Type = 8
+	Type_unsubscribed         Type = 9
+	Type_error                Type = 10
+)
+
+var Type_name = map[int32]string{
+	0:  "no_type",
+	1:  "welcome",
+	2:  "disconnect",
+	3:  "ping",
+	4:  "confirm_subscription",
+	5:  "reject_subscription",
+	6:  "confirm_history",
+	7:  "reject_history",
+	8:  "presence",
+	9:  "unsubscribed",
+	10: "error",
+}
+
+var Type_value = map[string]int32{
+	"no_type":              0,
+	"welcome":              1,
+	"disconnect":           2,
+	"ping":                 3,
+	"confirm_subscription": 4,
+	"reject_subscription":  5,
+	"confirm_history":      6,
+	"reject_history":       7,
+	"presence":             8,
+	"unsubscribed":         9,
+	"error":                10,
+}
+
+func (x Type) String() string {
+	return proto.EnumName(Type_name, int32(x))
+}
+
+func (Type) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_75ae909d4f019479, []int{0}
+}
+
+type Command int32
+
+const (
+	Command_unknown_command Command = 0
+	Command_subscribe       Command = 1
+	Command_unsubscribe     Command = 2
+	Command_message         Command = 3
+	Command_history         Command = 4
+	Command_pong            Command = 5
+	// The "presence" command (requests presence info)
+	Command_presence_info Command = 6
+	Command_join          Command = 7
+	Command_leave         Command = 8
+	Command_update        Command = 9
+	Command_whisper       Command = 10
+)
+
+var Command_name = map[int32]string{
+	0:  "unknown_command",
+	1:  "subscribe",
+	2:  "unsubscribe",
+	3:  "message",
+	4:  "history",
+	5:  "pong",
+	6:  "presence_info",
+	7:  "join",
+	8:  "leave",
+	9:  "update",
+	10: "whisper",
+}
+
+var Command_value = map[string]int32{
+	"unknown_command": 0,
+	"subscribe":       1,
+	"unsubscribe":     2,
+	"message":         3,
+	"history":         4,
+	"pong":            5,
+	"presence_info":   6,
+	"join":            7,
+	"leave":           8,
+	"update":          9,
+	"whisper":         10,
+}
+
+func (x Command) String() string {
+	return proto.EnumName(Command_name, int32(x))
+}
+
+func (Command) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_75ae909d4f019479, []int{1}
+}
+
+type StreamHistoryRequest struct {
+	Epoch                string   `protobuf:"bytes,2,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Offset               int64    `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StreamHistoryRequest) Reset()         { *m = StreamHistoryRequest{} }
+func (m *StreamHistoryRequest) String() string { return proto.CompactTextString(m) }
+func (*StreamHistoryRequest) ProtoMessage()    {}
+func (*StreamHistoryRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_75ae909d4f019479, []int{0}
+}
+
+func (m *StreamHistoryRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StreamHistoryRequest.Unmarshal(m, b)
+}
+func (m *StreamHistoryRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StreamHistoryRequest.Marshal(b, m, deterministic)
+}
+func (m *StreamHistoryRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StreamHistoryRequest.Merge(m, src)
+}
+func (m *StreamHistoryRequest) XXX_Size() int {
+	return xxx_messageInfo_StreamHistoryRequest.Size(m)
+}
+func (m *StreamHistoryRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StreamHistoryRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StreamHistoryRequest proto.InternalMessageInfo
+
+func (m *StreamHistoryRequest) GetEpoch() string {
+	if m != nil {
+		return m.Epoch
+	}
+	return ""
+}
+
+func (m *StreamHistoryRequest) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+type HistoryRequest struct {
+	Since                int64                            `protobuf:"varint,1,opt,name=since,proto3" json:"since,omitempty"`
+	Streams              map[string]*StreamHistoryRequest `protobuf:"bytes,2,rep,name=streams,proto3" json:"streams,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Limit                int32                            `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	Latest               bool                             `protobuf:"varint,4,opt,name=latest,proto3" json:"latest,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                         `json:"-"`
+	XXX_unrecognized     []byte                           `json:"-"`
+	XXX_sizecache        int32                            `json:"-"`
+}
+
+func (m *HistoryRequest) Reset()         { *m = HistoryRequest{} }
+func (m *HistoryRequest) String() string { return proto.CompactTextString(m) }
+func (*HistoryRequest) ProtoMessage()    {}
+func (*HistoryRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_75ae909d4f019479, []int{1}
+}
+
+func (m *HistoryRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HistoryRequest.Unmarshal(m, b)
+}
+func (m *HistoryRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HistoryRequest.Marshal(b, m, deterministic)
+}
+func (m *HistoryRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HistoryRequest.Merge(m, src)
+}
+func (m *HistoryRequest) XXX_Size() int {
+	return xxx_messageInfo_HistoryRequest.Size(m)
+}
+func (m *HistoryRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_HistoryRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HistoryRequest proto.InternalMessageInfo
+
+func (m *HistoryRequest) GetSince() int64 {
+	if m != nil {
+		return m.Since
+	}
+	return 0
+}
+
+func (m *HistoryRequest) GetStreams() map[string]*StreamHistoryRequest {
+	if m != nil {
+		return m.Streams
+	}
+	return nil
+}
+
+func (m *HistoryRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *HistoryRequest) GetLatest() bool {
+	if m != nil {
+		return m.Latest
+	}
+	return false
+}
+
+type HistoryCursor struct {
+	Truncated            bool                             `protobuf:"varint,1,opt,name=truncated,proto3" json:"truncated,omitempty"`
+	Streams              map[string]*StreamHistoryRequest `protobuf:"bytes,2,rep,name=streams,proto3" json:"streams,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}                         `json:"-"`
+	XXX_unrecognized     []byte                           `json:"-"`
+	XXX_sizecache        int32                            `json:"-"`
+}
+
+func (m *HistoryCursor) Reset()         { *m = HistoryCursor{} }
+func (m *HistoryCursor) String() string { return proto.CompactTextString(m) }
+func (*HistoryCursor) ProtoMessage()    {}
+func (*HistoryCursor) Descriptor() ([]byte, []int) {
+	return fileDescriptor_75ae909d4f019479, []int{2}
+}
+
+func (m *HistoryCursor) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HistoryCursor.Unmarshal(m, b)
+}
+func (m *HistoryCursor) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HistoryCursor.Marshal(b, m, deterministic)
+}
+func (m *HistoryCursor) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HistoryCursor.Merge(m, src)
+}
+func (m *HistoryCursor) XXX_Size() int {
+	return xxx_messageInfo_HistoryCursor.Size(m)
+}
+func (m *HistoryCursor) XXX_DiscardUnknown() {
+	xxx_messageInfo_HistoryCursor.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HistoryCursor proto.InternalMessageInfo
+
+func (m *HistoryCursor) GetTruncated() bool {
+	if m != nil {
+		return m.Truncated
+	}
+	return false
+}
+
+func (m *HistoryCursor) GetStreams() map[string]*StreamHistoryRequest {
+	if m != nil {
+		return m.Streams
+	}
+	return nil
+}
+
+type PresenceEvent struct {
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Id   string `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	// Info has no structure (JSON encoded)
+	Info                 []byte   `protobuf:"bytes,3,opt,name=info,proto3" json:"info,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PresenceEvent) Reset()         { *m = PresenceEvent{} }
+func (m *PresenceEvent) String() string { return proto.CompactTextString(m) }
+func (*PresenceEvent) ProtoMessage()    {}
+func (*PresenceEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_75ae909d4f019479, []int{3}
+}
+
+func (m *PresenceEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PresenceEvent.Unmarshal(m, b)
+}
+func (m *PresenceEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PresenceEvent.Marshal(b, m, deterministic)
+}
+func (m *PresenceEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PresenceEvent.Merge(m, src)
+}
+func (m *PresenceEvent) XXX_Size() int {
+	return xxx_messageInfo_PresenceEvent.Size(m)
+}
+func (m *PresenceEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_PresenceEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PresenceEvent proto.InternalMessageInfo
+
+func (m *PresenceEvent) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *PresenceEvent) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *PresenceEvent) GetInfo() []byte {
+	if m != nil {
+		return m.Info
+	}
+	return nil
+}
+
+type Message struct {
+	Type       Type    `protobuf:"varint,1,opt,name=type,proto3,enum=action_cable.Type" json:"type,omitempty"`
+	Command    Command `protobuf:"varint,2,opt,name=command,proto3,enum=action_cable.Command" json:"command,omitempty"`
+	Identifier string  `protobuf:"bytes,3,opt,name=identifier,proto3" json:"identifier,omitempty"`
+	// Data is JSON encoded (by Action Cable protocol design)
+	Data []byte `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	// Message has no structure (JSON encoded)
+	Message   []byte          `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	Reason    string          `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`
+	Reconnect bool            `protobuf:"varint,7,opt,name=reconnect,proto3" json:"reconnect,omitempty"`
+	History   *HistoryRequest `protobuf:"bytes,8,opt,name=history,proto3" json:"history,omitempty"`
+	Presence  *PresenceEvent  `protobuf:"bytes,9,opt,name=presence,proto3" json:"presence,omitempty"`
+	// Subscription filter expression (JSON encoded)
+	Filter               []byte   `protobuf:"bytes,10,opt,name=filter,proto3" json:"filter,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Message) Reset()         { *m = Message{} }
+func (m *Message) String() string { return proto.CompactTextString(m) }
+func (*Message) ProtoMessage()    {}
+func (*Message) Descriptor() ([]byte, []int) {
+	return fileDescriptor_75ae909d4f019479, []int{4}
+}
+
+func (m *Message) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Message.Unmarshal(m, b)
+}
+func (m *Message) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Message.Marshal(b, m, deterministic)
+}
+func (m *Message) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message.Merge(m, src)
+}
+func (m *Message) XXX_Size() int {
+	return xxx_messageInfo_Message.Size(m)
+}
+func (m *Message) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Message proto.InternalMessageInfo
+
+func (m *Message) GetType() Type {
+	if m != nil {
+		return m.Type
+	}
+	return Type_no_type
+}
+
+func (m *Message) GetCommand() Command {
+	if m != nil {
+		return m.Command
+	}
+	return Command_unknown_command
+}
+
+func (m *Message) GetIdentifier() string {
+	if m != nil {
+		return m.Identifier
+	}
+	return ""
+}
+
+func (m *Message) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *Message) GetMessage() []byte {
+	if m != nil {
+		return m.Message
+	}
+	return nil
+}
+
+func (m *Message) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *Message) GetReconnect() bool {
+	if m != nil {
+		return m.Reconnect
+	}
+	return false
+}
+
+func (m *Message) GetHistory() *HistoryRequest {
+	if m != nil {
+		return m.History
+	}
+	return nil
+}
+
+func (m *Message) GetPresence() *PresenceEvent {
+	if m != nil {
+		return m.Presence
+	}
+	return nil
+}
+
+func (m *Message) GetFilter() []byte {
+	if m != nil {
+		return m.Filter
+	}
+	return nil
+}
+
+type Reply struct {
+	Type       Type   `protobuf:"varint,1,opt,name=type,proto3,enum=action_cable.Type" json:"type,omitempty"`
+	Identifier string `protobuf:"bytes,2,opt,name=identifier,proto3" json:"identifier,omitempty"`
+	// Message has no structure (JSON encoded)
+	Message              []byte         `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	Reason               string         `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+	Reconnect            bool           `protobuf:"varint,5,opt,name=reconnect,proto3" json:"reconnect,omitempty"`
+	StreamId             string         `protobuf:"bytes,6,opt,name=stream_id,json=streamId,proto3" json:"stream_id,omitempty"`
+	Epoch                string         `protobuf:"bytes,7,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Offset               int64          `protobuf:"varint,8,opt,name=offset,proto3" json:"offset,omitempty"`
+	Sid                  string         `protobuf:"bytes,9,opt,name=sid,proto3" json:"sid,omitempty"`
+	Restored             bool           `protobuf:"varint,10,opt,name=restored,proto3" json:"restored,omitempty"`
+	RestoredIds          []string       `protobuf:"bytes,11,rep,name=restored_ids,json=restoredIds,proto3" json:"restored_ids,omitempty"`
+	Presence             *PresenceEvent `protobuf:"bytes,12,opt,name=presence,proto3" json:"presence,omitempty"`
+	History              *HistoryCursor `protobuf:"bytes,13,opt,name=history,proto3" json:"history,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *Reply) Reset()         { *m = Reply{} }
+func (m *Reply) String() string { return proto.CompactTextString(m) }
+func (*Reply) ProtoMessage()    {}
+func (*Reply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_75ae909d4f019479, []int{5}
+}
+
+func (m *Reply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Reply.Unmarshal(m, b)
+}
+func (m *Reply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Reply.Marshal(b, m, deterministic)
+}
+func (m *Reply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Reply.Merge(m, src)
+}
+func (m *Reply) XXX_Size() int {
+	return xxx_messageInfo_Reply.Size(m)
+}
+func (m *Reply) XXX_DiscardUnknown() {
+	xxx_messageInfo_Reply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Reply proto.InternalMessageInfo
+
+func (m *Reply) GetType() Type {
+	if m != nil {
+		return m.Type
+	}
+	return Type_no_type
+}
+
+func (m *Reply) GetIdentifier() string {
+	if m != nil {
+		return m.Identifier
+	}
+	return ""
+}
+
+func (m *Reply) GetMessage() []byte {
+	if m != nil {
+		return m.Message
+	}
+	return nil
+}
+
+func (m *Reply) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *Reply) GetReconnect() bool {
+	if m != nil {
+		return m.Reconnect
+	}
+	return false
+}
+
+func (m *Reply) GetStreamId() string {
+	if m != nil {
+		return m.StreamId
+	}
+	return ""
+}
+
+func (m *Reply) GetEpoch() string {
+	if m != nil {
+		return m.Epoch
+	}
+	return ""
+}
+
+func (m *Reply) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *Reply) GetSid() string {
+	if m != nil {
+		return m.Sid
+	}
+	return ""
+}
+
+func (m *Reply) GetRestored() bool {
+	if m != nil {
+		return m.Restored
+	}
+	return false
+}
+
+func (m *Reply) GetRestoredIds() []string {
+	if m != nil {
+		return m.RestoredIds
+	}
+	return nil
+}
+
+func (m *Reply) GetPresence() *PresenceEvent {
+	if m != nil {
+		return m.Presence
+	}
+	return nil
+}
+
+func (m *Reply) GetHistory() *HistoryCursor {
+	if m != nil {
+		return m.History
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("action_cable.Type", Type_name, Type_value)
+	proto.RegisterEnum("action_cable.Command", Command_name, Command_value)
+	proto.RegisterType((*StreamHistoryRequest)(nil), "action_cable.StreamHistoryRequest")
+	proto.RegisterType((*HistoryRequest)(nil), "action_cable.HistoryRequest")
+	proto.RegisterMapType((map[string]*StreamHistoryRequest)(nil), "action_cable.HistoryRequest.StreamsEntry")
+	proto.RegisterType((*HistoryCursor)(nil), "action_cable.HistoryCursor")
+	proto.RegisterMapType((map[string]*StreamHistoryRequest)(nil), "action_cable.HistoryCursor.StreamsEntry")
+	proto.RegisterType((*PresenceEvent)(nil), "action_cable.PresenceEvent")
+	proto.RegisterType((*Message)(nil), "action_cable.Message")
+	proto.RegisterType((*Reply)(nil), "action_cable.Reply")
+}
+
+func init() {
+	proto.RegisterFile("action_cable.proto", fileDescriptor_75ae909d4f019479)
+}
+
+var fileDescriptor_75ae909d4f019479 = []byte{
+	// 788 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x55, 0xcd, 0x92, 0xdb, 0x44,
+	0x10, 0x8e, 0x24, 0xcb, 0x92, 0xda, 0x3f, 0x19, 0x3a, 0x0b, 0xa8, 0x92, 0x14, 0x65, 0x7c, 0xa0,
+	0x4c, 0x0e, 0x4b, 0xd5, 0x52, 0x40, 0x8a, 0x63, 0x96, 0x14, 0xe4, 0x40, 0x15, 0x35, 0x70, 0xe2,
+	0x80, 0x4b, 0x2b, 0xb5, 0x77, 0x27, 0xb1, 0x67, 0xc4, 0xcc, 0x78, 0x53, 0x7e, 0x1e, 0x8e, 0x3c,
+	0x05, 0x8f, 0xc0, 0x93, 0x70, 0xe6, 0x46, 0xcd, 0x48, 0xb2, 0xad, 0xc4, 0x4b, 0xc1, 0x21, 0xb7,
+	0xe9, 0x9e, 0xee, 0x6f, 0xbe, 0xf9, 0xbe, 0xd6, 0x08, 0xb0, 0x28, 0xad, 0x50, 0x72, 0x59, 0x16,
+	0x57, 0x6b, 0x3a, 0xaf, 0xb5, 0xb2, 0x0a, 0xc7, 0xc7, 0xb9, 0xf9, 0x37, 0x70, 0xf6, 0xa3, 0xd5,
+	0x54, 0x6c, 0xbe, 0x13, 0xc6, 0x2a, 0xbd, 0xe3, 0xf4, 0xeb, 0x96, 0x8c, 0xc5, 0x33, 0x88, 0xa9,
+	0x56, 0xe5, 0x4d, 0x1e, 0xce, 0x82, 0x45, 0xc6, 0x9b, 0x00, 0x3f, 0x80, 0xa1, 0x5a, 0xad, 0x0c,
+	0xd9, 0x3c, 0x9a, 0x05, 0x8b, 0x88, 0xb7, 0xd1, 0xfc, 0xef, 0x00, 0xa6, 0x6f, 0x03, 0x18, 0x21,
+	0x4b, 0xca, 0x03, 0x5f, 0xd9, 0x04, 0x78, 0x09, 0x89, 0xf1, 0xc7, 0x99, 0x3c, 0x9c, 0x45, 0x8b,
+	0xd1, 0xc5, 0xa7, 0xe7, 0x3d, 0x8a, 0x7d, 0x90, 0xf3, 0x86, 0x9a, 0x79, 0x2e, 0xad, 0xde, 0xf1,
+	0xae, 0xd3, 0x41, 0xaf, 0xc5, 0x46, 0x34, 0x24, 0x62, 0xde, 0x04, 0x8e, 0xdb, 0xba, 0xb0, 0x64,
+	0x6c, 0x3e, 0x98, 0x05, 0x8b, 0x94, 0xb7, 0xd1, 0xc3, 0x5f, 0x60, 0x7c, 0x0c, 0x83, 0x0c, 0xa2,
+	0x57, 0xb4, 0xf3, 0xb4, 0x32, 0xee, 0x96, 0xf8, 0x14, 0xe2, 0xdb, 0x62, 0xbd, 0x25, 0x7f, 0xd7,
+	0xd1, 0xc5, 0xbc, 0x4f, 0xe9, 0x94, 0x3c, 0xbc, 0x69, 0xf8, 0x3a, 0x7c, 0x1a, 0xcc, 0xff, 0x0c,
+	0x60, 0xd2, 0xee, 0x5e, 0x6e, 0xb5, 0x51, 0x1a, 0x1f, 0x43, 0x66, 0xf5, 0x56, 0x96, 0x85, 0xa5,
+	0xca, 0x9f, 0x93, 0xf2, 0x43, 0x02, 0x9f, 0xbd, 0x29, 0xc1, 0xe2, 0xa4, 0x04, 0x0d, 0xd6, 0x69,
+	0x05, 0xde, 0xf9, 0x9d, 0xbe, 0x85, 0xc9, 0x0f, 0x9a, 0x0c, 0xc9, 0x92, 0x9e, 0xdf, 0x92, 0xb4,
+	0x88, 0x30, 0xb0, 0xbb, 0x9a, 0xda, 0x13, 0xfc, 0x1a, 0xa7, 0x10, 0x8a, 0xaa, 0x9d, 0x8f, 0x50,
+	0x54, 0xae, 0x46, 0xc8, 0x95, 0xf2, 0xae, 0x8c, 0xb9, 0x5f, 0xcf, 0xff, 0x0a, 0x21, 0xf9, 0x9e,
+	0x8c, 0x29, 0xae, 0x09, 0x3f, 0x39, 0xc2, 0x98, 0x5e, 0x60, 0x9f, 0xd1, 0x4f, 0xbb, 0x9a, 0x5a,
+	0xdc, 0xcf, 0x20, 0x29, 0xd5, 0x66, 0x53, 0xc8, 0x06, 0x7c, 0x7a, 0xf1, 0x7e, 0xbf, 0xf4, 0xb2,
+	0xd9, 0xe4, 0x5d, 0x15, 0x7e, 0x04, 0x20, 0x2a, 0x92, 0x56, 0xac, 0x04, 0x69, 0x7f, 0x7c, 0xc6,
+	0x8f, 0x32, 0x8e, 0x58, 0x55, 0xd8, 0xc2, 0xcf, 0xc5, 0x98, 0xfb, 0x35, 0xe6, 0x90, 0x6c, 0x1a,
+	0x5e, 0x79, 0xec, 0xd3, 0x5d, 0xe8, 0xe6, 0x48, 0x53, 0x61, 0x94, 0xcc, 0x87, 0x1e, 0xa9, 0x8d,
+	0x9c, 0xab, 0x9a, 0x4a, 0x25, 0x25, 0x95, 0x36, 0x4f, 0x1a, 0x57, 0xf7, 0x09, 0xfc, 0x12, 0x92,
+	0x9b, 0x46, 0xce, 0x3c, 0xf5, 0x8a, 0x3f, 0xfe, 0xb7, 0xc1, 0xe6, 0x5d, 0x31, 0x7e, 0x05, 0x69,
+	0xdd, 0x2a, 0x9d, 0x67, 0xbe, 0xf1, 0x51, 0xbf, 0xb1, 0xe7, 0x03, 0xdf, 0x17, 0x3b, 0x9a, 0x2b,
+	0xb1, 0xb6, 0xa4, 0x73, 0xf0, 0xfc, 0xdb, 0x68, 0xfe, 0x7b, 0x04, 0x31, 0xa7, 0x7a, 0xbd, 0xfb,
+	0xcf, 0x7a, 0xf7, 0xe5, 0x0b, 0xdf, 0x92, 0xef, 0x48, 0xaa, 0xe8, 0x2e, 0xa9, 0x06, 0x77, 0x4b,
+	0x15, 0xbf, 0x29, 0xd5, 0x23, 0xc8, 0x9a, 0x39, 0x5e, 0x8a, 0xaa, 0xd5, 0x38, 0x6d, 0x12, 0x2f,
+	0xaa, 0xc3, 0xbb, 0x93, 0x9c, 0x7e, 0x77, 0xd2, 0xe3, 0x77, 0xc7, 0xcd, 0xbd, 0x11, 0x95, 0x17,
+	0x2e, 0xe3, 0x6e, 0x89, 0x0f, 0x21, 0xd5, 0xe4, 0xa4, 0xa5, 0xca, 0x0b, 0x93, 0xf2, 0x7d, 0x8c,
+	0x1f, 0xc3, 0xb8, 0x5b, 0x2f, 0x45, 0x65, 0xf2, 0xd1, 0x2c, 0x5a, 0x64, 0x7c, 0xd4, 0xe5, 0x5e,
+	0x54, 0xa6, 0x67, 0xc7, 0xf8, 0xff, 0xd8, 0xf1, 0xc5, 0xc1, 0xff, 0xc9, 0xa9, 0xbe, 0xde, 0x57,
+	0xbd, 0xb7, 0xff, 0xc9, 0x1f, 0x01, 0x0c, 0x9c, 0x15, 0x38, 0x82, 0x44, 0xaa, 0xa5, 0xf3, 0x83,
+	0xdd, 0x73, 0xc1, 0x6b, 0x5a, 0x97, 0x6a, 0x43, 0x2c, 0xc0, 0x29, 0x40, 0x25, 0x4c, 0x2b, 0x1e,
+	0x0b, 0x31, 0x85, 0x41, 0x2d, 0xe4, 0x35, 0x8b, 0x30, 0x87, 0xb3, 0x52, 0xc9, 0x95, 0xd0, 0x9b,
+	0xa5, 0xd9, 0x5e, 0x99, 0x52, 0x8b, 0xda, 0x9d, 0xc8, 0x06, 0xf8, 0x21, 0x3c, 0xd0, 0xf4, 0x92,
+	0x4a, 0xdb, 0xdf, 0x88, 0xf1, 0x01, 0xdc, 0xef, 0x5a, 0x5a, 0x0a, 0x6c, 0x88, 0x08, 0xd3, 0xb6,
+	0xba, 0xcb, 0x25, 0x38, 0x3e, 0x08, 0xc1, 0x52, 0x64, 0x30, 0xde, 0xca, 0x16, 0xea, 0x8a, 0x2a,
+	0x96, 0x61, 0x06, 0x31, 0x69, 0xad, 0x34, 0x83, 0x27, 0xbf, 0x05, 0x90, 0xb4, 0xdf, 0xa4, 0xc3,
+	0xdf, 0xca, 0x57, 0x52, 0xbd, 0x96, 0xcb, 0xf6, 0xeb, 0x64, 0xf7, 0x70, 0x02, 0xd9, 0xbe, 0x97,
+	0x05, 0x78, 0x1f, 0x46, 0x47, 0x60, 0x2c, 0x74, 0xd7, 0x6d, 0x27, 0x8a, 0x45, 0x2e, 0xe8, 0x58,
+	0x0c, 0xfc, 0x5d, 0x95, 0xbc, 0x66, 0x31, 0xbe, 0x07, 0x93, 0x8e, 0xcf, 0xd2, 0xbd, 0x2c, 0x6c,
+	0xe8, 0x36, 0x5f, 0x2a, 0x21, 0x59, 0xe2, 0xc8, 0xac, 0xa9, 0xb8, 0x75, 0x4c, 0x01, 0x86, 0xdb,
+	0xba, 0x2a, 0x2c, 0xb1, 0xcc, 0xcb, 0x78, 0x23, 0x4c, 0x4d, 0x9a, 0xc1, 0xb3, 0xc9, 0xcf, 0xa3,
+	0xc6, 0x10, 0xef, 0xc7, 0xd5, 0xd0, 0xff, 0x0c, 0x3f, 0xff, 0x67, 0x00, 0x5f, 0xc7, 0x76, 0x7c,
+	0x22, 0x07, 0x00, 0x00,
+}
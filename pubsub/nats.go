@@ -18,6 +18,8 @@ import (
 type NATSConfig struct {
 	Channel string `toml:"channel"`
 	NATS    *nconfig.NATSConfig
+	// Whether wildcard streams are enabled (set from the app config)
+	Wildcards bool `toml:"-"`
 }
 
 func NewNATSConfig() NATSConfig {
@@ -42,7 +44,10 @@ type NATSSubscriber struct {
 	conn *nats.Conn
 
 	subscriptions map[string]*nats.Subscription
-	subMu         sync.RWMutex
+	// Wildcard subscriptions (wildcard stream -> NATS subject);
+	// different wildcard streams could share the same subscription
+	patterns map[string]string
+	subMu    sync.RWMutex
 
 	log *slog.Logger
 }
@@ -55,6 +60,7 @@ func NewNATSSubscriber(node Handler, config *NATSConfig, l *slog.Logger) (*NATSS
 		node:          node,
 		config:        config,
 		subscriptions: make(map[string]*nats.Subscription),
+		patterns:      make(map[string]string),
 		log:           l.With("context", "pubsub"),
 	}, nil
 }
@@ -116,6 +122,11 @@ func (s *NATSSubscriber) Subscribe(stream string) {
 	s.subMu.Lock()
 	defer s.subMu.Unlock()
 
+	if s.config.Wildcards && utils.IsStreamPattern(stream) {
+		s.subscribePattern(stream)
+		return
+	}
+
 	sub, err := s.conn.Subscribe(stream, s.handleMessage)
 
 	if err != nil {
@@ -130,10 +141,53 @@ func (s *NATSSubscriber) Unsubscribe(stream string) {
 	s.subMu.Lock()
 	defer s.subMu.Unlock()
 
-	if sub, ok := s.subscriptions[stream]; ok {
-		delete(s.subscriptions, stream)
-		sub.Unsubscribe() // nolint:errcheck
+	sub, ok := s.subscriptions[stream]
+
+	if !ok {
+		return
+	}
+
+	delete(s.subscriptions, stream)
+
+	if subject, ok := s.patterns[stream]; ok {
+		delete(s.patterns, stream)
+
+		for _, other := range s.patterns {
+			if other == subject {
+				return
+			}
+		}
 	}
+
+	sub.Unsubscribe() // nolint:errcheck
+}
+
+// subscribePattern subscribes to the NATS subject corresponding to the wildcard stream
+// (or reuses the existing subscription for the same subject). Must be called within the lock.
+func (s *NATSSubscriber) subscribePattern(stream string) {
+	subject := natsPatternSubject(stream)
+
+	for pattern, other := range s.patterns {
+		if other == subject {
+			s.patterns[stream] = subject
+			s.subscriptions[stream] = s.subscriptions[pattern]
+			return
+		}
+	}
+
+	if subject != stream {
+		s.log.Debug("subscribing to a broader subject for wildcard stream", "stream", stream, "subject", subject)
+	}
+
+	sub, err := s.conn.Subscribe(subject, s.handleMessage)
+
+	if err != nil {
+		s.log.Error("failed to subscribe", "stream", stream, "error", err)
+		return
+	}
+
+	s.patterns[stream] = subject
+	s.subscriptions[stream] = sub
 }
 
 func (s *NATSSubscriber) Broadcast(msg *common.StreamMessage) {
@@ -153,6 +207,11 @@ func (s *NATSSubscriber) Publish(stream string, msg interface{}) {
 }
 
 func (s *NATSSubscriber) handleMessage(m *nats.Msg) {
+	if s.config.Wildcards && m.Sub != nil && m.Sub.Subject != m.Subject && s.isDuplicate(m.Subject, m.Sub.Subject) {
+		s.log.With("channel", m.Subject).Debug("skip duplicate wildcard delivery", "pattern", m.Sub.Subject)
+		return
+	}
+
 	msg, err := common.PubSubMessageFromJSON(m.Data)
 
 	if err != nil {
@@ -171,3 +230,19 @@ func (s *NATSSubscriber) handleMessage(m *nats.Msg) {
 		s.log.With("channel", m.Subject).Warn("received unknown message", "data", logger.CompactValue(m.Data))
 	}
 }
+
+// isDuplicate returns true if the message received via the wildcard subscription must be skipped:
+// either it's delivered via the exact subscription, or another subscription is responsible for it,
+// or no wildcard stream matches it (when subscribed to a broader subject)
+func (s *NATSSubscriber) isDuplicate(stream string, subject string) bool {
+	s.subMu.RLock()
+	defer s.subMu.RUnlock()
+
+	if _, ok := s.subscriptions[stream]; ok {
+		return true
+	}
+
+	owner := wildcardOwner(stream, s.patterns)
+
+	return owner == "" || s.patterns[owner] != subject
+}
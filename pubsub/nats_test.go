@@ -105,6 +105,88 @@ func TestNATSReconnect(t *testing.T) {
 	assert.Equal(t, "2023", msg.Data)
 }
 
+func TestNATSWildcardSubscriptions(t *testing.T) {
+	server := buildNATSServer()
+	err := server.Start()
+	require.NoError(t, err)
+	defer server.Shutdown(context.Background()) // nolint:errcheck
+
+	handler := NewTestHandler()
+	nconfig := nats.NewNATSConfig()
+	config := NewNATSConfig()
+	config.NATS = &nconfig
+	config.Wildcards = true
+
+	subscriber, err := NewNATSSubscriber(handler, &config, slog.Default())
+	require.NoError(t, err)
+
+	done := make(chan error)
+
+	err = subscriber.Start(done)
+	require.NoError(t, err)
+
+	defer subscriber.Shutdown(context.Background()) // nolint:errcheck
+
+	subscriber.Subscribe("project.42.>")
+	subscriber.Subscribe("project.*.tasks")
+	subscriber.Subscribe("project.42.tasks")
+
+	require.NoError(t, waitNATSSubscription(subscriber, "project.42.tasks"))
+
+	subscriber.Broadcast(&common.StreamMessage{Stream: "project.42.tasks", Data: "1"})
+
+	msg := handler.Receive()
+	require.NotNil(t, msg)
+	assert.Equal(t, "project.42.tasks", msg.Stream)
+
+	// Delivered only once
+	assert.Nil(t, handler.Receive())
+
+	subscriber.Broadcast(&common.StreamMessage{Stream: "project.42.comments.1", Data: "2"})
+
+	msg = handler.Receive()
+	require.NotNil(t, msg)
+	assert.Equal(t, "project.42.comments.1", msg.Stream)
+
+	assert.Nil(t, handler.Receive())
+
+	subscriber.Broadcast(&common.StreamMessage{Stream: "project.1.tasks", Data: "3"})
+
+	msg = handler.Receive()
+	require.NotNil(t, msg)
+	assert.Equal(t, "project.1.tasks", msg.Stream)
+
+	assert.Nil(t, handler.Receive())
+
+	// Colon-separated wildcard streams share a broader subject
+	subscriber.Subscribe("chat:*")
+	subscriber.Subscribe("room:*:messages")
+
+	require.NoError(t, waitNATSSubscription(subscriber, "chat:*"))
+
+	subscriber.Broadcast(&common.StreamMessage{Stream: "chat:1", Data: "4"})
+
+	msg = handler.Receive()
+	require.NotNil(t, msg)
+	assert.Equal(t, "chat:1", msg.Stream)
+
+	assert.Nil(t, handler.Receive())
+
+	subscriber.Broadcast(&common.StreamMessage{Stream: "chat:1:typing", Data: "5"})
+
+	assert.Nil(t, handler.Receive())
+
+	subscriber.Unsubscribe("chat:*")
+
+	subscriber.Broadcast(&common.StreamMessage{Stream: "room:1:messages", Data: "6"})
+
+	msg = handler.Receive()
+	require.NotNil(t, msg)
+	assert.Equal(t, "room:1:messages", msg.Stream)
+
+	assert.Nil(t, handler.Receive())
+}
+
 func waitNATSSubscription(subscriber Subscriber, stream string) error {
 	s := subscriber.(*NATSSubscriber)
 
@@ -22,6 +22,8 @@ type subscriptionCmd = int
 const (
 	subscribeCmd subscriptionCmd = iota
 	unsubscribeCmd
+	psubscribeCmd
+	punsubscribeCmd
 )
 
 type clientCommand struct {
@@ -31,11 +33,15 @@ type clientCommand struct {
 
 type subscriptionEntry struct {
 	id string
+	// pattern is true for wildcard streams (id contains a glob-style pattern then)
+	pattern bool
 }
 
 type RedisConfig struct {
 	Channel string `toml:"channel"`
 	Redis   *rconfig.RedisConfig
+	// Whether wildcard streams are enabled (set from the app config)
+	Wildcards bool `toml:"-"`
 }
 
 func NewRedisConfig() RedisConfig {
@@ -63,7 +69,9 @@ type RedisSubscriber struct {
 	reconnectAttempt int
 
 	subscriptions map[string]*subscriptionEntry
-	subMu         sync.RWMutex
+	// Wildcard subscriptions (wildcard stream -> glob)
+	patterns map[string]string
+	subMu    sync.RWMutex
 
 	commandsCh chan (*clientCommand)
 	shutdownCh chan struct{}
@@ -92,6 +100,7 @@ func NewRedisSubscriber(node Handler, config *RedisConfig, l *slog.Logger) (*Red
 		config:         config,
 		clientOptions:  options,
 		subscriptions:  make(map[string]*subscriptionEntry),
+		patterns:       make(map[string]string),
 		log:            l.With("context", "pubsub"),
 		commandsCh:     make(chan *clientCommand, 2),
 		shutdownCh:     make(chan struct{}),
@@ -141,6 +150,11 @@ func (s *RedisSubscriber) IsMultiNode() bool {
 }
 
 func (s *RedisSubscriber) Subscribe(stream string) {
+	if s.config.Wildcards && utils.IsStreamPattern(stream) {
+		s.subscribePattern(stream)
+		return
+	}
+
 	s.subMu.Lock()
 	s.subscriptions[stream] = &subscriptionEntry{id: stream}
 	entry := s.subscriptions[stream]
@@ -151,15 +165,60 @@ func (s *RedisSubscriber) Subscribe(stream string) {
 
 func (s *RedisSubscriber) Unsubscribe(stream string) {
 	s.subMu.Lock()
-	if _, ok := s.subscriptions[stream]; !ok {
+	entry, ok := s.subscriptions[stream]
+
+	if !ok {
 		s.subMu.Unlock()
 		return
 	}
 
 	delete(s.subscriptions, stream)
+
+	if entry.pattern {
+		delete(s.patterns, stream)
+
+		// Different wildcard streams could share the same glob pattern
+		for _, glob := range s.patterns {
+			if glob == entry.id {
+				s.subMu.Unlock()
+				return
+			}
+		}
+	}
+
 	s.subMu.Unlock()
 
-	s.commandsCh <- &clientCommand{cmd: unsubscribeCmd, id: stream}
+	if entry.pattern {
+		s.commandsCh <- &clientCommand{cmd: punsubscribeCmd, id: entry.id}
+	} else {
+		s.commandsCh <- &clientCommand{cmd: unsubscribeCmd, id: stream}
+	}
+}
+
+func (s *RedisSubscriber) subscribePattern(stream string) {
+	s.subMu.Lock()
+	entry := &subscriptionEntry{id: utils.StreamPatternGlob(stream), pattern: true}
+	s.subscriptions[stream] = entry
+	s.patterns[stream] = entry.id
+	s.subMu.Unlock()
+
+	s.commandsCh <- &clientCommand{cmd: psubscribeCmd, id: entry.id}
+}
+
+// isDuplicate returns true if the message received via the glob pattern must be skipped:
+// Redis glob patterns are less strict than wildcard streams, and a message could be delivered via multiple subscriptions
+// (once per glob, while different wildcard streams could share the same glob)
+func (s *RedisSubscriber) isDuplicate(channel string, glob string) bool {
+	s.subMu.RLock()
+	defer s.subMu.RUnlock()
+
+	if entry, ok := s.subscriptions[channel]; ok && !entry.pattern {
+		return true
+	}
+
+	owner := wildcardOwner(channel, s.patterns)
+
+	return owner == "" || s.patterns[owner] != glob
 }
 
 func (s *RedisSubscriber) Broadcast(msg *common.StreamMessage) {
@@ -236,6 +295,11 @@ func (s *RedisSubscriber) runPubSub(done chan (error)) {
 			s.trackEvent(m.Kind, m.Channel)
 		},
 		OnMessage: func(m rueidis.PubSubMessage) {
+			if m.Pattern != "" && s.isDuplicate(m.Channel, m.Pattern) {
+				s.log.With("channel", m.Channel).Debug("skip duplicate wildcard delivery", "pattern", m.Pattern)
+				return
+			}
+
 			msg, err := common.PubSubMessageFromJSON([]byte(m.Message))
 
 			if err != nil {
@@ -279,6 +343,12 @@ func (s *RedisSubscriber) runPubSub(done chan (error)) {
 			case unsubscribeCmd:
 				s.log.With("channel", entry.id).Debug("unsubscribing")
 				client.Do(ctx, client.B().Unsubscribe().Channel(entry.id).Build())
+			case psubscribeCmd:
+				s.log.With("pattern", entry.id).Debug("subscribing")
+				client.Do(ctx, client.B().Psubscribe().Pattern(entry.id).Build())
+			case punsubscribeCmd:
+				s.log.With("pattern", entry.id).Debug("unsubscribing")
+				client.Do(ctx, client.B().Punsubscribe().Pattern(entry.id).Build())
 			}
 		}
 	}
@@ -314,9 +384,25 @@ const batchSubscribeSize = 256
 
 func (s *RedisSubscriber) resubscribe(client rueidis.DedicatedClient) {
 	s.subMu.RLock()
-	channels := maps.Keys(s.subscriptions)
+	channels := make([]string, 0, len(s.subscriptions))
+	globs := make(map[string]bool)
+
+	for id, entry := range s.subscriptions {
+		if entry.pattern {
+			globs[entry.id] = true
+		} else {
+			channels = append(channels, id)
+		}
+	}
 	s.subMu.RUnlock()
 
+	if len(globs) > 0 {
+		err := client.Do(context.Background(), client.B().Psubscribe().Pattern(maps.Keys(globs)...).Build()).Error()
+		if err != nil {
+			s.log.Error("failed to resubscribe to patterns", "error", err)
+		}
+	}
+
 	batch := make([]string, 0, batchSubscribeSize)
 
 	for i, id := range channels {
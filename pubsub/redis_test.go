@@ -225,3 +225,33 @@ func waitRedisPubSubConnections(client rueidis.Client) error {
 		attempts++
 	}
 }
+
+func TestRedisSubscriberIsDuplicate(t *testing.T) {
+	subscriber := &RedisSubscriber{
+		subscriptions: map[string]*subscriptionEntry{
+			"project:42:tasks": {id: "project:42:tasks"},
+			"project:*":        {id: "project:*", pattern: true},
+			"project:>":        {id: "project:*", pattern: true},
+			"project:42:*":     {id: "project:42:*", pattern: true},
+		},
+		patterns: map[string]string{
+			"project:*":    "project:*",
+			"project:>":    "project:*",
+			"project:42:*": "project:42:*",
+		},
+	}
+
+	// Delivered via the exact subscription
+	assert.True(t, subscriber.isDuplicate("project:42:tasks", "project:*"))
+	assert.True(t, subscriber.isDuplicate("project:42:tasks", "project:42:*"))
+
+	// Wildcard streams sharing the same glob get a single delivery
+	assert.False(t, subscriber.isDuplicate("project:1", "project:*"))
+
+	// The smallest matching wildcard stream ("project:42:*") is responsible for the message
+	assert.False(t, subscriber.isDuplicate("project:42:comments", "project:42:*"))
+	assert.True(t, subscriber.isDuplicate("project:42:comments", "project:*"))
+
+	// Glob matches, but no wildcard stream does
+	assert.True(t, subscriber.isDuplicate("projects", "project:*"))
+}
@@ -0,0 +1,43 @@
+package pubsub
+
+import (
+	"strings"
+
+	"github.com/anycable/anycable-go/utils"
+)
+
+// wildcardOwner returns the wildcard stream responsible for handling a message for the stream received via a pattern subscription.
+// Pub/sub servers deliver a message once per each matching subscription, but we must broadcast it only once,
+// so the first matching wildcard stream in lexicographical order is chosen.
+// The patterns map contains wildcard streams with the corresponding pub/sub subscription identifiers (globs, subjects).
+// Returns an empty string if no wildcard stream matches the stream.
+func wildcardOwner(stream string, patterns map[string]string) string {
+	owner := ""
+
+	for pattern := range patterns {
+		if (owner == "" || pattern < owner) && utils.MatchWildcardStream(pattern, stream) {
+			owner = pattern
+		}
+	}
+
+	return owner
+}
+
+// natsPatternSubject returns the NATS subject to subscribe to for the wildcard stream.
+// NATS wildcards only work for dot-separated tokens, so for other wildcard streams we subscribe to a broader subject
+// (literal leading tokens followed by ">") and filter received messages.
+func natsPatternSubject(pattern string) string {
+	if delim, _ := utils.StreamPatternDelimiter(pattern); delim == '.' {
+		return pattern
+	}
+
+	tokens := strings.Split(pattern, ".")
+
+	for i, token := range tokens {
+		if strings.ContainsAny(token, utils.StreamWildcard+utils.StreamTailWildcard) {
+			return strings.Join(append(tokens[:i:i], ">"), ".")
+		}
+	}
+
+	return pattern
+}
@@ -0,0 +1,25 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWildcardOwner(t *testing.T) {
+	patterns := map[string]string{
+		"project.42.>":    "project.42.>",
+		"project.*.tasks": "project.*.tasks",
+	}
+
+	assert.Equal(t, "project.*.tasks", wildcardOwner("project.42.tasks", patterns))
+	assert.Equal(t, "project.42.>", wildcardOwner("project.42.comments", patterns))
+	assert.Equal(t, "", wildcardOwner("chat.1", patterns))
+}
+
+func TestNATSPatternSubject(t *testing.T) {
+	assert.Equal(t, "project.42.>", natsPatternSubject("project.42.>"))
+	assert.Equal(t, "project.*.tasks", natsPatternSubject("project.*.tasks"))
+	assert.Equal(t, ">", natsPatternSubject("project:42:*"))
+	assert.Equal(t, "project.>", natsPatternSubject("project.42:*"))
+}
@@ -34,6 +34,9 @@ type Config struct {
 
 	// CableReadySecret is a custom secret key used to verify CableReady streams
 	CableReadySecret string `toml:"cable_ready_secret"`
+
+	// Wildcards determines if wildcard streams are enabled (set from the app config)
+	Wildcards bool `toml:"-"`
 }
 
 // NewConfig returns a new Config with the given key
@@ -17,6 +17,8 @@ type SubscribeRequest struct {
 
 	whisper  bool
 	presence bool
+	// wildcards is true if wildcard streams are enabled
+	wildcards bool
 }
 
 func (r *SubscribeRequest) IsPresent() bool {
@@ -79,6 +81,17 @@ func (c *Controller) Subscribe(sid string, env *common.SessionEnv, ids string, i
 	if request.StreamName != "" {
 		stream = request.StreamName
 
+		// Wildcard subscriptions must be authorized (e.g., via signed streams)
+		if request.wildcards && utils.IsStreamPattern(stream) {
+			c.log.With("identifier", identifier).Debug("wildcard streams must be signed", "stream", stream)
+
+			return &common.CommandResult{
+					Status:        common.FAILURE,
+					Transmissions: []string{common.RejectionMessage(identifier)},
+				},
+				nil
+		}
+
 		c.log.With("identifier", identifier).Debug("unsigned", "stream", stream)
 	} else {
 		verified, err := c.verifier.Verified(request.SignedStreamName)
@@ -111,6 +124,12 @@ func (c *Controller) Subscribe(sid string, env *common.SessionEnv, ids string, i
 
 	var state map[string]string
 
+	// Whispering and presence are not supported for wildcard streams
+	if request.wildcards && utils.IsStreamPattern(stream) {
+		request.whisper = false
+		request.presence = false
+	}
+
 	if request.whisper {
 		state = make(map[string]string)
 		state[common.WHISPER_STREAM_STATE] = stream
@@ -154,6 +173,7 @@ func NewStreamsController(conf *Config, l *slog.Logger) *Controller {
 	allowPublic := conf.Public
 	whispers := conf.Whisper
 	presence := conf.Presence
+	wildcards := conf.Wildcards
 
 	resolver := func(identifier string) (*SubscribeRequest, error) {
 		var request SubscribeRequest
@@ -174,6 +194,8 @@ func NewStreamsController(conf *Config, l *slog.Logger) *Controller {
 			request.presence = true
 		}
 
+		request.wildcards = wildcards
+
 		return &request, nil
 	}
 
@@ -56,6 +56,38 @@ func TestStreamsController(t *testing.T) {
 		assert.Equal(t, "chat:2024", res.IState[common.WHISPER_STREAM_STATE])
 	})
 
+	t.Run("Subscribe - public - wildcard", func(t *testing.T) {
+		conf := NewConfig()
+		conf.Public = true
+		conf.Wildcards = true
+		subject := NewStreamsController(&conf, slog.Default())
+
+		identifier := `{"channel":"$pubsub","stream_name":"chat:*"}`
+
+		res, err := subject.Subscribe("42", nil, "name=jack", identifier)
+
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		assert.Equal(t, common.FAILURE, res.Status)
+		assert.Equal(t, []string{common.RejectionMessage(identifier)}, res.Transmissions)
+	})
+
+	t.Run("Subscribe - public - wildcards disabled", func(t *testing.T) {
+		conf := NewConfig()
+		conf.Public = true
+		subject := NewStreamsController(&conf, slog.Default())
+
+		identifier := `{"channel":"$pubsub","stream_name":"chat:*"}`
+
+		res, err := subject.Subscribe("42", nil, "name=jack", identifier)
+
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		assert.Equal(t, common.SUCCESS, res.Status)
+		assert.Equal(t, []string{"chat:*"}, res.Streams)
+		assert.Equal(t, "chat:*", res.IState[common.WHISPER_STREAM_STATE])
+	})
+
 	t.Run("Subscribe - no public allowed", func(t *testing.T) {
 		conf := NewConfig()
 		subject := NewStreamsController(&conf, slog.Default())
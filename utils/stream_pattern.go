@@ -0,0 +1,153 @@
+package utils
+
+import "strings"
+
+const (
+	// StreamWildcard matches exactly one token of the stream name
+	StreamWildcard = "*"
+	// StreamTailWildcard matches one or more trailing tokens of the stream name
+	StreamTailWildcard = ">"
+)
+
+// StreamPatternDelimiter returns the token delimiter used by the wildcard stream pattern
+// (':' for "project:42:*" or '.' for "project.42.>").
+// Returns false if the stream name is not a pattern (i.e., contains no wildcard tokens).
+func StreamPatternDelimiter(stream string) (byte, bool) {
+	for i := 0; i < len(stream); i++ {
+		c := stream[i]
+
+		if c != '*' && c != '>' {
+			continue
+		}
+
+		last := i == len(stream)-1
+
+		// Tail wildcard could only be the last token
+		if c == '>' && !last {
+			continue
+		}
+
+		if i == 0 {
+			if !last && isStreamDelimiter(stream[1]) && c == '*' {
+				return stream[1], true
+			}
+
+			continue
+		}
+
+		delim := stream[i-1]
+
+		if !isStreamDelimiter(delim) {
+			continue
+		}
+
+		if last || stream[i+1] == delim {
+			return delim, true
+		}
+	}
+
+	return 0, false
+}
+
+// IsStreamPattern returns true if the stream name contains wildcard tokens
+func IsStreamPattern(stream string) bool {
+	_, ok := StreamPatternDelimiter(stream)
+	return ok
+}
+
+func isStreamDelimiter(c byte) bool {
+	return c == ':' || c == '.'
+}
+
+// MatchStreamPattern matches stream names against glob patterns,
+// where "*" matches any sequence of characters and "?" matches any single character
+func MatchStreamPattern(pattern string, name string) bool {
+	if !strings.ContainsAny(pattern, "*?") {
+		return pattern == name
+	}
+
+	// Fast path for prefix patterns
+	if strings.HasSuffix(pattern, "*") && !strings.ContainsAny(pattern[:len(pattern)-1], "*?") {
+		return strings.HasPrefix(name, pattern[:len(pattern)-1])
+	}
+
+	// Iterative glob matching with backtracking to the last star
+	p, n := 0, 0
+	star, match := -1, 0
+
+	for n < len(name) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == name[n]):
+			p++
+			n++
+		case p < len(pattern) && pattern[p] == '*':
+			star = p
+			match = n
+			p++
+		case star != -1:
+			p = star + 1
+			match++
+			n = match
+		default:
+			return false
+		}
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern)
+}
+
+// MatchWildcardStream matches the stream name against the wildcard pattern (see StreamPatternDelimiter)
+func MatchWildcardStream(pattern string, stream string) bool {
+	delim, ok := StreamPatternDelimiter(pattern)
+
+	if !ok {
+		return pattern == stream
+	}
+
+	patternTokens := strings.Split(pattern, string(delim))
+	tokens := strings.Split(stream, string(delim))
+
+	for i, token := range patternTokens {
+		if token == StreamTailWildcard && i == len(patternTokens)-1 {
+			return len(tokens) > i
+		}
+
+		if i >= len(tokens) {
+			return false
+		}
+
+		if token != StreamWildcard && token != tokens[i] {
+			return false
+		}
+	}
+
+	return len(tokens) == len(patternTokens)
+}
+
+// StreamPatternGlob converts a wildcard stream into a Redis-compatible glob-style pattern.
+// Glob wildcards match any characters (including delimiters), so the results must be filtered via MatchWildcardStream.
+func StreamPatternGlob(pattern string) string {
+	delim, ok := StreamPatternDelimiter(pattern)
+
+	if !ok {
+		return globEscaper.Replace(pattern)
+	}
+
+	tokens := strings.Split(pattern, string(delim))
+
+	for i, token := range tokens {
+		if token == StreamWildcard || (token == StreamTailWildcard && i == len(tokens)-1) {
+			tokens[i] = "*"
+		} else {
+			tokens[i] = globEscaper.Replace(token)
+		}
+	}
+
+	return strings.Join(tokens, string(delim))
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchStreamPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"chat", "chat", true},
+		{"chat", "chat:1", false},
+		{"chat:*", "chat:1", true},
+		{"chat:*", "chat:", true},
+		{"chat:*", "chats", false},
+		{"*", "anything", true},
+		{"*:cursors", "project:1:cursors", true},
+		{"*:cursors", "project:1:cursors:2", false},
+		{"project:*:cursors", "project:1:cursors", true},
+		{"project:*:cursors", "project:1:tasks", false},
+		{"room:?", "room:1", true},
+		{"room:?", "room:10", false},
+		{"a*b*c", "aXbYbZc", true},
+		{"a*b*c", "aXbYbZ", false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.match, MatchStreamPattern(c.pattern, c.name), "pattern: %s, name: %s", c.pattern, c.name)
+	}
+}
+
+func TestStreamPatternDelimiter(t *testing.T) {
+	cases := []struct {
+		stream string
+		delim  byte
+		ok     bool
+	}{
+		{"chat", 0, false},
+		{"project:42:*", ':', true},
+		{"project:*:cursors", ':', true},
+		{"project.42.>", '.', true},
+		{"project.*.tasks.>", '.', true},
+		{"*:cursors", ':', true},
+		{"project.42:*", ':', true},
+		{"project:42*", 0, false},
+		{"project:>:tasks", 0, false},
+		{"*", 0, false},
+		{">", 0, false},
+		{"a->b", 0, false},
+	}
+
+	for _, c := range cases {
+		delim, ok := StreamPatternDelimiter(c.stream)
+
+		assert.Equal(t, c.ok, ok, "stream: %s", c.stream)
+		assert.Equal(t, c.delim, delim, "stream: %s", c.stream)
+	}
+}
+
+func TestMatchWildcardStream(t *testing.T) {
+	cases := []struct {
+		pattern string
+		stream  string
+		match   bool
+	}{
+		{"chat", "chat", true},
+		{"chat", "chats", false},
+		{"project:42:*", "project:42:tasks", true},
+		{"project:42:*", "project:42:tasks:1", false},
+		{"project:42:*", "project:42", false},
+		{"project:*:cursors", "project:1:cursors", true},
+		{"project:*:cursors", "project:1:tasks", false},
+		{"project.42.>", "project.42.tasks", true},
+		{"project.42.>", "project.42.tasks.1", true},
+		{"project.42.>", "project.42", false},
+		{"project.42:*", "project.42:tasks", true},
+		{"*:cursors", "doc:cursors", true},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.match, MatchWildcardStream(c.pattern, c.stream), "pattern: %s, stream: %s", c.pattern, c.stream)
+	}
+}
+
+func TestStreamPatternGlob(t *testing.T) {
+	assert.Equal(t, "project:42:*", StreamPatternGlob("project:42:*"))
+	assert.Equal(t, "project.*.tasks.*", StreamPatternGlob("project.*.tasks.>"))
+	assert.Equal(t, `chat\?:*`, StreamPatternGlob("chat?:*"))
+	assert.Equal(t, `chat\*`, StreamPatternGlob("chat*"))
+}